@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// ABIProvider supplies the contract ABIs the client packs calls and decodes
+// results with, so a fork whose contracts differ slightly can bring its own.
+// The method and event names the client uses must still be present.
+type ABIProvider interface {
+	PoolABI() *abi.ABI
+	NPMABI() *abi.ABI
+	FactoryABI() *abi.ABI
+	ERC20ABI() *abi.ABI
+}
+
+// ABIs is an ABIProvider with fixed ABIs. Nil fields fall back to the
+// built-in Uniswap V3 ABIs, so ABIs{} is the default provider.
+type ABIs struct {
+	Pool    *abi.ABI
+	NPM     *abi.ABI
+	Factory *abi.ABI
+	ERC20   *abi.ABI
+}
+
+func (a ABIs) PoolABI() *abi.ABI    { return abiOr(a.Pool, &poolABI) }
+func (a ABIs) NPMABI() *abi.ABI     { return abiOr(a.NPM, &npmABI) }
+func (a ABIs) FactoryABI() *abi.ABI { return abiOr(a.Factory, &factoryABI) }
+func (a ABIs) ERC20ABI() *abi.ABI   { return abiOr(a.ERC20, &erc20ABI) }
+
+func abiOr(custom, builtin *abi.ABI) *abi.ABI {
+	if custom != nil {
+		return custom
+	}
+
+	return builtin
+}
+
+// WithABIProvider replaces the built-in ABIs, e.g. for a fork.
+func WithABIProvider(provider ABIProvider) Option {
+	return func(o *clientOptions) {
+		o.abis = provider
+	}
+}
+
+// readABIs is the provider of -pool-abi and -npm-abi: the ABIs of the JSON
+// files at the paths that aren't empty, and the built-in ones otherwise.
+func readABIs(poolPath, npmPath string) (ABIs, error) {
+	var abis ABIs
+	for _, f := range []struct {
+		path   string
+		parsed **abi.ABI
+	}{
+		{poolPath, &abis.Pool},
+		{npmPath, &abis.NPM},
+	} {
+		if f.path == "" {
+			continue
+		}
+		file, err := os.Open(f.path)
+		if err != nil {
+			return ABIs{}, err
+		}
+		parsed, err := abi.JSON(file)
+		file.Close()
+		if err != nil {
+			return ABIs{}, fmt.Errorf("parse %s: %w", f.path, err)
+		}
+		*f.parsed = &parsed
+	}
+
+	return abis, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// positionsOutputs are the outputs of positions(bytes32) in abiUniV3Pool.
+const positionsOutputs = `"outputs":[{"internalType":"uint128","name":"liquidity","type":"uint128"},{"internalType":"uint256","name":"feeGrowthInside0LastX128","type":"uint256"},{"internalType":"uint256","name":"feeGrowthInside1LastX128","type":"uint256"},{"internalType":"uint128","name":"tokensOwed0","type":"uint128"},{"internalType":"uint128","name":"tokensOwed1","type":"uint128"}]`
+
+// forkPoolABI is the pool ABI of a fork whose positions returns the owed
+// tokens before the fee growth.
+var forkPoolABI = strings.Replace(abiUniV3Pool, positionsOutputs, `"outputs":[{"internalType":"uint128","name":"liquidity","type":"uint128"},{"internalType":"uint128","name":"tokensOwed0","type":"uint128"},{"internalType":"uint128","name":"tokensOwed1","type":"uint128"},{"internalType":"uint256","name":"feeGrowthInside0LastX128","type":"uint256"},{"internalType":"uint256","name":"feeGrowthInside1LastX128","type":"uint256"}]`, 1)
+
+// forkNode serves a fork pool whose positions returns liquidity 1000, owed
+// tokens 7 and 8 and no fee growth, and answers every other pool call with
+// zeros.
+func forkNode(t *testing.T) *fakeNode {
+	parsed := mustParseABI(forkPoolABI)
+
+	return &fakeNode{head: 100, handle: func(to common.Address, data []byte) ([]byte, error) {
+		if methodOf(t, parsed, data).Name == positionsMethod {
+			return packOutputs(t, parsed, positionsMethod, big.NewInt(1000), big.NewInt(7), big.NewInt(8), new(big.Int), new(big.Int)), nil
+		}
+		return make([]byte, 32), nil
+	}}
+}
+
+func TestABIs(t *testing.T) {
+	var defaults ABIs
+	if defaults.PoolABI() != &poolABI || defaults.NPMABI() != &npmABI || defaults.FactoryABI() != &factoryABI || defaults.ERC20ABI() != &erc20ABI {
+		t.Error("ABIs{} doesn't fall back to the built-in ABIs")
+	}
+
+	custom := mustParseABI(forkPoolABI)
+	if got := (ABIs{Pool: &custom}).PoolABI(); got != &custom {
+		t.Error("ABIs{Pool} doesn't use the custom pool ABI")
+	}
+}
+
+func TestWithABIProvider(t *testing.T) {
+	if !strings.Contains(abiUniV3Pool, positionsOutputs) {
+		t.Fatal("positionsOutputs is not in the pool ABI")
+	}
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+
+	// the built-in ABI reads the owed tokens as fee growth
+	position, err := newFakeClient(t, forkNode(t)).Position(context.Background(), q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position.TokensOwed0.Sign() != 0 || position.FeeGrowthInside0LastX128.Int64() != 7 {
+		t.Errorf("built-in ABI position = %+v", position)
+	}
+
+	custom := mustParseABI(forkPoolABI)
+	client := newFakeClient(t, forkNode(t), WithABIProvider(ABIs{Pool: &custom}))
+	if position, err = client.Position(context.Background(), q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if position.Liquidity.Int64() != 1000 || position.TokensOwed0.Int64() != 7 || position.TokensOwed1.Int64() != 8 || position.FeeGrowthInside0LastX128.Sign() != 0 {
+		t.Errorf("position = %+v, want owed tokens 7 and 8", position)
+	}
+}
+
+func TestReadABIs(t *testing.T) {
+	dir := t.TempDir()
+	poolPath := filepath.Join(dir, "pool.json")
+	if err := os.WriteFile(poolPath, []byte(forkPoolABI), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	abis, err := readABIs(poolPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if abis.NPM != nil || abis.PoolABI().Methods[positionsMethod].Outputs[1].Name != "tokensOwed0" {
+		t.Errorf("abis = %+v, want the fork's pool ABI alone", abis)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{bad, filepath.Join(dir, "missing.json")} {
+		if _, err := readABIs("", path); err == nil {
+			t.Errorf("readABIs(%s) succeeded", path)
+		}
+	}
+}
+
+func TestRunPoolABI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool.json")
+	if err := os.WriteFile(path, []byte(forkPoolABI), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runOutput(t, "-node", forkNode(t).serve(t), "-max-lag", "0", "-pool-abi", path, "-format", "json")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"tokensOwed0": "7"`) || !strings.Contains(stdout, `"tokensOwed1": "8"`) {
+		t.Errorf("output lacks the owed tokens:\n%s", stdout)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ActivityEvent is a decoded pool log that changed a position's liquidity or
+// moved the price through its range.
+type ActivityEvent struct {
+	// Kind is the event name: Mint, Burn or Swap.
+	Kind string `json:"kind"`
+	// LiquidityDelta is the liquidity a Mint added (positive) or a Burn
+	// removed (negative); nil for swaps.
+	LiquidityDelta *big.Int `json:"liquidityDelta,omitempty"`
+	// Amount0 and Amount1 are the token amounts of the event. For swaps they
+	// are signed from the pool's side: positive means the pool received.
+	Amount0 *big.Int `json:"amount0"`
+	Amount1 *big.Int `json:"amount1"`
+	// SqrtPriceX96 and Tick are the pool price after a swap.
+	SqrtPriceX96 *big.Int `json:"sqrtPriceX96,omitempty"`
+	Tick         Tick     `json:"tick,omitempty"`
+
+	BlockNumber uint64      `json:"blockNumber"`
+	TxHash      common.Hash `json:"txHash"`
+	LogIndex    uint        `json:"logIndex"`
+}
+
+// PositionActivity returns, in chain order, the owner's Mint and Burn events
+// on [tickLower, tickUpper) and the pool's Swap events that ended in the range
+// or crossed it since the previous swap, in [fromBlock, toBlock]. Nil bounds
+// mean genesis and latest.
+//
+// Swaps can't be filtered by tick on the node, so every swap of the pool in
+// the block range is fetched; keep the range short on busy pools.
+func (c *Client) PositionActivity(ctx context.Context, pool, owner common.Address, tickLower, tickUpper Tick, fromBlock, toBlock *big.Int) ([]ActivityEvent, error) {
+	poolABI := c.abis.PoolABI()
+	mint, burn, swap := poolABI.Events[mintEvent], poolABI.Events[burnEvent], poolABI.Events[swapEvent]
+
+	// Mint and Burn index owner, tickLower and tickUpper in the same topics
+	positionLogs, err := c.eth.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+		Addresses: []common.Address{pool},
+		Topics: [][]common.Hash{
+			{mint.ID, burn.ID},
+			{common.BytesToHash(owner.Bytes())},
+			{int24Topic(tickLower)},
+			{int24Topic(tickUpper)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filter mint and burn logs: %w", err)
+	}
+
+	swapLogs, err := c.eth.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+		Addresses: []common.Address{pool},
+		Topics:    [][]common.Hash{{swap.ID}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filter swap logs: %w", err)
+	}
+
+	events := make([]ActivityEvent, 0, len(positionLogs))
+	for _, l := range positionLogs {
+		e, err := c.decodeLiquidityChange(l, owner, tickLower, tickUpper)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	var prevTick *Tick
+	for _, l := range swapLogs {
+		e, err := c.decodeSwap(l)
+		if err != nil {
+			return nil, err
+		}
+
+		from := e.Tick
+		if prevTick != nil {
+			from = *prevTick
+		}
+		prevTick = &e.Tick
+
+		if min(from, e.Tick) < tickUpper && max(from, e.Tick) >= tickLower {
+			events = append(events, e)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
+		}
+		return events[i].LogIndex < events[j].LogIndex
+	})
+
+	return events, nil
+}
+
+// writeActivity prints events as a table, or as JSON.
+func writeActivity(w io.Writer, format string, events []ActivityEvent) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(events)
+	case formatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, e := range events {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BLOCK\tEVENT\tLIQUIDITY\tAMOUNT0\tAMOUNT1\tTICK")
+	for _, e := range events {
+		liquidity, tick := "-", "-"
+		if e.LiquidityDelta != nil {
+			liquidity = e.LiquidityDelta.String()
+		}
+		if e.Kind == swapEvent {
+			tick = strconv.Itoa(int(e.Tick))
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", e.BlockNumber, e.Kind, liquidity, e.Amount0, e.Amount1, tick)
+	}
+
+	return tw.Flush()
+}
+
+// decodeLiquidityChange decodes a Mint or Burn log and checks its topics
+// name the position asked for, so a node ignoring topic filters can't slip
+// in another position's events.
+func (c *Client) decodeLiquidityChange(l types.Log, owner common.Address, tickLower, tickUpper Tick) (ActivityEvent, error) {
+	poolABI := c.abis.PoolABI()
+	if len(l.Topics) != 4 {
+		return ActivityEvent{}, fmt.Errorf("log %s:%d has %d topics", l.TxHash, l.Index, len(l.Topics))
+	}
+
+	event, err := poolABI.EventByID(l.Topics[0])
+	if err != nil || (event.Name != mintEvent && event.Name != burnEvent) {
+		return ActivityEvent{}, fmt.Errorf("log %s:%d is not a mint or burn", l.TxHash, l.Index)
+	}
+
+	if common.BytesToAddress(l.Topics[1].Bytes()) != owner || topicInt24(l.Topics[2]) != tickLower || topicInt24(l.Topics[3]) != tickUpper {
+		return ActivityEvent{}, fmt.Errorf("%s log %s:%d is for another position", event.Name, l.TxHash, l.Index)
+	}
+
+	var data struct {
+		Sender  common.Address // Mint only
+		Amount  *big.Int
+		Amount0 *big.Int
+		Amount1 *big.Int
+	}
+	if err := poolABI.UnpackIntoInterface(&data, event.Name, l.Data); err != nil {
+		return ActivityEvent{}, fmt.Errorf("parse %s log %s:%d: %w", event.Name, l.TxHash, l.Index, err)
+	}
+
+	delta := data.Amount
+	if event.Name == burnEvent {
+		delta = new(big.Int).Neg(delta)
+	}
+
+	return ActivityEvent{
+		Kind:           event.Name,
+		LiquidityDelta: delta,
+		Amount0:        data.Amount0,
+		Amount1:        data.Amount1,
+		BlockNumber:    l.BlockNumber,
+		TxHash:         l.TxHash,
+		LogIndex:       l.Index,
+	}, nil
+}
+
+func (c *Client) decodeSwap(l types.Log) (ActivityEvent, error) {
+	var data struct {
+		Amount0      *big.Int
+		Amount1      *big.Int
+		SqrtPriceX96 *big.Int
+		Liquidity    *big.Int
+		Tick         *big.Int
+	}
+	if err := c.abis.PoolABI().UnpackIntoInterface(&data, swapEvent, l.Data); err != nil {
+		return ActivityEvent{}, fmt.Errorf("parse swap log %s:%d: %w", l.TxHash, l.Index, err)
+	}
+
+	return ActivityEvent{
+		Kind:         swapEvent,
+		Amount0:      data.Amount0,
+		Amount1:      data.Amount1,
+		SqrtPriceX96: data.SqrtPriceX96,
+		Tick:         Tick(data.Tick.Int64()),
+		BlockNumber:  l.BlockNumber,
+		TxHash:       l.TxHash,
+		LogIndex:     l.Index,
+	}, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// liquidityLog is a pool Mint or Burn of amount liquidity for amount0 and
+// amount1 on the position of q.
+func liquidityLog(t *testing.T, name string, q PositionQuery, amount, amount0, amount1 int64, block uint64) types.Log {
+	t.Helper()
+
+	event := poolABI.Events[name]
+	values := []interface{}{big.NewInt(amount), big.NewInt(amount0), big.NewInt(amount1)}
+	if name == mintEvent {
+		values = append([]interface{}{q.Owner}, values...)
+	}
+	data, err := event.Inputs.NonIndexed().Pack(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return types.Log{
+		Address:     q.Pool,
+		Topics:      []common.Hash{event.ID, common.BytesToHash(q.Owner.Bytes()), int24Topic(q.TickLower), int24Topic(q.TickUpper)},
+		Data:        data,
+		BlockNumber: block,
+	}
+}
+
+// swapLog is a swap of testPool that left it at tick.
+func swapLog(t *testing.T, tick Tick, block uint64) types.Log {
+	t.Helper()
+
+	event := poolABI.Events[swapEvent]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(100), big.NewInt(-99), tick.SqrtRatio(), big.NewInt(1e18), big.NewInt(int64(tick)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return types.Log{
+		Address:     testPool,
+		Topics:      []common.Hash{event.ID, common.BytesToHash(testOwner.Bytes()), common.BytesToHash(testOwner.Bytes())},
+		Data:        data,
+		BlockNumber: block,
+	}
+}
+
+// activityNode has the Mint and Burn of testOwner's [-60, 60) position in
+// blocks 10 and 30, another position's Mint, and swaps in blocks 20-24 to
+// ticks 200, 100, 0, -200 and -300, of which the ones to 0 and -200 end in
+// or cross the range.
+func activityNode(t *testing.T) *fakeNode {
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	other := q
+	other.TickUpper = 120
+
+	return &fakeNode{head: 100, logs: []types.Log{
+		liquidityLog(t, mintEvent, q, 1000, 4, 5, 10),
+		liquidityLog(t, mintEvent, other, 7, 1, 1, 11),
+		swapLog(t, 200, 20),
+		swapLog(t, 100, 21),
+		swapLog(t, 0, 22),
+		swapLog(t, -200, 23),
+		swapLog(t, -300, 24),
+		liquidityLog(t, burnEvent, q, 400, 2, 2, 30),
+	}}
+}
+
+func TestPositionActivity(t *testing.T) {
+	client := newFakeClient(t, activityNode(t))
+
+	events, err := client.PositionActivity(context.Background(), testPool, testOwner, -60, 60, big.NewInt(0), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		kind  string
+		block uint64
+		delta int64
+	}{
+		{mintEvent, 10, 1000},
+		{swapEvent, 22, 0},
+		{swapEvent, 23, 0},
+		{burnEvent, 30, -400},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Kind != w.kind || e.BlockNumber != w.block {
+			t.Errorf("event %d = %s at %d, want %s at %d", i, e.Kind, e.BlockNumber, w.kind, w.block)
+		}
+		if w.kind != swapEvent && e.LiquidityDelta.Int64() != w.delta {
+			t.Errorf("event %d liquidity delta = %s, want %d", i, e.LiquidityDelta, w.delta)
+		}
+		if w.kind == swapEvent && (e.LiquidityDelta != nil || e.Amount1.Int64() != -99) {
+			t.Errorf("swap %d = %+v", i, e)
+		}
+	}
+	if events[2].Tick != -200 || events[2].SqrtPriceX96.Cmp(Tick(-200).SqrtRatio()) != 0 {
+		t.Errorf("swap price = %d, %s", events[2].Tick, events[2].SqrtPriceX96)
+	}
+}
+
+func TestDecodeLiquidityChangeOtherPosition(t *testing.T) {
+	client := newFakeClient(t, &fakeNode{head: 100})
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 120}
+
+	if _, err := client.decodeLiquidityChange(liquidityLog(t, mintEvent, q, 1, 1, 1, 1), testOwner, -60, 60); err == nil {
+		t.Error("want an error for another position's mint")
+	}
+}
+
+func TestRunActivity(t *testing.T) {
+	code, stdout, stderr := runOutput(t, "-node", activityNode(t).serve(t), "-max-lag", "0", "-activity", "0-25",
+		"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("output:\n%s", stdout)
+	}
+	for i, want := range [][]string{
+		{"10", "Mint", "1000", "4", "5", "-"},
+		{"22", "Swap", "-", "100", "-99", "0"},
+		{"23", "Swap", "-", "100", "-99", "-200"},
+	} {
+		if got := strings.Fields(lines[i+1]); strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("line %d = %q, want %q", i+1, got, want)
+		}
+	}
+}
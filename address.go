@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// parseAddress is a strict common.HexToAddress for user input. HexToAddress
+// pads short input and ignores letter case, so a truncated paste or a typo
+// silently becomes another address. Input must be 40 hex digits with an
+// optional 0x prefix, and mixed-case input must carry a valid EIP-55 checksum;
+// all-lowercase and all-uppercase input has no checksum to check.
+// https://eips.ethereum.org/EIPS/eip-55
+func parseAddress(s string) (common.Address, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(digits) != 2*common.AddressLength {
+		return common.Address{}, fmt.Errorf("address %q has %d hex digits, want %d (truncated copy?)", s, len(digits), 2*common.AddressLength)
+	}
+	if !common.IsHexAddress(digits) {
+		return common.Address{}, fmt.Errorf("address %q is not hex", s)
+	}
+
+	address := common.HexToAddress(digits)
+	if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) && "0x"+digits != address.Hex() {
+		return common.Address{}, fmt.Errorf("address %q has an invalid EIP-55 checksum, did you mean %s?", s, address.Hex())
+	}
+
+	return address, nil
+}
+
+// checkedAddress is an address read from JSON through parseAddress, so a
+// file gets the same checks and hints as a flag.
+type checkedAddress struct {
+	common.Address
+}
+
+func (a *checkedAddress) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("address must be a hex string: %w", err)
+	}
+
+	address, err := parseAddress(s)
+	if err != nil {
+		return err
+	}
+	a.Address = address
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// PositionAmounts is what a position held at a block.
+type PositionAmounts struct {
+	Block        *big.Int `json:"block"`
+	SqrtPriceX96 *big.Int `json:"sqrtPriceX96"`
+	Tick         Tick     `json:"tick"`
+	Liquidity    *big.Int `json:"liquidity"`
+	Amount0      *big.Int `json:"amount0"`
+	Amount1      *big.Int `json:"amount1"`
+}
+
+// PositionAmountsAt reads slot0 and the position at block in one batch and
+// returns the token amounts the position's liquidity was worth there,
+// excluding uncollected fees. Past blocks need an archive node; a node
+// without the state fails with an error matching ErrStateUnavailable.
+func (c *Client) PositionAmountsAt(ctx context.Context, q PositionQuery, block *big.Int) (PositionAmounts, error) {
+	if err := validateTicks(q.TickLower, q.TickUpper); err != nil {
+		return PositionAmounts{}, err
+	}
+
+	positionData, err := c.positionCalldata(q)
+	if err != nil {
+		return PositionAmounts{}, err
+	}
+	slot0Data, err := c.abis.PoolABI().Pack(slot0Method)
+	if err != nil {
+		return PositionAmounts{}, fmt.Errorf("pack slot0: %w", err)
+	}
+
+	responses, err := c.aggregate(ctx, []call{{Target: q.Pool, CallData: positionData}, {Target: q.Pool, CallData: slot0Data}}, block)
+	if err != nil {
+		return PositionAmounts{}, fmt.Errorf("read position at block %s: %w", blockString(block), err)
+	}
+
+	position, err := c.unpackPosition(responses[0])
+	if err != nil {
+		return PositionAmounts{}, err
+	}
+	out, err := c.abis.PoolABI().Unpack(slot0Method, responses[1])
+	if err != nil {
+		return PositionAmounts{}, fmt.Errorf("parse slot0 result: %w", err)
+	}
+
+	amounts := PositionAmounts{
+		Block:        block,
+		SqrtPriceX96: out[0].(*big.Int),
+		Tick:         Tick(out[1].(*big.Int).Int64()),
+		Liquidity:    position.Liquidity,
+	}
+	amounts.Amount0, amounts.Amount1 = AmountsForLiquidity(amounts.SqrtPriceX96, q.TickLower, q.TickUpper, position.Liquidity)
+
+	return amounts, nil
+}
+
+// writeAmounts prints a as lines, or as JSON.
+func writeAmounts(w io.Writer, format string, a PositionAmounts) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(a)
+	}
+
+	fmt.Fprintf(w, "block %s, tick %d\n", a.Block, a.Tick)
+	fmt.Fprintf(w, "liquidity: %s\n", a.Liquidity)
+	_, err := fmt.Fprintf(w, "amounts:   %s token0 %s token1\n", a.Amount0, a.Amount1)
+
+	return err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// amountsNode serves a pool at tick 0 holding 1e18 liquidity of testOwner
+// on [-60, 60), with state from block 50 on.
+func amountsNode(t *testing.T) *fakeNode {
+	pool := newFakePool(0, 1e18)
+	pool.setPosition(t, PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}, Position{Liquidity: big.NewInt(1e18)})
+
+	return &fakeNode{head: 100, prunedBelow: 50, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)}
+}
+
+func TestPositionAmountsAt(t *testing.T) {
+	client := newFakeClient(t, amountsNode(t))
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+
+	amounts, err := client.PositionAmountsAt(context.Background(), q, big.NewInt(80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amounts.Block.Int64() != 80 || amounts.Tick != 0 || amounts.Liquidity.Int64() != 1e18 {
+		t.Errorf("amounts = %+v", amounts)
+	}
+	// at price 1, L(1 - 1/sqrt(1.0001^60)) of each token
+	want := 1e18 * (1 - math.Pow(1.0001, -30))
+	for i, amount := range []*big.Int{amounts.Amount0, amounts.Amount1} {
+		if got := toFloat64(amount); math.Abs(got-want)/want > 1e-9 {
+			t.Errorf("amount%d = %s, want about %g", i, amount, want)
+		}
+	}
+
+	if _, err := client.PositionAmountsAt(context.Background(), q, big.NewInt(10)); !errors.Is(err, ErrStateUnavailable) {
+		t.Errorf("err at a pruned block = %v, want ErrStateUnavailable", err)
+	}
+	q.TickLower, q.TickUpper = 60, -60
+	if _, err := client.PositionAmountsAt(context.Background(), q, nil); err == nil {
+		t.Error("want an error for inverted ticks")
+	}
+}
+
+func TestRunAmounts(t *testing.T) {
+	node := amountsNode(t)
+	url := node.serve(t)
+	args := []string{"-node", url, "-max-lag", "0", "-amounts", "-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60"}
+
+	code, stdout, stderr := runOutput(t, append(args, "-block", "80")...)
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "block 80, tick 0\nliquidity: 1000000000000000000\namounts:   2995") {
+		t.Errorf("output:\n%s", stdout)
+	}
+
+	code, _, stderr = runOutput(t, append(args, "-block", "10")...)
+	if code != exitNode || !strings.Contains(stderr, "archive node") {
+		t.Errorf("pruned block: exit code = %d, want %d; stderr:\n%s", code, exitNode, stderr)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"time"
+)
+
+const year = 365 * 24 * time.Hour
+
+// EstimateAPR annualizes earned over principal across elapsed, as a fraction:
+// 0.12 is 12% a year. Both amounts must be in the same unit.
+func EstimateAPR(earned, principal *big.Int, elapsed time.Duration) (*big.Float, error) {
+	if principal.Sign() <= 0 {
+		return nil, errors.New("principal must be positive")
+	}
+	if elapsed <= 0 {
+		return nil, errors.New("elapsed must be positive")
+	}
+
+	apr := new(big.Float).SetInt(earned)
+	apr.Quo(apr, new(big.Float).SetInt(principal))
+	apr.Mul(apr, big.NewFloat(float64(year)/float64(elapsed)))
+
+	return apr, nil
+}
+
+// ReinvestedAPR compounds apr, a simple APR as EstimateAPR returns it, for
+// fees collected and added back to the position every period:
+// (1 + apr * period / year)^(year / period) - 1. It assumes the position
+// earns apr all year long: the price stays in range, volume and the fee tier
+// hold, reinvested fees earn like the rest of the liquidity, and the gas and
+// the swap putting the fees in the range's token ratio cost nothing. A period
+// of a year or more is the simple apr.
+func ReinvestedAPR(apr *big.Float, period time.Duration) (*big.Float, error) {
+	if period <= 0 {
+		return nil, errors.New("reinvest period must be positive")
+	}
+	if period >= year {
+		return new(big.Float).Set(apr), nil
+	}
+
+	simple, _ := apr.Float64()
+	periods := float64(year) / float64(period)
+	compounded := math.Pow(1+simple/periods, periods) - 1
+	if math.IsInf(compounded, 0) || math.IsNaN(compounded) {
+		return nil, errors.New("reinvested APR overflows")
+	}
+
+	return big.NewFloat(compounded), nil
+}
+
+// valueInToken1 prices amount0 at sqrtPriceX96 and adds amount1, in raw
+// token1 units.
+func valueInToken1(amount0, amount1, sqrtPriceX96 *big.Int) *big.Int {
+	priceX192 := new(big.Int).Mul(sqrtPriceX96, sqrtPriceX96)
+	value := mulDiv(amount0, priceX192, new(big.Int).Lsh(big.NewInt(1), 192))
+
+	return value.Add(value, amount1)
+}
+
+type aprSample struct {
+	time      uint64
+	liquidity *big.Int
+	fees0     *big.Int
+	fees1     *big.Int
+}
+
+// rollingAPR is the fee APR of a position over a trailing window: the fees
+// earned since the oldest sample in the window, valued in token1 at the
+// current price, against what the position's liquidity is worth now.
+// Collecting or changing liquidity restarts the window.
+type rollingAPR struct {
+	window    time.Duration
+	tickLower Tick
+	tickUpper Tick
+	samples   []aprSample
+}
+
+// add records fees read at a block with timestamp blockTime (unix seconds)
+// and returns the APR over the window, or false while there is not enough
+// history yet.
+func (r *rollingAPR) add(blockTime uint64, fees PositionFees) (*big.Float, bool) {
+	if n := len(r.samples); n > 0 {
+		last := r.samples[n-1]
+		if last.liquidity.Cmp(fees.Liquidity) != 0 || fees.Fees0.Cmp(last.fees0) < 0 || fees.Fees1.Cmp(last.fees1) < 0 {
+			r.samples = r.samples[:0]
+		}
+	}
+	r.samples = append(r.samples, aprSample{time: blockTime, liquidity: fees.Liquidity, fees0: fees.Fees0, fees1: fees.Fees1})
+
+	cutoff := int64(blockTime) - int64(r.window/time.Second)
+	for len(r.samples) > 1 && int64(r.samples[1].time) <= cutoff {
+		r.samples = r.samples[1:]
+	}
+
+	base := r.samples[0]
+	if base.time >= blockTime {
+		return nil, false
+	}
+
+	earned := valueInToken1(new(big.Int).Sub(fees.Fees0, base.fees0), new(big.Int).Sub(fees.Fees1, base.fees1), fees.SqrtPriceX96)
+	amount0, amount1 := AmountsForLiquidity(fees.SqrtPriceX96, r.tickLower, r.tickUpper, fees.Liquidity)
+	principal := valueInToken1(amount0, amount1, fees.SqrtPriceX96)
+
+	apr, err := EstimateAPR(earned, principal, time.Duration(blockTime-base.time)*time.Second)
+	if err != nil {
+		return nil, false
+	}
+
+	return apr, true
+}
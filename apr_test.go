@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestEstimateAPR(t *testing.T) {
+	for _, tc := range []struct {
+		earned, principal int64
+		elapsed           time.Duration
+		want              float64
+	}{
+		{12, 100, year, 0.12},
+		{6, 100, year / 2, 0.12},
+		{1, 1000, 24 * time.Hour, 0.365},
+		{0, 1, time.Second, 0},
+	} {
+		apr, err := EstimateAPR(big.NewInt(tc.earned), big.NewInt(tc.principal), tc.elapsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := apr.Float64(); !closeTo(got, tc.want) {
+			t.Errorf("EstimateAPR(%d, %d, %s) = %g, want %g", tc.earned, tc.principal, tc.elapsed, got, tc.want)
+		}
+	}
+
+	if _, err := EstimateAPR(big.NewInt(1), big.NewInt(0), year); err == nil {
+		t.Error("accepted a zero principal")
+	}
+	if _, err := EstimateAPR(big.NewInt(1), big.NewInt(1), 0); err == nil {
+		t.Error("accepted a zero elapsed time")
+	}
+}
+
+func closeTo(got, want float64) bool {
+	return got == want || (got-want)/want < 1e-9 && (want-got)/want < 1e-9
+}
+
+func TestRollingAPR(t *testing.T) {
+	liquidity := big.NewInt(1e18)
+	r := &rollingAPR{window: time.Hour, tickLower: -60, tickUpper: 60}
+	amount0, amount1 := AmountsForLiquidity(q96, -60, 60, liquidity)
+	principal, _ := new(big.Float).SetInt(new(big.Int).Add(amount0, amount1)).Float64()
+
+	apr := func(seconds, fees0, fees1 int64) (float64, bool) {
+		fees := PositionFees{Liquidity: liquidity, SqrtPriceX96: q96, InRange: true, Fees0: big.NewInt(fees0), Fees1: big.NewInt(fees1)}
+		got, ok := r.add(uint64(seconds), fees)
+		if !ok {
+			return 0, false
+		}
+		f, _ := got.Float64()
+		return f, true
+	}
+
+	if _, ok := apr(1000, 0, 0); ok {
+		t.Error("an APR from a single snapshot")
+	}
+	// 1e9 of each token per second, both worth the same at price 1
+	if got, ok := apr(1600, 600e9, 600e9); !ok || !closeTo(got, 2e9*float64(year/time.Second)/principal) {
+		t.Errorf("APR after 10 minutes = %g, %t", got, ok)
+	}
+	// the snapshot at 1600 starts the window now, and the one at 1000 is
+	// dropped; 3e9 of token1 a second since then
+	if got, ok := apr(5200, 600e9, 600e9+3600*3e9); !ok || !closeTo(got, 3e9*float64(year/time.Second)/principal) {
+		t.Errorf("APR over the window = %g, %t", got, ok)
+	}
+
+	// a collect restarts the window
+	if _, ok := apr(5212, 0, 0); ok {
+		t.Error("an APR right after the fees were collected")
+	}
+	if got, ok := apr(5224, 12e9, 0); !ok || !closeTo(got, 1e9*float64(year/time.Second)/principal) {
+		t.Errorf("APR after a collect = %g, %t", got, ok)
+	}
+}
+
+func TestReinvestedAPR(t *testing.T) {
+	for _, tc := range []struct {
+		apr    float64
+		period time.Duration
+		want   float64
+	}{
+		// 0.1% a day, 365 times
+		{0.365, 24 * time.Hour, math.Pow(1.001, 365) - 1},
+		{0.12, year / 2, 0.1236},
+		{0.12, year, 0.12},
+		{0.12, 2 * year, 0.12},
+		{0, time.Hour, 0},
+	} {
+		got, err := ReinvestedAPR(big.NewFloat(tc.apr), tc.period)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f, _ := got.Float64(); !closeTo(f, tc.want) {
+			t.Errorf("ReinvestedAPR(%g, %s) = %g, want %g", tc.apr, tc.period, f, tc.want)
+		}
+	}
+
+	if _, err := ReinvestedAPR(big.NewFloat(0.1), 0); err == nil {
+		t.Error("accepted a zero period")
+	}
+	if _, err := ReinvestedAPR(big.NewFloat(1e6), time.Second); err == nil {
+		t.Error("accepted an overflowing APR")
+	}
+}
+
+func TestRunReinvest(t *testing.T) {
+	pool := newFakePool(0, 1e18)
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	pool.setPosition(t, q, Position{Liquidity: big.NewInt(1e18)})
+	pools := poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)
+	// a millionth of a token0 per unit of liquidity in the window
+	node := &fakeNode{head: 100}
+	node.handle = func(to common.Address, data []byte) ([]byte, error) {
+		node.mu.Lock()
+		latest := node.blocks[len(node.blocks)-1] == "0x64"
+		node.mu.Unlock()
+
+		pool.global0 = new(big.Int)
+		if latest {
+			pool.global0.Quo(q128, big.NewInt(1e6))
+		}
+		return pools(to, data)
+	}
+
+	code, stdout, stderr := runOutput(t, "-since", "7m", "-reinvest", "1d", "-format", "json", "-price-precision", "12", "-node", node.serve(t), "-max-lag", "0",
+		"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	var out struct {
+		APR             string `json:"apr"`
+		ReinvestSeconds uint64 `json:"reinvestSeconds"`
+		ReinvestedAPR   string `json:"reinvestedApr"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, stdout)
+	}
+	apr, _ := strconv.ParseFloat(out.APR, 64)
+	reinvested, _ := strconv.ParseFloat(out.ReinvestedAPR, 64)
+	if want := math.Pow(1+apr/365, 365) - 1; apr <= 0 || out.ReinvestSeconds != 86400 || math.Abs(reinvested-want) > want*1e-8 {
+		t.Errorf("output = %+v, want the APR of %g compounded daily: %g", out, apr, want)
+	}
+
+	if code, _, _ := runOutput(t, "-reinvest", "1d"); code != exitBadInput {
+		t.Errorf("-reinvest without -since: exit code = %d, want %d", code, exitBadInput)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Block numbers on Arbitrum
+//
+// An Arbitrum chain has its own L2 block numbers, and those are what the JSON-RPC
+// block parameter of eth_call, eth_getBlockByNumber and eth_blockNumber
+// means. Inside a contract, block.number returns an approximate L1 (Ethereum)
+// block number instead, shared by many consecutive L2 blocks. So -block takes
+// an L2 number, and an L1 number has to be mapped to L2 blocks first, which the
+// NodeInterface precompile does.
+// https://docs.arbitrum.io/build-decentralized-apps/arbitrum-vs-ethereum/block-numbers-and-time
+// https://docs.arbitrum.io/build-decentralized-apps/nodeinterface/reference
+
+const (
+	abiNodeInterface   = `[{"inputs":[{"internalType":"uint64","name":"l2BlockNum","type":"uint64"}],"name":"blockL1Num","outputs":[{"internalType":"uint64","name":"l1BlockNum","type":"uint64"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"uint64","name":"blockNum","type":"uint64"}],"name":"l2BlockRangeForL1","outputs":[{"internalType":"uint64","name":"firstBlock","type":"uint64"},{"internalType":"uint64","name":"lastBlock","type":"uint64"}],"stateMutability":"view","type":"function"}]`
+	blockL1NumMethod   = "blockL1Num"
+	l2BlockRangeMethod = "l2BlockRangeForL1"
+)
+
+// NodeInterface is not deployed code: Arbitrum nodes serve eth_calls to this
+// address themselves.
+var nodeInterfaceAddress = common.HexToAddress("0x00000000000000000000000000000000000000C8")
+
+var nodeInterfaceABI = mustParseABI(abiNodeInterface)
+
+// L1BlockNumber returns the L1 block number that block.number reported
+// during L2 block l2Block.
+func (c *Client) L1BlockNumber(ctx context.Context, l2Block uint64) (uint64, error) {
+	out, err := c.callNodeInterface(ctx, blockL1NumMethod, l2Block)
+	if err != nil {
+		return 0, err
+	}
+
+	return out[0].(uint64), nil
+}
+
+// L2BlockRange returns the first and last L2 blocks whose block.number is
+// l1Block.
+func (c *Client) L2BlockRange(ctx context.Context, l1Block uint64) (first, last uint64, err error) {
+	out, err := c.callNodeInterface(ctx, l2BlockRangeMethod, l1Block)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return out[0].(uint64), out[1].(uint64), nil
+}
+
+func (c *Client) callNodeInterface(ctx context.Context, method string, args ...interface{}) ([]interface{}, error) {
+	ch, err := c.chain(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ch.Name != "arbitrum" {
+		return nil, fmt.Errorf("%s: NodeInterface only exists on Arbitrum, node is on %s", method, ch.Name)
+	}
+
+	calldata, err := nodeInterfaceABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("pack %s: %w", method, err)
+	}
+
+	response, err := c.callContract(ctx, ethereum.CallMsg{To: &nodeInterfaceAddress, Data: calldata}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("call %s: %w", method, err)
+	}
+
+	out, err := nodeInterfaceABI.Unpack(method, response)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", method, err)
+	}
+
+	return out, nil
+}
+
+// readBlock picks the block one-shot reads run at. l2Block is an L2 block number
+// on Arbitrum and a plain block number elsewhere; l1Block resolves to the last
+// L2 block of that L1 block. Zero values mean unset, and with everything unset
+// the reads stay at latest.
+func (c *Client) readBlock(ctx context.Context, l2Block, l1Block, confirmations uint64) (*big.Int, error) {
+	switch {
+	case l2Block != 0:
+		return new(big.Int).SetUint64(l2Block), nil
+	case l1Block != 0:
+		_, last, err := c.L2BlockRange(ctx, l1Block)
+		if err != nil {
+			return nil, fmt.Errorf("map L1 block %d: %w", l1Block, err)
+		}
+		return new(big.Int).SetUint64(last), nil
+	}
+
+	return c.ConfirmedBlock(ctx, confirmations)
+}
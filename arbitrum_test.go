@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// arbitrumNode is positionNode with NodeInterface, where L1 block l1Block
+// spans the L2 blocks first to last. It records the L1 blocks it maps.
+func arbitrumNode(t *testing.T, l1Block, first, last uint64, mapped *[]uint64) *fakeNode {
+	node := positionNode(t, 1000)
+	pools := node.handle
+	node.handle = func(to common.Address, data []byte) ([]byte, error) {
+		if to != nodeInterfaceAddress {
+			return pools(to, data)
+		}
+		method := methodOf(t, nodeInterfaceABI, data)
+		in, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch method.Name {
+		case l2BlockRangeMethod:
+			*mapped = append(*mapped, in[0].(uint64))
+			if in[0].(uint64) != l1Block {
+				return nil, errRevert{}
+			}
+			return packOutputs(t, nodeInterfaceABI, l2BlockRangeMethod, first, last), nil
+		case blockL1NumMethod:
+			return packOutputs(t, nodeInterfaceABI, blockL1NumMethod, l1Block), nil
+		}
+		return nil, errRevert{}
+	}
+
+	return node
+}
+
+func TestReadBlockL2Number(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		// block is the tag of every position read
+		block string
+		// mapped are the L1 blocks sent to NodeInterface
+		mapped []uint64
+	}{
+		// -block is sent as is, never mapped from L1
+		{"block", []string{"-block", "80"}, "0x50", nil},
+		{"block verbose", []string{"-block", "80", "-verbose"}, "0x50", nil},
+		// the last L2 block of the L1 block
+		{"l1 block", []string{"-l1-block", "5000"}, "0x4b", []uint64{5000}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var mapped []uint64
+			node := arbitrumNode(t, 5000, 70, 75, &mapped)
+
+			code, _, stderr := runOutput(t, append(tc.args, "-node", node.serve(t), "-max-lag", "0")...)
+			if code != exitOK {
+				t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+			}
+
+			reads := 0
+			for i, block := range node.blocks {
+				// NodeInterface answers for the head
+				if block == "latest" {
+					continue
+				}
+				reads++
+				if block != tc.block {
+					t.Errorf("call %d read block %s, want %s", i, block, tc.block)
+				}
+			}
+			if reads == 0 {
+				t.Errorf("no call read block %s: %q", tc.block, node.blocks)
+			}
+			if len(mapped) != len(tc.mapped) || len(mapped) > 0 && mapped[0] != tc.mapped[0] {
+				t.Errorf("mapped L1 blocks %v, want %v", mapped, tc.mapped)
+			}
+		})
+	}
+}
+
+func TestReadBlockUnknownL1Block(t *testing.T) {
+	var mapped []uint64
+	node := arbitrumNode(t, 5000, 70, 75, &mapped)
+
+	if code, _, stderr := runOutput(t, "-l1-block", "6000", "-node", node.serve(t), "-max-lag", "0"); code == exitOK {
+		t.Errorf("exit code %d for an L1 block NodeInterface can't map; stderr:\n%s", code, stderr)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Node backends.
+const (
+	BackendRPC     = "rpc"
+	BackendArchive = "archive"
+)
+
+// An archive directory holds captured node responses so the tool can run
+// fully offline:
+//
+//	<dir>/chain.json             {"chainId": 42161, "blockNumber": 250000000}
+//	<dir>/calls/<to>/<key>.hex   hex return data of eth_call{to, input}
+//
+// <to> is the lower-case 0x address of the called contract and <key> is the
+// lower-case 0x keccak256 of the calldata. Calls are answered whatever block
+// they ask for. The archive reports no contract code, so batches are replayed
+// as one eth_call per read instead of through Multicall3.
+const (
+	archiveChainFile = "chain.json"
+	archiveCallsDir  = "calls"
+)
+
+type archiveChain struct {
+	ChainID     uint64 `json:"chainId"`
+	BlockNumber uint64 `json:"blockNumber"`
+}
+
+// archiveService answers the eth_ methods the tool uses from an archive
+// directory.
+type archiveService struct {
+	dir string
+}
+
+// newArchiveServer serves the archive at dir over in-process JSON-RPC, so the
+// rest of the client is unaware it is offline.
+func newArchiveServer(dir string) (*rpc.Server, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("archive %s is not a directory", dir)
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &archiveService{dir: dir}); err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+func archiveCallPath(dir string, to common.Address, calldata []byte) string {
+	return filepath.Join(dir, archiveCallsDir, strings.ToLower(to.Hex()), crypto.Keccak256Hash(calldata).Hex()+".hex")
+}
+
+func (s *archiveService) chain() (archiveChain, error) {
+	var ch archiveChain
+
+	data, err := os.ReadFile(filepath.Join(s.dir, archiveChainFile))
+	if err != nil {
+		return ch, fmt.Errorf("archive: %w", err)
+	}
+	if err := json.Unmarshal(data, &ch); err != nil {
+		return ch, fmt.Errorf("archive %s: %w", archiveChainFile, err)
+	}
+
+	return ch, nil
+}
+
+func (s *archiveService) ChainId() (hexutil.Uint64, error) {
+	ch, err := s.chain()
+	return hexutil.Uint64(ch.ChainID), err
+}
+
+func (s *archiveService) BlockNumber() (hexutil.Uint64, error) {
+	ch, err := s.chain()
+	return hexutil.Uint64(ch.BlockNumber), err
+}
+
+type archiveCallArgs struct {
+	To    *common.Address `json:"to"`
+	Input hexutil.Bytes   `json:"input"`
+	Data  hexutil.Bytes   `json:"data"`
+}
+
+func (s *archiveService) Call(args archiveCallArgs, _ json.RawMessage) (hexutil.Bytes, error) {
+	if args.To == nil {
+		return nil, errors.New("archive: call without a target")
+	}
+
+	calldata := args.Input
+	if calldata == nil {
+		calldata = args.Data
+	}
+
+	path := archiveCallPath(s.dir, *args.To, calldata)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("archive: no response for call to %s, expected %s", args.To, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+
+	response, err := hexutil.Decode(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("archive %s: %w", path, err)
+	}
+
+	return response, nil
+}
+
+func (s *archiveService) GetCode(_ common.Address, _ json.RawMessage) (hexutil.Bytes, error) {
+	return hexutil.Bytes{}, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Attestation is a position snapshot signed as EIP-712 typed data.
+type Attestation struct {
+	ChainID   *big.Int       `json:"chainId"`
+	Pool      common.Address `json:"pool"`
+	Owner     common.Address `json:"owner"`
+	TickLower Tick           `json:"tickLower"`
+	TickUpper Tick           `json:"tickUpper"`
+	Liquidity *big.Int       `json:"liquidity"`
+	Block     uint64         `json:"block"`
+	Signer    common.Address `json:"signer"`
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+var positionSnapshotTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+	},
+	"PositionSnapshot": {
+		{Name: "pool", Type: "address"},
+		{Name: "owner", Type: "address"},
+		{Name: "tickLower", Type: "int24"},
+		{Name: "tickUpper", Type: "int24"},
+		{Name: "liquidity", Type: "uint128"},
+		{Name: "block", Type: "uint256"},
+	},
+}
+
+// typedData is the EIP-712 message the attestation signs.
+func (a *Attestation) typedData() apitypes.TypedData {
+	return apitypes.TypedData{
+		Types:       positionSnapshotTypes,
+		PrimaryType: "PositionSnapshot",
+		Domain: apitypes.TypedDataDomain{
+			Name:    "UniswapGetPosition",
+			Version: "1",
+			ChainId: (*math.HexOrDecimal256)(a.ChainID),
+		},
+		Message: apitypes.TypedDataMessage{
+			"pool":      a.Pool.Hex(),
+			"owner":     a.Owner.Hex(),
+			"tickLower": big.NewInt(int64(a.TickLower)),
+			"tickUpper": big.NewInt(int64(a.TickUpper)),
+			"liquidity": a.Liquidity,
+			"block":     new(big.Int).SetUint64(a.Block),
+		},
+	}
+}
+
+// Sign fills Signer and Signature. The signature is 65 bytes r||s||v with v
+// in {27, 28}.
+func (a *Attestation) Sign(key *ecdsa.PrivateKey) error {
+	hash, _, err := apitypes.TypedDataAndHash(a.typedData())
+	if err != nil {
+		return fmt.Errorf("hash typed data: %w", err)
+	}
+
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		return fmt.Errorf("sign: %w", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+
+	a.Signer = crypto.PubkeyToAddress(key.PublicKey)
+	a.Signature = signature
+
+	return nil
+}
+
+// RecoverSigner returns the address that produced Signature.
+func (a *Attestation) RecoverSigner() (common.Address, error) {
+	if len(a.Signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("signature must be %d bytes", crypto.SignatureLength)
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(a.typedData())
+	if err != nil {
+		return common.Address{}, fmt.Errorf("hash typed data: %w", err)
+	}
+
+	signature := common.CopyBytes(a.Signature)
+	signature[crypto.RecoveryIDOffset] -= 27
+
+	pub, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// Attest reads q at the current head and signs the snapshot with key.
+func (c *Client) Attest(ctx context.Context, q PositionQuery, key *ecdsa.PrivateKey) (*Attestation, error) {
+	chainID, err := c.eth.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get chain id: %w", err)
+	}
+
+	head, err := c.eth.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get block number: %w", err)
+	}
+
+	position, err := c.Position(ctx, q, new(big.Int).SetUint64(head))
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Attestation{
+		ChainID:   chainID,
+		Pool:      q.Pool,
+		Owner:     q.Owner,
+		TickLower: q.TickLower,
+		TickUpper: q.TickUpper,
+		Liquidity: position.Liquidity,
+		Block:     head,
+	}
+	if err := a.Sign(key); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
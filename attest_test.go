@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testSignKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func TestAttestationSign(t *testing.T) {
+	key, err := crypto.HexToECDSA(testSignKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &Attestation{
+		ChainID:   big.NewInt(42161),
+		Pool:      testPool,
+		Owner:     testOwner,
+		TickLower: -887272,
+		TickUpper: 60,
+		Liquidity: big.NewInt(1000),
+		Block:     100,
+	}
+	if err := a.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Signer != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("signer = %s, want the key's address", a.Signer.Hex())
+	}
+	if v := a.Signature[crypto.RecoveryIDOffset]; len(a.Signature) != crypto.SignatureLength || v != 27 && v != 28 {
+		t.Errorf("signature = %x, want 65 bytes ending in 27 or 28", a.Signature)
+	}
+	if signer, err := a.RecoverSigner(); err != nil || signer != a.Signer {
+		t.Errorf("RecoverSigner() = %s, %v, want %s", signer.Hex(), err, a.Signer.Hex())
+	}
+
+	// every field is signed
+	for name, change := range map[string]func(*Attestation){
+		"chain id":  func(a *Attestation) { a.ChainID = big.NewInt(1) },
+		"owner":     func(a *Attestation) { a.Owner = common.Address{} },
+		"tickLower": func(a *Attestation) { a.TickLower = -60 },
+		"liquidity": func(a *Attestation) { a.Liquidity = big.NewInt(1001) },
+		"block":     func(a *Attestation) { a.Block = 101 },
+	} {
+		changed := *a
+		change(&changed)
+		if signer, err := changed.RecoverSigner(); err == nil && signer == a.Signer {
+			t.Errorf("changing the %s keeps the signer", name)
+		}
+	}
+
+	short := *a
+	short.Signature = short.Signature[:64]
+	if _, err := short.RecoverSigner(); err == nil {
+		t.Error("RecoverSigner accepted a 64 byte signature")
+	}
+}
+
+func TestRunSignKey(t *testing.T) {
+	node := positionNode(t, 1000)
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-sign-key", "0x"+testSignKey)
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+
+	var a Attestation
+	if err := json.Unmarshal([]byte(stdout), &a); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, stdout)
+	}
+	key, _ := crypto.HexToECDSA(testSignKey)
+	if signer, err := a.RecoverSigner(); err != nil || signer != crypto.PubkeyToAddress(key.PublicKey) || signer != a.Signer {
+		t.Errorf("output signed by %s, %v, want %s", signer.Hex(), err, a.Signer.Hex())
+	}
+	if a.Liquidity.Int64() != 1000 || a.Block != 100 || a.ChainID.Int64() != 42161 {
+		t.Errorf("attestation = %+v", a)
+	}
+
+	if code, _, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-sign-key", "0x1234"); code != exitBadInput || !strings.Contains(stderr, "invalid -sign-key") {
+		t.Errorf("bad key: exit code %d, want %d; stderr:\n%s", code, exitBadInput, stderr)
+	}
+}
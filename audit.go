@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// AuditEntry is one line of the audit log: an eth_call, the block it ran at
+// and hashes of exactly what was sent and received, so a read can be replayed
+// and checked against an archive node later.
+type AuditEntry struct {
+	Time   time.Time      `json:"time"`
+	Method string         `json:"method"`
+	To     common.Address `json:"to"`
+	// Block and BlockTime are null when the node couldn't return the block
+	// header, e.g. with -backend archive.
+	Block     *uint64 `json:"block"`
+	BlockTime *uint64 `json:"blockTime"`
+	// Request is keccak256 of the calldata, Result of the returned bytes.
+	Request common.Hash  `json:"request"`
+	Result  *common.Hash `json:"result,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+type headerReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// auditLog writes AuditEntry JSON lines. Block timestamps are read once per
+// block.
+type auditLog struct {
+	headers headerReader
+
+	mu    sync.Mutex
+	enc   *json.Encoder
+	times map[uint64]uint64
+}
+
+// WithAuditLog writes an AuditEntry line to w for every eth_call sent to the
+// node, retries included. Reads at latest are logged with the head number
+// read right after the call, which can be newer on a fast chain.
+func WithAuditLog(w io.Writer) Option {
+	return func(o *clientOptions) {
+		o.audit = w
+	}
+}
+
+func newAuditLog(w io.Writer, headers headerReader) *auditLog {
+	return &auditLog{headers: headers, enc: json.NewEncoder(w), times: make(map[uint64]uint64)}
+}
+
+func (a *auditLog) middleware(next ethereum.ContractCaller) ethereum.ContractCaller {
+	return CallerFunc(func(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+		response, err := next.CallContract(ctx, msg, block)
+
+		entry := AuditEntry{
+			Time:    time.Now().UTC(),
+			Method:  methodName(msg.Data),
+			Request: crypto.Keccak256Hash(msg.Data),
+		}
+		if msg.To != nil {
+			entry.To = *msg.To
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			result := crypto.Keccak256Hash(response)
+			entry.Result = &result
+		}
+
+		if number, t, ok := a.blockTime(ctx, block); ok {
+			entry.Block, entry.BlockTime = &number, &t
+		} else if block != nil {
+			number := block.Uint64()
+			entry.Block = &number
+		}
+
+		if err := a.write(entry); err != nil {
+			return nil, fmt.Errorf("audit log: %w", err)
+		}
+
+		return response, err
+	})
+}
+
+func (a *auditLog) write(entry AuditEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.enc.Encode(entry)
+}
+
+// blockTime returns the number and timestamp of block, or of the head when
+// block is nil.
+func (a *auditLog) blockTime(ctx context.Context, block *big.Int) (uint64, uint64, bool) {
+	if block != nil {
+		a.mu.Lock()
+		t, ok := a.times[block.Uint64()]
+		a.mu.Unlock()
+		if ok {
+			return block.Uint64(), t, true
+		}
+	}
+
+	header, err := a.headers.HeaderByNumber(ctx, block)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	a.mu.Lock()
+	a.times[header.Number.Uint64()] = header.Time
+	a.mu.Unlock()
+
+	return header.Number.Uint64(), header.Time, true
+}
+
+// auditABIs are searched, in order, for the method of logged calldata.
+var auditABIs = []*abi.ABI{&multicall3ABI, &poolABI, &npmABI, &factoryABI, &erc20ABI, &nodeInterfaceABI}
+
+// methodName is the name of the method calldata calls, or its hex selector
+// when no known ABI has it.
+func methodName(calldata []byte) string {
+	if method, ok := knownMethod(calldata); ok {
+		return method.Name
+	}
+	if len(calldata) < 4 {
+		return hexutil.Encode(calldata)
+	}
+
+	return hexutil.Encode(calldata[:4])
+}
+
+// knownMethod looks up the method calldata calls in auditABIs.
+func knownMethod(calldata []byte) (*abi.Method, bool) {
+	if len(calldata) < 4 {
+		return nil, false
+	}
+
+	for _, a := range auditABIs {
+		if method, err := a.MethodById(calldata[:4]); err == nil {
+			return method, true
+		}
+	}
+
+	return nil, false
+}
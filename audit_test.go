@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeHeaders serves headers with a timestamp of 1000 times the number, and
+// head for latest.
+type fakeHeaders struct {
+	head  uint64
+	reads int
+}
+
+func (h *fakeHeaders) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	h.reads++
+	if number == nil {
+		number = new(big.Int).SetUint64(h.head)
+	}
+	if number.Uint64() > h.head {
+		return nil, errors.New("header not found")
+	}
+
+	return &types.Header{Number: number, Time: number.Uint64() * 1000}, nil
+}
+
+func readAudit(t *testing.T, data []byte) []AuditEntry {
+	t.Helper()
+
+	var entries []AuditEntry
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var entry AuditEntry
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("parse audit log: %v\n%s", err, data)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestAuditLog(t *testing.T) {
+	var out bytes.Buffer
+	headers := &fakeHeaders{head: 100}
+	// reads at latest revert
+	node := CallerFunc(func(_ context.Context, _ ethereum.CallMsg, block *big.Int) ([]byte, error) {
+		if block == nil {
+			return nil, errRevert{}
+		}
+		return []byte{1}, nil
+	})
+	caller := chain(node, newAuditLog(&out, headers).middleware)
+
+	calldata, err := poolABI.Pack(slot0Method)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := ethereum.CallMsg{To: &testPool, Data: calldata}
+	for _, block := range []*big.Int{big.NewInt(90), big.NewInt(90), nil, big.NewInt(200)} {
+		caller.CallContract(context.Background(), msg, block)
+	}
+
+	entries := readAudit(t, out.Bytes())
+	if len(entries) != 4 {
+		t.Fatalf("got %d audit entries, want 4:\n%s", len(entries), out.String())
+	}
+	request := crypto.Keccak256Hash(calldata)
+	result := crypto.Keccak256Hash([]byte{1})
+	for i, tc := range []struct {
+		block, blockTime uint64
+		// failed calls have an error instead of a result
+		failed bool
+	}{
+		{90, 90000, false},
+		{90, 90000, false},
+		{100, 100000, true},
+		// a block the node has no header of
+		{200, 0, false},
+	} {
+		entry := entries[i]
+		if entry.Method != slot0Method || entry.To != testPool || entry.Request != request {
+			t.Errorf("entry %d = %+v", i, entry)
+		}
+		if entry.Block == nil || *entry.Block != tc.block {
+			t.Errorf("entry %d block = %v, want %d", i, entry.Block, tc.block)
+		}
+		switch {
+		case tc.blockTime == 0 && entry.BlockTime != nil:
+			t.Errorf("entry %d block time = %d, want null", i, *entry.BlockTime)
+		case tc.blockTime != 0 && (entry.BlockTime == nil || *entry.BlockTime != tc.blockTime):
+			t.Errorf("entry %d block time = %v, want %d", i, entry.BlockTime, tc.blockTime)
+		}
+		if tc.failed && (entry.Result != nil || entry.Error != "execution reverted") {
+			t.Errorf("entry %d: result %v, error %q, want the revert", i, entry.Result, entry.Error)
+		}
+		if !tc.failed && (entry.Result == nil || *entry.Result != result || entry.Error != "") {
+			t.Errorf("entry %d: result %v, error %q, want %s", i, entry.Result, entry.Error, result)
+		}
+	}
+	// block 90 once, the head, and the missing block
+	if headers.reads != 3 {
+		t.Errorf("read %d headers, want 3", headers.reads)
+	}
+}
+
+func TestMethodName(t *testing.T) {
+	calldata, err := npmABI.Pack(balanceOfMethod, testOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		calldata []byte
+		want     string
+	}{
+		{calldata, balanceOfMethod},
+		{[]byte{0xde, 0xad, 0xbe, 0xef, 0x01}, "0xdeadbeef"},
+		{[]byte{0x01}, "0x01"},
+	} {
+		if got := methodName(tc.calldata); got != tc.want {
+			t.Errorf("methodName(%x) = %s, want %s", tc.calldata, got, tc.want)
+		}
+	}
+}
+
+func TestRunAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	node := positionNode(t, 1000)
+
+	code, _, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-block", "90", "-audit-log", path)
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := readAudit(t, data)
+	if n := node.count("eth_call"); len(entries) != n {
+		t.Errorf("got %d audit entries for %d eth_calls:\n%s", len(entries), n, data)
+	}
+	for i, entry := range entries {
+		if entry.Block == nil || *entry.Block != 90 || entry.Result == nil {
+			t.Errorf("entry %d = %+v", i, entry)
+		}
+	}
+	if !strings.Contains(string(data), `"method":"aggregate3"`) {
+		t.Errorf("audit log lacks the multicall:\n%s", data)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Backend is a source of position state. *Client is one for every node or
+// archive it is connected to.
+type Backend interface {
+	Positions(ctx context.Context, queries []PositionQuery, block *big.Int) ([]Position, error)
+}
+
+// FallbackBackend tries its backends in order for every request and returns
+// the first complete answer, for example the primary node, then a second node,
+// then an archive.
+//
+// A revert, or a partial answer whose failed reads all reverted, is returned
+// right away since the same call reverts everywhere.
+// When no backend answers completely, the first partial answer is returned
+// with its *BatchError, or all errors if there was none.
+type FallbackBackend []Backend
+
+func (f FallbackBackend) Positions(ctx context.Context, queries []PositionQuery, block *big.Int) ([]Position, error) {
+	if len(f) == 0 {
+		return nil, errors.New("no backends")
+	}
+
+	var (
+		errs    []error
+		partial []Position
+		partErr error
+	)
+
+	for i, backend := range f {
+		positions, err := backend.Positions(ctx, queries, block)
+		if err == nil {
+			return positions, nil
+		}
+
+		// a *BatchError reads as a revert by its message, so it goes first
+		var batchErr *BatchError
+		if errors.As(err, &batchErr) && batchErr.reverted() {
+			return positions, err
+		}
+		if !errors.As(err, &batchErr) && isRevert(err) {
+			return nil, err
+		}
+		if errors.As(err, &batchErr) && partial == nil {
+			partial, partErr = positions, err
+		}
+		errs = append(errs, fmt.Errorf("backend %d: %w", i, err))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if partial != nil {
+		return partial, partErr
+	}
+
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// stubBackend answers with positions and err and counts its requests.
+type stubBackend struct {
+	positions []Position
+	err       error
+	calls     int
+}
+
+func (s *stubBackend) Positions(context.Context, []PositionQuery, *big.Int) ([]Position, error) {
+	s.calls++
+	return s.positions, s.err
+}
+
+func TestFallbackBackend(t *testing.T) {
+	found := []Position{{Liquidity: big.NewInt(1000)}}
+	partial := []Position{{Liquidity: big.NewInt(1)}}
+	transport := &CallError{Kind: KindTransport, Err: errors.New("connection refused")}
+	revert := &CallError{Kind: KindRevert, Err: errRevert{}}
+
+	for _, tc := range []struct {
+		name     string
+		backends []*stubBackend
+		// want is the liquidity of the answer, or -1 for none
+		want    int64
+		wantErr bool
+		// calls are the requests each backend got
+		calls []int
+	}{
+		{"first answers", []*stubBackend{{positions: found}, {positions: partial}}, 1000, false, []int{1, 0}},
+		{"first errors", []*stubBackend{{err: transport}, {positions: found}}, 1000, false, []int{1, 1}},
+		{"both error", []*stubBackend{{err: transport}, {err: transport}}, -1, true, []int{1, 1}},
+		// the same call reverts on the second backend too
+		{"revert", []*stubBackend{{err: revert}, {positions: found}}, -1, true, []int{1, 0}},
+		{"reverted entry", []*stubBackend{{positions: partial, err: &BatchError{Errs: []error{revert}}}, {positions: found}}, 1, true, []int{1, 0}},
+		{"partial then complete", []*stubBackend{{positions: partial, err: &BatchError{Errs: []error{transport}}}, {positions: found}}, 1000, false, []int{1, 1}},
+		{"partial then error", []*stubBackend{{positions: partial, err: &BatchError{Errs: []error{transport}}}, {err: transport}}, 1, true, []int{1, 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var fallback FallbackBackend
+			for _, b := range tc.backends {
+				fallback = append(fallback, b)
+			}
+
+			positions, err := fallback.Positions(context.Background(), testQueries[:1], nil)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("err = %v, want an error: %t", err, tc.wantErr)
+			}
+			switch {
+			case tc.want < 0 && positions != nil:
+				t.Errorf("positions = %+v, want none", positions)
+			case tc.want >= 0 && (len(positions) != 1 || positions[0].Liquidity.Int64() != tc.want):
+				t.Errorf("positions = %+v, want liquidity %d", positions, tc.want)
+			}
+			for i, b := range tc.backends {
+				if b.calls != tc.calls[i] {
+					t.Errorf("backend %d got %d requests, want %d", i, b.calls, tc.calls[i])
+				}
+			}
+		})
+	}
+
+	if _, err := (FallbackBackend{}).Positions(context.Background(), testQueries[:1], nil); err == nil {
+		t.Error("empty FallbackBackend answered")
+	}
+}
+
+func TestRunFallbackNode(t *testing.T) {
+	primary := positionNode(t, 1000)
+	pools := primary.handle
+	primary.handle = func(to common.Address, data []byte) ([]byte, error) {
+		if methodOf(t, poolABI, data).Name == positionsMethod {
+			return nil, errRateLimited{}
+		}
+		return pools(to, data)
+	}
+	fallback := positionNode(t, 1000)
+
+	code, _, stderr := runOutput(t, "-node", primary.serve(t), "-fallback-node", fallback.serve(t), "-retries", "0", "-max-lag", "0")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	if fallback.count("eth_call") == 0 {
+		t.Error("the fallback node got no calls")
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// BackfillPoint is the position read at one sampled block of a Backfill.
+type BackfillPoint struct {
+	Block    uint64   `json:"block"`
+	Time     uint64   `json:"time"`
+	Position Position `json:"position"`
+	// Error is set when the node no longer has the block's state; the point
+	// is kept so gaps in the series are visible.
+	Error string `json:"error,omitempty"`
+}
+
+// parseBlockRange parses a -backfill range, FROM-TO with FROM <= TO.
+func parseBlockRange(s string) (from, to uint64, err error) {
+	rawFrom, rawTo, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q is not a FROM-TO block range", s)
+	}
+	if from, err = strconv.ParseUint(rawFrom, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid first block %q", rawFrom)
+	}
+	if to, err = strconv.ParseUint(rawTo, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid last block %q", rawTo)
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("first block %d is after last block %d", from, to)
+	}
+
+	return from, to, nil
+}
+
+// Backfill reads q at from, from+step, ... and to, with at most workers reads
+// in flight, and returns the points in block order. Rate limited calls are
+// retried by the client's RetryMiddleware, so workers is what bounds the load
+// on the node. Blocks whose state the node pruned come back with Error set
+// instead of failing the backfill; any other error stops it. Each point read
+// is counted on progress, which may be nil.
+func (c *Client) Backfill(ctx context.Context, q PositionQuery, from, to, step uint64, workers int, progress *Progress) ([]BackfillPoint, error) {
+	if step == 0 {
+		return nil, errors.New("backfill step must be positive")
+	}
+
+	var points []BackfillPoint
+	for block := from; ; block += step {
+		points = append(points, BackfillPoint{Block: block})
+		if to-block < step {
+			break
+		}
+	}
+	if last := points[len(points)-1].Block; last != to {
+		points = append(points, BackfillPoint{Block: to})
+	}
+	progress.setTotal(len(points))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	next := make(chan int)
+
+	for w := 0; w < min(workers, len(points)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				err := c.backfillPoint(ctx, q, &points[i])
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+				progress.add(err != nil || points[i].Error != "")
+			}
+		}()
+	}
+
+send:
+	for i := range points {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+func (c *Client) backfillPoint(ctx context.Context, q PositionQuery, point *BackfillPoint) error {
+	number := new(big.Int).SetUint64(point.Block)
+
+	position, err := c.Position(ctx, q, number)
+	if errors.Is(err, ErrStateUnavailable) {
+		point.Error = err.Error()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read block %d: %w", point.Block, err)
+	}
+
+	header, err := c.eth.HeaderByNumber(ctx, number)
+	if err != nil {
+		return fmt.Errorf("get block %d: %w", point.Block, err)
+	}
+	point.Time, point.Position = header.Time, position
+
+	return nil
+}
+
+// writeBackfill prints the points as a table, a JSON array or with -format
+// ndjson one point per line, the shape to load into a time series.
+func writeBackfill(w io.Writer, format string, points []BackfillPoint) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(points)
+	case formatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, p := range points {
+			if err := enc.Encode(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BLOCK\tTIME\tLIQUIDITY\tOWED0\tOWED1")
+	for _, p := range points {
+		if p.Error != "" {
+			fmt.Fprintf(tw, "%d\t-\t%s\t\t\n", p.Block, p.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", p.Block, time.Unix(int64(p.Time), 0).UTC().Format(time.RFC3339),
+			p.Position.Liquidity, p.Position.TokensOwed0, p.Position.TokensOwed1)
+	}
+
+	return tw.Flush()
+}
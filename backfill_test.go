@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParseBlockRange(t *testing.T) {
+	if from, to, err := parseBlockRange("100-200"); err != nil || from != 100 || to != 200 {
+		t.Errorf("parseBlockRange(100-200) = %d, %d, %v", from, to, err)
+	}
+	for _, s := range []string{"100", "200-100", "a-200", "100-", "-100"} {
+		if _, _, err := parseBlockRange(s); err == nil {
+			t.Errorf("parseBlockRange(%q) accepted", s)
+		}
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	var inFlight, most atomic.Int64
+	positions := positionNode(t, 1000).handle
+	// the node pruned the state of blocks before 30
+	node := &fakeNode{head: 100, prunedBelow: 30, handle: func(to common.Address, data []byte) ([]byte, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		return positions(to, data)
+	}}
+	client := newFakeClient(t, node)
+
+	points, err := client.Backfill(context.Background(), testQueries[0], 10, 75, 20, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every step, then the last block of the range
+	wantBlocks := []uint64{10, 30, 50, 70, 75}
+	if len(points) != len(wantBlocks) {
+		t.Fatalf("got %d points, want blocks %v: %+v", len(points), wantBlocks, points)
+	}
+	for i, p := range points {
+		if p.Block != wantBlocks[i] {
+			t.Errorf("point %d at block %d, want %d", i, p.Block, wantBlocks[i])
+		}
+		if p.Block < 30 {
+			if !strings.Contains(p.Error, "missing trie node") || p.Position.Liquidity != nil {
+				t.Errorf("pruned point %+v, want the gap kept with its error", p)
+			}
+			continue
+		}
+		if p.Error != "" || p.Position.Liquidity.Int64() != 1000 || p.Time != p.Block*12 {
+			t.Errorf("point %+v", p)
+		}
+	}
+	if n := most.Load(); n > 2 {
+		t.Errorf("%d reads in flight, want at most 2 workers", n)
+	}
+
+	if _, err := client.Backfill(context.Background(), testQueries[0], 10, 20, 0, 2, nil); err == nil {
+		t.Error("step 0 accepted")
+	}
+}
+
+func TestBackfillFails(t *testing.T) {
+	node := &fakeNode{head: 100, handle: func(common.Address, []byte) ([]byte, error) {
+		return nil, errRevert{}
+	}}
+	client := newFakeClient(t, node)
+
+	if _, err := client.Backfill(context.Background(), testQueries[0], 10, 90, 10, 3, nil); err == nil || !isRevert(err) {
+		t.Errorf("err = %v, want the revert", err)
+	}
+}
+
+func TestRunBackfill(t *testing.T) {
+	node := positionNode(t, 1000)
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-backfill", "40-60", "-backfill-step", "10", "-format", "ndjson")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], `{"block":40,"time":480,`) || !strings.HasPrefix(lines[2], `{"block":60,`) {
+		t.Errorf("stdout:\n%s", stdout)
+	}
+}
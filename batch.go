@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Batch backends for reading many calls at once.
+const (
+	// BatchMulticall packs the calls into one Multicall3 eth_call.
+	BatchMulticall = "multicall"
+	// BatchNative sends one JSON-RPC batch request with an eth_call per call.
+	// It works without Multicall3, and each call goes through the client
+	// middleware on its own.
+	BatchNative = "native"
+)
+
+// batchNative runs every call through the client middleware concurrently,
+// and the calls that reach the node together are sent as one JSON-RPC batch:
+// all of them at first, then the ones retried after the same backoff. Calls
+// that revert are returned in a *BatchError; any other failure fails the
+// whole batch.
+func (c *Client) batchNative(ctx context.Context, calls []call, block *big.Int) ([][]byte, error) {
+	batch := &nativeBatch{ctx: ctx, client: c.eth.Client(), active: len(calls)}
+	caller := chain(classifying(batch), c.middleware...)
+
+	result := make([][]byte, len(calls))
+	errs := make([]error, len(calls))
+	var wg sync.WaitGroup
+	for i, cl := range calls {
+		wg.Add(1)
+		go func(i int, cl call) {
+			defer wg.Done()
+			defer batch.finish()
+			result[i], errs[i] = caller.CallContract(ctx, ethereum.CallMsg{To: &cl.Target, Data: cl.CallData}, block)
+		}(i, cl)
+	}
+	wg.Wait()
+
+	reverted := false
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		errs[i] = fmt.Errorf("call %s: %w", calls[i].Target, err)
+		if !isRevert(err) {
+			return nil, errs[i]
+		}
+		reverted = true
+	}
+
+	if reverted {
+		return result, &BatchError{Errs: errs}
+	}
+
+	return result, nil
+}
+
+// nativeBatch is the ContractCaller under the middleware of a batchNative
+// batch. It holds back each call until every call still in the middleware
+// is waiting on it, then sends them all in one batch request.
+type nativeBatch struct {
+	ctx    context.Context
+	client *rpc.Client
+
+	mu sync.Mutex
+	// active is the number of calls that haven't returned from the
+	// middleware yet
+	active  int
+	pending []*nativeCall
+}
+
+type nativeCall struct {
+	elem   rpc.BatchElem
+	result hexutil.Bytes
+	done   chan struct{}
+}
+
+func (b *nativeBatch) CallContract(_ context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	nc := &nativeCall{done: make(chan struct{})}
+	nc.elem = rpc.BatchElem{
+		Method: "eth_call",
+		Args:   []interface{}{callArg(msg), blockArg(block)},
+		Result: &nc.result,
+	}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, nc)
+	ready := b.ready()
+	b.mu.Unlock()
+	b.send(ready)
+
+	<-nc.done
+	if nc.elem.Error != nil {
+		return nil, nc.elem.Error
+	}
+
+	return nc.result, nil
+}
+
+// finish marks a call as returned from the middleware, which may leave only
+// waiting calls behind.
+func (b *nativeBatch) finish() {
+	b.mu.Lock()
+	b.active--
+	ready := b.ready()
+	b.mu.Unlock()
+	b.send(ready)
+}
+
+// ready takes the pending calls once no call is left in the middleware. The
+// caller must hold mu.
+func (b *nativeBatch) ready() []*nativeCall {
+	if len(b.pending) == 0 || len(b.pending) < b.active {
+		return nil
+	}
+
+	ready := b.pending
+	b.pending = nil
+
+	return ready
+}
+
+func (b *nativeBatch) send(calls []*nativeCall) {
+	if len(calls) == 0 {
+		return
+	}
+
+	elems := make([]rpc.BatchElem, len(calls))
+	for i, nc := range calls {
+		elems[i] = nc.elem
+	}
+
+	err := b.client.BatchCallContext(b.ctx, elems)
+	for i, nc := range calls {
+		nc.elem.Error = elems[i].Error
+		if err != nil {
+			nc.elem.Error = fmt.Errorf("batch call: %w", err)
+		}
+		close(nc.done)
+	}
+}
+
+func callArg(msg ethereum.CallMsg) interface{} {
+	return map[string]interface{}{
+		"to":    msg.To,
+		"input": hexutil.Bytes(msg.Data),
+	}
+}
+
+// blockArg mirrors ethclient's block number encoding.
+func blockArg(block *big.Int) string {
+	if block == nil {
+		return "latest"
+	}
+
+	return hexutil.EncodeBig(block)
+}
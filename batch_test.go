@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// errRateLimited is a node's request limit error.
+type errRateLimited struct{}
+
+func (errRateLimited) Error() string  { return "request limit exceeded" }
+func (errRateLimited) ErrorCode() int { return codeLimitExceeded }
+
+func TestBatchNativeThroughMiddleware(t *testing.T) {
+	flakyPool := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	var mu sync.Mutex
+	failed := false
+	positions := positionsHandler(t)
+
+	node := &fakeNode{head: 100, multicallFrom: -1, handle: func(to common.Address, data []byte) ([]byte, error) {
+		if to == flakyPool {
+			mu.Lock()
+			defer mu.Unlock()
+			if !failed {
+				failed = true
+				return nil, errRateLimited{}
+			}
+			to = testPool
+		}
+		return positions(to, data)
+	}}
+
+	var logs, audit bytes.Buffer
+	var calls atomic.Int64
+	client := newFakeClient(t, node,
+		WithBatch(BatchNative),
+		WithMiddleware(
+			LoggingMiddleware(log.New(&logs, "", 0)),
+			RetryMiddleware(2, time.Millisecond),
+			CountingMiddleware(&calls),
+		),
+		WithAuditLog(&audit),
+	)
+
+	queries := []PositionQuery{
+		testQueries[0],
+		{Pool: flakyPool, Owner: testOwner, TickLower: -60, TickUpper: 60},
+		testQueries[2],
+	}
+	got, err := client.Positions(context.Background(), queries, big.NewInt(90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range got {
+		if p.Liquidity == nil || p.Liquidity.Int64() != 1000 {
+			t.Errorf("position %d = %+v", i, p)
+		}
+	}
+
+	// one per query, and the retry
+	const want = 4
+	if n := calls.Load(); n != want {
+		t.Errorf("counted %d calls, want %d", n, want)
+	}
+	if n := strings.Count(audit.String(), "\n"); n != want {
+		t.Errorf("audit log has %d entries, want %d:\n%s", n, want, audit.String())
+	}
+	// the logger sees each call once, after its retries
+	if n := strings.Count(logs.String(), "eth_call "); n != len(queries) {
+		t.Errorf("logged %d calls, want %d:\n%s", n, len(queries), logs.String())
+	}
+	// all calls in one batch, then the retry on its own
+	if n := node.count("batch"); n != 2 {
+		t.Errorf("sent %d batch requests, want 2", n)
+	}
+	if n := node.count("eth_call"); n != want {
+		t.Errorf("node got %d eth_calls, want %d", n, want)
+	}
+}
+
+func TestBatchNativeRetryBudget(t *testing.T) {
+	node := &fakeNode{head: 100, multicallFrom: -1, handle: func(common.Address, []byte) ([]byte, error) {
+		return nil, errRateLimited{}
+	}}
+
+	var budget atomic.Int64
+	budget.Store(1)
+	client := newFakeClient(t, node,
+		WithBatch(BatchNative),
+		WithMiddleware(BudgetedRetryMiddleware(5, time.Millisecond, &budget)),
+	)
+
+	_, err := client.Positions(context.Background(), testQueries, big.NewInt(90))
+	if err == nil {
+		t.Fatal("want an error from a node that is always rate limited")
+	}
+	// every call once, and the single retry the budget allows
+	if n := node.count("eth_call"); n != len(testQueries)+1 {
+		t.Errorf("node got %d eth_calls, want %d", n, len(testQueries)+1)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// breakEvenTickStep is the scan resolution, in ticks, used to bracket roots.
+const breakEvenTickStep = 10
+
+// BreakEvenPrice returns the prices at which the position, with its fees,
+// is worth as much as simply holding entry0 and entry1.
+//
+// The model: prices are raw token1 per raw token0 (no decimals applied), the
+// position holds liquidity on [tickLower, tickUpper) and its token amounts at
+// price P follow the constant-product curve inside the range; fees0/fees1 are
+// kept as is. LP value is amount0(P)*P + amount1(P) + fees0*P + fees1 and HODL
+// value is entry0*P + entry1, both in token1. Every crossing between
+// MIN_TICK and MAX_TICK is returned in increasing order; an empty result means
+// the LP is always above or always below HODL. The math is float64, so the
+// prices are estimates, not bit-exact.
+func BreakEvenPrice(liquidity *big.Int, tickLower, tickUpper Tick, entry0, entry1, fees0, fees1 *big.Int) ([]*big.Float, error) {
+	if err := validateTicks(tickLower, tickUpper); err != nil {
+		return nil, err
+	}
+	if liquidity.Sign() < 0 || entry0.Sign() < 0 || entry1.Sign() < 0 || fees0.Sign() < 0 || fees1.Sign() < 0 {
+		return nil, errors.New("negative amount")
+	}
+
+	var (
+		l  = toFloat64(liquidity)
+		e0 = toFloat64(entry0)
+		e1 = toFloat64(entry1)
+		f0 = toFloat64(fees0)
+		f1 = toFloat64(fees1)
+		sa = sqrtPriceAtTickFloat(tickLower)
+		sb = sqrtPriceAtTickFloat(tickUpper)
+	)
+
+	// value difference as a function of sqrt(P)
+	diff := func(sp float64) float64 {
+		var x, y float64
+		switch {
+		case sp <= sa:
+			x = l * (1/sa - 1/sb)
+		case sp >= sb:
+			y = l * (sb - sa)
+		default:
+			x = l * (1/sp - 1/sb)
+			y = l * (sp - sa)
+		}
+		p := sp * sp
+
+		return (x+f0-e0)*p + y + f1 - e1
+	}
+
+	var prices []*big.Float
+
+	prev := sqrtPriceAtTickFloat(MinTick)
+	prevDiff := diff(prev)
+	for tick := MinTick + breakEvenTickStep; tick <= MaxTick; tick += breakEvenTickStep {
+		cur := sqrtPriceAtTickFloat(tick)
+		curDiff := diff(cur)
+
+		switch {
+		case prevDiff == 0:
+			prices = append(prices, big.NewFloat(prev*prev))
+		case prevDiff*curDiff < 0:
+			root := bisect(diff, prev, cur)
+			prices = append(prices, big.NewFloat(root*root))
+		}
+
+		prev, prevDiff = cur, curDiff
+	}
+
+	return prices, nil
+}
+
+// parseEntry parses a -break-even deposit, AMOUNT0,AMOUNT1 in raw token
+// units.
+func parseEntry(s string) (entry0, entry1 *big.Int, err error) {
+	raw0, raw1, ok := strings.Cut(s, ",")
+	if !ok {
+		return nil, nil, fmt.Errorf("%q is not AMOUNT0,AMOUNT1", s)
+	}
+	entry0, ok0 := new(big.Int).SetString(strings.TrimSpace(raw0), 10)
+	entry1, ok1 := new(big.Int).SetString(strings.TrimSpace(raw1), 10)
+	if !ok0 || !ok1 || entry0.Sign() < 0 || entry1.Sign() < 0 {
+		return nil, nil, fmt.Errorf("%q is not two raw token amounts", s)
+	}
+
+	return entry0, entry1, nil
+}
+
+// BreakEven is the -break-even result: the break-even prices of a
+// position next to the pool's current price, all raw token1 per raw token0.
+type BreakEven struct {
+	Price  *big.Float   `json:"price"`
+	Prices []*big.Float `json:"breakEvenPrices"`
+}
+
+// writeBreakEven prints b as lines, or as JSON.
+func writeBreakEven(w io.Writer, format string, b BreakEven) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(b)
+	}
+
+	fmt.Fprintf(w, "current price:    %s\n", formatPrice(b.Price))
+	if len(b.Prices) == 0 {
+		_, err := fmt.Fprintln(w, "no break-even price: the position is worth more or less than holding at every price")
+		return err
+	}
+	for _, p := range b.Prices {
+		if _, err := fmt.Fprintf(w, "break-even price: %s\n", formatPrice(p)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func bisect(f func(float64) float64, lo, hi float64) float64 {
+	flo := f(lo)
+	for i := 0; i < 200 && lo < hi; i++ {
+		mid := lo + (hi-lo)/2
+		fmid := f(mid)
+		if fmid == 0 {
+			return mid
+		}
+		if (fmid < 0) == (flo < 0) {
+			lo, flo = mid, fmid
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo + (hi-lo)/2
+}
+
+func sqrtPriceAtTickFloat(tick Tick) float64 {
+	return math.Pow(1.0001, float64(tick)/2)
+}
+
+func toFloat64(n *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f
+}
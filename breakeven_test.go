@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// breakEvenEntry is what liquidity on [tickLower, tickUpper) holds at price
+// 1: the deposit of a position opened at tick 0.
+func breakEvenEntry(liquidity float64, tickLower, tickUpper Tick) (entry0, entry1 *big.Int) {
+	sa, sb := sqrtPriceAtTickFloat(tickLower), sqrtPriceAtTickFloat(tickUpper)
+	entry0, _ = big.NewFloat(liquidity * (1 - 1/sb)).Int(nil)
+	entry1, _ = big.NewFloat(liquidity * (sa - 1)).Int(nil)
+
+	return entry0, entry1.Neg(entry1)
+}
+
+// In range, a position opened at price 1 is worth L(s-1)^2 less than
+// holding at price s^2, so fees f1 pay for the loss until s = 1 ± sqrt(f1/L):
+// with f1 = L/100, at prices 0.9^2 and 1.1^2.
+func TestBreakEvenPrice(t *testing.T) {
+	const liquidity = 1e18
+	entry0, entry1 := breakEvenEntry(liquidity, -4000, 4000)
+
+	prices, err := BreakEvenPrice(big.NewInt(liquidity), -4000, 4000, entry0, entry1, new(big.Int), big.NewInt(liquidity/100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0.81, 1.21}
+	if len(prices) != len(want) {
+		t.Fatalf("prices = %v, want %v", prices, want)
+	}
+	for i, p := range prices {
+		if got, _ := p.Float64(); math.Abs(got-want[i]) > 1e-6 {
+			t.Errorf("price %d = %g, want %g", i, got, want[i])
+		}
+	}
+
+	// without fees the position is never worth more than holding
+	prices, err = BreakEvenPrice(big.NewInt(liquidity), -4000, 4000, entry0, entry1, new(big.Int), new(big.Int))
+	if err != nil || len(prices) != 0 {
+		t.Errorf("prices without fees = %v, %v, want none", prices, err)
+	}
+
+	if _, err := BreakEvenPrice(big.NewInt(liquidity), 60, -60, entry0, entry1, new(big.Int), new(big.Int)); err == nil {
+		t.Error("want an error for inverted ticks")
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	entry0, entry1, err := parseEntry("1000, 2000")
+	if err != nil || entry0.Int64() != 1000 || entry1.Int64() != 2000 {
+		t.Errorf("parseEntry = %v, %v, %v", entry0, entry1, err)
+	}
+	for _, s := range []string{"", "1000", "1000,-1", "1.5,2", "a,b"} {
+		if _, _, err := parseEntry(s); err == nil {
+			t.Errorf("parseEntry(%q) succeeded", s)
+		}
+	}
+}
+
+func TestRunBreakEven(t *testing.T) {
+	const liquidity = 1e18
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -4000, TickUpper: 4000}
+	pool := newFakePool(0, liquidity)
+	pool.setPosition(t, q, Position{Liquidity: big.NewInt(liquidity), TokensOwed1: big.NewInt(liquidity / 100)})
+	node := &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)}
+	entry0, entry1 := breakEvenEntry(liquidity, q.TickLower, q.TickUpper)
+
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-break-even", entry0.String()+","+entry1.String(),
+		"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-4000", "-tick-upper", "4000")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	want := "current price:    1\nbreak-even price: 0.81\nbreak-even price: 1.21\n"
+	if stdout != want {
+		t.Errorf("output = %q, want %q", stdout, want)
+	}
+
+	if code, _, _ := runOutput(t, "-break-even", "1000"); code != exitBadInput {
+		t.Errorf("-break-even 1000: exit code = %d, want %d", code, exitBadInput)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrCallTimeout marks a call of a batch that ran out of its share of the
+// overall deadline.
+var ErrCallTimeout = errors.New("call timed out")
+
+// BatchError is returned with partial results when some calls of a batch
+// failed. Errs is indexed like the calls, with nil for the ones that
+// succeeded.
+type BatchError struct {
+	Errs []error
+}
+
+func (e *BatchError) Error() string {
+	var failed []string
+	for i, err := range e.Errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("call %d: %v", i, err))
+		}
+	}
+
+	return fmt.Sprintf("%d of %d calls failed: %s", len(failed), len(e.Errs), strings.Join(failed, "; "))
+}
+
+// reverted reports whether every failed call of the batch reverted, so that
+// sending the batch again, to the same node or another, can't do better.
+func (e *BatchError) reverted() bool {
+	for _, err := range e.Errs {
+		if err != nil && !isRevert(err) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// callBudget gives one of pending remaining calls an equal share of the time
+// left before ctx's deadline, so a slow call can't use up the budget of the
+// calls after it. Without a deadline ctx is returned as is.
+func callBudget(ctx context.Context, pending int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || pending < 1 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(pending))
+}
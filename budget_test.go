@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestCallBudget(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	callCtx, callCancel := callBudget(ctx, 4)
+	defer callCancel()
+	deadline, ok := callCtx.Deadline()
+	if left := time.Until(deadline); !ok || left > 250*time.Millisecond || left < 200*time.Millisecond {
+		t.Errorf("a quarter of 1s left %s", left)
+	}
+
+	// no deadline to share
+	callCtx, callCancel = callBudget(context.Background(), 4)
+	defer callCancel()
+	if _, ok := callCtx.Deadline(); ok {
+		t.Error("callBudget set a deadline without one to share")
+	}
+}
+
+func TestCallSequentialSlowCall(t *testing.T) {
+	const slow = 300 * time.Millisecond
+	var calls atomic.Int64
+	positions := positionsHandler(t)
+	node := &fakeNode{head: 100, multicallFrom: -1, handle: func(to common.Address, data []byte) ([]byte, error) {
+		if calls.Add(1) == 1 {
+			time.Sleep(slow)
+		}
+		return positions(to, data)
+	}}
+	client := newFakeClient(t, node)
+
+	queries := []PositionQuery{testQueries[0], testQueries[2], testQueries[0]}
+	ctx, cancel := context.WithTimeout(context.Background(), slow)
+	defer cancel()
+
+	start := time.Now()
+	got, err := client.Positions(ctx, queries, nil)
+	if elapsed := time.Since(start); elapsed >= slow {
+		t.Errorf("took %s, longer than the whole budget", elapsed)
+	}
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("err = %v, want a *BatchError", err)
+	}
+	if !errors.Is(batchErr.Errs[0], ErrCallTimeout) {
+		t.Errorf("error of the slow call = %v, want ErrCallTimeout", batchErr.Errs[0])
+	}
+	for _, i := range []int{1, 2} {
+		if batchErr.Errs[i] != nil || got[i].Liquidity == nil || got[i].Liquidity.Int64() != 1000 {
+			t.Errorf("call %d = %+v, %v", i, got[i], batchErr.Errs[i])
+		}
+	}
+}
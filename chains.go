@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Chain holds the Uniswap V3 deployment the tool uses on one network.
+type Chain struct {
+	ID        uint64         `json:"chainId"`
+	Name      string         `json:"name"`
+	Factory   common.Address `json:"factory"`
+	NPM       common.Address `json:"npm"`
+	Multicall common.Address `json:"multicall"`
+	RPC       string         `json:"rpc"`
+	// BlockTime is the network's usual time between blocks, which -watch
+	// times its resubscriptions by; in nanoseconds in JSON.
+	BlockTime time.Duration `json:"blockTime"`
+}
+
+// defaultBlockTime is the block time of chains not in the registry.
+const defaultBlockTime = 12 * time.Second
+
+// https://docs.uniswap.org/contracts/v3/reference/deployments/
+var chains = []Chain{
+	{
+		ID:        1,
+		Name:      "ethereum",
+		Factory:   common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984"),
+		NPM:       common.HexToAddress("0xC36442b4a4522E871399CD717aBDD847Ab11FE88"),
+		Multicall: multicall3Address,
+		RPC:       "https://eth.llamarpc.com",
+		BlockTime: 12 * time.Second,
+	},
+	{
+		ID:        42161,
+		Name:      "arbitrum",
+		Factory:   common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984"),
+		NPM:       common.HexToAddress("0xC36442b4a4522E871399CD717aBDD847Ab11FE88"),
+		Multicall: multicall3Address,
+		RPC:       nodeAddr,
+		BlockTime: 250 * time.Millisecond,
+	},
+	{
+		ID:        11155111,
+		Name:      "sepolia",
+		Factory:   common.HexToAddress("0x0227628f3F023bb0B980b67D528571c95c6DaC1c"),
+		NPM:       common.HexToAddress("0x1238536071E1c677A632429e3655c799b22cDA52"),
+		Multicall: multicall3Address,
+		RPC:       "https://ethereum-sepolia-rpc.publicnode.com",
+		BlockTime: 12 * time.Second,
+	},
+}
+
+func chainByID(id uint64) (Chain, bool) {
+	for _, ch := range chains {
+		if ch.ID == id {
+			return ch, true
+		}
+	}
+
+	return Chain{}, false
+}
+
+// swapRouters are the Uniswap V3 routers, which aren't in the registry as the
+// tool never calls them; they are only recognized when pasted as a pool.
+var swapRouters = map[common.Address]string{
+	common.HexToAddress("0xE592427A0AEce92De3Edee1F18E0157C05861564"): "Uniswap V3 SwapRouter",
+	common.HexToAddress("0x68b3465833fb72A70ecDF485E0e4C7bD8665Fc45"): "Uniswap SwapRouter02",
+	common.HexToAddress("0x3bFA4769FB09eefC5a80d6E87c3B9C650f7Ae48E"): "Uniswap SwapRouter02",
+}
+
+// notAPool returns an error saying what address is when it is one of the
+// Uniswap contracts users paste as a pool by mistake: the position manager
+// above all, whose positions(uint256) a positions(bytes32) call lands on.
+func notAPool(address common.Address) error {
+	name, ok := swapRouters[address]
+	for _, ch := range chains {
+		switch address {
+		case ch.NPM:
+			name, ok = "Uniswap V3 NonfungiblePositionManager", true
+		case ch.Factory:
+			name, ok = "Uniswap V3 factory", true
+		case ch.Multicall:
+			name, ok = "Multicall3 contract", true
+		}
+		if ok {
+			break
+		}
+	}
+	if !ok {
+		return nil
+	}
+
+	return fmt.Errorf("%s is the %s, not a pool: read an NFT position by its token id (-url, -list or a tokenId entry in -input) or give the address of the pool it is in", address.Hex(), name)
+}
+
+// chainIDEnv names the environment variable that picks the chain, and with
+// it the default node, when -node isn't given.
+const chainIDEnv = "CHAIN_ID"
+
+// envChain returns the registry entry named by CHAIN_ID, or false when the
+// variable is unset.
+func envChain() (Chain, bool, error) {
+	raw := os.Getenv(chainIDEnv)
+	if raw == "" {
+		return Chain{}, false, nil
+	}
+
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return Chain{}, false, fmt.Errorf("invalid %s %q: want a decimal chain id", chainIDEnv, raw)
+	}
+	ch, ok := chainByID(id)
+	if !ok {
+		return Chain{}, false, fmt.Errorf("%s %d is not in the registry, see -chains", chainIDEnv, id)
+	}
+	if ch.RPC == "" {
+		return Chain{}, false, fmt.Errorf("no default RPC for %s %d (%s), set -node", chainIDEnv, id, ch.Name)
+	}
+
+	return ch, true, nil
+}
+
+// writeChains prints the registry, as a table or with -format json as a JSON
+// array.
+func writeChains(w io.Writer, format string) error {
+	if format == formatJSON || format == formatNDJSON {
+		enc := json.NewEncoder(w)
+		if format == formatJSON {
+			enc.SetIndent("", "  ")
+			return enc.Encode(chains)
+		}
+		for _, ch := range chains {
+			if err := enc.Encode(ch); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHAIN ID\tNAME\tFACTORY\tNPM\tMULTICALL\tDEFAULT RPC\tBLOCK TIME")
+	for _, ch := range chains {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", ch.ID, ch.Name, ch.Factory.Hex(), ch.NPM.Hex(), ch.Multicall.Hex(), ch.RPC, ch.BlockTime)
+	}
+
+	return tw.Flush()
+}
+
+// chain returns the registry entry of the node's chain, detected once.
+func (c *Client) chain(ctx context.Context) (Chain, error) {
+	c.chainMu.Lock()
+	defer c.chainMu.Unlock()
+
+	if c.chainInfo != nil {
+		return *c.chainInfo, nil
+	}
+
+	id, err := c.eth.ChainID(ctx)
+	if err != nil {
+		return Chain{}, fmt.Errorf("get chain id: %w", err)
+	}
+
+	ch, ok := chainByID(id.Uint64())
+	if !ok {
+		return Chain{}, fmt.Errorf("chain %s is not in the registry, Uniswap V3 addresses are unknown", id)
+	}
+	c.chainInfo = &ch
+
+	return ch, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestChainRegistry(t *testing.T) {
+	seen := make(map[uint64]bool)
+	for _, ch := range chains {
+		if seen[ch.ID] {
+			t.Errorf("chain %d registered twice", ch.ID)
+		}
+		seen[ch.ID] = true
+
+		if ch.Name == "" || ch.Factory == (common.Address{}) || ch.NPM == (common.Address{}) || ch.Multicall == (common.Address{}) {
+			t.Errorf("incomplete registry entry %+v", ch)
+		}
+		if got, ok := chainByID(ch.ID); !ok || got != ch {
+			t.Errorf("chainByID(%d) = %+v, %t", ch.ID, got, ok)
+		}
+	}
+
+	if _, ok := chainByID(10); ok {
+		t.Error("chainByID found an unregistered chain")
+	}
+}
+
+func TestRunChains(t *testing.T) {
+	code, stdout, stderr := runOutput(t, "-chains")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 1+len(chains) {
+		t.Fatalf("-chains printed %d lines for %d chains:\n%s", len(lines), len(chains), stdout)
+	}
+	for i, ch := range chains {
+		fields := strings.Fields(lines[i+1])
+		want := []string{fmt.Sprint(ch.ID), ch.Name, ch.Factory.Hex(), ch.NPM.Hex(), ch.Multicall.Hex(), ch.RPC, ch.BlockTime.String()}
+		if !reflect.DeepEqual(fields, want) {
+			t.Errorf("row %d = %q, want %q", i, fields, want)
+		}
+	}
+
+	code, stdout, _ = runOutput(t, "-chains", "-format", "json")
+	if code != exitOK {
+		t.Fatalf("-format json: exit code %d", code)
+	}
+	var listed []Chain
+	if err := json.Unmarshal([]byte(stdout), &listed); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, stdout)
+	}
+	if !reflect.DeepEqual(listed, chains) {
+		t.Errorf("-chains -format json = %+v, want the registry %+v", listed, chains)
+	}
+}
+
+func TestEnvChain(t *testing.T) {
+	sepolia, _ := chainByID(11155111)
+
+	for _, tc := range []struct {
+		env  string
+		want Chain
+		set  bool
+		err  string
+	}{
+		{"", Chain{}, false, ""},
+		{"11155111", sepolia, true, ""},
+		{"sepolia", Chain{}, false, "want a decimal chain id"},
+		{"10", Chain{}, false, "not in the registry"},
+	} {
+		t.Setenv(chainIDEnv, tc.env)
+		ch, set, err := envChain()
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s=%q: err = %v, want %q", chainIDEnv, tc.env, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || set != tc.set || ch != tc.want {
+			t.Errorf("%s=%q: envChain = %+v, %t, %v, want %+v, %t", chainIDEnv, tc.env, ch, set, err, tc.want, tc.set)
+		}
+	}
+
+	// a chain without a default RPC needs -node
+	saved := chains
+	t.Cleanup(func() { chains = saved })
+	chains = append(slices.Clip(chains), Chain{ID: 10, Name: "optimism", Factory: arbitrumFactory, NPM: arbitrumNPM, Multicall: multicall3Address})
+	t.Setenv(chainIDEnv, "10")
+	if _, _, err := envChain(); err == nil || !strings.Contains(err.Error(), "set -node") {
+		t.Errorf("chain without RPC: err = %v", err)
+	}
+}
+
+func TestRunChainIDEnv(t *testing.T) {
+	sepolia, _ := chainByID(11155111)
+	var mu sync.Mutex
+	var targets []common.Address
+	node := &fakeNode{chainID: sepolia.ID, head: 100, handle: func(to common.Address, data []byte) ([]byte, error) {
+		mu.Lock()
+		targets = append(targets, to)
+		mu.Unlock()
+		if to == sepolia.NPM && methodOf(t, npmABI, data).Name == balanceOfMethod {
+			return packOutputs(t, npmABI, balanceOfMethod, new(big.Int)), nil
+		}
+		return nil, errRevert{}
+	}}
+	url := node.serve(t)
+
+	t.Setenv(chainIDEnv, "11155111")
+	code, _, stderr := runOutput(t, "-node", url, "-max-lag", "0", "-list", "-owner", testOwner.Hex())
+	if code != exitNotFound {
+		t.Fatalf("exit code %d, want %d for an owner without positions; stderr:\n%s", code, exitNotFound, stderr)
+	}
+	if !slices.Contains(targets, sepolia.NPM) || slices.Contains(targets, arbitrumNPM) {
+		t.Errorf("called %v, want the Sepolia position manager %s", targets, sepolia.NPM)
+	}
+
+	// the node must be on the chain CHAIN_ID names
+	t.Setenv(chainIDEnv, "42161")
+	if code, _, stderr := runOutput(t, "-node", url, "-max-lag", "0"); code != exitBadInput || !strings.Contains(stderr, "CHAIN_ID is 42161") {
+		t.Errorf("node on another chain: exit code %d, want %d; stderr:\n%s", code, exitBadInput, stderr)
+	}
+}
+
+func TestNotAPool(t *testing.T) {
+	sepolia, _ := chainByID(11155111)
+	for _, tc := range []struct {
+		address common.Address
+		name    string
+	}{
+		{arbitrumNPM, "NonfungiblePositionManager"},
+		{sepolia.NPM, "NonfungiblePositionManager"},
+		{arbitrumFactory, "factory"},
+		{multicall3Address, "Multicall3"},
+		{common.HexToAddress("0xE592427A0AEce92De3Edee1F18E0157C05861564"), "SwapRouter"},
+	} {
+		err := notAPool(tc.address)
+		if err == nil || !strings.Contains(err.Error(), tc.name) || !strings.Contains(err.Error(), "token id") {
+			t.Errorf("notAPool(%s) = %v, want an error naming the %s", tc.address.Hex(), err, tc.name)
+		}
+	}
+	if err := notAPool(testPool); err != nil {
+		t.Errorf("notAPool(%s) = %v, want nil", testPool.Hex(), err)
+	}
+}
+
+func TestRunPositionManagerAsPool(t *testing.T) {
+	node := positionNode(t, 1000)
+	code, _, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-pool", arbitrumNPM.Hex())
+	if code != exitBadInput {
+		t.Fatalf("exit code %d, want %d; stderr:\n%s", code, exitBadInput, stderr)
+	}
+	if !strings.Contains(stderr, "is the Uniswap V3 NonfungiblePositionManager, not a pool") || !strings.Contains(stderr, "-url") {
+		t.Errorf("stderr doesn't point to reading the NFT:\n%s", stderr)
+	}
+	if n := node.count("eth_call"); n != 0 {
+		t.Errorf("sent %d eth_calls for a rejected pool", n)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var poolABI = mustParseABI(abiUniV3Pool)
+
+// Client reads Uniswap V3 state from a node.
+type Client struct {
+	eth    *ethclient.Client
+	caller ethereum.ContractCaller
+	batch  string
+
+	middleware []Middleware
+	processors []PositionProcessor
+	abis       ABIProvider
+	layouts    []PositionLayout
+	layoutLog  layoutLog
+	prices     PriceOverrides
+	blockTime  time.Duration
+
+	chainMu   sync.Mutex
+	chainInfo *Chain
+
+	multicallMu            sync.Mutex
+	multicallChecked       bool
+	multicallOK            bool
+	multicallHistoryWarned bool
+
+	// pinned, when set, replaces latest in every read, see Pin.
+	pinned *big.Int
+}
+
+// PositionQuery identifies a single position inside a pool.
+type PositionQuery struct {
+	Pool      common.Address
+	Owner     common.Address
+	TickLower Tick
+	TickUpper Tick
+}
+
+type clientOptions struct {
+	middleware []Middleware
+	batch      string
+	proxy      string
+	archiveDir string
+	dryRun     io.Writer
+	// dryRunChain is the chain id a dry run reports.
+	dryRunChain uint64
+	processors  []PositionProcessor
+	abis        ABIProvider
+	audit       io.Writer
+	layouts     []PositionLayout
+	prices      PriceOverrides
+	blockTime   time.Duration
+}
+
+// Option configures a Client.
+type Option func(*clientOptions)
+
+// WithMiddleware adds middleware around every eth_call, in the given order:
+// the first one sees the call first.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(o *clientOptions) {
+		o.middleware = append(o.middleware, middleware...)
+	}
+}
+
+// WithBatch selects how batched reads are sent: BatchMulticall (default) or
+// BatchNative.
+func WithBatch(backend string) Option {
+	return func(o *clientOptions) {
+		o.batch = backend
+	}
+}
+
+// WithProxy sends node requests through proxyURL instead of the proxy from
+// the environment.
+func WithProxy(proxyURL string) Option {
+	return func(o *clientOptions) {
+		o.proxy = proxyURL
+	}
+}
+
+// WithArchive reads from the archive directory at dir instead of a node; the
+// node URL is ignored. See archiveService for the directory layout.
+func WithArchive(dir string) Option {
+	return func(o *clientOptions) {
+		o.archiveDir = dir
+	}
+}
+
+// NewClient connects to the node at rawURL. ctx bounds the dial only.
+func NewClient(ctx context.Context, rawURL string, opts ...Option) (*Client, error) {
+	options := clientOptions{batch: BatchMulticall, abis: ABIs{}, layouts: defaultPositionLayouts}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.batch != BatchMulticall && options.batch != BatchNative {
+		return nil, fmt.Errorf("unsupported batch backend: %s", options.batch)
+	}
+
+	rpcClient, err := dial(ctx, rawURL, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClient(ethclient.NewClient(rpcClient), options), nil
+}
+
+func newClient(eth *ethclient.Client, options clientOptions) *Client {
+	middleware := options.middleware
+	if options.audit != nil {
+		// innermost, to log every request the node sees
+		middleware = append(slices.Clip(middleware), newAuditLog(options.audit, eth).middleware)
+	}
+
+	return &Client{
+		eth:        eth,
+		caller:     chain(classifying(eth), middleware...),
+		batch:      options.batch,
+		middleware: middleware,
+		processors: options.processors,
+		abis:       options.abis,
+		layouts:    options.layouts,
+		prices:     options.prices,
+		blockTime:  options.blockTime,
+	}
+}
+
+func dial(ctx context.Context, rawURL string, options clientOptions) (*rpc.Client, error) {
+	if options.archiveDir != "" {
+		server, err := newArchiveServer(options.archiveDir)
+		if err != nil {
+			return nil, err
+		}
+		return rpc.DialInProc(server), nil
+	}
+
+	if options.dryRun != nil {
+		server, err := newDryRunServer(options.dryRun, options.dryRunChain)
+		if err != nil {
+			return nil, err
+		}
+		return rpc.DialInProc(server), nil
+	}
+
+	if isIPCPath(rawURL) {
+		// The proxy and HTTP transport settings don't apply to a socket.
+		return rpc.DialIPC(ctx, rawURL)
+	}
+
+	if err := validateNodeURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	dialOpts, err := dialOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return rpc.DialOptions(ctx, rawURL, dialOpts...)
+}
+
+var nodeSchemes = []string{"http", "https", "ws", "wss"}
+
+// isIPCPath reports whether rawURL names a local node's IPC endpoint: an
+// absolute socket path, a relative path ending in .ipc, or a Windows named
+// pipe. Bare hosts like arbitrum.llamarpc.com are not IPC paths.
+func isIPCPath(rawURL string) bool {
+	return filepath.IsAbs(rawURL) || strings.HasSuffix(rawURL, ".ipc") || strings.HasPrefix(rawURL, `\\.\pipe\`)
+}
+
+// validateNodeURL catches bare hosts and wrong schemes before ethclient
+// mistakes them for an IPC path. IPC paths are accepted as is.
+func validateNodeURL(rawURL string) error {
+	if isIPCPath(rawURL) {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid node URL %q: %w", rawURL, err)
+	}
+
+	switch {
+	case u.Scheme == "":
+		return fmt.Errorf("node URL %q has no scheme, expected one of %s or an IPC path", rawURL, strings.Join(nodeSchemes, ", "))
+	case !slices.Contains(nodeSchemes, u.Scheme):
+		return fmt.Errorf("unsupported node URL scheme %q, expected one of %s", u.Scheme, strings.Join(nodeSchemes, ", "))
+	case u.Host == "":
+		return fmt.Errorf("node URL %q has no host", rawURL)
+	}
+
+	return nil
+}
+
+// Position reads a single position. A nil block means latest.
+func (c *Client) Position(ctx context.Context, q PositionQuery, block *big.Int) (Position, error) {
+	calldata, err := c.positionCalldata(q)
+	if err != nil {
+		return Position{}, err
+	}
+
+	response, err := c.callContract(ctx, ethereum.CallMsg{To: &q.Pool, Data: calldata}, block)
+	if err != nil {
+		return Position{}, fmt.Errorf("call contract: %w", err)
+	}
+
+	return c.unpackPosition(response)
+}
+
+// Positions reads several positions, possibly across pools, in one multicall.
+// When only some reads revert or time out, the others are returned with a
+// *BatchError and the failed ones are left zero.
+func (c *Client) Positions(ctx context.Context, queries []PositionQuery, block *big.Int) ([]Position, error) {
+	inputs := make([]KeyInput, len(queries))
+	for i, q := range queries {
+		inputs[i] = KeyInput{Owner: q.Owner, TickLower: q.TickLower, TickUpper: q.TickUpper}
+	}
+
+	calls := make([]call, len(queries))
+	for i, key := range calcPositionKeys(inputs) {
+		calldata, err := c.abis.PoolABI().Pack(positionsMethod, key)
+		if err != nil {
+			return nil, err
+		}
+		calls[i] = call{Target: queries[i].Pool, CallData: calldata}
+	}
+
+	responses, err := c.aggregate(ctx, calls, block)
+	var batchErr *BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		return nil, err
+	}
+
+	positions := make([]Position, len(responses))
+	for i, response := range responses {
+		if batchErr != nil && batchErr.Errs[i] != nil {
+			continue
+		}
+		if positions[i], err = c.unpackPosition(response); err != nil {
+			return nil, err
+		}
+	}
+
+	if batchErr != nil {
+		return positions, batchErr
+	}
+
+	return positions, nil
+}
+
+// ConfirmedBlock returns the block confirmations below the head, or nil (the
+// latest block) for zero confirmations.
+func (c *Client) ConfirmedBlock(ctx context.Context, confirmations uint64) (*big.Int, error) {
+	if confirmations == 0 {
+		return nil, nil
+	}
+
+	head, err := c.eth.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get block number: %w", err)
+	}
+	if head < confirmations {
+		return nil, fmt.Errorf("head %d has fewer than %d confirmations", head, confirmations)
+	}
+
+	return new(big.Int).SetUint64(head - confirmations), nil
+}
+
+func (c *Client) callContract(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	return c.caller.CallContract(ctx, msg, c.at(block))
+}
+
+// Pin makes every later read that asks for latest read block instead, so
+// all reads of one command see the same state. Pin is not safe to call
+// concurrently with reads.
+func (c *Client) Pin(block *big.Int) {
+	c.pinned = block
+}
+
+// at resolves a nil block to the pinned one.
+func (c *Client) at(block *big.Int) *big.Int {
+	if block == nil {
+		return c.pinned
+	}
+
+	return block
+}
+
+func (c *Client) positionCalldata(q PositionQuery) ([]byte, error) {
+	positionKey, err := calcPositionKey(q.Owner, q.TickLower, q.TickUpper)
+	if err != nil {
+		return nil, fmt.Errorf("calc position key: %w", err)
+	}
+
+	return c.abis.PoolABI().Pack(positionsMethod, positionKey)
+}
+
+// unpackPosition decodes a positions(bytes32) response, falling back to the
+// client's alternate layouts when it isn't the canonical five words.
+func (c *Client) unpackPosition(response []byte) (Position, error) {
+	if len(response) > 0 && len(response) != 5*32 {
+		return c.unpackLayouts(response)
+	}
+
+	var position Position
+
+	if err := c.abis.PoolABI().UnpackIntoInterface(&position, positionsMethod, response); err != nil {
+		return Position{}, fmt.Errorf("parse result contract: %w, response: %x", err, response)
+	}
+
+	return position, nil
+}
+
+// callPool calls a view method of the pool and returns the unpacked outputs.
+func (c *Client) callPool(ctx context.Context, pool common.Address, method string, block *big.Int, args ...interface{}) ([]interface{}, error) {
+	calldata, err := c.abis.PoolABI().Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("pack %s: %w", method, err)
+	}
+
+	response, err := c.callContract(ctx, ethereum.CallMsg{To: &pool, Data: calldata}, block)
+	if err != nil {
+		return nil, fmt.Errorf("call %s: %w", method, err)
+	}
+
+	out, err := c.abis.PoolABI().Unpack(method, response)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s result: %w", method, err)
+	}
+
+	return out, nil
+}
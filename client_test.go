@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestValidateNodeURL(t *testing.T) {
+	for _, tc := range []struct {
+		url string
+		err string
+	}{
+		{"https://arb1.arbitrum.io/rpc", ""},
+		{"http://localhost:8545", ""},
+		{"wss://arbitrum-one.publicnode.com", ""},
+		{"/var/run/geth.ipc", ""},
+		{"geth.ipc", ""},
+		{`\\.\pipe\geth.ipc`, ""},
+		{"arbitrum.llamarpc.com", "has no scheme"},
+		{"ftp://example.com", `unsupported node URL scheme "ftp"`},
+		{"https://", "has no host"},
+		{"http://[::1", "invalid node URL"},
+	} {
+		err := validateNodeURL(tc.url)
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("validateNodeURL(%q): %v", tc.url, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("validateNodeURL(%q) = %v, want an error containing %q", tc.url, err, tc.err)
+		}
+	}
+}
+
+func TestIsIPCPath(t *testing.T) {
+	for url, want := range map[string]bool{
+		"/home/user/.ethereum/geth.ipc": true,
+		"data/geth.ipc":                 true,
+		`\\.\pipe\geth.ipc`:             true,
+		"arbitrum.llamarpc.com":         false,
+		"https://arb1.arbitrum.io/rpc":  false,
+	} {
+		if got := isIPCPath(url); got != want {
+			t.Errorf("isIPCPath(%q) = %t, want %t", url, got, want)
+		}
+	}
+}
+
+func TestIPCNode(t *testing.T) {
+	node := positionNode(t, 1000)
+	path := node.serveIPC(t)
+
+	// the proxy and HTTP transport settings are skipped for the socket
+	code, _, stderr := runOutput(t, "-node", path, "-max-lag", "0", "-proxy", "http://proxy.invalid:3128")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	if node.count("eth_call") == 0 {
+		t.Error("no eth_call reached the IPC node")
+	}
+}
+
+func TestConfirmedBlock(t *testing.T) {
+	client := newFakeClient(t, &fakeNode{head: 100})
+
+	for _, tc := range []struct {
+		confirmations uint64
+		want          string
+	}{
+		{0, "<nil>"},
+		{1, "99"},
+		{10, "90"},
+		{100, "0"},
+	} {
+		block, err := client.ConfirmedBlock(context.Background(), tc.confirmations)
+		if err != nil || block.String() != tc.want {
+			t.Errorf("ConfirmedBlock(%d) = %s, %v, want %s", tc.confirmations, block, err, tc.want)
+		}
+	}
+	if _, err := client.ConfirmedBlock(context.Background(), 101); err == nil {
+		t.Error("ConfirmedBlock(101) at head 100 succeeded")
+	}
+}
+
+func TestConfirmationsReadBlock(t *testing.T) {
+	node := positionNode(t, 1000)
+
+	code, _, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-confirmations", "10")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	if len(node.blocks) == 0 {
+		t.Fatal("no eth_call sent")
+	}
+	// head 100 - 10
+	for i, block := range node.blocks {
+		if block != "0x5a" {
+			t.Errorf("call %d read block %s, want 0x5a", i, block)
+		}
+	}
+}
+
+func TestPinnedBlock(t *testing.T) {
+	for _, tc := range []struct {
+		at   string
+		args []string
+		want string
+	}{
+		// the head at startup
+		{"latest", nil, "0x64"},
+		{"80", nil, "0x50"},
+		{"latest", []string{"-block", "90"}, "0x5a"},
+		{"latest", []string{"-list", "-owner", testOwner.Hex(), "-format", "table"}, "0x64"},
+		{"80", []string{"-list", "-owner", testOwner.Hex(), "-format", "ndjson"}, "0x50"},
+	} {
+		npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {1, 2, 3}}}
+		node := listNode(t, npm)
+		args := append([]string{"-node", node.serve(t), "-max-lag", "0", "-pool", testPool.Hex(), "-at", tc.at}, tc.args...)
+
+		if code, _, stderr := runOutput(t, args...); code != exitOK && code != exitNotFound {
+			t.Errorf("%q: exit code %d; stderr:\n%s", args[4:], code, stderr)
+			continue
+		}
+		if len(node.blocks) == 0 {
+			t.Errorf("%q: no eth_call sent", args[4:])
+		}
+		for i, block := range node.blocks {
+			if block != tc.want {
+				t.Errorf("%q: call %d read block %s, want %s", args[4:], i, block, tc.want)
+			}
+		}
+	}
+}
+
+func TestPinnedBlockConflicts(t *testing.T) {
+	for _, args := range [][]string{
+		{"-at", "80", "-block", "90"},
+		{"-at", "80", "-confirmations", "2"},
+		{"-at", "head"},
+		{"-at", "latest", "-watch"},
+	} {
+		if code, _, _ := runOutput(t, append(args, "-quiet")...); code != exitBadInput {
+			t.Errorf("%q: exit code %d, want %d", args, code, exitBadInput)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadConfig applies a config file to the flags of fs that were not set on
+// the command line, so explicit flags win.
+//
+// The file is a flat TOML subset: one `key = value` per line, where key is a
+// flag name and value is a quoted string, a bare number or boolean, or an
+// array of quoted strings for repeatable flags such as owner. Blank lines and
+// # comments are ignored; tables and unknown keys are errors.
+//
+//	node = "wss://arbitrum-one-rpc.publicnode.com"
+//	format = "json"
+//	retries = 4
+//	owner = ["0xF829c130478599E4EF49F6e02EDaA1F8736E9B00"]
+func loadConfig(fs *flag.FlagSet, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		key, values, err := parseConfigLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if key == "" {
+			continue
+		}
+
+		if fs.Lookup(key) == nil || key == "config" {
+			return fmt.Errorf("%s:%d: unknown key %q", path, line, key)
+		}
+		if explicit[key] {
+			continue
+		}
+
+		for _, value := range values {
+			if err := fs.Set(key, value); err != nil {
+				return fmt.Errorf("%s:%d: %s: %w", path, line, key, err)
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// parseConfigLine returns the key and values of one line, or an empty key for
+// blank and comment lines.
+func parseConfigLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+	if strings.HasPrefix(line, "[") {
+		return "", nil, fmt.Errorf("tables are not supported: %s", line)
+	}
+
+	key, raw, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", nil, fmt.Errorf("expected key = value: %s", line)
+	}
+	key, raw = strings.TrimSpace(key), strings.TrimSpace(raw)
+
+	if strings.HasPrefix(raw, "[") {
+		if !strings.HasSuffix(raw, "]") {
+			return "", nil, fmt.Errorf("%s: unterminated array", key)
+		}
+
+		var values []string
+		for _, item := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(raw, "["), "]"), ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			value, err := parseConfigValue(item)
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: %w", key, err)
+			}
+			values = append(values, value)
+		}
+
+		return key, values, nil
+	}
+
+	value, err := parseConfigValue(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return key, []string{value}, nil
+}
+
+func parseConfigValue(raw string) (string, error) {
+	if strings.HasPrefix(raw, `"`) {
+		quoted, rest, err := cutQuoted(raw)
+		if err != nil {
+			return "", err
+		}
+		if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected %q after string", rest)
+		}
+		return quoted, nil
+	}
+
+	if value, _, _ := strings.Cut(raw, "#"); strings.TrimSpace(value) != "" {
+		return strings.TrimSpace(value), nil
+	}
+
+	return "", fmt.Errorf("missing value")
+}
+
+// cutQuoted unquotes the basic string at the start of s and returns the rest.
+func cutQuoted(s string) (string, string, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return value, s[i+1:], nil
+		}
+	}
+
+	return "", "", fmt.Errorf("unterminated string %s", s)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	node := fs.String("node", "default", "")
+	format := fs.String("format", "text", "")
+	retries := fs.Int("retries", 2, "")
+	verbose := fs.Bool("verbose", false, "")
+	var owners addressList
+	fs.Var(&owners, "owner", "")
+	fs.String("config", "", "")
+
+	path := writeConfig(t, `# defaults
+node = "wss://arbitrum-one-rpc.publicnode.com"   # comment
+format = "json"
+retries = 4
+verbose = true
+
+owner = ["0x3333333333333333333333333333333333333333", "0x4444444444444444444444444444444444444444"]
+`)
+	if err := fs.Parse([]string{"-format", "table", "-config", path}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(fs, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if *node != "wss://arbitrum-one-rpc.publicnode.com" || *retries != 4 || !*verbose {
+		t.Errorf("node %q, retries %d, verbose %t", *node, *retries, *verbose)
+	}
+	// the command line wins
+	if *format != "table" {
+		t.Errorf("format = %q, want the explicit table", *format)
+	}
+	if len(owners) != 2 || owners[0] != testOwner {
+		t.Errorf("owners = %v", owners)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		content string
+		err     string
+	}{
+		{`nodes = "x"`, `:1: unknown key "nodes"`},
+		{`config = "other.toml"`, `unknown key "config"`},
+		{"\n[rpc]", ":2: tables are not supported"},
+		{`node "x"`, "expected key = value"},
+		{`node = "x`, "unterminated string"},
+		{`node = "x" y`, `unexpected "y" after string`},
+		{`owner = ["0x1"`, "unterminated array"},
+		{`retries = `, "missing value"},
+		{`retries = "many"`, ":1: retries: parse error"},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("node", "", "")
+		fs.Int("retries", 0, "")
+		fs.String("config", "", "")
+		var owners addressList
+		fs.Var(&owners, "owner", "")
+
+		err := loadConfig(fs, writeConfig(t, tc.content))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%q: err = %v, want %q", tc.content, err, tc.err)
+		}
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	node := positionNode(t, 1000)
+	path := writeConfig(t, `node = "`+node.serve(t)+`"
+format = "json"
+max-lag = "0s"
+`)
+
+	code, stdout, stderr := runOutput(t, "-config", path)
+	if code != exitOK || !strings.HasPrefix(stdout, "[") {
+		t.Errorf("exit code %d, output %q; stderr:\n%s", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runOutput(t, "-config", path, "-format", "table")
+	if code != exitOK || !strings.HasPrefix(stdout, "POOL") {
+		t.Errorf("-format table over the config: exit code %d, output %q", code, stdout)
+	}
+
+	if code, _, _ := runOutput(t, "-config", writeConfig(t, "colour = true")); code != exitBadInput {
+		t.Errorf("unknown key: exit code %d, want %d", code, exitBadInput)
+	}
+}
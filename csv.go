@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"math/big"
+	"strconv"
+)
+
+// formatCSV is one comma separated row per result under a header row, for
+// spreadsheets.
+const formatCSV = "csv"
+
+func init() {
+	RegisterFormatter(formatCSV, FormatterFunc(formatCSVResults))
+}
+
+var csvHeader = []string{"pool", "owner", "tokenId", "tickLower", "tickUpper", "liquidity", "tokensOwed0", "tokensOwed1", "error"}
+
+// formatCSVResults writes results with full addresses whatever the options,
+// since a spreadsheet has the room.
+func formatCSVResults(w io.Writer, results []PositionResult, _ FormatOptions) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if err := cw.Write([]string{
+			r.Pool.Hex(),
+			r.Owner.Hex(),
+			csvInt(r.TokenID),
+			strconv.Itoa(int(r.TickLower)),
+			strconv.Itoa(int(r.TickUpper)),
+			csvInt(r.Position.Liquidity),
+			csvInt(r.Position.TokensOwed0),
+			csvInt(r.Position.TokensOwed1),
+			r.Error,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// csvInt is x in decimal, or an empty cell when it is nil.
+func csvInt(x *big.Int) string {
+	if x == nil {
+		return ""
+	}
+
+	return x.String()
+}
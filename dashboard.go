@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// rangeBarWidth is how many cells the tick range takes in the panel.
+	rangeBarWidth = 40
+	clearScreen   = "\033[H\033[2J"
+)
+
+// renderDashboard formats one position as a multi-line panel. Amounts are in
+// raw token units like the rest of the output; value is in token1.
+func renderDashboard(q PositionQuery, info PoolInfo, block *big.Int, fees PositionFees, color, fullAddresses bool) string {
+	amount0, amount1 := AmountsForLiquidity(fees.SqrtPriceX96, q.TickLower, q.TickUpper, fees.Liquidity)
+	value := valueInToken1(new(big.Int).Add(amount0, fees.Fees0), new(big.Int).Add(amount1, fees.Fees1), fees.SqrtPriceX96)
+
+	status, statusColor := "out of range", colorRed
+	if fees.InRange {
+		status, statusColor = "in range", colorGreen
+	}
+	if color {
+		status = statusColor + status + colorReset
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "pool       %s  %s/%s  %s\n", displayAddress(q.Pool, fullAddresses), displayAddress(info.Token0, fullAddresses), displayAddress(info.Token1, fullAddresses), feePercent(info.Fee))
+	fmt.Fprintf(&b, "owner      %s  block %s\n", displayAddress(q.Owner, fullAddresses), blockString(block))
+	fmt.Fprintf(&b, "range      [%d, %d)  tick %d  %s\n", q.TickLower, q.TickUpper, fees.Tick, status)
+	fmt.Fprintf(&b, "           %s\n", rangeBar(q.TickLower, q.TickUpper, fees.Tick, rangeBarWidth))
+	fmt.Fprintf(&b, "liquidity  %s\n", fees.Liquidity)
+	fmt.Fprintf(&b, "amounts    %s token0  %s token1\n", amount0, amount1)
+	fmt.Fprintf(&b, "fees       %s token0  %s token1\n", fees.Fees0, fees.Fees1)
+	fmt.Fprintf(&b, "value      %s token1\n", value)
+
+	return b.String()
+}
+
+// rangeBar draws [lower, upper) as width cells with a marker at tick, or the
+// marker outside the brackets when tick is out of range:
+//
+//	[-------------|------------------------]
+//	|[--------------------------------------]
+func rangeBar(lower, upper, tick Tick, width int) string {
+	cells := []byte(strings.Repeat("-", width))
+
+	switch {
+	case tick < lower:
+		return "|[" + string(cells) + "]"
+	case tick >= upper:
+		return "[" + string(cells) + "]|"
+	}
+
+	i := int(int64(tick-lower) * int64(width) / int64(upper-lower))
+	cells[i] = '|'
+
+	return "[" + string(cells) + "]"
+}
+
+// showDashboard prints the panel of q at block, or with watch redraws it at
+// every new head.
+func showDashboard(ctx context.Context, client *Client, q PositionQuery, block *big.Int, watch, fullAddresses bool) error {
+	infos, err := client.PoolInfos(ctx, []common.Address{q.Pool}, nil)
+	if err != nil {
+		return fmt.Errorf("get pool: %w", err)
+	}
+	info := infos[q.Pool]
+
+	var w io.Writer = os.Stdout
+	terminal := isTerminal(os.Stdout)
+
+	if !watch {
+		fees, err := client.ComputeUncollectedFees(ctx, q, block)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, renderDashboard(q, info, block, fees, terminal, fullAddresses))
+		return err
+	}
+
+	return client.eachHead(ctx, func(head *types.Header) error {
+		fees, err := client.ComputeUncollectedFees(ctx, q, head.Number)
+		if err != nil {
+			log.Printf("dashboard: read position block=%s err=%q", head.Number, err)
+			return nil
+		}
+
+		panel := renderDashboard(q, info, head.Number, fees, terminal, fullAddresses)
+		if terminal {
+			panel = clearScreen + panel
+		} else {
+			panel += "\n"
+		}
+		_, err = io.WriteString(w, panel)
+		return err
+	})
+}
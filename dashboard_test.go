@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestRangeBar(t *testing.T) {
+	for _, tc := range []struct {
+		tick Tick
+		want string
+	}{
+		{-60, "[|---------]"},
+		{0, "[-----|----]"},
+		{59, "[---------|]"},
+		{-61, "|[----------]"},
+		{60, "[----------]|"},
+	} {
+		if got := rangeBar(-60, 60, tc.tick, 10); got != tc.want {
+			t.Errorf("rangeBar at tick %d = %s, want %s", tc.tick, got, tc.want)
+		}
+	}
+}
+
+func TestRenderDashboard(t *testing.T) {
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	info := PoolInfo{Token0: arbWETH, Token1: arbUSDC, Fee: 500}
+	fees := PositionFees{
+		Liquidity:    big.NewInt(1e18),
+		Tick:         0,
+		SqrtPriceX96: Tick(0).SqrtRatio(),
+		InRange:      true,
+		Fees0:        big.NewInt(7),
+		Fees1:        big.NewInt(9),
+	}
+	amount0, amount1 := AmountsForLiquidity(fees.SqrtPriceX96, q.TickLower, q.TickUpper, fees.Liquidity)
+	// at price 1, value is the sum of everything
+	value := new(big.Int).Add(amount0, amount1)
+	value.Add(value, big.NewInt(16))
+
+	panel := renderDashboard(q, info, big.NewInt(100), fees, false, false)
+	want := []string{
+		"pool       " + shortAddress(testPool) + "  " + shortAddress(arbWETH) + "/" + shortAddress(arbUSDC) + "  0.05%",
+		"owner      " + shortAddress(testOwner) + "  block 100",
+		"range      [-60, 60)  tick 0  in range",
+		"           " + rangeBar(-60, 60, 0, rangeBarWidth),
+		"liquidity  1000000000000000000",
+		"amounts    " + amount0.String() + " token0  " + amount1.String() + " token1",
+		"fees       7 token0  9 token1",
+		"value      " + value.String() + " token1",
+	}
+	if got := strings.Split(strings.TrimSuffix(panel, "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("panel:\n%s\nwant:\n%s", panel, strings.Join(want, "\n"))
+	}
+
+	fees.Tick, fees.InRange = 120, false
+	panel = renderDashboard(q, info, nil, fees, true, true)
+	for _, want := range []string{testPool.Hex(), "block latest", colorRed + "out of range" + colorReset, "]|"} {
+		if !strings.Contains(panel, want) {
+			t.Errorf("panel lacks %q:\n%s", want, panel)
+		}
+	}
+}
+
+func TestRunDashboard(t *testing.T) {
+	pool := newFakePool(0, 1e18)
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	pool.setPosition(t, q, Position{Liquidity: big.NewInt(1e18), TokensOwed0: big.NewInt(7)})
+	node := &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)}
+
+	code, stdout, stderr := runOutput(t, "-dashboard", "-node", node.serve(t), "-max-lag", "0",
+		"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+
+	client := newFakeClient(t, node)
+	fees, err := client.ComputeUncollectedFees(context.Background(), q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err := client.PoolInfos(context.Background(), []common.Address{testPool}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := renderDashboard(q, infos[testPool], nil, fees, false, false); stdout != want {
+		t.Errorf("stdout:\n%s\nwant:\n%s", stdout, want)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DEX is a Uniswap V3 style exchange whose pool addresses follow from CREATE2.
+// Deployers holds the contract that deploys its pools on each chain by chain
+// id: the factory itself for Uniswap and most forks, a separate PoolDeployer
+// for PancakeSwap.
+type DEX struct {
+	Name         string
+	InitCodeHash common.Hash
+	Deployers    map[uint64]common.Address
+}
+
+// https://github.com/Uniswap/v3-periphery/blob/main/contracts/libraries/PoolAddress.sol
+var uniswapV3 = DEX{
+	Name:         "uniswap-v3",
+	InitCodeHash: common.HexToHash("0xe34f199b19b2b4f47f68442619d555527d244f78a3297ea89325f843f87b8b54"),
+	Deployers: map[uint64]common.Address{
+		1:        common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984"),
+		42161:    common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984"),
+		11155111: common.HexToAddress("0x0227628f3F023bb0B980b67D528571c95c6DaC1c"),
+	},
+}
+
+// SushiSwap V3 deploys unmodified Uniswap V3 pools from its own factories.
+// https://docs.sushi.com/contracts/cpamm
+var sushiSwapV3 = DEX{
+	Name:         "sushiswap-v3",
+	InitCodeHash: uniswapV3.InitCodeHash,
+	Deployers: map[uint64]common.Address{
+		1:     common.HexToAddress("0xbACEB8eC6b9355Dfc0269C18bac9d6E2Bdc29C4F"),
+		42161: common.HexToAddress("0x1af415a1EbA07a4986a52B6f2e7dE7003D82231e"),
+	},
+}
+
+// PancakeSwap V3 pools come from a PoolDeployer at the same address on every
+// chain, with their own bytecode.
+// https://developer.pancakeswap.finance/contracts/v3/addresses
+var pancakeSwapV3 = DEX{
+	Name:         "pancakeswap-v3",
+	InitCodeHash: common.HexToHash("0x6ce8eb472fa82df5469c6ab6d485f17c3ad13c8cd7af59b3d4a8026c5ce0f7e2"),
+	Deployers: map[uint64]common.Address{
+		1:     common.HexToAddress("0x41ff9AA7e16B8B1a8a8dc4f0eFacd93D02d071c9"),
+		42161: common.HexToAddress("0x41ff9AA7e16B8B1a8a8dc4f0eFacd93D02d071c9"),
+	},
+}
+
+var dexes = []DEX{uniswapV3, sushiSwapV3, pancakeSwapV3}
+
+// defaultDEXChain is the chain of the default -node, which -pool-of uses
+// without CHAIN_ID.
+const defaultDEXChain = 42161
+
+func dexByName(name string) (DEX, bool) {
+	for _, d := range dexes {
+		if d.Name == name {
+			return d, true
+		}
+	}
+
+	return DEX{}, false
+}
+
+// dexNames are the names of dexes, for errors.
+func dexNames() string {
+	names := make([]string, len(dexes))
+	for i, d := range dexes {
+		names[i] = d.Name
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// parsePoolSpec parses a -pool-of pool, TOKEN_A,TOKEN_B,FEE with the fee in
+// hundredths of a basis point.
+func parsePoolSpec(s string) (tokenA, tokenB common.Address, fee uint32, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return common.Address{}, common.Address{}, 0, fmt.Errorf("%q is not TOKEN_A,TOKEN_B,FEE", s)
+	}
+	if tokenA, err = parseAddress(strings.TrimSpace(parts[0])); err != nil {
+		return common.Address{}, common.Address{}, 0, err
+	}
+	if tokenB, err = parseAddress(strings.TrimSpace(parts[1])); err != nil {
+		return common.Address{}, common.Address{}, 0, err
+	}
+	if tokenA == tokenB {
+		return common.Address{}, common.Address{}, 0, fmt.Errorf("both tokens are %s", tokenA.Hex())
+	}
+	f, err := strconv.ParseUint(strings.TrimSpace(parts[2]), 10, 24)
+	if err != nil {
+		return common.Address{}, common.Address{}, 0, fmt.Errorf("invalid fee %q: want hundredths of a basis point, e.g. 500 for 0.05%%", parts[2])
+	}
+
+	return tokenA, tokenB, uint32(f), nil
+}
+
+// PoolAddress is ComputePoolAddress with the DEX's deployer on chainID.
+func (d DEX) PoolAddress(chainID uint64, tokenA, tokenB common.Address, fee uint32) (common.Address, error) {
+	deployer, ok := d.Deployers[chainID]
+	if !ok {
+		return common.Address{}, fmt.Errorf("%s has no known deployment on chain %d", d.Name, chainID)
+	}
+
+	return ComputePoolAddress(deployer, d.InitCodeHash, tokenA, tokenB, fee), nil
+}
+
+// ComputePoolAddress is PoolAddress.computeAddress: the CREATE2 address of the
+// pool of tokenA and tokenB, in either order, with fee, deployed by deployer
+// from bytecode hashing to initCodeHash. It doesn't check the pool exists.
+func ComputePoolAddress(deployer common.Address, initCodeHash common.Hash, tokenA, tokenB common.Address, fee uint32) common.Address {
+	if bytes.Compare(tokenA[:], tokenB[:]) > 0 {
+		tokenA, tokenB = tokenB, tokenA
+	}
+
+	// keccak256(abi.encode(token0, token1, fee))
+	feeWord := common.BigToHash(new(big.Int).SetUint64(uint64(fee)))
+	salt := crypto.Keccak256(common.LeftPadBytes(tokenA[:], 32), common.LeftPadBytes(tokenB[:], 32), feeWord[:])
+
+	return crypto.CreateAddress2(deployer, common.BytesToHash(salt), initCodeHash[:])
+}
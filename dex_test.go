@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	arbitrumWETH = common.HexToAddress("0x82aF49447D8a07e3bd95BD0d56f35241523fBab1")
+	arbitrumUSDC = common.HexToAddress("0xaf88d065e77c8cC2239327C5EDb3A432268e5831")
+	mainnetWETH  = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	mainnetUSDC  = common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+)
+
+func TestComputePoolAddress(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		chainID        uint64
+		tokenA, tokenB common.Address
+		fee            uint32
+		want           common.Address
+	}{
+		// the default -pool
+		{"arbitrum WETH/USDC 0.05%", 42161, arbitrumWETH, arbitrumUSDC, 500, poolAddress},
+		{"arbitrum USDC/WETH 0.05%", 42161, arbitrumUSDC, arbitrumWETH, 500, poolAddress},
+		{"ethereum USDC/WETH 0.05%", 1, mainnetUSDC, mainnetWETH, 500, common.HexToAddress("0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640")},
+		{"ethereum USDC/WETH 0.3%", 1, mainnetUSDC, mainnetWETH, 3000, common.HexToAddress("0x8ad599c3A0ff1De082011EFDDc58f1908eb6e6D8")},
+	} {
+		got, err := uniswapV3.PoolAddress(tc.chainID, tc.tokenA, tc.tokenB, tc.fee)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: pool = %s, %v, want %s", tc.name, got.Hex(), err, tc.want.Hex())
+		}
+	}
+
+	// forks deploy the same pair elsewhere
+	sushi, err := sushiSwapV3.PoolAddress(42161, arbitrumWETH, arbitrumUSDC, 500)
+	if err != nil || sushi == poolAddress {
+		t.Errorf("sushiswap pool = %s, %v, want another address", sushi.Hex(), err)
+	}
+	if _, err := pancakeSwapV3.PoolAddress(11155111, arbitrumWETH, arbitrumUSDC, 500); err == nil {
+		t.Error("want an error for a chain without a deployment")
+	}
+}
+
+func TestParsePoolSpec(t *testing.T) {
+	tokenA, tokenB, fee, err := parsePoolSpec(arbitrumWETH.Hex() + ", " + arbitrumUSDC.Hex() + ", 500")
+	if err != nil || tokenA != arbitrumWETH || tokenB != arbitrumUSDC || fee != 500 {
+		t.Errorf("parsePoolSpec = %s, %s, %d, %v", tokenA.Hex(), tokenB.Hex(), fee, err)
+	}
+
+	for _, spec := range []string{
+		arbitrumWETH.Hex() + "," + arbitrumUSDC.Hex(),
+		arbitrumWETH.Hex() + "," + arbitrumWETH.Hex() + ",500",
+		arbitrumWETH.Hex() + "," + arbitrumUSDC.Hex() + ",0.05%",
+		arbitrumWETH.Hex() + "," + arbitrumUSDC.Hex() + ",16777216",
+		"0x1234," + arbitrumUSDC.Hex() + ",500",
+	} {
+		if _, _, _, err := parsePoolSpec(spec); err == nil {
+			t.Errorf("parsePoolSpec(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestComputePool(t *testing.T) {
+	var out bytes.Buffer
+	if err := computePool(&out, mainnetUSDC.Hex()+","+mainnetWETH.Hex()+",500", uniswapV3.Name, 1); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640\n" {
+		t.Errorf("output = %q", out.String())
+	}
+
+	if err := computePool(&out, mainnetUSDC.Hex()+","+mainnetWETH.Hex()+",500", "curve", 1); exitCode(err) != exitBadInput || !strings.Contains(err.Error(), "uniswap-v3, sushiswap-v3, pancakeswap-v3") {
+		t.Errorf("unknown dex: err = %v", err)
+	}
+}
+
+func TestRunPoolOf(t *testing.T) {
+	code, stdout, stderr := runOutput(t, "-pool-of", arbitrumWETH.Hex()+","+arbitrumUSDC.Hex()+",500")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if stdout != poolAddress.Hex()+"\n" {
+		t.Errorf("output = %q, want the default pool", stdout)
+	}
+
+	t.Setenv(chainIDEnv, "1")
+	if code, stdout, _ := runOutput(t, "-pool-of", mainnetUSDC.Hex()+","+mainnetWETH.Hex()+",3000"); code != exitOK || stdout != "0x8ad599c3A0ff1De082011EFDDc58f1908eb6e6D8\n" {
+		t.Errorf("CHAIN_ID 1: exit code %d, output %q", code, stdout)
+	}
+
+	if code, _, _ := runOutput(t, "-dex", sushiSwapV3.Name); code != exitBadInput {
+		t.Errorf("-dex alone: exit code = %d, want %d", code, exitBadInput)
+	}
+}
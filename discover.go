@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// discoverChunk is how many blocks one eth_getLogs request covers; public
+// nodes commonly cap ranges around 10k blocks.
+const discoverChunk = 10_000
+
+// DiscoverTokenIDs scans NPM Transfer logs in [fromBlock, toBlock] for
+// position NFTs minted or transferred to or from any of owners, and returns
+// their ids in the order they were found. Ids whose last Transfer in the
+// range goes to the zero address were burned, and are left out since the
+// NPM reverts every read of them. The range is read in chunks of
+// discoverChunk blocks.
+func (c *Client) DiscoverTokenIDs(ctx context.Context, owners []common.Address, fromBlock, toBlock uint64) ([]*big.Int, error) {
+	ch, err := c.chain(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerTopics := make([]common.Hash, len(owners))
+	for i, owner := range owners {
+		ownerTopics[i] = common.BytesToHash(owner.Bytes())
+	}
+	event := c.abis.NPMABI().Events[transferEvent]
+
+	var found []common.Hash
+	last := make(map[common.Hash]types.Log)
+	for start := fromBlock; start <= toBlock; start += discoverChunk {
+		end := min(start+discoverChunk-1, toBlock)
+
+		// received and sent, as topics can't OR across positions
+		for _, topics := range [][][]common.Hash{
+			{{event.ID}, nil, ownerTopics},
+			{{event.ID}, ownerTopics},
+		} {
+			logs, err := c.eth.FilterLogs(ctx, ethereum.FilterQuery{
+				FromBlock: new(big.Int).SetUint64(start),
+				ToBlock:   new(big.Int).SetUint64(end),
+				Addresses: []common.Address{ch.NPM},
+				Topics:    topics,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("filter transfer logs %d-%d: %w", start, end, err)
+			}
+
+			for _, l := range logs {
+				if len(l.Topics) != 4 {
+					return nil, fmt.Errorf("transfer log %s:%d has %d topics", l.TxHash, l.Index, len(l.Topics))
+				}
+				id := l.Topics[3]
+				prev, seen := last[id]
+				if !seen {
+					found = append(found, id)
+				}
+				if !seen || laterLog(l, prev) {
+					last[id] = l
+				}
+			}
+		}
+
+		if end == toBlock {
+			break
+		}
+	}
+
+	var tokenIDs []*big.Int
+	for _, id := range found {
+		if last[id].Topics[2] != (common.Hash{}) {
+			tokenIDs = append(tokenIDs, id.Big())
+		}
+	}
+
+	return tokenIDs, nil
+}
+
+// laterLog reports whether a was emitted after b.
+func laterLog(a, b types.Log) bool {
+	if a.BlockNumber != b.BlockNumber {
+		return a.BlockNumber > b.BlockNumber
+	}
+
+	return a.Index > b.Index
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var arbitrumNPM = common.HexToAddress("0xC36442b4a4522E871399CD717aBDD847Ab11FE88")
+
+// transferLog is an NPM Transfer of tokenID from from to to.
+func transferLog(from, to common.Address, tokenID int64, block uint64, index uint) types.Log {
+	return types.Log{
+		Address: arbitrumNPM,
+		Topics: []common.Hash{
+			npmABI.Events[transferEvent].ID,
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+			common.BigToHash(big.NewInt(tokenID)),
+		},
+		BlockNumber: block,
+		Index:       index,
+	}
+}
+
+func TestDiscoverTokenIDs(t *testing.T) {
+	other := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	var zero common.Address
+
+	node := &fakeNode{head: 30_000, logs: []types.Log{
+		transferLog(zero, testOwner, 1, 10, 0),
+		transferLog(zero, testOwner, 2, 11, 0),
+		// burned after being sent away and back
+		transferLog(testOwner, other, 2, 12, 0),
+		transferLog(other, testOwner, 2, 12, 1),
+		transferLog(testOwner, zero, 2, 12_500, 0),
+		// in a later chunk
+		transferLog(zero, testOwner, 3, 25_000, 0),
+		// sent away, still exists
+		transferLog(zero, testOwner, 4, 25_001, 0),
+		transferLog(testOwner, other, 4, 25_002, 0),
+		// someone else's
+		transferLog(zero, other, 5, 25_003, 0),
+	}}
+	client := newFakeClient(t, node)
+
+	tokenIDs, err := client.DiscoverTokenIDs(context.Background(), []common.Address{testOwner}, 0, node.head)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int64{1, 3, 4}
+	if len(tokenIDs) != len(want) {
+		t.Fatalf("got token ids %v, want %v", tokenIDs, want)
+	}
+	for i, id := range tokenIDs {
+		if id.Int64() != want[i] {
+			t.Errorf("token id %d = %s, want %d", i, id, want[i])
+		}
+	}
+}
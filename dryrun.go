@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"reflect"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// dryRunHead is the block number a dry run reports as the head, high enough
+// for -confirmations and -since to resolve.
+const dryRunHead = 100_000_000
+
+// DryRunRequest is one line of the -dry-run log: a JSON-RPC request the tool
+// would have sent. Calls lists the reads inside a Multicall3 aggregate3.
+type DryRunRequest struct {
+	Method string          `json:"method,omitempty"`
+	To     *common.Address `json:"to,omitempty"`
+	Call   string          `json:"call,omitempty"`
+	Data   hexutil.Bytes   `json:"data,omitempty"`
+	Block  string          `json:"block,omitempty"`
+	Calls  []DryRunRequest `json:"calls,omitempty"`
+}
+
+// WithDryRun logs every request to w as a DryRunRequest JSON line instead of
+// sending it; the node URL is ignored. Reads are answered with zero values
+// and the chain is chainID, so a command runs through with the requests it
+// would send to a real node, minus those that depend on answers.
+func WithDryRun(w io.Writer, chainID uint64) Option {
+	return func(o *clientOptions) {
+		o.dryRun = w
+		o.dryRunChain = chainID
+	}
+}
+
+// dryRunChainID is the chain a dry run against node pretends to be on: the
+// registry chain with node as its default RPC, otherwise the default node's.
+func dryRunChainID(node string) uint64 {
+	for _, url := range []string{node, nodeAddr} {
+		for _, ch := range chains {
+			if ch.RPC == url {
+				return ch.ID
+			}
+		}
+	}
+
+	return 0
+}
+
+// dryRunService answers the eth_ methods the tool uses, logging each request.
+// It reports code at every address so batches go through Multicall3 as they
+// would on a chain that has it.
+type dryRunService struct {
+	chainID uint64
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newDryRunServer(w io.Writer, chainID uint64) (*rpc.Server, error) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &dryRunService{chainID: chainID, enc: json.NewEncoder(w)}); err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+func (s *dryRunService) log(req DryRunRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enc.Encode(req)
+}
+
+func (s *dryRunService) ChainId() (hexutil.Uint64, error) {
+	return hexutil.Uint64(s.chainID), s.log(DryRunRequest{Method: "eth_chainId"})
+}
+
+func (s *dryRunService) BlockNumber() (hexutil.Uint64, error) {
+	return dryRunHead, s.log(DryRunRequest{Method: "eth_blockNumber"})
+}
+
+func (s *dryRunService) GetCode(address common.Address, block json.RawMessage) (hexutil.Bytes, error) {
+	return hexutil.Bytes{0}, s.log(DryRunRequest{Method: "eth_getCode", To: &address, Block: blockParam(block)})
+}
+
+// GetBlockByNumber returns an empty header numbered as asked, with the number
+// as its timestamp so that block times increase.
+func (s *dryRunService) GetBlockByNumber(block json.RawMessage, _ bool) (*types.Header, error) {
+	param := blockParam(block)
+	if err := s.log(DryRunRequest{Method: "eth_getBlockByNumber", Block: param}); err != nil {
+		return nil, err
+	}
+
+	number := uint64(dryRunHead)
+	if n, err := hexutil.DecodeUint64(param); err == nil {
+		number = n
+	}
+
+	return &types.Header{Number: new(big.Int).SetUint64(number), Time: number, Difficulty: new(big.Int)}, nil
+}
+
+func (s *dryRunService) Call(args archiveCallArgs, block json.RawMessage) (hexutil.Bytes, error) {
+	calldata := args.Input
+	if calldata == nil {
+		calldata = args.Data
+	}
+
+	req := DryRunRequest{Method: "eth_call", To: args.To, Call: methodName(calldata), Data: calldata, Block: blockParam(block)}
+	if args.To == nil || *args.To != multicall3Address || req.Call != aggregate3Method {
+		return zeroReturn(calldata), s.log(req)
+	}
+
+	aggregate3 := multicall3ABI.Methods[aggregate3Method]
+	in, err := aggregate3.Inputs.Unpack(calldata[4:])
+	if err != nil {
+		return nil, err
+	}
+	calls := *abi.ConvertType(in[0], new([]call3)).(*[]call3)
+
+	results := make([]call3Result, len(calls))
+	for i, cl := range calls {
+		req.Calls = append(req.Calls, DryRunRequest{To: &cl.Target, Call: methodName(cl.CallData), Data: cl.CallData})
+		results[i] = call3Result{Success: true, ReturnData: zeroReturn(cl.CallData)}
+	}
+	if err := s.log(req); err != nil {
+		return nil, err
+	}
+
+	return aggregate3.Outputs.Pack(results)
+}
+
+// blockParam is a block number or tag as sent, "latest" or "0x...".
+func blockParam(raw json.RawMessage) string {
+	var param string
+	if err := json.Unmarshal(raw, &param); err != nil {
+		return string(raw)
+	}
+
+	return param
+}
+
+// zeroReturn is the ABI encoding of zero values for every output of the
+// method calldata calls, or a zero word for an unknown method.
+func zeroReturn(calldata []byte) []byte {
+	method, ok := knownMethod(calldata)
+	if !ok {
+		return make([]byte, 32)
+	}
+
+	values := make([]interface{}, len(method.Outputs))
+	for i, output := range method.Outputs {
+		if t := output.Type.GetType(); t == reflect.TypeOf((*big.Int)(nil)) {
+			values[i] = new(big.Int)
+		} else {
+			values[i] = reflect.Zero(t).Interface()
+		}
+	}
+
+	response, err := method.Outputs.Pack(values...)
+	if err != nil {
+		return make([]byte, 32*len(method.Outputs))
+	}
+
+	return response
+}
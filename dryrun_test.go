@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// dryRunRequests parses the request lines of -dry-run output, skipping log
+// lines.
+func dryRunRequests(t *testing.T, out string) []DryRunRequest {
+	t.Helper()
+
+	var requests []DryRunRequest
+	for scanner := bufio.NewScanner(strings.NewReader(out)); scanner.Scan(); {
+		if !strings.HasPrefix(scanner.Text(), "{") {
+			continue
+		}
+		var req DryRunRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			t.Fatalf("parse %s: %v", scanner.Text(), err)
+		}
+		requests = append(requests, req)
+	}
+
+	return requests
+}
+
+func TestRunDryRun(t *testing.T) {
+	// nothing listens there: the dry run never dials
+	code, stdout, stderr := runOutput(t, "-dry-run", "-node", "http://node.invalid:8545", "-block", "90",
+		"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60")
+	// every read is answered with zeros, which is an empty position
+	if code != exitNotFound {
+		t.Fatalf("exit code %d, want %d; stderr:\n%s", code, exitNotFound, stderr)
+	}
+	if stdout != "" {
+		t.Errorf("stdout = %q, want the requests on stderr only", stdout)
+	}
+
+	key, err := calcPositionKey(testOwner, -60, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calldata, err := poolABI.Pack(positionsMethod, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := dryRunRequests(t, stderr)
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3:\n%s", len(requests), stderr)
+	}
+	if r := requests[0]; r.Method != "eth_getCode" || *r.To != multicall3Address {
+		t.Errorf("request 0 = %+v, want the Multicall3 code check", r)
+	}
+	for i, want := range [][]string{
+		{positionsMethod},
+		{feeMethod, token0Method, token1Method, tickSpacingMethod},
+	} {
+		r := requests[i+1]
+		if r.Method != "eth_call" || *r.To != multicall3Address || r.Call != aggregate3Method || r.Block != "0x5a" {
+			t.Errorf("request %d = %+v, want an aggregate3 at 0x5a", i+1, r)
+		}
+		if len(r.Calls) != len(want) {
+			t.Errorf("request %d has %d calls, want %v", i+1, len(r.Calls), want)
+			continue
+		}
+		for j, cl := range r.Calls {
+			if cl.Call != want[j] || *cl.To != testPool {
+				t.Errorf("request %d call %d = %s on %s, want %s on %s", i+1, j, cl.Call, cl.To, want[j], testPool)
+			}
+		}
+	}
+	if got := requests[1].Calls[0].Data; hexutil.Encode(got) != hexutil.Encode(calldata) {
+		t.Errorf("positions calldata = %s, want %s", got, hexutil.Bytes(calldata))
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrorKind tells why an eth_call failed.
+type ErrorKind int
+
+const (
+	KindUnknown ErrorKind = iota
+	// KindRevert means the node executed the call and the contract reverted.
+	KindRevert
+	// KindTransport means the request didn't make it to the node or back.
+	KindTransport
+	// KindRateLimit means the node refused the request because of a quota.
+	KindRateLimit
+	// KindStateUnavailable means the node no longer has the state of the
+	// requested block, typically a pruned full node asked for history.
+	KindStateUnavailable
+)
+
+// ErrStateUnavailable matches, with errors.Is, calls that failed because the
+// node lacks the requested block's state. Historical reads need an archive
+// node.
+var ErrStateUnavailable = errors.New("historical state unavailable")
+
+// JSON-RPC error codes
+// https://github.com/ethereum/EIPs/blob/master/EIPS/eip-1474.md#error-codes
+const (
+	codeExecutionReverted = 3
+	codeLimitExceeded     = -32005
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindRevert:
+		return "revert"
+	case KindTransport:
+		return "transport"
+	case KindRateLimit:
+		return "rate limit"
+	case KindStateUnavailable:
+		return "state unavailable"
+	default:
+		return "unknown"
+	}
+}
+
+// Retryable reports whether the same call may succeed if sent again.
+func (k ErrorKind) Retryable() bool {
+	return k == KindTransport || k == KindRateLimit
+}
+
+// CallError is a failed eth_call with its classification.
+type CallError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("%s error: %v", e.Kind, e.Err)
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CallError) Is(target error) bool {
+	return target == ErrStateUnavailable && e.Kind == KindStateUnavailable
+}
+
+// isRevert reports whether err is a call the contract reverted, classified
+// already as a *CallError or not.
+func isRevert(err error) bool {
+	var callErr *CallError
+	if errors.As(err, &callErr) {
+		return callErr.Kind == KindRevert
+	}
+
+	return classifyError(err) == KindRevert
+}
+
+// classifyError sorts errors returned by go-ethereum's CallContract. Reverts
+// and transport failures come back through the same error value, so typed
+// errors are checked first and the message is the last resort.
+func classifyError(err error) ErrorKind {
+	if err == nil {
+		return KindUnknown
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.StatusCode == http.StatusTooManyRequests:
+			return KindRateLimit
+		case httpErr.StatusCode >= http.StatusInternalServerError:
+			return KindTransport
+		}
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.ErrorCode() {
+		case codeExecutionReverted:
+			return KindRevert
+		case codeLimitExceeded:
+			return KindRateLimit
+		}
+	}
+
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) && dataErr.ErrorData() != nil {
+		return KindRevert
+	}
+
+	// an HTML page won't turn into JSON-RPC on a retry
+	if errors.Is(err, ErrBadEndpoint) {
+		return KindUnknown
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.As(err, &netErr):
+		return KindTransport
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	// geth, erigon and nethermind wording for pruned state
+	case strings.Contains(msg, "missing trie node"),
+		strings.Contains(msg, "header not found"),
+		strings.Contains(msg, "historical state"),
+		strings.Contains(msg, "state is not available"),
+		strings.Contains(msg, "state histories haven't been fully indexed"):
+		return KindStateUnavailable
+	case strings.Contains(msg, "revert"):
+		return KindRevert
+	case strings.Contains(msg, "rate limit"), strings.Contains(msg, "too many requests"), strings.Contains(msg, "429"):
+		return KindRateLimit
+	case strings.Contains(msg, "connection"), strings.Contains(msg, "timeout"), strings.Contains(msg, "eof"):
+		return KindTransport
+	}
+
+	return KindUnknown
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestClassifyError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want ErrorKind
+	}{
+		{nil, KindUnknown},
+		{errRevert{}, KindRevert},
+		{errRateLimited{}, KindRateLimit},
+		{rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, KindRateLimit},
+		{rpc.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}, KindTransport},
+		{fmt.Errorf("%w: text/html", ErrBadEndpoint), KindUnknown},
+		{context.DeadlineExceeded, KindTransport},
+		{io.ErrUnexpectedEOF, KindTransport},
+		{fmt.Errorf("post: %w", syscall.ECONNREFUSED), KindTransport},
+		{&net.OpError{Op: "dial", Err: errors.New("no route to host")}, KindTransport},
+		{errors.New("missing trie node 9a3f (path ) state 0x9a3f is not available"), KindStateUnavailable},
+		{errors.New("header not found"), KindStateUnavailable},
+		{errors.New("execution reverted: Not approved"), KindRevert},
+		{errors.New("daily request count exceeded, request rate limited"), KindRateLimit},
+		{errors.New("429 Too Many Requests: {}"), KindRateLimit},
+		{errors.New("read: connection reset by peer"), KindTransport},
+		{errors.New("invalid argument 0: hex string without 0x prefix"), KindUnknown},
+	} {
+		if got := classifyError(tc.err); got != tc.want {
+			t.Errorf("classifyError(%v) = %s, want %s", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestCallError(t *testing.T) {
+	err := fmt.Errorf("call: %w", &CallError{Kind: KindStateUnavailable, Err: errors.New("missing trie node")})
+	if !errors.Is(err, ErrStateUnavailable) {
+		t.Errorf("%v doesn't match ErrStateUnavailable", err)
+	}
+	if isRevert(err) || errorKind(err).Retryable() {
+		t.Errorf("%v is a revert or retryable", err)
+	}
+
+	// a classified error keeps its kind whatever the message says
+	err = &CallError{Kind: KindTransport, Err: errors.New("execution reverted")}
+	if isRevert(err) || !errorKind(err).Retryable() {
+		t.Errorf("%v: kind %s, want transport", err, errorKind(err))
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// CollectEvent is a decoded pool Collect log.
+type CollectEvent struct {
+	Owner       common.Address `json:"owner"`
+	Recipient   common.Address `json:"recipient"`
+	TickLower   Tick           `json:"tickLower"`
+	TickUpper   Tick           `json:"tickUpper"`
+	Amount0     *big.Int       `json:"amount0"`
+	Amount1     *big.Int       `json:"amount1"`
+	BlockNumber uint64         `json:"blockNumber"`
+	TxHash      common.Hash    `json:"txHash"`
+	LogIndex    uint           `json:"logIndex"`
+}
+
+// CollectHistory returns the Collect events of one position in
+// [fromBlock, toBlock]. Nil bounds mean genesis and latest.
+func (c *Client) CollectHistory(ctx context.Context, pool common.Address, owner common.Address, tickLower, tickUpper Tick, fromBlock, toBlock *big.Int) ([]CollectEvent, error) {
+	poolABI := c.abis.PoolABI()
+	event := poolABI.Events[collectEvent]
+
+	logs, err := c.eth.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+		Addresses: []common.Address{pool},
+		Topics: [][]common.Hash{
+			{event.ID},
+			{common.BytesToHash(owner.Bytes())},
+			{int24Topic(tickLower)},
+			{int24Topic(tickUpper)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filter collect logs: %w", err)
+	}
+
+	events := make([]CollectEvent, 0, len(logs))
+	for _, l := range logs {
+		e, err := decodeCollect(poolABI, l)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// writeCollects prints events as a table with their totals, or as JSON.
+func writeCollects(w io.Writer, format string, events []CollectEvent) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(events)
+	case formatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, e := range events {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	total0, total1 := new(big.Int), new(big.Int)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BLOCK\tTX\tRECIPIENT\tAMOUNT0\tAMOUNT1")
+	for _, e := range events {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", e.BlockNumber, e.TxHash.Hex(), e.Recipient.Hex(), e.Amount0, e.Amount1)
+		total0.Add(total0, e.Amount0)
+		total1.Add(total1, e.Amount1)
+	}
+	fmt.Fprintf(tw, "total\t\t\t%s\t%s\n", total0, total1)
+
+	return tw.Flush()
+}
+
+func decodeCollect(poolABI *abi.ABI, l types.Log) (CollectEvent, error) {
+	if len(l.Topics) != 4 {
+		return CollectEvent{}, fmt.Errorf("collect log %s:%d has %d topics", l.TxHash, l.Index, len(l.Topics))
+	}
+
+	var data struct {
+		Recipient common.Address
+		Amount0   *big.Int
+		Amount1   *big.Int
+	}
+	if err := poolABI.UnpackIntoInterface(&data, collectEvent, l.Data); err != nil {
+		return CollectEvent{}, fmt.Errorf("parse collect log %s:%d: %w", l.TxHash, l.Index, err)
+	}
+
+	return CollectEvent{
+		Owner:       common.BytesToAddress(l.Topics[1].Bytes()),
+		Recipient:   data.Recipient,
+		TickLower:   topicInt24(l.Topics[2]),
+		TickUpper:   topicInt24(l.Topics[3]),
+		Amount0:     data.Amount0,
+		Amount1:     data.Amount1,
+		BlockNumber: l.BlockNumber,
+		TxHash:      l.TxHash,
+		LogIndex:    l.Index,
+	}, nil
+}
+
+// int24Topic encodes an indexed int24 the way the EVM stores it in a topic:
+// sign-extended to 32 bytes.
+func int24Topic(v Tick) common.Hash {
+	var topic common.Hash
+	if v < 0 {
+		for i := range topic {
+			topic[i] = 0xff
+		}
+	}
+	binary.BigEndian.PutUint32(topic[common.HashLength-4:], uint32(v))
+
+	return topic
+}
+
+func topicInt24(topic common.Hash) Tick {
+	return Tick(binary.BigEndian.Uint32(topic[common.HashLength-4:]))
+}
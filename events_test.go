@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// collectLog is a pool Collect of amount0 and amount1 from the position of
+// q, paid to q's owner.
+func collectLog(t *testing.T, q PositionQuery, amount0, amount1 int64, block uint64) types.Log {
+	t.Helper()
+
+	event := poolABI.Events[collectEvent]
+	data, err := event.Inputs.NonIndexed().Pack(q.Owner, big.NewInt(amount0), big.NewInt(amount1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return types.Log{
+		Address:     q.Pool,
+		Topics:      []common.Hash{event.ID, common.BytesToHash(q.Owner.Bytes()), int24Topic(q.TickLower), int24Topic(q.TickUpper)},
+		Data:        data,
+		BlockNumber: block,
+		TxHash:      common.BigToHash(new(big.Int).SetUint64(block)),
+	}
+}
+
+func TestInt24Topic(t *testing.T) {
+	for _, tick := range []Tick{0, 60, -60, MinTick, MaxTick} {
+		if got := topicInt24(int24Topic(tick)); got != tick {
+			t.Errorf("topicInt24(int24Topic(%d)) = %d", tick, got)
+		}
+	}
+	if got := int24Topic(-1); got != common.MaxHash {
+		t.Errorf("int24Topic(-1) = %s, want all ones", got)
+	}
+}
+
+func TestCollectHistory(t *testing.T) {
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	other := q
+	other.TickUpper = 120
+
+	node := &fakeNode{head: 100, logs: []types.Log{
+		collectLog(t, q, 10, 20, 30),
+		collectLog(t, other, 1, 2, 40),
+		collectLog(t, q, 5, 0, 50),
+		// after the range
+		collectLog(t, q, 7, 7, 99),
+	}}
+	client := newFakeClient(t, node)
+
+	events, err := client.CollectHistory(context.Background(), q.Pool, q.Owner, q.TickLower, q.TickUpper, big.NewInt(0), big.NewInt(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	for i, want := range []struct{ amount0, amount1 int64 }{{10, 20}, {5, 0}} {
+		e := events[i]
+		if e.Amount0.Int64() != want.amount0 || e.Amount1.Int64() != want.amount1 {
+			t.Errorf("event %d amounts = %s, %s, want %d, %d", i, e.Amount0, e.Amount1, want.amount0, want.amount1)
+		}
+		if e.Owner != testOwner || e.Recipient != testOwner || e.TickLower != -60 || e.TickUpper != 60 {
+			t.Errorf("event %d = %+v", i, e)
+		}
+	}
+}
+
+func TestRunCollects(t *testing.T) {
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	node := &fakeNode{head: 100, logs: []types.Log{collectLog(t, q, 10, 20, 30), collectLog(t, q, 5, 1, 50)}}
+
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-collects", "0-100",
+		"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[1], "30 ") || !strings.HasPrefix(lines[2], "50 ") {
+		t.Fatalf("output = \n%s", stdout)
+	}
+	if fields := strings.Fields(lines[3]); len(fields) != 3 || fields[1] != "15" || fields[2] != "21" {
+		t.Errorf("totals = %q, want 15 and 21", lines[3])
+	}
+
+	if code, _, _ := runOutput(t, "-collects", "100-1"); code != exitBadInput {
+		t.Errorf("-collects 100-1: exit code = %d, want %d", code, exitBadInput)
+	}
+}
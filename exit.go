@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// Exit codes of the command.
+const (
+	exitOK       = 0
+	exitFailure  = 1
+	exitBadInput = 2
+	exitNotFound = 3
+	exitNode     = 4
+)
+
+// ErrNotFound means none of the requested positions exists: all of them read
+// back empty, or the owners hold no position.
+var ErrNotFound = errors.New("position not found")
+
+// inputError is a bad flag, file or value given by the user.
+type inputError struct {
+	err error
+	// reported is set when the message was already printed
+	reported bool
+}
+
+func (e *inputError) Error() string { return e.err.Error() }
+func (e *inputError) Unwrap() error { return e.err }
+
+func badInput(format string, args ...interface{}) error {
+	return &inputError{err: fmt.Errorf(format, args...)}
+}
+
+// nodeError is a failed read from the node or backend.
+type nodeError struct {
+	err error
+}
+
+func (e *nodeError) Error() string { return e.err.Error() }
+func (e *nodeError) Unwrap() error { return e.err }
+
+func nodeFailure(format string, args ...interface{}) error {
+	return &nodeError{err: fmt.Errorf(format, args...)}
+}
+
+// exitCode maps an error returned by execute to the process exit code: 0 on
+// success, 2 for bad input, 3 when nothing was found, 4 when the node failed
+// and 1 otherwise.
+func exitCode(err error) int {
+	var inputErr *inputError
+	var nodeErr *nodeError
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &inputErr):
+		return exitBadInput
+	case errors.Is(err, ErrNotFound):
+		return exitNotFound
+	case errors.As(err, &nodeErr):
+		return exitNode
+	default:
+		return exitFailure
+	}
+}
+
+func reported(err error) bool {
+	var inputErr *inputError
+
+	return errors.Is(err, flag.ErrHelp) || errors.As(err, &inputErr) && inputErr.reported
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{flag.ErrHelp, exitOK},
+		{badInput("invalid -pool: %s", "0x"), exitBadInput},
+		{fmt.Errorf("list: %w", ErrNotFound), exitNotFound},
+		{nodeFailure("get position: %w", errors.New("connection refused")), exitNode},
+		// the input is at fault before the node is asked
+		{badInput("read input: %w", nodeFailure("unreachable")), exitBadInput},
+		{errors.New("write output: broken pipe"), exitFailure},
+	} {
+		if got := exitCode(tc.err); got != tc.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
+
+// positionNode answers positions(bytes32) with liquidity and every other
+// pool read with zeros; a zero liquidity is a position that doesn't exist.
+func positionNode(t *testing.T, liquidity int64) *fakeNode {
+	return &fakeNode{head: 100, handle: func(to common.Address, data []byte) ([]byte, error) {
+		if method := methodOf(t, poolABI, data); method.Name != positionsMethod {
+			return make([]byte, 32), nil
+		}
+		return packOutputs(t, poolABI, positionsMethod, big.NewInt(liquidity), new(big.Int), new(big.Int), new(big.Int), new(big.Int)), nil
+	}}
+}
+
+func TestRunExitCodes(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	t.Cleanup(down.Close)
+
+	for _, tc := range []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"found", []string{"-node", positionNode(t, 1000).serve(t)}, exitOK},
+		{"not found", []string{"-node", positionNode(t, 0).serve(t)}, exitNotFound},
+		{"node error", []string{"-node", down.URL, "-retries", "0"}, exitNode},
+		{"unknown flag", []string{"-no-such-flag"}, exitBadInput},
+		{"bad pool", []string{"-pool", "0x1234"}, exitBadInput},
+		{"help", []string{"-h"}, exitOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if code, _, stderr := runOutput(t, append(tc.args, "-quiet", "-max-lag", "0")...); code != tc.want {
+				t.Errorf("run(%q) = %d, want %d; stderr:\n%s", tc.args, code, tc.want, stderr)
+			}
+		})
+	}
+}
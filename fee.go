@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strconv"
+)
+
+// IsFullRange reports whether [tickLower, tickUpper) spans every tick a
+// position can use at tickSpacing: MIN_TICK and MAX_TICK rounded inwards to
+// a multiple of the spacing, as the interface picks them for "full range".
+// Such a position provides liquidity at every price like a V2 pair.
+func IsFullRange(tickLower, tickUpper Tick, tickSpacing int32) bool {
+	if tickSpacing <= 0 {
+		return false
+	}
+
+	return tickLower == minUsableTick(tickSpacing) && tickUpper == maxUsableTick(tickSpacing)
+}
+
+// minUsableTick and maxUsableTick are MIN_TICK and MAX_TICK rounded inwards
+// to a multiple of tickSpacing, the outermost ticks a position can use. Go's
+// division truncates towards zero, which rounds both inwards.
+func minUsableTick(tickSpacing int32) Tick {
+	return MinTick / Tick(tickSpacing) * Tick(tickSpacing)
+}
+
+func maxUsableTick(tickSpacing int32) Tick {
+	return MaxTick / Tick(tickSpacing) * Tick(tickSpacing)
+}
+
+// maxTickSpacing is the largest tick spacing UniswapV3Factory's
+// enableFeeAmount allows.
+const maxTickSpacing = 16383
+
+// NearestUsableTick rounds tick to the nearest multiple of tickSpacing,
+// clamped to the usable range, like nearestUsableTick of the Uniswap SDK:
+// halfway ticks round up, as JavaScript's Math.round does, so -5 at spacing
+// 10 is 0 and 5 is 10. A non-positive spacing returns tick unchanged.
+func NearestUsableTick(tick Tick, tickSpacing int32) Tick {
+	if tickSpacing <= 0 {
+		return tick
+	}
+
+	// floor((2*tick + spacing) / (2*spacing)): Go's division truncates, so
+	// negative quotients with a remainder are moved down by one.
+	n, d := 2*int64(tick)+int64(tickSpacing), 2*int64(tickSpacing)
+	q := n / d
+	if n%d != 0 && n < 0 {
+		q--
+	}
+	rounded := q * int64(tickSpacing)
+
+	return Tick(max(int64(minUsableTick(tickSpacing)), min(rounded, int64(maxUsableTick(tickSpacing)))))
+}
+
+// feePercent formats a fee in hundredths of a bip, e.g. 500 as "0.05%".
+func feePercent(fee uint32) string {
+	return strconv.FormatFloat(float64(fee)/1e4, 'f', -1, 64) + "%"
+}
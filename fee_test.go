@@ -0,0 +1,91 @@
+package main
+
+import "testing"
+
+func TestFeePercent(t *testing.T) {
+	for fee, want := range map[uint32]string{
+		100:    "0.01%",
+		500:    "0.05%",
+		2500:   "0.25%",
+		3000:   "0.3%",
+		10000:  "1%",
+		0:      "0%",
+		100000: "10%",
+	} {
+		if got := feePercent(fee); got != want {
+			t.Errorf("feePercent(%d) = %s, want %s", fee, got, want)
+		}
+	}
+}
+
+func TestNearestUsableTick(t *testing.T) {
+	for _, tc := range []struct {
+		tick    Tick
+		spacing int32
+		want    Tick
+	}{
+		{0, 60, 0},
+		{29, 60, 0},
+		{30, 60, 60},
+		{-29, 60, 0},
+		// halfway rounds up, toward zero below it
+		{-30, 60, 0},
+		{-31, 60, -60},
+		{-5, 10, 0},
+		{5, 10, 10},
+		{-200312, 60, -200340},
+		{-200312, 10, -200310},
+		{-200312, 1, -200312},
+		// the nearest multiple is beyond the usable range
+		{MinTick, 60, -887220},
+		{MaxTick, 60, 887220},
+		{42, 0, 42},
+		{42, -60, 42},
+	} {
+		if got := NearestUsableTick(tc.tick, tc.spacing); got != tc.want {
+			t.Errorf("NearestUsableTick(%d, %d) = %d, want %d", tc.tick, tc.spacing, got, tc.want)
+		}
+	}
+}
+
+func TestIsFullRange(t *testing.T) {
+	for _, tc := range []struct {
+		lower, upper Tick
+		spacing      int32
+		want         bool
+	}{
+		// the 0.01%, 0.05%, 0.3% and 1% tiers
+		{-887272, 887272, 1, true},
+		{-887270, 887270, 10, true},
+		{-887220, 887220, 60, true},
+		{-887200, 887200, 200, true},
+		// MIN_TICK isn't a multiple of 60
+		{MinTick, MaxTick, 60, false},
+		// one spacing in
+		{-887160, 887220, 60, false},
+		{-887220, 887160, 60, false},
+		// full range of another tier
+		{-887220, 887220, 10, false},
+		{-887220, 887220, 0, false},
+	} {
+		if got := IsFullRange(tc.lower, tc.upper, tc.spacing); got != tc.want {
+			t.Errorf("IsFullRange(%d, %d, %d) = %t, want %t", tc.lower, tc.upper, tc.spacing, got, tc.want)
+		}
+	}
+}
+
+func TestSetPoolFullRange(t *testing.T) {
+	for _, tc := range []struct {
+		lower, upper Tick
+		want         bool
+	}{
+		{-887220, 887220, true},
+		{-60, 60, false},
+	} {
+		r := PositionResult{TickLower: tc.lower, TickUpper: tc.upper}
+		r.setPool(PoolInfo{Fee: 3000, TickSpacing: 60})
+		if r.FullRange != tc.want || r.FeePercent != "0.3%" {
+			t.Errorf("[%d, %d) at spacing 60: FullRange %t, fee %s, want %t, 0.3%%", tc.lower, tc.upper, r.FullRange, r.FeePercent, tc.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// FeeRate is how well a position's liquidity earned over a window.
+type FeeRate struct {
+	FeeAccrual
+	// InRange is how long the pool's price was inside the position's range
+	// during the window, the only time the liquidity earned fees.
+	InRange time.Duration
+	// Rate is the fees earned per unit of value deployed over the window, as
+	// a fraction: fees over what the liquidity is worth, both valued in
+	// token1 at the price of ToBlock.
+	Rate *big.Float
+	// InRangeAPR annualizes Rate over InRange instead of the whole window:
+	// what the liquidity earns a year while it is earning at all. It is nil
+	// when the price never entered the range.
+	InRangeAPR *big.Float
+}
+
+// RealizedFeeRate returns the fee rate q realized in the window of length
+// since that ends at block, nil meaning latest. The liquidity has to stay the
+// same through the window, so it is also the average liquidity deployed;
+// otherwise RealizedFeeRate fails with ErrPositionChanged.
+//
+// The time in range isn't estimated from samples: the pool's
+// snapshotCumulativesInside counts the seconds the price spent inside a
+// range of initialized ticks, and the difference between both ends of the
+// window is exact. It counts whole seconds of block time, so blocks sharing
+// a timestamp add nothing.
+func (c *Client) RealizedFeeRate(ctx context.Context, q PositionQuery, since time.Duration, block *big.Int) (FeeRate, error) {
+	from, to, err := c.BlockSince(ctx, since, block)
+	if err != nil {
+		return FeeRate{}, err
+	}
+
+	accrual, fees, err := c.feesBetween(ctx, q, from, to)
+	if err != nil {
+		return FeeRate{}, err
+	}
+	if fees.Liquidity.Sign() == 0 {
+		return FeeRate{}, errors.New("position has no liquidity in the window")
+	}
+
+	var secondsInside [2]uint32
+	for i, number := range []*big.Int{from.Number, to.Number} {
+		out, err := c.callPool(ctx, q.Pool, snapshotInsideMethod, number, big.NewInt(int64(q.TickLower)), big.NewInt(int64(q.TickUpper)))
+		if err != nil {
+			return FeeRate{}, fmt.Errorf("read time in range at block %s: %w", number, err)
+		}
+		secondsInside[i] = out[2].(uint32)
+	}
+
+	rate := FeeRate{
+		FeeAccrual: accrual,
+		// secondsInside is a uint32 that wraps like the contract's
+		InRange: time.Duration(secondsInside[1]-secondsInside[0]) * time.Second,
+	}
+
+	earned := valueInToken1(accrual.Fees0, accrual.Fees1, fees.SqrtPriceX96)
+	amount0, amount1 := AmountsForLiquidity(fees.SqrtPriceX96, q.TickLower, q.TickUpper, fees.Liquidity)
+	principal := valueInToken1(amount0, amount1, fees.SqrtPriceX96)
+	if principal.Sign() == 0 {
+		return FeeRate{}, errors.New("position is worth nothing at the current price")
+	}
+	rate.Rate = new(big.Float).Quo(new(big.Float).SetInt(earned), new(big.Float).SetInt(principal))
+
+	if rate.InRange > 0 {
+		if rate.InRangeAPR, err = EstimateAPR(earned, principal, rate.InRange); err != nil {
+			return FeeRate{}, err
+		}
+	}
+
+	return rate, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// feeRateNode serves a position of 1e18 liquidity in [-60, 60) at tick 0
+// whose range grew 1 token0 of fees per unit of liquidity and spent 210
+// seconds in range between blocks 65 and 100, 7 minutes of 12 second blocks.
+func feeRateNode(t *testing.T) (*fakeNode, PositionQuery) {
+	pool := newFakePool(0, 1e18)
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	pool.setPosition(t, q, Position{Liquidity: big.NewInt(1e18)})
+	pools := poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)
+
+	node := &fakeNode{head: 100}
+	node.handle = func(to common.Address, data []byte) ([]byte, error) {
+		node.mu.Lock()
+		latest := node.blocks[len(node.blocks)-1] == "0x64"
+		node.mu.Unlock()
+
+		var secondsInside uint32
+		pool.global0 = new(big.Int)
+		if latest {
+			secondsInside = 210
+			pool.global0.Set(q128)
+		}
+		if methodOf(t, poolABI, data).Name == snapshotInsideMethod {
+			return packOutputs(t, poolABI, snapshotInsideMethod, big.NewInt(0), new(big.Int), secondsInside), nil
+		}
+		return pools(to, data)
+	}
+
+	return node, q
+}
+
+func TestRealizedFeeRate(t *testing.T) {
+	node, q := feeRateNode(t)
+	client := newFakeClient(t, node)
+
+	rate, err := client.RealizedFeeRate(context.Background(), q, 7*time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate.FromBlock != 65 || rate.ToBlock != 100 || rate.Elapsed != 7*time.Minute || rate.InRange != 210*time.Second {
+		t.Errorf("rate = %+v, want blocks 65-100 with 3m30s in range", rate)
+	}
+	if rate.Fees0.Cmp(big.NewInt(1e18)) != 0 || rate.Fees1.Sign() != 0 {
+		t.Errorf("fees = %s, %s, want 1e18, 0", rate.Fees0, rate.Fees1)
+	}
+
+	// at price 1 the position is worth its amounts, and the in-range APR
+	// annualizes the same rate over half the window
+	amount0, amount1 := AmountsForLiquidity(Tick(0).SqrtRatio(), -60, 60, big.NewInt(1e18))
+	principal := new(big.Int).Add(amount0, amount1)
+	p, _ := new(big.Float).SetInt(principal).Float64()
+	want := 1e18 / p
+	if got, _ := rate.Rate.Float64(); !closeTo(got, want) {
+		t.Errorf("rate = %g, want %g", got, want)
+	}
+	if rate.InRangeAPR == nil {
+		t.Fatal("no in-range APR")
+	}
+	want *= float64(year) / float64(210*time.Second)
+	if got, _ := rate.InRangeAPR.Float64(); !closeTo(got, want) {
+		t.Errorf("in-range APR = %g, want %g", got, want)
+	}
+}
+
+func TestRunFeeRate(t *testing.T) {
+	args := []string{"-since", "7m", "-fee-rate", "-max-lag", "0", "-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60"}
+
+	node, _ := feeRateNode(t)
+	code, stdout, stderr := runOutput(t, append([]string{"-node", node.serve(t)}, args...)...)
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	for _, want := range []string{"fees in blocks 65-100 (7m0s): 1000000000000000000 token0 0 token1", "\nin range 3m30s of 7m0s, rate=", " in-range apr="} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %q:\n%s", want, stdout)
+		}
+	}
+
+	node, _ = feeRateNode(t)
+	code, stdout, stderr = runOutput(t, append([]string{"-node", node.serve(t), "-format", "json"}, args...)...)
+	if code != exitOK {
+		t.Fatalf("json: exit code %d; stderr:\n%s", code, stderr)
+	}
+	var out struct {
+		Fees0          string  `json:"fees0"`
+		InRangeSeconds uint64  `json:"inRangeSeconds"`
+		Rate           string  `json:"rate"`
+		InRangeAPR     *string `json:"inRangeApr"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, stdout)
+	}
+	if out.Fees0 != "1000000000000000000" || out.InRangeSeconds != 210 || out.Rate == "" || out.InRangeAPR == nil {
+		t.Errorf("output = %+v", out)
+	}
+
+	if code, _, _ := runOutput(t, "-fee-rate"); code != exitBadInput {
+		t.Errorf("-fee-rate without -since: exit code = %d, want %d", code, exitBadInput)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PositionFees is the fee state of a position at one block.
+type PositionFees struct {
+	Liquidity *big.Int
+	// Tick and SqrtPriceX96 are the pool's current tick and price.
+	Tick         Tick
+	SqrtPriceX96 *big.Int
+	InRange      bool
+	// Fees0 and Fees1 are what collect would pay out: tokensOwed plus the fees
+	// accrued since the position was last touched.
+	Fees0 *big.Int
+	Fees1 *big.Int
+}
+
+// ComputeUncollectedFees reads the position, slot0, the pool's global fee
+// growth and both boundary ticks in one batch and replays the pool's fee
+// accounting off-chain. The feeGrowthOutside values are only meaningful
+// against the tick and globals of the same block, so all reads see one block:
+// through Multicall3 the batch is a single eth_call, and with BatchNative or
+// the sequential fallback latest is resolved to a block number first.
+func (c *Client) ComputeUncollectedFees(ctx context.Context, q PositionQuery, block *big.Int) (PositionFees, error) {
+	positionData, err := c.positionCalldata(q)
+	if err != nil {
+		return PositionFees{}, err
+	}
+
+	if block, err = c.consistentBlock(ctx, block); err != nil {
+		return PositionFees{}, err
+	}
+
+	stateCalls, err := c.rangeFeeCalls(q.Pool, q.TickLower, q.TickUpper)
+	if err != nil {
+		return PositionFees{}, err
+	}
+
+	responses, err := c.aggregate(ctx, append([]call{{Target: q.Pool, CallData: positionData}}, stateCalls...), block)
+	if err != nil {
+		return PositionFees{}, err
+	}
+
+	position, err := c.unpackPosition(responses[0])
+	if err != nil {
+		return PositionFees{}, err
+	}
+
+	state, err := c.unpackRangeFees(responses[1:], q.TickLower, q.TickUpper)
+	if err != nil {
+		return PositionFees{}, err
+	}
+
+	return state.fees(position, q.TickLower, q.TickUpper), nil
+}
+
+// feesOf applies the fee state of q's range at block to position, which must
+// have been read at the same block.
+func (c *Client) feesOf(ctx context.Context, q PositionQuery, position Position, block *big.Int) (PositionFees, error) {
+	stateCalls, err := c.rangeFeeCalls(q.Pool, q.TickLower, q.TickUpper)
+	if err != nil {
+		return PositionFees{}, err
+	}
+
+	responses, err := c.aggregate(ctx, stateCalls, block)
+	if err != nil {
+		return PositionFees{}, err
+	}
+
+	state, err := c.unpackRangeFees(responses, q.TickLower, q.TickUpper)
+	if err != nil {
+		return PositionFees{}, err
+	}
+
+	return state.fees(position, q.TickLower, q.TickUpper), nil
+}
+
+// rangeFees is the pool state fees of a tick range derive from.
+type rangeFees struct {
+	sqrtPriceX96 *big.Int
+	tick         Tick
+	inside0      *big.Int
+	inside1      *big.Int
+}
+
+const rangeFeeCallCount = 5
+
+// rangeFeeCalls are the rangeFeeCallCount reads rangeFees is built from:
+// slot0, both global fee growths and both boundary ticks.
+func (c *Client) rangeFeeCalls(pool common.Address, tickLower, tickUpper Tick) ([]call, error) {
+	calls := make([]call, 0, rangeFeeCallCount)
+
+	for _, args := range [][]interface{}{
+		{slot0Method},
+		{feeGrowthGlobal0Method},
+		{feeGrowthGlobal1Method},
+		{ticksMethod, big.NewInt(int64(tickLower))},
+		{ticksMethod, big.NewInt(int64(tickUpper))},
+	} {
+		calldata, err := c.abis.PoolABI().Pack(args[0].(string), args[1:]...)
+		if err != nil {
+			return nil, fmt.Errorf("pack %s: %w", args[0], err)
+		}
+		calls = append(calls, call{Target: pool, CallData: calldata})
+	}
+
+	return calls, nil
+}
+
+func (c *Client) unpackRangeFees(responses [][]byte, tickLower, tickUpper Tick) (rangeFees, error) {
+	var out [rangeFeeCallCount][]interface{}
+	for i, method := range []string{slot0Method, feeGrowthGlobal0Method, feeGrowthGlobal1Method, ticksMethod, ticksMethod} {
+		var err error
+		if out[i], err = c.abis.PoolABI().Unpack(method, responses[i]); err != nil {
+			return rangeFees{}, fmt.Errorf("parse %s result: %w", method, err)
+		}
+	}
+
+	tick := Tick(out[0][1].(*big.Int).Int64())
+	global := [2]*big.Int{out[1][0].(*big.Int), out[2][0].(*big.Int)}
+	var outside [2][2]*big.Int // [lower, upper][token0, token1]
+	for i, ticks := range out[3:] {
+		outside[i] = [2]*big.Int{ticks[2].(*big.Int), ticks[3].(*big.Int)}
+	}
+
+	return rangeFees{
+		sqrtPriceX96: out[0][0].(*big.Int),
+		tick:         tick,
+		inside0:      feeGrowthInside(tick, tickLower, tickUpper, global[0], outside[0][0], outside[1][0]),
+		inside1:      feeGrowthInside(tick, tickLower, tickUpper, global[1], outside[0][1], outside[1][1]),
+	}, nil
+}
+
+// fees applies the range state to a position's own accounting, which is
+// either the pool's or, for an NFT, the NPM's per-token one.
+func (r rangeFees) fees(position Position, tickLower, tickUpper Tick) PositionFees {
+	return PositionFees{
+		Liquidity:    position.Liquidity,
+		Tick:         r.tick,
+		SqrtPriceX96: r.sqrtPriceX96,
+		InRange:      r.tick.InRange(tickLower, tickUpper),
+		Fees0:        uncollected(position.Liquidity, position.TokensOwed0, r.inside0, position.FeeGrowthInside0LastX128),
+		Fees1:        uncollected(position.Liquidity, position.TokensOwed1, r.inside1, position.FeeGrowthInside1LastX128),
+	}
+}
+
+// feeGrowthInside is Tick.getFeeGrowthInside. A tick's feeGrowthOutside is
+// the growth on the side of it away from the current tick, so it is the
+// growth below the lower tick only while the current tick is at or above it,
+// and the growth above the upper tick only while the current tick is below
+// it; otherwise the complement global - outside is. The arithmetic wraps at
+// 2^256 like the contract's unchecked uint256 math.
+// https://github.com/Uniswap/v3-core/blob/d8b1c635c275d2a9450bd6a78f3fa2484fef73eb/contracts/libraries/Tick.sol
+func feeGrowthInside(tickCurrent, tickLower, tickUpper Tick, global, outsideLower, outsideUpper *big.Int) *big.Int {
+	below := outsideLower
+	if tickCurrent < tickLower {
+		below = sub256(global, outsideLower)
+	}
+
+	above := outsideUpper
+	if tickCurrent >= tickUpper {
+		above = sub256(global, outsideUpper)
+	}
+
+	inside := sub256(sub256(global, below), above)
+	traceHex("feeGrowthInside.global", global)
+	traceHex("feeGrowthInside.below", below)
+	traceHex("feeGrowthInside.above", above)
+	traceHex("feeGrowthInside.inside", inside)
+
+	return inside
+}
+
+// consistentBlock returns the block a batch of reads must use to see a single
+// block: block itself, or the pinned block, or nil when the batch is one
+// Multicall3 eth_call anyway. Otherwise latest is resolved to the head's
+// number, since separate calls at latest may land on different blocks.
+func (c *Client) consistentBlock(ctx context.Context, block *big.Int) (*big.Int, error) {
+	if c.at(block) == nil && c.batch != BatchNative {
+		deployed, err := c.multicallDeployed(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if deployed {
+			return nil, nil
+		}
+	}
+
+	return c.fixedBlock(ctx, block)
+}
+
+// fixedBlock is block, or the pinned block, or else the head's number, for
+// reads spread over several eth_calls that must all see one block.
+func (c *Client) fixedBlock(ctx context.Context, block *big.Int) (*big.Int, error) {
+	if block = c.at(block); block != nil {
+		return block, nil
+	}
+
+	head, err := c.eth.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get block number: %w", err)
+	}
+
+	return new(big.Int).SetUint64(head), nil
+}
+
+// uncollected is Position.update's fee accrual on top of tokensOwed. Like the
+// contract, the accrual is cast to uint128 and the sum wraps at 2^128; that
+// only happens once fees past type(uint128).max were left uncollected.
+func uncollected(liquidity, tokensOwed, inside, insideLast *big.Int) *big.Int {
+	delta := sub256(inside, insideLast)
+	traceHex("uncollected.feeGrowthDelta", delta)
+	accrued := mulDiv(delta, liquidity, q128)
+	accrued.And(accrued, maxUint128)
+	traceHex("uncollected.accrued", accrued)
+	accrued.Add(accrued, tokensOwed)
+
+	accrued.And(accrued, maxUint128)
+	traceHex("uncollected.fees", accrued)
+
+	return accrued
+}
+
+// sub256 is a - b modulo 2^256.
+func sub256(a, b *big.Int) *big.Int {
+	diff := new(big.Int).Sub(a, b)
+
+	return diff.And(diff, maxUint)
+}
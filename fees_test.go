@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestFeeGrowthInside(t *testing.T) {
+	wrapped := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(10))
+
+	for _, tc := range []struct {
+		name                 string
+		current              Tick
+		global, lower, upper *big.Int
+		want                 *big.Int
+	}{
+		{"in range", 0, big.NewInt(100), big.NewInt(10), big.NewInt(20), big.NewInt(70)},
+		{"at the lower tick", -60, big.NewInt(100), big.NewInt(10), big.NewInt(20), big.NewInt(70)},
+		{"below range", -120, big.NewInt(100), big.NewInt(30), big.NewInt(20), big.NewInt(10)},
+		{"at the upper tick", 60, big.NewInt(100), big.NewInt(10), big.NewInt(20), big.NewInt(10)},
+		{"above range", 120, big.NewInt(100), big.NewInt(10), big.NewInt(20), big.NewInt(10)},
+		// the lower tick was crossed before the global growth wrapped
+		{"wrapped global", 0, big.NewInt(5), wrapped, big.NewInt(3), big.NewInt(12)},
+	} {
+		if got := feeGrowthInside(tc.current, -60, 60, tc.global, tc.lower, tc.upper); got.Cmp(tc.want) != 0 {
+			t.Errorf("%s: feeGrowthInside = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestUncollected(t *testing.T) {
+	liquidity := big.NewInt(1e18)
+	growth := new(big.Int).Mul(q128, big.NewInt(3))
+
+	// 3 per unit of liquidity since the last update, on top of 7 owed
+	if got := uncollected(liquidity, big.NewInt(7), growth, new(big.Int)); got.Cmp(big.NewInt(3e18+7)) != 0 {
+		t.Errorf("uncollected = %s, want %s", got, big.NewInt(3e18+7))
+	}
+
+	// feeGrowthInside wrapped past 2^256 since the last update
+	last := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), q128)
+	if got := uncollected(liquidity, new(big.Int), new(big.Int).Set(q128), last); got.Cmp(big.NewInt(2e18)) != 0 {
+		t.Errorf("uncollected after a wrap = %s, want %s", got, big.NewInt(2e18))
+	}
+}
+
+func TestComputeUncollectedFees(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		multicallFrom int64
+		batch         string
+		// block is the tag every call must use
+		block string
+		// calls is the number of eth_calls of the position and its pool state
+		calls int
+	}{
+		// one aggregate3 of every read
+		{"multicall", 0, BatchMulticall, "latest", 1},
+		{"sequential", -1, BatchMulticall, "0x64", 1 + rangeFeeCallCount},
+		{"native", 0, BatchNative, "0x64", 1 + rangeFeeCallCount},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pool := newFakePool(0, 1e18)
+			pool.global0 = new(big.Int).Mul(q128, big.NewInt(5))
+			pool.global1 = new(big.Int).Set(q128)
+			// 1 of the 5 token0 fees per liquidity accrued below the range
+			pool.ticks[-60] = fakeTick{outside0: new(big.Int).Set(q128)}
+			q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+			pool.setPosition(t, q, Position{Liquidity: big.NewInt(1e18), TokensOwed0: big.NewInt(7)})
+
+			node := &fakeNode{head: 100, multicallFrom: tc.multicallFrom, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)}
+			client := newFakeClient(t, node, WithBatch(tc.batch))
+
+			fees, err := client.ComputeUncollectedFees(context.Background(), q, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !fees.InRange || fees.Tick != 0 || fees.Liquidity.Int64() != 1e18 {
+				t.Errorf("fees = %+v", fees)
+			}
+			if fees.Fees0.Cmp(big.NewInt(4e18+7)) != 0 || fees.Fees1.Cmp(big.NewInt(1e18)) != 0 {
+				t.Errorf("fees = %s, %s, want %d, %d", fees.Fees0, fees.Fees1, int64(4e18+7), int64(1e18))
+			}
+			if n := node.count("eth_call"); n != tc.calls {
+				t.Errorf("sent %d eth_calls, want %d", n, tc.calls)
+			}
+			if tc.batch == BatchMulticall && tc.multicallFrom == 0 && !slices.Equal(node.aggregates, []int{1 + rangeFeeCallCount}) {
+				t.Errorf("aggregate3 calls of %v reads, want one of all %d", node.aggregates, 1+rangeFeeCallCount)
+			}
+			for i, block := range node.blocks {
+				if block != tc.block {
+					t.Errorf("call %d read block %s, want %s", i, block, tc.block)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// addressList is a flag that can be repeated and takes comma separated
+// addresses.
+type addressList []common.Address
+
+func (l *addressList) String() string {
+	if l == nil {
+		return ""
+	}
+
+	hexes := make([]string, len(*l))
+	for i, address := range *l {
+		hexes[i] = address.Hex()
+	}
+
+	return strings.Join(hexes, ",")
+}
+
+func (l *addressList) Set(value string) error {
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		address, err := parseAddress(s)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, address)
+	}
+
+	return nil
+}
+
+// stringList is a flag that can be repeated and takes comma separated values.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+
+	return nil
+}
+
+// priceList is a flag that can be repeated and takes comma separated
+// TOKEN=PRICE manual token prices, each a positive decimal.
+type priceList PriceOverrides
+
+func (l *priceList) String() string {
+	if l == nil {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(*l))
+	for token, price := range *l {
+		pairs = append(pairs, token.Hex()+"="+price.Text('g', -1))
+	}
+	slices.Sort(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (l *priceList) Set(value string) error {
+	for _, s := range strings.Split(value, ",") {
+		token, price, ok := strings.Cut(strings.TrimSpace(s), "=")
+		if !ok {
+			return fmt.Errorf("price %q is not TOKEN=PRICE", s)
+		}
+
+		address, err := parseAddress(token)
+		if err != nil {
+			return err
+		}
+		p, ok := new(big.Float).SetPrec(pricePrec).SetString(price)
+		if !ok || p.Sign() <= 0 || p.IsInf() {
+			return fmt.Errorf("price of %s: %q is not a positive decimal", address.Hex(), price)
+		}
+
+		if *l == nil {
+			*l = make(priceList)
+		}
+		(*l)[address] = p
+	}
+
+	return nil
+}
+
+// tickFlag is a tick flag that also takes the keywords min and max, the
+// lowest and highest tick usable at the pool's tick spacing. Keywords are
+// resolved once the pool can be read.
+type tickFlag struct {
+	tick    Tick
+	keyword string
+}
+
+const (
+	tickMin = "min"
+	tickMax = "max"
+)
+
+func (f *tickFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	if f.keyword != "" {
+		return f.keyword
+	}
+
+	return strconv.Itoa(int(f.tick))
+}
+
+func (f *tickFlag) Set(value string) error {
+	if value == tickMin || value == tickMax {
+		f.keyword = value
+		return nil
+	}
+
+	tick, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return fmt.Errorf("want a tick, %s or %s", tickMin, tickMax)
+	}
+	f.tick, f.keyword = Tick(tick), ""
+
+	return nil
+}
+
+// resolve returns the tick, with a keyword resolved for tickSpacing.
+func (f *tickFlag) resolve(tickSpacing int32) Tick {
+	switch f.keyword {
+	case tickMin:
+		return minUsableTick(tickSpacing)
+	case tickMax:
+		return maxUsableTick(tickSpacing)
+	default:
+		return f.tick
+	}
+}
+
+// flagSet reports whether the flag name was given, on the command line or in
+// a config file.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
+// flagGiven reports whether the flag name was given a value other than its
+// default, so that -watch=false or -block 0 count as not given.
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	f := fs.Lookup(name)
+
+	return f != nil && flagSet(fs, name) && f.Value.String() != f.DefValue
+}
+
+// firstGiven returns the first of names that was given, or "".
+func firstGiven(fs *flag.FlagSet, names ...string) string {
+	for _, name := range names {
+		if flagGiven(fs, name) {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// positionModes each read one position their own way: one of them can be
+// given at a time, with a single -owner, and none with positionModeConflicts.
+var positionModes = []string{"share", "amounts", "report", "exit-impact", "collects", "activity", "break-even", "project", "token-uri"}
+
+// positionModeConflicts are the other sources and modes of reads, which
+// positionModes can't be combined with.
+var positionModeConflicts = []string{"list", "input", "discover", "url", "since", "backfill", "reconcile", "watch", "follow", "dashboard", "sign-key", "diagnose-key"}
+
+// oneShotModes print what they read once and exit.
+var oneShotModes = append([]string{"watch", "follow", "dashboard", "sign-key", "diagnose-key", "since", "backfill", "summary"}, positionModes...)
+
+// builtinFormatModes write their own output in the built-in formats only,
+// rather than with the Formatter registered as -format.
+var builtinFormatModes = append([]string{"chains", "keys", "group", "liquidity-profile", "reconcile"}, oneShotModes...)
+
+// flagConflicts are the flags that can't be given together: flag with any
+// of with.
+var flagConflicts = []struct {
+	flag string
+	with []string
+}{
+	{"nodes", []string{"node"}},
+	{"quiet", []string{"verbose"}},
+	{"since", []string{"list", "input", "watch", "follow", "dashboard", "sign-key", "diagnose-key"}},
+	{"backfill", []string{"list", "input", "discover", "since", "watch", "follow", "dashboard", "sign-key", "diagnose-key", "url"}},
+	{"reconcile", []string{"list", "input", "discover", "since", "backfill", "watch", "follow", "dashboard", "sign-key", "diagnose-key", "url"}},
+	{"url", []string{"list", "input", "discover"}},
+	{"fee-rate", []string{"discover", "url"}},
+	{"reinvest", []string{"discover", "url", "fee-rate"}},
+	{"override", append(slices.Clone(positionModeConflicts), positionModes...)},
+	{"simulate", append(slices.Clone(positionModeConflicts), positionModes...)},
+	{"group", oneShotModes},
+	{"liquidity-profile", append([]string{"group"}, oneShotModes...)},
+	{"pushgateway", oneShotModes},
+	{"metrics-addr", []string{"dashboard"}},
+	{"at", []string{"watch", "follow"}},
+	{"exit-impact", []string{"block", "l1-block", "confirmations", "at"}},
+	{"block", []string{"l1-block", "confirmations"}},
+	{"l1-block", []string{"confirmations"}},
+}
+
+// flagNeeds are the flags that only work with another: flag with need.
+var flagNeeds = []struct {
+	flag string
+	need string
+}{
+	{"summary", "list"},
+	{"fee-threshold0", "follow"},
+	{"fee-threshold1", "follow"},
+	{"min-change0", "follow"},
+	{"min-change1", "follow"},
+	{"token-image", "token-uri"},
+	{"fee-rate", "since"},
+	{"reinvest", "since"},
+	{"metrics-addr", "watch"},
+	{"dex", "pool-of"},
+	{"tick-spacing", "price-tick"},
+	{"base", "quote"},
+	{"quote", "base"},
+}
+
+// singleOwnerFlags are the modes that read one position, and so take a
+// single -owner, unless the flag unless is given too.
+var singleOwnerFlags = []struct {
+	flag   string
+	unless string
+}{
+	{"watch", ""},
+	{"follow", ""},
+	{"dashboard", ""},
+	{"sign-key", ""},
+	{"since", "discover"},
+	{"backfill", ""},
+	{"reconcile", ""},
+}
+
+// checkFlags rejects flags given together, by the command line or a config
+// file, that don't work together; owners is the number of -owner addresses.
+func checkFlags(fs *flag.FlagSet, owners int) error {
+	for _, c := range flagConflicts {
+		if !flagGiven(fs, c.flag) {
+			continue
+		}
+		if other := firstGiven(fs, c.with...); other != "" {
+			return badInput("-%s can't be combined with -%s", c.flag, other)
+		}
+	}
+
+	for i, mode := range positionModes {
+		if !flagGiven(fs, mode) {
+			continue
+		}
+		if other := firstGiven(fs, append(slices.Clone(positionModes[i+1:]), positionModeConflicts...)...); other != "" {
+			return badInput("-%s can't be combined with -%s", mode, other)
+		}
+		if owners > 1 {
+			return badInput("-%s takes a single -owner", mode)
+		}
+	}
+
+	if format := fs.Lookup("format").Value.String(); !slices.Contains(builtinFormats, format) {
+		if mode := firstGiven(fs, builtinFormatModes...); mode != "" {
+			return badInput("-%s can't be combined with -format %s: want one of %s", mode, format, strings.Join(builtinFormats, ", "))
+		}
+	}
+
+	for _, n := range flagNeeds {
+		if flagGiven(fs, n.flag) && !flagGiven(fs, n.need) {
+			return badInput("-%s needs -%s", n.flag, n.need)
+		}
+	}
+
+	if owners > 1 {
+		for _, s := range singleOwnerFlags {
+			if flagGiven(fs, s.flag) && !flagGiven(fs, s.unless) {
+				return badInput("-%s takes a single -owner", s.flag)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestTickFlag(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  Tick
+	}{
+		// at the 0.05% tier's spacing of 10
+		{"min", -887270},
+		{"max", 887270},
+		{"-600", -600},
+		{"0", 0},
+	} {
+		var f tickFlag
+		if err := f.Set(tc.value); err != nil {
+			t.Errorf("Set(%q): %v", tc.value, err)
+			continue
+		}
+		if got := f.resolve(10); got != tc.want {
+			t.Errorf("%s resolves to %d at spacing 10, want %d", tc.value, got, tc.want)
+		}
+		if f.String() != tc.value {
+			t.Errorf("String() = %s, want %s", f.String(), tc.value)
+		}
+	}
+
+	for _, value := range []string{"MIN", "minimum", "1.5", "", "99999999999"} {
+		var f tickFlag
+		if err := f.Set(value); err == nil {
+			t.Errorf("Set(%q) accepted", value)
+		}
+	}
+
+	// a number replaces an earlier keyword
+	var f tickFlag
+	f.Set("max")
+	f.Set("60")
+	if got := f.resolve(10); got != 60 {
+		t.Errorf("max then 60 resolves to %d", got)
+	}
+}
+
+func TestRunTickKeywords(t *testing.T) {
+	pool := newFakePool(0, 1000)
+	pool.fee, pool.tickSpacing = 500, 10
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -887270, TickUpper: 887270}
+	pool.setPosition(t, q, Position{Liquidity: big.NewInt(1000)})
+	node := &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)}
+
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-format", "json",
+		"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "min", "-tick-upper", "max")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+
+	var results []PositionResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, stdout)
+	}
+	if len(results) != 1 || results[0].TickLower != q.TickLower || results[0].TickUpper != q.TickUpper || results[0].Position.Liquidity.Int64() != 1000 {
+		t.Errorf("results = %+v, want the full range position", results)
+	}
+
+	if code, _, _ := runOutput(t, "-quiet", "-tick-lower", "lowest"); code != exitBadInput {
+		t.Errorf("-tick-lower lowest: exit code %d, want %d", code, exitBadInput)
+	}
+}
+
+func TestCheckFlags(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		// err is the error the args give, "" for none
+		err string
+	}{
+		{nil, ""},
+		{[]string{"-dashboard", "-watch"}, ""},
+		{[]string{"-list", "-summary", "0x1"}, ""},
+		{[]string{"-watch=false", "-since", "1d"}, ""},
+		{[]string{"-block", "0", "-confirmations", "3"}, ""},
+		{[]string{"-since", "1d", "-discover", "-owner", "0x3333333333333333333333333333333333333333,0x4444444444444444444444444444444444444444"}, ""},
+		{[]string{"-list", "-format", "csv"}, ""},
+		{[]string{"-report", "-format", "table"}, ""},
+		{[]string{"-report", "-format", "csv"}, "-report can't be combined with -format csv"},
+		{[]string{"-chains", "-format", "csv"}, "-chains can't be combined with -format csv"},
+		{[]string{"-quiet", "-verbose"}, "-quiet can't be combined with -verbose"},
+		{[]string{"-since", "1d", "-list"}, "-since can't be combined with -list"},
+		{[]string{"-backfill", "1-2", "-url", "x"}, "-backfill can't be combined with -url"},
+		{[]string{"-reconcile", "1", "-watch"}, "-reconcile can't be combined with -watch"},
+		{[]string{"-group", "-follow"}, "-group can't be combined with -follow"},
+		{[]string{"-pushgateway", "http://gw", "-since", "1d"}, "-pushgateway can't be combined with -since"},
+		{[]string{"-at", "latest", "-watch"}, "-at can't be combined with -watch"},
+		{[]string{"-block", "5", "-l1-block", "5"}, "-block can't be combined with -l1-block"},
+		{[]string{"-share", "-collects", "1-2"}, "-share can't be combined with -collects"},
+		{[]string{"-collects", "1-2", "-list"}, "-collects can't be combined with -list"},
+		{[]string{"-share", "-owner", "0x3333333333333333333333333333333333333333,0x4444444444444444444444444444444444444444"}, "-share takes a single -owner"},
+		{[]string{"-summary", "0x1"}, "-summary needs -list"},
+		{[]string{"-fee-threshold1", "5"}, "-fee-threshold1 needs -follow"},
+		{[]string{"-quote", "0x1"}, "-quote needs -base"},
+		{[]string{"-metrics-addr", ":9101"}, "-metrics-addr needs -watch"},
+		{[]string{"-metrics-addr", ":9101", "-watch", "-dashboard"}, "-metrics-addr can't be combined with -dashboard"},
+		{[]string{"-watch", "-owner", "0x3333333333333333333333333333333333333333,0x4444444444444444444444444444444444444444"}, "-watch takes a single -owner"},
+		{[]string{"-since", "1d", "-owner", "0x3333333333333333333333333333333333333333,0x4444444444444444444444444444444444444444"}, "-since takes a single -owner"},
+	} {
+		fs, o := newFlagSet()
+		if err := fs.Parse(tc.args); err != nil {
+			t.Fatalf("parse %q: %v", tc.args, err)
+		}
+
+		err := checkFlags(fs, len(o.owners))
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("checkFlags(%q) = %v, want nil", tc.args, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("checkFlags(%q) = %v, want %q", tc.args, err, tc.err)
+		case err != nil && exitCode(err) != exitBadInput:
+			t.Errorf("checkFlags(%q) exits with %d, want %d", tc.args, exitCode(err), exitBadInput)
+		}
+	}
+}
+
+// Every flag the rules name must exist, or the rule never fires.
+func TestFlagRulesNameFlags(t *testing.T) {
+	fs, _ := newFlagSet()
+	var names []string
+	for _, c := range flagConflicts {
+		names = append(append(names, c.flag), c.with...)
+	}
+	names = append(append(names, positionModes...), positionModeConflicts...)
+	for _, n := range flagNeeds {
+		names = append(names, n.flag, n.need)
+	}
+	for _, s := range singleOwnerFlags {
+		names = append(names, s.flag)
+		if s.unless != "" {
+			names = append(names, s.unless)
+		}
+	}
+
+	for _, name := range names {
+		if fs.Lookup(name) == nil {
+			t.Errorf("flag rules name -%s, which isn't a flag", name)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	colorReset = "\033[0m"
+	colorGreen = "\033[32m"
+	colorRed   = "\033[31m"
+)
+
+// follower prints one compact line per block, like tail -f.
+type follower struct {
+	w     io.Writer
+	color bool
+	last  *PositionFees
+
+	// thresholds are raw uncollected fee amounts of token0 and token1 that
+	// trigger an alert; nil disables one. An alert fires once when fees
+	// reach the threshold and re-arms after they drop below it, e.g. after
+	// a collect.
+	thresholds [2]*big.Int
+	fired      [2]bool
+
+	// apr, when set, adds the trailing fee APR to every line.
+	apr *rollingAPR
+
+	// filter, when set, skips lines that aren't a significant change from
+	// the last printed one.
+	filter *ChangeFilter
+}
+
+// ChangeFilter tells significant position updates from noise such as a few
+// wei of fees accruing every block. An update is significant when liquidity
+// or the range status changed, or when the uncollected fees of either token
+// moved by at least MinFees0 or MinFees1 raw units, in either direction. A
+// nil minimum ignores that token's fees.
+type ChangeFilter struct {
+	MinFees0 *big.Int
+	MinFees1 *big.Int
+}
+
+// Significant reports whether next is worth reporting after prev.
+func (f ChangeFilter) Significant(prev, next PositionFees) bool {
+	if prev.Liquidity.Cmp(next.Liquidity) != 0 || prev.InRange != next.InRange {
+		return true
+	}
+
+	for i, minimum := range [2]*big.Int{f.MinFees0, f.MinFees1} {
+		if minimum == nil {
+			continue
+		}
+		prevFees, nextFees := prev.Fees0, next.Fees0
+		if i == 1 {
+			prevFees, nextFees = prev.Fees1, next.Fees1
+		}
+		if diff := new(big.Int).Sub(nextFees, prevFees); diff.Sign() != 0 && diff.CmpAbs(minimum) >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// line formats fees read at block, with the fee change since the previous line.
+func (f *follower) line(block *big.Int, fees PositionFees) string {
+	status, color := "out", colorRed
+	if fees.InRange {
+		status, color = "in", colorGreen
+	}
+	if f.color {
+		status = color + status + colorReset
+	}
+
+	delta0, delta1 := new(big.Int), new(big.Int)
+	if f.last != nil {
+		delta0.Sub(fees.Fees0, f.last.Fees0)
+		delta1.Sub(fees.Fees1, f.last.Fees1)
+	}
+	f.last = &fees
+
+	return fmt.Sprintf("block=%s liquidity=%s range=%s tick=%d fees0=%s (%+d) fees1=%s (%+d)",
+		block, fees.Liquidity, status, fees.Tick, fees.Fees0, delta0, fees.Fees1, delta1)
+}
+
+// alerts returns an alert line for every threshold fees just reached.
+func (f *follower) alerts(block *big.Int, fees PositionFees) []string {
+	var lines []string
+
+	for i, amount := range [2]*big.Int{fees.Fees0, fees.Fees1} {
+		threshold := f.thresholds[i]
+		if threshold == nil {
+			continue
+		}
+
+		reached := amount.Cmp(threshold) >= 0
+		if reached && !f.fired[i] {
+			lines = append(lines, fmt.Sprintf("alert block=%s fees%d=%s reached threshold %s", block, i, amount, threshold))
+		}
+		f.fired[i] = reached
+	}
+
+	return lines
+}
+
+// followPosition prints a line for q at every new head, or with a filter only
+// at significant changes, plus an alert line when uncollected fees reach
+// thresholds.
+func followPosition(ctx context.Context, client *Client, q PositionQuery, thresholds [2]*big.Int, aprWindow time.Duration, filter *ChangeFilter) error {
+	f := &follower{w: os.Stdout, color: isTerminal(os.Stdout), thresholds: thresholds, filter: filter}
+	if aprWindow > 0 {
+		f.apr = &rollingAPR{window: aprWindow, tickLower: q.TickLower, tickUpper: q.TickUpper}
+	}
+
+	return client.eachHead(ctx, func(head *types.Header) error {
+		fees, err := client.ComputeUncollectedFees(ctx, q, head.Number)
+		if err != nil {
+			log.Printf("follow: read position block=%s err=%q", head.Number, err)
+			return nil
+		}
+
+		return f.print(head, fees)
+	})
+}
+
+// print writes the line of fees read at head, unless the filter skips it,
+// and the alerts they trigger.
+func (f *follower) print(head *types.Header, fees PositionFees) error {
+	var apr *big.Float
+	if f.apr != nil {
+		apr, _ = f.apr.add(head.Time, fees)
+	}
+
+	if f.filter == nil || f.last == nil || f.filter.Significant(*f.last, fees) {
+		line := f.line(head.Number, fees)
+		if apr != nil {
+			line += " apr=" + formatPercent(apr)
+		}
+		if _, err := fmt.Fprintln(f.w, line); err != nil {
+			return err
+		}
+	}
+	for _, alert := range f.alerts(head.Number, fees) {
+		if _, err := fmt.Fprintln(f.w, alert); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// feeThresholds converts human fee thresholds of the pool's token0 and
+// token1 into raw units. A negative decimals is read from the token.
+func feeThresholds(ctx context.Context, client *Client, pool common.Address, human [2]string, decimals [2]int) ([2]*big.Int, error) {
+	var thresholds [2]*big.Int
+	var tokens [2]common.Address
+
+	for i := range human {
+		if human[i] == "" {
+			continue
+		}
+
+		if decimals[i] < 0 {
+			if tokens[i] == (common.Address{}) {
+				infos, err := client.PoolInfos(ctx, []common.Address{pool}, nil)
+				if err != nil {
+					return thresholds, fmt.Errorf("get pool tokens: %w", err)
+				}
+				tokens = [2]common.Address{infos[pool].Token0, infos[pool].Token1}
+			}
+
+			d, err := client.TokenDecimals(ctx, tokens[i], nil)
+			if err != nil {
+				return thresholds, fmt.Errorf("token%d decimals unknown, set -token%d-decimals: %w", i, i, err)
+			}
+			decimals[i] = int(d)
+		}
+		if decimals[i] > 255 {
+			return thresholds, fmt.Errorf("token%d decimals %d out of range", i, decimals[i])
+		}
+
+		raw, err := parseUnits(human[i], uint8(decimals[i]))
+		if err != nil {
+			return thresholds, fmt.Errorf("fee threshold%d: %w", i, err)
+		}
+		thresholds[i] = raw
+	}
+
+	return thresholds, nil
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// followBlock is a block of a followed position: its number and fees, with
+// liquidity 1000 and the range status of tick in [-60, 60).
+type followBlock struct {
+	number       int64
+	tick         Tick
+	fees0, fees1 int64
+}
+
+// drive prints blocks through f and returns the lines it wrote.
+func drive(t *testing.T, f *follower, blocks []followBlock) []string {
+	t.Helper()
+
+	var out bytes.Buffer
+	f.w = &out
+	for _, b := range blocks {
+		fees := PositionFees{
+			Liquidity: big.NewInt(1000),
+			Tick:      b.tick,
+			InRange:   b.tick.InRange(-60, 60),
+			Fees0:     big.NewInt(b.fees0),
+			Fees1:     big.NewInt(b.fees1),
+		}
+		if err := f.print(&types.Header{Number: big.NewInt(b.number), Time: uint64(b.number) * 12}, fees); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func TestFollowDeltas(t *testing.T) {
+	lines := drive(t, &follower{}, []followBlock{
+		{100, 0, 10, 20},
+		{101, 10, 15, 20},
+		{102, 70, 15, 21},
+		// a collect
+		{103, 70, 0, 0},
+	})
+
+	want := []string{
+		"block=100 liquidity=1000 range=in tick=0 fees0=10 (+0) fees1=20 (+0)",
+		"block=101 liquidity=1000 range=in tick=10 fees0=15 (+5) fees1=20 (+0)",
+		"block=102 liquidity=1000 range=out tick=70 fees0=15 (+0) fees1=21 (+1)",
+		"block=103 liquidity=1000 range=out tick=70 fees0=0 (-15) fees1=0 (-21)",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestFollowColor(t *testing.T) {
+	// the upper tick is out of range
+	lines := drive(t, &follower{color: true}, []followBlock{{100, 0, 0, 0}, {101, 60, 0, 0}})
+
+	if !strings.Contains(lines[0], "range="+colorGreen+"in"+colorReset) {
+		t.Errorf("in range line %q isn't green", lines[0])
+	}
+	if !strings.Contains(lines[1], "range="+colorRed+"out"+colorReset) {
+		t.Errorf("out of range line %q isn't red", lines[1])
+	}
+	if plain := drive(t, &follower{}, []followBlock{{101, 60, 0, 0}}); strings.Contains(plain[0], "\033") {
+		t.Errorf("line %q is colored without a terminal", plain[0])
+	}
+}
+
+func TestFeeThresholds(t *testing.T) {
+	pool := newFakePool(0, 1000)
+	node := &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, map[common.Address]uint8{pool.token1: 18})}
+	client := newFakeClient(t, node)
+
+	// token0 decimals given, token1's read from the token
+	thresholds, err := feeThresholds(context.Background(), client, testPool, [2]string{"1.5", "0.01"}, [2]int{6, -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thresholds[0].String() != "1500000" || thresholds[1].String() != "10000000000000000" {
+		t.Fatalf("thresholds = %s, %s", thresholds[0], thresholds[1])
+	}
+
+	// no decimals() on token0
+	if _, err := feeThresholds(context.Background(), client, testPool, [2]string{"1", ""}, [2]int{-1, -1}); err == nil || !strings.Contains(err.Error(), "set -token0-decimals") {
+		t.Errorf("err = %v, want a hint to set -token0-decimals", err)
+	}
+	if _, err := feeThresholds(context.Background(), client, testPool, [2]string{"1.0000001", ""}, [2]int{6, -1}); err == nil {
+		t.Error("accepted a threshold finer than the token's decimals")
+	}
+}
+
+func TestFollowAlerts(t *testing.T) {
+	thresholds, err := feeThresholds(context.Background(), nil, testPool, [2]string{"1.5", ""}, [2]int{6, -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := drive(t, &follower{thresholds: thresholds}, []followBlock{
+		{100, 0, 1_499_999, 0},
+		{101, 0, 1_500_000, 0},
+		{102, 0, 1_600_000, 0},
+		// collected, then reached again
+		{103, 0, 0, 0},
+		{104, 0, 2_000_000, 0},
+	})
+
+	var alerts []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "alert ") {
+			alerts = append(alerts, line)
+		}
+	}
+	want := []string{
+		"alert block=101 fees0=1500000 reached threshold 1500000",
+		"alert block=104 fees0=2000000 reached threshold 1500000",
+	}
+	if !slices.Equal(alerts, want) {
+		t.Errorf("alerts = %q, want %q", alerts, want)
+	}
+}
+
+func TestChangeFilterSignificant(t *testing.T) {
+	fees := func(liquidity int64, inRange bool, fees0, fees1 int64) PositionFees {
+		return PositionFees{Liquidity: big.NewInt(liquidity), InRange: inRange, Fees0: big.NewInt(fees0), Fees1: big.NewInt(fees1)}
+	}
+	prev := fees(1000, true, 100, 100)
+	filter := ChangeFilter{MinFees0: big.NewInt(10), MinFees1: big.NewInt(50)}
+
+	for _, tc := range []struct {
+		name   string
+		filter ChangeFilter
+		next   PositionFees
+		want   bool
+	}{
+		{"unchanged", filter, fees(1000, true, 100, 100), false},
+		{"dust fees", filter, fees(1000, true, 109, 149), false},
+		{"token0 fees at the minimum", filter, fees(1000, true, 110, 100), true},
+		{"token1 fees at the minimum", filter, fees(1000, true, 100, 150), true},
+		// a collect is a change too
+		{"fees dropped", filter, fees(1000, true, 0, 100), true},
+		{"liquidity", filter, fees(1001, true, 100, 100), true},
+		{"range status", filter, fees(1000, false, 100, 100), true},
+		{"token1 fees ignored", ChangeFilter{MinFees0: big.NewInt(10)}, fees(1000, true, 105, 1e6), false},
+		{"no minimums", ChangeFilter{}, fees(1000, true, 1e6, 1e6), false},
+	} {
+		if got := tc.filter.Significant(prev, tc.next); got != tc.want {
+			t.Errorf("%s: Significant = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFollowFilter(t *testing.T) {
+	lines := drive(t, &follower{filter: &ChangeFilter{MinFees0: big.NewInt(10)}}, []followBlock{
+		{100, 0, 10, 20},
+		// dust, measured from the last printed line
+		{101, 0, 14, 20},
+		{102, 0, 19, 25},
+		{103, 0, 20, 25},
+		{104, 70, 20, 25},
+	})
+
+	want := []string{
+		"block=100 liquidity=1000 range=in tick=0 fees0=10 (+0) fees1=20 (+0)",
+		"block=103 liquidity=1000 range=in tick=0 fees0=20 (+10) fees1=25 (+5)",
+		"block=104 liquidity=1000 range=out tick=70 fees0=20 (+0) fees1=25 (+0)",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
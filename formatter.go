@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Formatter writes the results of a read in one -format. Registering one
+// with RegisterFormatter makes it selectable by name like the built-in text,
+// json, ndjson and table formats, for the positions a read prints; modes with
+// output of their own, such as -report, reject it.
+type Formatter interface {
+	Format(w io.Writer, results []PositionResult, opts FormatOptions) error
+}
+
+// FormatOptions are the output flags a Formatter may honour.
+type FormatOptions struct {
+	// FullAddresses is -full-addresses: print addresses unshortened.
+	FullAddresses bool
+}
+
+// FormatterFunc is a Formatter as a plain function.
+type FormatterFunc func(w io.Writer, results []PositionResult, opts FormatOptions) error
+
+func (f FormatterFunc) Format(w io.Writer, results []PositionResult, opts FormatOptions) error {
+	return f(w, results, opts)
+}
+
+// builtinFormats are the formats every mode writes; the formatters
+// registered on top of them only apply to the positions a read prints.
+var builtinFormats = []string{formatText, formatJSON, formatNDJSON, formatTable}
+
+var (
+	formattersMu sync.RWMutex
+	formatters   = map[string]Formatter{
+		formatText:   FormatterFunc(formatTextResults),
+		formatJSON:   FormatterFunc(formatJSONResults),
+		formatNDJSON: FormatterFunc(formatNDJSONResults),
+		formatTable:  FormatterFunc(formatTableResults),
+	}
+)
+
+// RegisterFormatter makes f the -format name. It panics if name is taken,
+// built-in formats included, like database/sql.Register.
+func RegisterFormatter(name string, f Formatter) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+
+	if f == nil {
+		panic("register formatter " + name + ": nil Formatter")
+	}
+	if _, taken := formatters[name]; taken {
+		panic("register formatter " + name + ": name already registered")
+	}
+	formatters[name] = f
+}
+
+// lookupFormatter returns the formatter registered as name.
+func lookupFormatter(name string) (Formatter, bool) {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+
+	f, ok := formatters[name]
+	return f, ok
+}
+
+// formatterNames returns the registered format names, sorted.
+func formatterNames() []string {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func formatTextResults(w io.Writer, results []PositionResult, _ FormatOptions) error {
+	for _, r := range results {
+		if _, err := fmt.Fprintf(w, "%+v\n", r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatJSONResults(w io.Writer, results []PositionResult, _ FormatOptions) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(results)
+}
+
+func formatNDJSONResults(w io.Writer, results []PositionResult, _ FormatOptions) error {
+	return writeNDJSON(w, results)
+}
+
+func formatTableResults(w io.Writer, results []PositionResult, opts FormatOptions) error {
+	return writeTable(w, results, opts.FullAddresses)
+}
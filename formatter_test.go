@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegisterFormatter(t *testing.T) {
+	RegisterFormatter("liquidity-only", FormatterFunc(func(w io.Writer, results []PositionResult, opts FormatOptions) error {
+		for _, r := range results {
+			fmt.Fprintf(w, "%s %t\n", r.Position.Liquidity, opts.FullAddresses)
+		}
+		return nil
+	}))
+	if !slices.Contains(formatterNames(), "liquidity-only") {
+		t.Errorf("formats = %v, want liquidity-only", formatterNames())
+	}
+
+	code, stdout, stderr := runOutput(t, "-node", positionNode(t, 1000).serve(t), "-max-lag", "0", "-format", "liquidity-only", "-full-addresses")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if stdout != "1000 true\n" {
+		t.Errorf("output = %q, want %q", stdout, "1000 true\n")
+	}
+
+	for name, f := range map[string]Formatter{
+		formatJSON:       FormatterFunc(formatJSONResults),
+		"liquidity-only": FormatterFunc(formatJSONResults),
+		"nil":            nil,
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterFormatter(%s) didn't panic", name)
+				}
+			}()
+			RegisterFormatter(name, f)
+		}()
+	}
+}
+
+func TestFormatCSV(t *testing.T) {
+	results := []PositionResult{
+		{Pool: testPool, Owner: testOwner, TokenID: big.NewInt(7), TickLower: -60, TickUpper: 60,
+			Position: Position{Liquidity: big.NewInt(1000), TokensOwed0: big.NewInt(1), TokensOwed1: big.NewInt(2)}},
+		{Pool: testPool, Owner: testOwner, TickLower: -120, TickUpper: 120, Error: "call 1: revert error, execution reverted"},
+	}
+
+	var out bytes.Buffer
+	if err := writeResults(&out, formatCSV, results, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "pool,owner,tokenId,tickLower,tickUpper,liquidity,tokensOwed0,tokensOwed1,error\n" +
+		testPool.Hex() + "," + testOwner.Hex() + ",7,-60,60,1000,1,2,\n" +
+		testPool.Hex() + "," + testOwner.Hex() + ",,-120,120,,,,\"call 1: revert error, execution reverted\"\n"
+	if out.String() != want {
+		t.Errorf("csv =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestRunUnknownFormat(t *testing.T) {
+	code, _, stderr := runOutput(t, "-format", "xml")
+	if code != exitBadInput || !strings.Contains(stderr, "want one of ") || !strings.Contains(stderr, "csv") {
+		t.Errorf("exit code = %d, want %d listing the formats; stderr:\n%s", code, exitBadInput, stderr)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// GroupedPosition is a position with the figures its group sums up.
+type GroupedPosition struct {
+	Result PositionResult
+	// Value is the liquidity's amounts plus Fees0 and Fees1 in raw token1
+	// units at the pool's price, or the price overrides of its tokens.
+	Value *big.Int
+	Fees0 *big.Int
+	Fees1 *big.Int
+}
+
+// PoolGroup is the positions of one pool with their subtotals.
+type PoolGroup struct {
+	Pool      common.Address
+	Token0    common.Address
+	Token1    common.Address
+	Positions []GroupedPosition
+	Liquidity *big.Int
+	Value     *big.Int
+	Fees0     *big.Int
+	Fees1     *big.Int
+}
+
+// GroupTotal is the grand total of all groups. Fees and values of different
+// pools are in different tokens, so both are summed per token; a value is in
+// its pool's token1.
+type GroupTotal struct {
+	Positions int
+	Value     map[common.Address]*big.Int
+	Fees      map[common.Address]*big.Int
+}
+
+// GroupByPool groups results by pool, in pool address order, and sums each
+// pool's liquidity, value and uncollected fees. Values are at the pool price
+// or the client's price overrides. Results need their pool
+// tokens annotated; results with an Error are left out. The fee state of
+// every position is read at one block, in batches of at most
+// enumerationChunk calls.
+func (c *Client) GroupByPool(ctx context.Context, results []PositionResult, block *big.Int) ([]PoolGroup, GroupTotal, error) {
+	block, err := c.fixedBlock(ctx, block)
+	if err != nil {
+		return nil, GroupTotal{}, err
+	}
+
+	var read []PositionResult
+	var calls []call
+	for _, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		stateCalls, err := c.rangeFeeCalls(r.Pool, r.TickLower, r.TickUpper)
+		if err != nil {
+			return nil, GroupTotal{}, err
+		}
+		read = append(read, r)
+		calls = append(calls, stateCalls...)
+	}
+
+	responses, err := c.aggregateChunked(ctx, calls, block, enumerationChunk)
+	if err != nil {
+		return nil, GroupTotal{}, err
+	}
+
+	pairs := make([][2]common.Address, len(read))
+	for i, r := range read {
+		pairs[i] = [2]common.Address{r.Token0, r.Token1}
+	}
+	decimals, err := c.overrideDecimals(ctx, block, pairs)
+	if err != nil {
+		return nil, GroupTotal{}, err
+	}
+
+	total := GroupTotal{Value: make(map[common.Address]*big.Int), Fees: make(map[common.Address]*big.Int)}
+	byPool := make(map[common.Address]*PoolGroup)
+	for i, r := range read {
+		state, err := c.unpackRangeFees(responses[i*rangeFeeCallCount:], r.TickLower, r.TickUpper)
+		if err != nil {
+			return nil, GroupTotal{}, err
+		}
+		fees := state.fees(r.Position, r.TickLower, r.TickUpper)
+
+		amount0, amount1 := AmountsForLiquidity(fees.SqrtPriceX96, r.TickLower, r.TickUpper, fees.Liquidity)
+		valuePrice := c.valuationPrice(r.Token0, r.Token1, decimals[r.Token0], decimals[r.Token1], fees.SqrtPriceX96)
+		value := valueInToken1(amount0.Add(amount0, fees.Fees0), amount1.Add(amount1, fees.Fees1), valuePrice)
+
+		group := byPool[r.Pool]
+		if group == nil {
+			group = &PoolGroup{Pool: r.Pool, Token0: r.Token0, Token1: r.Token1, Liquidity: new(big.Int), Value: new(big.Int), Fees0: new(big.Int), Fees1: new(big.Int)}
+			byPool[r.Pool] = group
+		}
+		group.Positions = append(group.Positions, GroupedPosition{Result: r, Value: value, Fees0: fees.Fees0, Fees1: fees.Fees1})
+		group.Liquidity.Add(group.Liquidity, fees.Liquidity)
+		group.Value.Add(group.Value, value)
+		group.Fees0.Add(group.Fees0, fees.Fees0)
+		group.Fees1.Add(group.Fees1, fees.Fees1)
+
+		total.Positions++
+		addTo(total.Value, r.Token1, value)
+		addTo(total.Fees, r.Token0, fees.Fees0)
+		addTo(total.Fees, r.Token1, fees.Fees1)
+	}
+
+	groups := make([]PoolGroup, 0, len(byPool))
+	for _, group := range byPool {
+		groups = append(groups, *group)
+	}
+	slices.SortFunc(groups, func(a, b PoolGroup) int { return a.Pool.Cmp(b.Pool) })
+
+	return groups, total, nil
+}
+
+// addTo adds amount to sums[token].
+func addTo(sums map[common.Address]*big.Int, token common.Address, amount *big.Int) {
+	if sums[token] == nil {
+		sums[token] = new(big.Int)
+	}
+	sums[token].Add(sums[token], amount)
+}
+
+// writeGroups prints groups and their total as a table, or with -format json
+// as one JSON document.
+func writeGroups(w io.Writer, format string, groups []PoolGroup, total GroupTotal, fullAddresses bool) error {
+	if format == formatJSON || format == formatNDJSON {
+		enc := json.NewEncoder(w)
+		if format == formatJSON {
+			enc.SetIndent("", "  ")
+		}
+		return enc.Encode(groupsJSON{Pools: groups, Total: total})
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POOL\tOWNER\tTOKEN ID\tRANGE\tLIQUIDITY\tVALUE1\tFEES0\tFEES1")
+	for _, g := range groups {
+		pool := displayAddress(g.Pool, fullAddresses)
+		for _, p := range g.Positions {
+			tokenID := "-"
+			if p.Result.TokenID != nil {
+				tokenID = p.Result.TokenID.String()
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t[%d, %d)\t%s\t%s\t%s\t%s\n",
+				pool, displayAddress(p.Result.Owner, fullAddresses), tokenID, p.Result.TickLower, p.Result.TickUpper,
+				p.Result.Position.Liquidity, p.Value, p.Fees0, p.Fees1)
+		}
+		fmt.Fprintf(tw, "%s\tsubtotal\t%d positions\t\t%s\t%s\t%s\t%s\n", pool, len(g.Positions), g.Liquidity, g.Value, g.Fees0, g.Fees1)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "total: %d positions\n", total.Positions)
+	for _, sums := range []struct {
+		name    string
+		amounts map[common.Address]*big.Int
+	}{{"value", total.Value}, {"fees", total.Fees}} {
+		tokens := make([]common.Address, 0, len(sums.amounts))
+		for token := range sums.amounts {
+			tokens = append(tokens, token)
+		}
+		slices.SortFunc(tokens, func(a, b common.Address) int { return a.Cmp(b) })
+		for _, token := range tokens {
+			fmt.Fprintf(w, "%s: %s %s\n", sums.name, sums.amounts[token], displayAddress(token, fullAddresses))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestGroupByPool(t *testing.T) {
+	tokenA := common.HexToAddress("0x000000000000000000000000000000000000000a")
+	tokenB := common.HexToAddress("0x000000000000000000000000000000000000000b")
+	tokenC := common.HexToAddress("0x000000000000000000000000000000000000000c")
+	otherPool := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	other := newFakePool(0, 1000)
+	other.token1 = tokenC
+
+	client := newFakeClient(t, &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: newFakePool(0, 1000), otherPool: other}, nil)})
+
+	result := func(pool, token1 common.Address, liquidity, owed0, owed1 int64) PositionResult {
+		return PositionResult{Pool: pool, Owner: testOwner, Token0: tokenA, Token1: token1, TickLower: -60, TickUpper: 60, Position: Position{
+			Liquidity:                big.NewInt(liquidity),
+			FeeGrowthInside0LastX128: new(big.Int),
+			FeeGrowthInside1LastX128: new(big.Int),
+			TokensOwed0:              big.NewInt(owed0),
+			TokensOwed1:              big.NewInt(owed1),
+		}}
+	}
+	results := []PositionResult{
+		result(testPool, tokenB, 1e6, 7, 9),
+		result(otherPool, tokenC, 1e6, 0, 5),
+		result(testPool, tokenB, 2e6, 1, 0),
+		{Pool: badPool, Owner: testOwner, Error: "execution reverted"},
+	}
+
+	groups, total, err := client.GroupByPool(context.Background(), results, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// at price 1 a value is the sum of the amounts and fees
+	value := func(liquidity int64, fees int64) *big.Int {
+		amount0, amount1 := AmountsForLiquidity(Tick(0).SqrtRatio(), -60, 60, big.NewInt(liquidity))
+		v := new(big.Int).Add(amount0, amount1)
+		return v.Add(v, big.NewInt(fees))
+	}
+	testValue := new(big.Int).Add(value(1e6, 16), value(2e6, 1))
+	otherValue := value(1e6, 5)
+
+	if len(groups) != 2 || groups[0].Pool != otherPool || groups[1].Pool != testPool {
+		t.Fatalf("groups = %+v, want otherPool then testPool", groups)
+	}
+	for _, tc := range []struct {
+		group                   PoolGroup
+		positions               int
+		liquidity, fees0, fees1 int64
+		value                   *big.Int
+	}{
+		{groups[0], 1, 1e6, 0, 5, otherValue},
+		{groups[1], 2, 3e6, 8, 9, testValue},
+	} {
+		g := tc.group
+		if len(g.Positions) != tc.positions || g.Liquidity.Int64() != tc.liquidity || g.Fees0.Int64() != tc.fees0 || g.Fees1.Int64() != tc.fees1 || g.Value.Cmp(tc.value) != 0 {
+			t.Errorf("pool %s: %d positions, liquidity %s, fees %s/%s, value %s; want %d, %d, %d/%d, %s",
+				g.Pool, len(g.Positions), g.Liquidity, g.Fees0, g.Fees1, g.Value, tc.positions, tc.liquidity, tc.fees0, tc.fees1, tc.value)
+		}
+	}
+
+	if total.Positions != 3 {
+		t.Errorf("total counts %d positions, want 3", total.Positions)
+	}
+	for token, want := range map[common.Address]*big.Int{tokenB: testValue, tokenC: otherValue} {
+		if got := total.Value[token]; got == nil || got.Cmp(want) != 0 {
+			t.Errorf("total value in %s = %s, want %s", token, got, want)
+		}
+	}
+	for token, want := range map[common.Address]int64{tokenA: 8, tokenB: 9, tokenC: 5} {
+		if got := total.Fees[token]; got == nil || got.Int64() != want {
+			t.Errorf("total fees in %s = %s, want %d", token, got, want)
+		}
+	}
+
+	var table strings.Builder
+	if err := writeGroups(&table, formatTable, groups, total, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"subtotal", "3000000", "total: 3 positions\n", "fees: 8 " + shortAddress(tokenA) + "\n"} {
+		if !strings.Contains(table.String(), want) {
+			t.Errorf("table lacks %q:\n%s", want, table.String())
+		}
+	}
+
+	var out strings.Builder
+	if err := writeGroups(&out, formatJSON, groups, total, false); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Pools []struct {
+			Pool  common.Address `json:"pool"`
+			Value decimal        `json:"value"`
+		} `json:"pools"`
+		Total struct {
+			Positions int                        `json:"positions"`
+			Fees      map[common.Address]decimal `json:"fees"`
+		} `json:"total"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &doc); err != nil {
+		t.Fatalf("parse JSON: %v\n%s", err, out.String())
+	}
+	if len(doc.Pools) != 2 || doc.Pools[1].Value.Cmp(testValue) != 0 || doc.Total.Positions != 3 || doc.Total.Fees[tokenC].Int64() != 5 {
+		t.Errorf("JSON = %+v", doc)
+	}
+}
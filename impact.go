@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// feeDenominator is the unit of pool fees: hundredths of a bip.
+var feeDenominator = big.NewInt(1_000_000)
+
+// WithdrawalPriceImpact estimates the exit slippage of withdrawing liquidity
+// on [tickLower, tickUpper) from pool: the relative change of the pool price,
+// token1 per token0, when the withdrawn token0 is swapped into token1, the
+// unit positions are valued in. A burn alone leaves the price where it is; it
+// only takes the position's liquidity out of the active liquidity the swap
+// then trades against. The result is zero or negative, e.g. -0.004 for a
+// 0.4% drop, and zero when the withdrawal holds no token0.
+//
+// The swap is SqrtPriceMath.getNextSqrtPriceFromAmount0RoundingUp on the
+// liquidity left after the burn, less the pool fee. Ticks crossed on the way
+// aren't read, so the estimate assumes that liquidity holds over the whole
+// move; it is exact while the price stays above the next initialized tick.
+func (c *Client) WithdrawalPriceImpact(ctx context.Context, pool common.Address, liquidity *big.Int, tickLower, tickUpper Tick) (*big.Float, error) {
+	if err := validateTicks(tickLower, tickUpper); err != nil {
+		return nil, err
+	}
+
+	block, err := c.fixedBlock(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	slot0, err := c.Slot0(ctx, pool, block)
+	if err != nil {
+		return nil, err
+	}
+	active, err := c.ActiveLiquidity(ctx, pool, block)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.callPool(ctx, pool, feeMethod, block)
+	if err != nil {
+		return nil, err
+	}
+	fee := out[0].(*big.Int)
+
+	amount0, _ := AmountsForLiquidity(slot0.SqrtPriceX96, tickLower, tickUpper, liquidity)
+	if amount0.Sign() == 0 {
+		return new(big.Float), nil
+	}
+
+	remaining := new(big.Int).Set(active)
+	if slot0.Tick.InRange(tickLower, tickUpper) {
+		remaining.Sub(remaining, liquidity)
+	}
+	if remaining.Sign() <= 0 {
+		return nil, errors.New("no active liquidity left to swap against after the withdrawal")
+	}
+
+	amountIn := new(big.Int).Sub(feeDenominator, fee)
+	amountIn.Mul(amountIn, amount0).Quo(amountIn, feeDenominator)
+	next := nextSqrtPriceFromAmount0In(slot0.SqrtPriceX96, remaining, amountIn)
+
+	// price is the square of sqrtPriceX96, so the ratio of prices is the
+	// square of the ratio of square roots
+	ratio := new(big.Float).SetPrec(pricePrec).Quo(
+		new(big.Float).SetPrec(pricePrec).SetInt(next),
+		new(big.Float).SetPrec(pricePrec).SetInt(slot0.SqrtPriceX96),
+	)
+	ratio.Mul(ratio, ratio)
+
+	return ratio.Sub(ratio, big.NewFloat(1)), nil
+}
+
+// nextSqrtPriceFromAmount0In is SqrtPriceMath.getNextSqrtPriceFromAmount0RoundingUp
+// for amount added: liquidity * sqrtPriceX96 / (liquidity + amount *
+// sqrtPriceX96 / 2^96), rounded up. big.Int can't overflow, so the
+// contract's fallback formula for an overflowing product isn't needed.
+// https://github.com/Uniswap/v3-core/blob/d8b1c635c275d2a9450bd6a78f3fa2484fef73eb/contracts/libraries/SqrtPriceMath.sol
+func nextSqrtPriceFromAmount0In(sqrtPriceX96, liquidity, amount *big.Int) *big.Int {
+	numerator := new(big.Int).Lsh(liquidity, 96)
+	denominator := new(big.Int).Mul(amount, sqrtPriceX96)
+	denominator.Add(denominator, numerator)
+
+	product := numerator.Mul(numerator, sqrtPriceX96)
+	next, remainder := new(big.Int).QuoRem(product, denominator, new(big.Int))
+	if remainder.Sign() != 0 {
+		next.Add(next, big.NewInt(1))
+	}
+
+	return next
+}
+
+// ExitImpact is the WithdrawalPriceImpact of withdrawing a position's
+// liquidity.
+type ExitImpact struct {
+	Liquidity *big.Int   `json:"liquidity"`
+	Impact    *big.Float `json:"impact"`
+}
+
+// writeExitImpact prints e as a line, or as JSON.
+func writeExitImpact(w io.Writer, format string, e ExitImpact) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(e)
+	}
+
+	_, err := fmt.Fprintf(w, "withdrawing liquidity %s and selling its token0 moves the price %s\n", e.Liquidity, formatPercent(e.Impact))
+
+	return err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestNextSqrtPriceFromAmount0In(t *testing.T) {
+	// adding 1/3 of the liquidity in token0 at price 1 takes the sqrt price
+	// to 3/4, rounded up
+	next := nextSqrtPriceFromAmount0In(q96, big.NewInt(3e18), big.NewInt(1e18))
+	want := new(big.Int).Mul(q96, big.NewInt(3))
+	want.Quo(want, big.NewInt(4))
+	if new(big.Int).Sub(next, want).CmpAbs(big.NewInt(1)) > 0 || next.Cmp(want) < 0 {
+		t.Errorf("next = %s, want %s rounded up", next, want)
+	}
+}
+
+func TestWithdrawalPriceImpact(t *testing.T) {
+	pool := newFakePool(0, 2e18)
+	client := newFakeClient(t, &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)})
+	liquidity := big.NewInt(1e18)
+
+	// in range, the position holds 1e18*(1-1.0001^-30) token0; 0.3% of it
+	// goes to the fee and the rest is sold against the 1e18 left
+	amount0 := 1e18 * (1 - math.Pow(1.0001, -30))
+	inRange := math.Pow(1e18/(1e18+amount0*0.997), 2) - 1
+	// above the price, all 1e18*(1.0001^-30-1.0001^-60) of it is token0,
+	// sold against all 2e18
+	amount0 = 1e18 * (math.Pow(1.0001, -30) - math.Pow(1.0001, -60))
+	above := math.Pow(2e18/(2e18+amount0*0.997), 2) - 1
+
+	for _, tc := range []struct {
+		name         string
+		lower, upper Tick
+		want         float64
+	}{
+		{"in range", -60, 60, inRange},
+		{"above the price", 60, 120, above},
+		// all token1: nothing to sell
+		{"below the price", -120, -60, 0},
+	} {
+		impact, err := client.WithdrawalPriceImpact(context.Background(), testPool, liquidity, tc.lower, tc.upper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := impact.Float64(); !closeTo(got, tc.want) {
+			t.Errorf("%s: impact = %g, want %g", tc.name, got, tc.want)
+		}
+	}
+
+	// the withdrawal is all of the active liquidity
+	if _, err := client.WithdrawalPriceImpact(context.Background(), testPool, big.NewInt(2e18), -60, 60); err == nil {
+		t.Error("withdrawing all the liquidity succeeded")
+	}
+}
+
+func TestRunExitImpact(t *testing.T) {
+	pool := newFakePool(0, 2e18)
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	pool.setPosition(t, q, Position{Liquidity: big.NewInt(1e18)})
+	node := &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)}
+
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-pool", testPool.Hex(), "-owner", testOwner.Hex(),
+		"-tick-lower", "-60", "-tick-upper", "60", "-exit-impact")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if want := "withdrawing liquidity 1000000000000000000 and selling its token0 moves the price -0.59460887%\n"; stdout != want {
+		t.Errorf("output = %q, want %q", stdout, want)
+	}
+
+	if code, _, _ := runOutput(t, "-exit-impact", "-block", "80"); code != exitBadInput {
+		t.Errorf("-exit-impact -block: exit code = %d, want %d", code, exitBadInput)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// InputEntry is one element of the -input file. It is either a pool position
+// given by pool, owner and ticks, or an NFT position given by tokenId:
+//
+//	[
+//	  {"pool": "0xc696...", "owner": "0xF829...", "tickLower": -197740, "tickUpper": -197640},
+//	  {"tokenId": 1234567},
+//	  {"tokenId": "1234568"}
+//	]
+//
+// A tokenId may be a number or, as the tool prints it, a decimal string.
+// Addresses are checked like -pool and -owner, EIP-55 checksum included.
+type InputEntry struct {
+	Pool      *checkedAddress `json:"pool"`
+	Owner     *checkedAddress `json:"owner"`
+	TickLower *Tick           `json:"tickLower"`
+	TickUpper *Tick           `json:"tickUpper"`
+	TokenID   *decimal        `json:"tokenId"`
+}
+
+func readInput(path string) ([]InputEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []InputEntry
+
+	dec := json.NewDecoder(file)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	for i, entry := range entries {
+		if err := entry.validate(); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+	}
+
+	return entries, nil
+}
+
+func (e InputEntry) validate() error {
+	byPool := e.Pool != nil || e.Owner != nil || e.TickLower != nil || e.TickUpper != nil
+
+	switch {
+	case e.TokenID != nil && byPool:
+		return errors.New("tokenId can't be combined with pool, owner or ticks")
+	case e.TokenID != nil:
+		if e.TokenID.Sign() < 0 {
+			return errors.New("negative tokenId")
+		}
+		return nil
+	case e.Pool == nil || e.Owner == nil || e.TickLower == nil || e.TickUpper == nil:
+		return errors.New("either tokenId or all of pool, owner, tickLower and tickUpper are required")
+	}
+	if err := notAPool(e.Pool.Address); err != nil {
+		return err
+	}
+
+	return validateTicks(*e.TickLower, *e.TickUpper)
+}
+
+// fetchEntries reads all entries, batching pool and NFT positions separately,
+// and returns the results in input order. Entries that reverted or timed out
+// are returned with their Error set, together with a *BatchError indexed
+// like entries.
+func fetchEntries(ctx context.Context, client *Client, entries []InputEntry, block *big.Int) ([]PositionResult, error) {
+	var (
+		queries  []PositionQuery
+		queryIdx []int
+		tokenIDs []*big.Int
+		tokenIdx []int
+		results  = make([]PositionResult, len(entries))
+	)
+
+	for i, e := range entries {
+		if e.TokenID != nil {
+			tokenIDs = append(tokenIDs, e.TokenID.Int)
+			tokenIdx = append(tokenIdx, i)
+			continue
+		}
+		queries = append(queries, PositionQuery{Pool: e.Pool.Address, Owner: e.Owner.Address, TickLower: *e.TickLower, TickUpper: *e.TickUpper})
+		queryIdx = append(queryIdx, i)
+	}
+
+	var errs []error
+	positions, err := client.Positions(ctx, queries, block)
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		errs = scatterErrs(errs, batchErr, queryIdx, len(entries))
+	} else if err != nil {
+		return nil, err
+	}
+	for i, r := range newPositionResults(queries, positions, err) {
+		results[queryIdx[i]] = r
+	}
+
+	tokenPositions, err := client.TokenPositions(ctx, tokenIDs, block)
+	if errors.As(err, &batchErr) {
+		errs = scatterErrs(errs, batchErr, tokenIdx, len(entries))
+	} else if err != nil {
+		return nil, err
+	}
+	for i, r := range newTokenPositionResults(tokenPositions, err) {
+		results[tokenIdx[i]] = r
+	}
+
+	if errs != nil {
+		return results, &BatchError{Errs: errs}
+	}
+
+	return results, nil
+}
+
+// scatterErrs copies the errors of a batch over some of n entries, at
+// indexes idx, into errs, allocating it on first use.
+func scatterErrs(errs []error, batchErr *BatchError, idx []int, n int) []error {
+	if errs == nil {
+		errs = make([]error, n)
+	}
+	for i, err := range batchErr.Errs {
+		errs[idx[i]] = err
+	}
+
+	return errs
+}
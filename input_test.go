@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func writeInput(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "input.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestReadInputTokenIDs(t *testing.T) {
+	path := writeInput(t, `[
+		{"tokenId": 1234567},
+		{"tokenId": "1234568"},
+		{"tokenId": "115792089237316195423570985008687907853269984665640564039457584007913129639935"}
+	]`)
+
+	entries, err := readInput(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"1234567", "1234568", maxUint.String()}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e.TokenID == nil || e.TokenID.String() != want[i] {
+			t.Errorf("entry %d tokenId = %v, want %s", i, e.TokenID, want[i])
+		}
+	}
+}
+
+func TestReadInputPoolEntry(t *testing.T) {
+	entries, err := readInput(writeInput(t, `[{
+		"pool": "0xc36442b4a4522e871399cd717abdd847ab11fe00",
+		"owner": "0xC36442b4a4522E871399CD717aBDD847Ab11FE88",
+		"tickLower": -887220,
+		"tickUpper": 887220
+	}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := entries[0]
+	if want := common.HexToAddress("0xc36442b4a4522e871399cd717abdd847ab11fe00"); e.Pool.Address != want {
+		t.Errorf("pool = %s, want %s", e.Pool.Hex(), want.Hex())
+	}
+	if e.Owner.Address != arbitrumNPM {
+		t.Errorf("owner = %s, want %s", e.Owner.Hex(), arbitrumNPM.Hex())
+	}
+	if *e.TickLower != -887220 || *e.TickUpper != 887220 {
+		t.Errorf("ticks %d, %d", *e.TickLower, *e.TickUpper)
+	}
+}
+
+func TestReadInputInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name, content, err string
+	}{
+		{"negative tokenId", `[{"tokenId": "-1"}]`, "negative tokenId"},
+		{"non-decimal tokenId", `[{"tokenId": "0x10"}]`, "invalid decimal integer"},
+		{"tokenId with pool", `[{"tokenId": 1, "pool": "0x1111111111111111111111111111111111111111"}]`, "can't be combined"},
+		{"incomplete pool entry", `[{"pool": "0x1111111111111111111111111111111111111111"}]`, "are required"},
+		{"unknown field", `[{"token": 1}]`, "unknown field"},
+		{"bad checksum", `[{"pool": "0xC36442b4a4522E871399CD717aBDD847Ab11FE89", "owner": "0x3333333333333333333333333333333333333333", "tickLower": -60, "tickUpper": 60}]`, "invalid EIP-55 checksum"},
+		{"truncated owner", `[{"pool": "0x1111111111111111111111111111111111111111", "owner": "0x33333333333333333333333333333333333333", "tickLower": -60, "tickUpper": 60}]`, "has 38 hex digits"},
+		{"numeric owner", `[{"pool": "0x1111111111111111111111111111111111111111", "owner": 3, "tickLower": -60, "tickUpper": 60}]`, "must be a hex string"},
+		{"position manager as pool", `[{"pool": "0xC36442b4a4522E871399CD717aBDD847Ab11FE88", "owner": "0x3333333333333333333333333333333333333333", "tickLower": -60, "tickUpper": 60}]`, "NonfungiblePositionManager"},
+		{"bad ticks", `[{"pool": "0x1111111111111111111111111111111111111111", "owner": "0x3333333333333333333333333333333333333333", "tickLower": 60, "tickUpper": -60}]`, "must be below"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := readInput(writeInput(t, tc.content))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("err = %v, want one containing %q", err, tc.err)
+			}
+		})
+	}
+}
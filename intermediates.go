@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// intermediates, set by -hex-intermediates, receives a line per intermediate
+// value of the fee and amount math, to be diffed against a trace of the
+// contracts. It is nil, and tracing off, otherwise.
+var (
+	intermediates   io.Writer
+	intermediatesMu sync.Mutex
+)
+
+// traceHex writes name and v as hexWord to intermediates, if set.
+func traceHex(name string, v *big.Int) {
+	if intermediates == nil {
+		return
+	}
+
+	intermediatesMu.Lock()
+	defer intermediatesMu.Unlock()
+	fmt.Fprintf(intermediates, "%s %s\n", name, hexWord(v))
+}
+
+// hexWord formats v as 0x and 64 hex digits, a uint256 word as the EVM holds
+// it: a negative v is its two's complement modulo 2^256. A mulDiv product
+// wider than a word is padded to 128 digits, the 512 bits FullMath keeps.
+func hexWord(v *big.Int) string {
+	if v.Sign() < 0 {
+		v = new(big.Int).And(v, maxUint)
+	}
+	if v.BitLen() > 256 {
+		return fmt.Sprintf("0x%0128x", v)
+	}
+
+	return fmt.Sprintf("0x%064x", v)
+}
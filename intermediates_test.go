@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestHexWord(t *testing.T) {
+	for _, tc := range []struct {
+		in   *big.Int
+		want string
+	}{
+		{big.NewInt(0), "0x" + strings.Repeat("0", 64)},
+		{big.NewInt(500), "0x" + strings.Repeat("0", 61) + "1f4"},
+		{big.NewInt(-1), "0x" + strings.Repeat("f", 64)},
+		{maxUint, "0x" + strings.Repeat("f", 64)},
+		// a mulDiv product past one word
+		{new(big.Int).Lsh(big.NewInt(1), 256), "0x" + strings.Repeat("0", 63) + "1" + strings.Repeat("0", 64)},
+	} {
+		if got := hexWord(tc.in); got != tc.want {
+			t.Errorf("hexWord(%s) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestTraceHexUncollected(t *testing.T) {
+	var trace bytes.Buffer
+	intermediates = &trace
+	t.Cleanup(func() { intermediates = nil })
+
+	uncollected(big.NewInt(2), big.NewInt(1), new(big.Int).Mul(q128, big.NewInt(3)), new(big.Int))
+
+	want := strings.Join([]string{
+		"uncollected.feeGrowthDelta " + hexWord(new(big.Int).Mul(q128, big.NewInt(3))),
+		"mulDiv.product " + hexWord(new(big.Int).Mul(q128, big.NewInt(6))),
+		"mulDiv.denominator " + hexWord(q128),
+		"uncollected.accrued " + hexWord(big.NewInt(6)),
+		"uncollected.fees " + hexWord(big.NewInt(7)),
+	}, "\n") + "\n"
+	if trace.String() != want {
+		t.Errorf("trace =\n%s\nwant\n%s", trace.String(), want)
+	}
+}
+
+func TestRunHexIntermediates(t *testing.T) {
+	token1 := common.HexToAddress("0x000000000000000000000000000000000000000b")
+	line := regexp.MustCompile(`^[a-zA-Z0-9]+\.[a-zA-Z0-9]+ 0x([0-9a-f]{64}|[0-9a-f]{128})$`)
+
+	for _, traced := range []bool{true, false} {
+		npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {1}}}
+		args := []string{"-node", listNode(t, npm).serve(t), "-max-lag", "0", "-quiet", "-list", "-owner", testOwner.Hex(), "-summary", token1.Hex()}
+		if traced {
+			args = append(args, "-hex-intermediates")
+		}
+		code, _, stderr := runOutput(t, args...)
+		if code != exitOK {
+			t.Fatalf("exit code %d, want %d; stderr:\n%s", code, exitOK, stderr)
+		}
+
+		if !traced {
+			if stderr != "" {
+				t.Errorf("stderr without -hex-intermediates:\n%s", stderr)
+			}
+			continue
+		}
+		for _, l := range strings.Split(strings.TrimSuffix(stderr, "\n"), "\n") {
+			if !line.MatchString(l) {
+				t.Errorf("trace line %q isn't a name and a hex word", l)
+			}
+		}
+		for _, want := range []string{
+			"feeGrowthInside.inside 0x",
+			"uncollected.fees 0x",
+			"amounts.liquidity 0x" + strings.Repeat("0", 61) + "1f4\n",
+		} {
+			if !strings.Contains(stderr, want) {
+				t.Errorf("trace lacks %q:\n%s", want, stderr)
+			}
+		}
+	}
+}
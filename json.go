@@ -0,0 +1,585 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// decimal is a *big.Int that goes to JSON as a decimal string, so values
+// above 2^53 survive consumers that parse numbers as float64.
+type decimal struct {
+	*big.Int
+}
+
+func (d decimal) MarshalJSON() ([]byte, error) {
+	if d.Int == nil {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(d.String())
+}
+
+func (d *decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		d.Int = nil
+		return nil
+	}
+
+	// bare numbers are still accepted so older output parses
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("invalid decimal integer %q", s)
+	}
+	d.Int = n
+
+	return nil
+}
+
+// priceText is a price, price impact or rate that goes to JSON as the string
+// formatPrice renders, with the digits of -price-precision.
+type priceText struct {
+	*big.Float
+}
+
+func (p priceText) MarshalJSON() ([]byte, error) {
+	if p.Float == nil {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(formatPrice(p.Float))
+}
+
+type positionJSON struct {
+	Liquidity                decimal `json:"liquidity"`
+	FeeGrowthInside0LastX128 decimal `json:"feeGrowthInside0LastX128"`
+	FeeGrowthInside1LastX128 decimal `json:"feeGrowthInside1LastX128"`
+	TokensOwed0              decimal `json:"tokensOwed0"`
+	TokensOwed1              decimal `json:"tokensOwed1"`
+}
+
+func newPositionJSON(p Position) positionJSON {
+	return positionJSON{
+		Liquidity:                decimal{p.Liquidity},
+		FeeGrowthInside0LastX128: decimal{p.FeeGrowthInside0LastX128},
+		FeeGrowthInside1LastX128: decimal{p.FeeGrowthInside1LastX128},
+		TokensOwed0:              decimal{p.TokensOwed0},
+		TokensOwed1:              decimal{p.TokensOwed1},
+	}
+}
+
+func (p Position) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newPositionJSON(p))
+}
+
+func (p *Position) UnmarshalJSON(data []byte) error {
+	var v positionJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*p = Position{
+		Liquidity:                v.Liquidity.Int,
+		FeeGrowthInside0LastX128: v.FeeGrowthInside0LastX128.Int,
+		FeeGrowthInside1LastX128: v.FeeGrowthInside1LastX128.Int,
+		TokensOwed0:              v.TokensOwed0.Int,
+		TokensOwed1:              v.TokensOwed1.Int,
+	}
+
+	return nil
+}
+
+// The aliases below drop the methods of the original types so that the
+// wrapping structs can override just their big.Int fields.
+type (
+	plainPositionResult PositionResult
+	plainAttestation    Attestation
+	plainBaseQuote      BaseQuote
+	plainPositionReport PositionReport
+	plainPoolShare      PoolShare
+	plainCollectEvent   CollectEvent
+	plainActivityEvent  ActivityEvent
+)
+
+type positionResultJSON struct {
+	plainPositionResult
+	TokenID *decimal `json:"tokenId,omitempty"`
+}
+
+// scaledPositionJSON is a position with its owed amounts also in whole
+// tokens, next to the raw ones.
+type scaledPositionJSON struct {
+	positionJSON
+	TokensOwed0Scaled string `json:"tokensOwed0Scaled,omitempty"`
+	TokensOwed1Scaled string `json:"tokensOwed1Scaled,omitempty"`
+}
+
+// scaledPositionResultJSON overrides the position of a -scaled result.
+type scaledPositionResultJSON struct {
+	positionResultJSON
+	Position scaledPositionJSON `json:"position"`
+}
+
+func (r PositionResult) MarshalJSON() ([]byte, error) {
+	v := positionResultJSON{plainPositionResult: plainPositionResult(r)}
+	if r.TokenID != nil {
+		v.TokenID = &decimal{r.TokenID}
+	}
+	if r.Decimals0 == nil && r.Decimals1 == nil {
+		return json.Marshal(v)
+	}
+
+	scaled := scaledPositionResultJSON{positionResultJSON: v, Position: scaledPositionJSON{positionJSON: newPositionJSON(r.Position)}}
+	if r.Decimals0 != nil && r.Position.TokensOwed0 != nil {
+		scaled.Position.TokensOwed0Scaled = formatUnits(r.Position.TokensOwed0, *r.Decimals0)
+	}
+	if r.Decimals1 != nil && r.Position.TokensOwed1 != nil {
+		scaled.Position.TokensOwed1Scaled = formatUnits(r.Position.TokensOwed1, *r.Decimals1)
+	}
+
+	return json.Marshal(scaled)
+}
+
+func (r *PositionResult) UnmarshalJSON(data []byte) error {
+	var v positionResultJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*r = PositionResult(v.plainPositionResult)
+	if v.TokenID != nil {
+		r.TokenID = v.TokenID.Int
+	}
+
+	return nil
+}
+
+type attestationJSON struct {
+	plainAttestation
+	ChainID   decimal `json:"chainId"`
+	Liquidity decimal `json:"liquidity"`
+}
+
+func (a Attestation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(attestationJSON{
+		plainAttestation: plainAttestation(a),
+		ChainID:          decimal{a.ChainID},
+		Liquidity:        decimal{a.Liquidity},
+	})
+}
+
+func (a *Attestation) UnmarshalJSON(data []byte) error {
+	var v attestationJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*a = Attestation(v.plainAttestation)
+	a.ChainID = v.ChainID.Int
+	a.Liquidity = v.Liquidity.Int
+
+	return nil
+}
+
+type baseQuoteJSON struct {
+	plainBaseQuote
+	OwedBase  decimal `json:"owedBase"`
+	OwedQuote decimal `json:"owedQuote"`
+}
+
+func (b BaseQuote) MarshalJSON() ([]byte, error) {
+	return json.Marshal(baseQuoteJSON{
+		plainBaseQuote: plainBaseQuote(b),
+		OwedBase:       decimal{b.OwedBase},
+		OwedQuote:      decimal{b.OwedQuote},
+	})
+}
+
+func (b *BaseQuote) UnmarshalJSON(data []byte) error {
+	var v baseQuoteJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*b = BaseQuote(v.plainBaseQuote)
+	b.OwedBase = v.OwedBase.Int
+	b.OwedQuote = v.OwedQuote.Int
+
+	return nil
+}
+
+type portfolioJSON struct {
+	Quote      common.Address             `json:"quote"`
+	Positions  int                        `json:"positions"`
+	InRange    int                        `json:"inRange"`
+	OutOfRange int                        `json:"outOfRange"`
+	Value      decimal                    `json:"value"`
+	Unpriced   int                        `json:"unpriced"`
+	Fees       map[common.Address]decimal `json:"fees"`
+}
+
+func (p Portfolio) MarshalJSON() ([]byte, error) {
+	fees := make(map[common.Address]decimal, len(p.Fees))
+	for token, amount := range p.Fees {
+		fees[token] = decimal{amount}
+	}
+
+	return json.Marshal(portfolioJSON{
+		Quote:      p.Quote,
+		Positions:  p.Positions,
+		InRange:    p.InRange,
+		OutOfRange: p.OutOfRange,
+		Value:      decimal{p.Value},
+		Unpriced:   p.Unpriced,
+		Fees:       fees,
+	})
+}
+
+type feeAccrualJSON struct {
+	FromBlock uint64  `json:"fromBlock"`
+	ToBlock   uint64  `json:"toBlock"`
+	Seconds   uint64  `json:"seconds"`
+	Fees0     decimal `json:"fees0"`
+	Fees1     decimal `json:"fees1"`
+	APR       *string `json:"apr"`
+}
+
+func (a FeeAccrual) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.json())
+}
+
+func (a FeeAccrual) json() feeAccrualJSON {
+	out := feeAccrualJSON{
+		FromBlock: a.FromBlock,
+		ToBlock:   a.ToBlock,
+		Seconds:   uint64(a.Elapsed / time.Second),
+		Fees0:     decimal{a.Fees0},
+		Fees1:     decimal{a.Fees1},
+	}
+	if a.APR != nil {
+		apr := formatPrice(a.APR)
+		out.APR = &apr
+	}
+
+	return out
+}
+
+type liquiditySegmentJSON struct {
+	FromBlock uint64  `json:"fromBlock"`
+	ToBlock   uint64  `json:"toBlock"`
+	Seconds   uint64  `json:"seconds"`
+	Liquidity decimal `json:"liquidity"`
+	Fees0     decimal `json:"fees0"`
+	Fees1     decimal `json:"fees1"`
+}
+
+type segmentedAPRJSON struct {
+	feeAccrualJSON
+	Segments []liquiditySegmentJSON `json:"segments"`
+}
+
+func (a SegmentedAPR) MarshalJSON() ([]byte, error) {
+	out := segmentedAPRJSON{feeAccrualJSON: a.FeeAccrual.json(), Segments: make([]liquiditySegmentJSON, len(a.Segments))}
+	for i, s := range a.Segments {
+		out.Segments[i] = liquiditySegmentJSON{
+			FromBlock: s.FromBlock,
+			ToBlock:   s.ToBlock,
+			Seconds:   uint64(s.Elapsed / time.Second),
+			Liquidity: decimal{s.Liquidity},
+			Fees0:     decimal{s.Fees0},
+			Fees1:     decimal{s.Fees1},
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+type reinvestedAccrualJSON struct {
+	feeAccrualJSON
+	ReinvestSeconds uint64  `json:"reinvestSeconds"`
+	ReinvestedAPR   *string `json:"reinvestedApr"`
+}
+
+func (a ReinvestedAccrual) MarshalJSON() ([]byte, error) {
+	out := reinvestedAccrualJSON{feeAccrualJSON: a.FeeAccrual.json(), ReinvestSeconds: uint64(a.Period / time.Second)}
+	if a.ReinvestedAPR != nil {
+		apr := formatPrice(a.ReinvestedAPR)
+		out.ReinvestedAPR = &apr
+	}
+
+	return json.Marshal(out)
+}
+
+type feeRateJSON struct {
+	feeAccrualJSON
+	InRangeSeconds uint64  `json:"inRangeSeconds"`
+	Rate           string  `json:"rate"`
+	InRangeAPR     *string `json:"inRangeApr"`
+}
+
+func (r FeeRate) MarshalJSON() ([]byte, error) {
+	out := feeRateJSON{
+		feeAccrualJSON: r.FeeAccrual.json(),
+		InRangeSeconds: uint64(r.InRange / time.Second),
+		Rate:           formatPrice(r.Rate),
+	}
+	if r.InRangeAPR != nil {
+		apr := formatPrice(r.InRangeAPR)
+		out.InRangeAPR = &apr
+	}
+
+	return json.Marshal(out)
+}
+
+type groupsJSON struct {
+	Pools []PoolGroup `json:"pools"`
+	Total GroupTotal  `json:"total"`
+}
+
+type groupedPositionJSON struct {
+	Result PositionResult `json:"result"`
+	Value  decimal        `json:"value"`
+	Fees0  decimal        `json:"fees0"`
+	Fees1  decimal        `json:"fees1"`
+}
+
+func (p GroupedPosition) MarshalJSON() ([]byte, error) {
+	return json.Marshal(groupedPositionJSON{
+		Result: p.Result,
+		Value:  decimal{p.Value},
+		Fees0:  decimal{p.Fees0},
+		Fees1:  decimal{p.Fees1},
+	})
+}
+
+type poolGroupJSON struct {
+	Pool      common.Address    `json:"pool"`
+	Token0    common.Address    `json:"token0"`
+	Token1    common.Address    `json:"token1"`
+	Positions []GroupedPosition `json:"positions"`
+	Liquidity decimal           `json:"liquidity"`
+	Value     decimal           `json:"value"`
+	Fees0     decimal           `json:"fees0"`
+	Fees1     decimal           `json:"fees1"`
+}
+
+func (g PoolGroup) MarshalJSON() ([]byte, error) {
+	return json.Marshal(poolGroupJSON{
+		Pool:      g.Pool,
+		Token0:    g.Token0,
+		Token1:    g.Token1,
+		Positions: g.Positions,
+		Liquidity: decimal{g.Liquidity},
+		Value:     decimal{g.Value},
+		Fees0:     decimal{g.Fees0},
+		Fees1:     decimal{g.Fees1},
+	})
+}
+
+type groupTotalJSON struct {
+	Positions int                        `json:"positions"`
+	Value     map[common.Address]decimal `json:"value"`
+	Fees      map[common.Address]decimal `json:"fees"`
+}
+
+func (t GroupTotal) MarshalJSON() ([]byte, error) {
+	out := groupTotalJSON{
+		Positions: t.Positions,
+		Value:     make(map[common.Address]decimal, len(t.Value)),
+		Fees:      make(map[common.Address]decimal, len(t.Fees)),
+	}
+	for token, amount := range t.Value {
+		out.Value[token] = decimal{amount}
+	}
+	for token, amount := range t.Fees {
+		out.Fees[token] = decimal{amount}
+	}
+
+	return json.Marshal(out)
+}
+
+type reconciliationJSON struct {
+	TokenID    decimal        `json:"tokenId"`
+	Owner      common.Address `json:"owner"`
+	Block      decimal        `json:"block"`
+	Predicted0 decimal        `json:"predicted0"`
+	Predicted1 decimal        `json:"predicted1"`
+	Collected0 decimal        `json:"collected0"`
+	Collected1 decimal        `json:"collected1"`
+	Agrees     bool           `json:"agrees"`
+}
+
+func (r Reconciliation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(reconciliationJSON{
+		TokenID:    decimal{r.TokenID},
+		Owner:      r.Owner,
+		Block:      decimal{r.Block},
+		Predicted0: decimal{r.Predicted0},
+		Predicted1: decimal{r.Predicted1},
+		Collected0: decimal{r.Collected0},
+		Collected1: decimal{r.Collected1},
+		Agrees:     r.Agrees(),
+	})
+}
+
+type positionReportJSON struct {
+	plainPositionReport
+	Block        decimal `json:"block"`
+	SqrtPriceX96 decimal `json:"sqrtPriceX96"`
+	Amount0      decimal `json:"amount0"`
+	Amount1      decimal `json:"amount1"`
+	Fees0        decimal `json:"fees0"`
+	Fees1        decimal `json:"fees1"`
+	Value        decimal `json:"value"`
+
+	PriceLower priceText `json:"priceLower"`
+	PriceUpper priceText `json:"priceUpper"`
+	Price      priceText `json:"price"`
+	ValuePrice priceText `json:"valuePrice"`
+}
+
+func (r PositionReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(positionReportJSON{
+		plainPositionReport: plainPositionReport(r),
+		PriceLower:          priceText{r.PriceLower},
+		PriceUpper:          priceText{r.PriceUpper},
+		Price:               priceText{r.Price},
+		ValuePrice:          priceText{r.ValuePrice},
+		Block:               decimal{r.Block},
+		SqrtPriceX96:        decimal{r.SqrtPriceX96},
+		Amount0:             decimal{r.Amount0},
+		Amount1:             decimal{r.Amount1},
+		Fees0:               decimal{r.Fees0},
+		Fees1:               decimal{r.Fees1},
+		Value:               decimal{r.Value},
+	})
+}
+
+type poolShareJSON struct {
+	plainPoolShare
+	Block     decimal   `json:"block"`
+	Liquidity decimal   `json:"liquidity"`
+	Share     priceText `json:"share"`
+}
+
+func (s PoolShare) MarshalJSON() ([]byte, error) {
+	return json.Marshal(poolShareJSON{
+		plainPoolShare: plainPoolShare(s),
+		Share:          priceText{s.Share},
+		Block:          decimal{s.Block},
+		Liquidity:      decimal{s.Liquidity},
+	})
+}
+
+type exitImpactJSON struct {
+	Liquidity decimal   `json:"liquidity"`
+	Impact    priceText `json:"impact"`
+}
+
+func (e ExitImpact) MarshalJSON() ([]byte, error) {
+	return json.Marshal(exitImpactJSON{Liquidity: decimal{e.Liquidity}, Impact: priceText{e.Impact}})
+}
+
+type positionAmountsJSON struct {
+	Block        decimal `json:"block"`
+	SqrtPriceX96 decimal `json:"sqrtPriceX96"`
+	Tick         Tick    `json:"tick"`
+	Liquidity    decimal `json:"liquidity"`
+	Amount0      decimal `json:"amount0"`
+	Amount1      decimal `json:"amount1"`
+}
+
+func (a PositionAmounts) MarshalJSON() ([]byte, error) {
+	return json.Marshal(positionAmountsJSON{
+		Block:        decimal{a.Block},
+		SqrtPriceX96: decimal{a.SqrtPriceX96},
+		Tick:         a.Tick,
+		Liquidity:    decimal{a.Liquidity},
+		Amount0:      decimal{a.Amount0},
+		Amount1:      decimal{a.Amount1},
+	})
+}
+
+type collectEventJSON struct {
+	plainCollectEvent
+	Amount0 decimal `json:"amount0"`
+	Amount1 decimal `json:"amount1"`
+}
+
+func (e CollectEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(collectEventJSON{
+		plainCollectEvent: plainCollectEvent(e),
+		Amount0:           decimal{e.Amount0},
+		Amount1:           decimal{e.Amount1},
+	})
+}
+
+type activityEventJSON struct {
+	plainActivityEvent
+	LiquidityDelta *decimal `json:"liquidityDelta,omitempty"`
+	Amount0        decimal  `json:"amount0"`
+	Amount1        decimal  `json:"amount1"`
+	SqrtPriceX96   *decimal `json:"sqrtPriceX96,omitempty"`
+}
+
+func (e ActivityEvent) MarshalJSON() ([]byte, error) {
+	v := activityEventJSON{plainActivityEvent: plainActivityEvent(e), Amount0: decimal{e.Amount0}, Amount1: decimal{e.Amount1}}
+	if e.LiquidityDelta != nil {
+		v.LiquidityDelta = &decimal{e.LiquidityDelta}
+	}
+	if e.SqrtPriceX96 != nil {
+		v.SqrtPriceX96 = &decimal{e.SqrtPriceX96}
+	}
+
+	return json.Marshal(v)
+}
+
+type liquidityStepJSON struct {
+	Tick      Tick    `json:"tick"`
+	Liquidity decimal `json:"liquidity"`
+}
+
+func (s LiquidityStep) MarshalJSON() ([]byte, error) {
+	return json.Marshal(liquidityStepJSON{Tick: s.Tick, Liquidity: decimal{s.Liquidity}})
+}
+
+type breakEvenJSON struct {
+	Price  priceText   `json:"price"`
+	Prices []priceText `json:"breakEvenPrices"`
+}
+
+func (b BreakEven) MarshalJSON() ([]byte, error) {
+	out := breakEvenJSON{Price: priceText{b.Price}, Prices: make([]priceText, len(b.Prices))}
+	for i, p := range b.Prices {
+		out.Prices[i] = priceText{p}
+	}
+
+	return json.Marshal(out)
+}
+
+type projectionJSON struct {
+	Price          priceText  `json:"price"`
+	Value          *big.Float `json:"value"`
+	ProjectedPrice priceText  `json:"projectedPrice"`
+	ProjectedValue *big.Float `json:"projectedValue"`
+}
+
+func (p Projection) MarshalJSON() ([]byte, error) {
+	return json.Marshal(projectionJSON{
+		Price:          priceText{p.Price},
+		Value:          p.Value,
+		ProjectedPrice: priceText{p.ProjectedPrice},
+		ProjectedValue: p.ProjectedValue,
+	})
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestDecimalJSON(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{`"0"`, "0"},
+		{`"115792089237316195423570985008687907853269984665640564039457584007913129639935"`, maxUint.String()},
+		{`"-8388608"`, "-8388608"},
+		// numbers from older output, beyond float64 precision
+		{`340282366920938463463374607431768211455`, maxUint128.String()},
+	} {
+		var d decimal
+		if err := json.Unmarshal([]byte(tc.in), &d); err != nil {
+			t.Errorf("unmarshal %s: %v", tc.in, err)
+			continue
+		}
+		if d.String() != tc.want {
+			t.Errorf("unmarshal %s = %s, want %s", tc.in, d, tc.want)
+		}
+
+		out, err := json.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `"` + tc.want + `"`; string(out) != want {
+			t.Errorf("marshal %s = %s, want %s", tc.want, out, want)
+		}
+	}
+}
+
+func TestDecimalJSONNull(t *testing.T) {
+	out, err := json.Marshal(decimal{})
+	if err != nil || string(out) != "null" {
+		t.Errorf("marshal nil decimal = %s, %v, want null", out, err)
+	}
+
+	d := decimal{big.NewInt(1)}
+	if err := json.Unmarshal([]byte("null"), &d); err != nil || d.Int != nil {
+		t.Errorf("unmarshal null = %v, %v, want nil", d.Int, err)
+	}
+}
+
+func TestDecimalJSONInvalid(t *testing.T) {
+	for _, in := range []string{`"0x10"`, `"1.5"`, `""`, `1e18`, `true`} {
+		var d decimal
+		if err := json.Unmarshal([]byte(in), &d); err == nil {
+			t.Errorf("unmarshal %s = %s, want an error", in, d)
+		}
+	}
+}
+
+func TestPositionResultJSONRoundTrip(t *testing.T) {
+	r := PositionResult{
+		Pool:      testPool,
+		Owner:     testOwner,
+		TokenID:   new(big.Int).Lsh(big.NewInt(1), 100),
+		TickLower: MinTick,
+		TickUpper: MaxTick,
+		Position: Position{
+			Liquidity:                maxUint128,
+			FeeGrowthInside0LastX128: maxUint,
+			FeeGrowthInside1LastX128: big.NewInt(1),
+			TokensOwed0:              big.NewInt(0),
+			TokensOwed1:              new(big.Int).Sub(maxUint128, big.NewInt(1)),
+		},
+	}
+
+	out, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"tokenId":"1267650600228229401496703205376"`,
+		`"liquidity":"340282366920938463463374607431768211455"`,
+		`"tickLower":-887272`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("JSON lacks %s: %s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "Scaled") {
+		t.Errorf("JSON has scaled amounts without -scaled: %s", out)
+	}
+
+	var back PositionResult
+	if err := json.Unmarshal(out, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.TokenID.Cmp(r.TokenID) != 0 || back.TickLower != r.TickLower || back.TickUpper != r.TickUpper {
+		t.Errorf("round trip = %+v, want %+v", back, r)
+	}
+	for i, pair := range [][2]*big.Int{
+		{back.Position.Liquidity, r.Position.Liquidity},
+		{back.Position.FeeGrowthInside0LastX128, r.Position.FeeGrowthInside0LastX128},
+		{back.Position.FeeGrowthInside1LastX128, r.Position.FeeGrowthInside1LastX128},
+		{back.Position.TokensOwed0, r.Position.TokensOwed0},
+		{back.Position.TokensOwed1, r.Position.TokensOwed1},
+	} {
+		if pair[0].Cmp(pair[1]) != 0 {
+			t.Errorf("position field %d = %s, want %s", i, pair[0], pair[1])
+		}
+	}
+}
+
+func TestPositionResultJSONScaled(t *testing.T) {
+	decimals0, decimals1 := uint8(18), uint8(6)
+	r := PositionResult{
+		Pool:      testPool,
+		Owner:     testOwner,
+		Position:  Position{TokensOwed0: big.NewInt(1_500_000_000_000_000_000), TokensOwed1: big.NewInt(2_000_001)},
+		Decimals0: &decimals0,
+		Decimals1: &decimals1,
+	}
+
+	out, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"tokensOwed0":"1500000000000000000"`,
+		`"tokensOwed0Scaled":"1.5"`,
+		`"tokensOwed1Scaled":"2.000001"`,
+		`"decimals1":6`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("JSON lacks %s: %s", want, out)
+		}
+	}
+}
+
+func TestPoolShareJSON(t *testing.T) {
+	out, err := json.Marshal(PoolShare{Block: big.NewInt(100), Tick: -5, InRange: true, Liquidity: maxUint128, Share: big.NewFloat(0.25)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONFields(t, out, map[string]interface{}{"block": "100", "tick": -5.0, "inRange": true, "liquidity": maxUint128.String(), "share": "0.25"})
+}
+
+// assertJSONFields checks that the JSON object out has exactly the fields of
+// want, big integers as decimal strings.
+func assertJSONFields(t *testing.T, out []byte, want map[string]interface{}) {
+	t.Helper()
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("parse %s: %v", out, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("marshal = %s, want %v", out, want)
+	}
+}
+
+func TestExitImpactJSON(t *testing.T) {
+	out, err := json.Marshal(ExitImpact{Liquidity: maxUint128, Impact: big.NewFloat(-0.005)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONFields(t, out, map[string]interface{}{"liquidity": maxUint128.String(), "impact": "-0.005"})
+}
+
+func TestPositionAmountsJSON(t *testing.T) {
+	out, err := json.Marshal(PositionAmounts{Block: big.NewInt(100), SqrtPriceX96: q96, Tick: 0, Liquidity: maxUint128, Amount0: big.NewInt(1), Amount1: big.NewInt(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONFields(t, out, map[string]interface{}{
+		"block": "100", "sqrtPriceX96": q96.String(), "tick": 0.0, "liquidity": maxUint128.String(), "amount0": "1", "amount1": "2",
+	})
+}
+
+func TestCollectEventJSON(t *testing.T) {
+	out, err := json.Marshal(CollectEvent{Owner: testOwner, Recipient: testOwner, TickLower: -60, TickUpper: 60, Amount0: maxUint128, Amount1: big.NewInt(2), BlockNumber: 80, LogIndex: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONFields(t, out, map[string]interface{}{
+		"owner": testOwner.Hex(), "recipient": testOwner.Hex(), "tickLower": -60.0, "tickUpper": 60.0, "amount0": maxUint128.String(), "amount1": "2",
+		"blockNumber": 80.0, "txHash": common.Hash{}.Hex(), "logIndex": 3.0,
+	})
+}
+
+func TestActivityEventJSON(t *testing.T) {
+	out, err := json.Marshal(ActivityEvent{Kind: "Burn", LiquidityDelta: new(big.Int).Neg(maxUint128), Amount0: big.NewInt(1), Amount1: big.NewInt(2), BlockNumber: 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONFields(t, out, map[string]interface{}{
+		"kind": "Burn", "liquidityDelta": "-" + maxUint128.String(), "amount0": "1", "amount1": "2", "blockNumber": 80.0, "txHash": common.Hash{}.Hex(), "logIndex": 0.0,
+	})
+
+	out, err = json.Marshal(ActivityEvent{Kind: "Swap", Amount0: big.NewInt(-1), Amount1: big.NewInt(2), SqrtPriceX96: q96, Tick: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONFields(t, out, map[string]interface{}{
+		"kind": "Swap", "amount0": "-1", "amount1": "2", "sqrtPriceX96": q96.String(), "tick": 1.0, "blockNumber": 0.0, "txHash": common.Hash{}.Hex(), "logIndex": 0.0,
+	})
+}
+
+func TestLiquidityStepJSON(t *testing.T) {
+	out, err := json.Marshal(LiquidityStep{Tick: -60, Liquidity: maxUint128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONFields(t, out, map[string]interface{}{"tick": -60.0, "liquidity": maxUint128.String()})
+}
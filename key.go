@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// KeyInput is what a pool position key is derived from.
+type KeyInput struct {
+	Owner     common.Address
+	TickLower Tick
+	TickUpper Tick
+}
+
+const packedKeyLength = common.AddressLength + 3 + 3
+
+var keccakPool = sync.Pool{
+	New: func() interface{} { return crypto.NewKeccakState() },
+}
+
+// calcPositionKeys computes the same keys as calcPositionKey, but reuses one
+// hasher and one packed buffer for the whole batch.
+func calcPositionKeys(inputs []KeyInput) []common.Hash {
+	hasher := keccakPool.Get().(crypto.KeccakState)
+	defer keccakPool.Put(hasher)
+
+	var buf [packedKeyLength]byte
+	keys := make([]common.Hash, len(inputs))
+
+	for i, in := range inputs {
+		copy(buf[:common.AddressLength], in.Owner[:])
+		in.TickLower.putInt24(buf[common.AddressLength:])
+		in.TickUpper.putInt24(buf[common.AddressLength+3:])
+
+		hasher.Reset()
+		hasher.Write(buf[:])
+		hasher.Read(keys[i][:])
+	}
+
+	return keys
+}
+
+// decodePositionKey is the inverse of positionCalldata: it returns the key
+// argument of a positions(bytes32) call.
+func decodePositionKey(calldata []byte) (common.Hash, error) {
+	method := poolABI.Methods[positionsMethod]
+	if len(calldata) < 4 || !bytes.Equal(calldata[:4], method.ID) {
+		return common.Hash{}, fmt.Errorf("calldata is not a %s call", method.Sig)
+	}
+
+	args, err := method.Inputs.Unpack(calldata[4:])
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("unpack %s arguments: %w", method.Sig, err)
+	}
+	if len(args) != 1 {
+		return common.Hash{}, errors.New("unexpected number of arguments")
+	}
+
+	return common.Hash(args[0].([32]byte)), nil
+}
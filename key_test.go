@@ -0,0 +1,180 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// keyInputs are n inputs spread over owners and ranges.
+func keyInputs(n int) []KeyInput {
+	inputs := make([]KeyInput, n)
+	for i := range inputs {
+		var owner common.Address
+		owner[0], owner[19] = byte(i>>8), byte(i)
+		lower := Tick(i%1000*60) - 30_000
+		inputs[i] = KeyInput{Owner: owner, TickLower: lower, TickUpper: lower + Tick(i%7+1)*60}
+	}
+
+	return inputs
+}
+
+func TestCalcPositionKeysMatchesSingle(t *testing.T) {
+	inputs := append(keyInputs(1000),
+		KeyInput{Owner: testOwner, TickLower: MinTick, TickUpper: MaxTick},
+		KeyInput{Owner: testOwner, TickLower: -1, TickUpper: 0},
+		KeyInput{Owner: common.Address{}, TickLower: minInt24, TickUpper: maxInt24},
+	)
+
+	keys := calcPositionKeys(inputs)
+	if len(keys) != len(inputs) {
+		t.Fatalf("got %d keys for %d inputs", len(keys), len(inputs))
+	}
+	for i, in := range inputs {
+		want, err := calcPositionKey(in.Owner, in.TickLower, in.TickUpper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys[i] != want {
+			t.Errorf("key of %+v = %s, calcPositionKey gives %s", in, keys[i], want)
+		}
+	}
+}
+
+// The keys are keccak256 of the packed bytes, hashed directly rather than
+// through encodePacked, as PositionKey.compute does on chain:
+// keccak256(abi.encodePacked(owner, tickLower, tickUpper)).
+func TestPositionKeyExtremeTicks(t *testing.T) {
+	npm := common.HexToAddress("0xC36442b4a4522E871399CD717aBDD847Ab11FE88")
+
+	for _, tc := range []struct {
+		name   string
+		owner  common.Address
+		lower  Tick
+		upper  Tick
+		packed string
+		key    string
+	}{
+		{
+			name:   "MIN_TICK to MAX_TICK",
+			owner:  npm,
+			lower:  MinTick,
+			upper:  MaxTick,
+			packed: "c36442b4a4522e871399cd717abdd847ab11fe88f276180d89e8",
+			key:    "0xb5342070c6429c3d7e8fea8fb0b62ca4eea5905f734d065c722e31227ccfb8fb",
+		},
+		{
+			name:   "full range at spacing 60",
+			owner:  npm,
+			lower:  -887220,
+			upper:  887220,
+			packed: "c36442b4a4522e871399cd717abdd847ab11fe88f2764c0d89b4",
+			key:    "0x381aa6c2062f30ec4294109119a35ae6c664cb629c3c1fad6610316d27488a48",
+		},
+		{
+			name:   "zero owner",
+			lower:  MinTick,
+			upper:  MaxTick,
+			packed: "0000000000000000000000000000000000000000f276180d89e8",
+			key:    "0x56b107aebac8272b9d358b98bd792ef6236525c6fa4054cb3f501bb220475726",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			packed, err := encodePacked(tc.owner, tc.lower, tc.upper)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := common.Bytes2Hex(packed); got != tc.packed {
+				t.Errorf("encodePacked = %s, want %s", got, tc.packed)
+			}
+
+			key, err := calcPositionKey(tc.owner, tc.lower, tc.upper)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.Hex() != tc.key {
+				t.Errorf("calcPositionKey = %s, want %s", key.Hex(), tc.key)
+			}
+
+			batched := calcPositionKeys([]KeyInput{{Owner: tc.owner, TickLower: tc.lower, TickUpper: tc.upper}})
+			if batched[0].Hex() != tc.key {
+				t.Errorf("calcPositionKeys = %s, want %s", batched[0].Hex(), tc.key)
+			}
+		})
+	}
+}
+
+const benchmarkKeys = 100_000
+
+func TestDecodePositionKey(t *testing.T) {
+	// positions(bytes32) of the full range key of the NPM
+	const key = "0xb5342070c6429c3d7e8fea8fb0b62ca4eea5905f734d065c722e31227ccfb8fb"
+	calldata := "0x514ea4bf" + key[2:]
+
+	got, err := decodePositionKey(hexutil.MustDecode(calldata))
+	if err != nil || got.Hex() != key {
+		t.Errorf("decodePositionKey(%s) = %s, %v, want %s", calldata, got.Hex(), err, key)
+	}
+
+	code, stdout, stderr := runOutput(t, "-decode-calldata", calldata)
+	if code != exitOK || strings.TrimSpace(stdout) != key {
+		t.Errorf("-decode-calldata printed %q, exit code %d, want %s; stderr:\n%s", stdout, code, key, stderr)
+	}
+
+	for _, bad := range []string{
+		"0x",
+		// slot0()
+		"0x3850c7bd",
+		// the key cut short
+		calldata[:len(calldata)-2],
+		"514ea4bf" + key[2:],
+	} {
+		if code, _, _ := runOutput(t, "-decode-calldata", bad); code != exitBadInput {
+			t.Errorf("-decode-calldata %s: exit code %d, want %d", bad, code, exitBadInput)
+		}
+	}
+}
+
+func TestDecodePositionKeyRoundTrip(t *testing.T) {
+	client := newFakeClient(t, &fakeNode{handle: positionsHandler(t)})
+
+	for _, in := range keyInputs(50) {
+		want, err := calcPositionKey(in.Owner, in.TickLower, in.TickUpper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		calldata, err := client.positionCalldata(PositionQuery{Pool: testPool, Owner: in.Owner, TickLower: in.TickLower, TickUpper: in.TickUpper})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := decodePositionKey(calldata); err != nil || got != want {
+			t.Errorf("%+v: decoded %s, %v, want %s", in, got, err, want)
+		}
+	}
+}
+
+func BenchmarkCalcPositionKey(b *testing.B) {
+	inputs := keyInputs(benchmarkKeys)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		for _, in := range inputs {
+			if _, err := calcPositionKey(in.Owner, in.TickLower, in.TickUpper); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCalcPositionKeys(b *testing.B) {
+	inputs := keyInputs(benchmarkKeys)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		calcPositionKeys(inputs)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// KeyDerivation is one way a fork might derive a position key.
+type KeyDerivation struct {
+	Name string
+	Key  common.Hash
+}
+
+// KeyMatch is a derivation under which the pool has a nonempty position.
+type KeyMatch struct {
+	KeyDerivation
+	Position Position
+}
+
+// keyDerivations lists the standard key of q first, then variants seen in
+// forks or easy to get wrong: other argument orders, abi.encode instead of
+// abi.encodePacked, a trailing bytes32 salt as in Uniswap V4, and the NPM as
+// owner for positions minted through the NonfungiblePositionManager.
+func keyDerivations(q PositionQuery, npm *common.Address, salts []common.Hash) []KeyDerivation {
+	owner := q.Owner.Bytes()
+	lower, upper := packedInt24(q.TickLower), packedInt24(q.TickUpper)
+	wordLower, wordUpper := int24Topic(q.TickLower), int24Topic(q.TickUpper)
+	wordOwner := common.BytesToHash(owner)
+
+	derivations := []KeyDerivation{
+		{"encodePacked(owner, tickLower, tickUpper)", crypto.Keccak256Hash(owner, lower, upper)},
+		{"encodePacked(owner, tickUpper, tickLower)", crypto.Keccak256Hash(owner, upper, lower)},
+		{"encodePacked(tickLower, tickUpper, owner)", crypto.Keccak256Hash(lower, upper, owner)},
+		{"encode(owner, tickLower, tickUpper)", crypto.Keccak256Hash(wordOwner[:], wordLower[:], wordUpper[:])},
+		{"encodePacked(owner, tickLower, tickUpper, bytes32(0))", crypto.Keccak256Hash(owner, lower, upper, common.Hash{}.Bytes())},
+	}
+	for _, salt := range salts {
+		derivations = append(derivations, KeyDerivation{
+			Name: fmt.Sprintf("encodePacked(owner, tickLower, tickUpper, %s)", salt),
+			Key:  crypto.Keccak256Hash(owner, lower, upper, salt.Bytes()),
+		})
+	}
+	if npm != nil && *npm != q.Owner {
+		derivations = append(derivations, KeyDerivation{
+			Name: fmt.Sprintf("encodePacked(npm %s, tickLower, tickUpper)", npm),
+			Key:  crypto.Keccak256Hash(npm.Bytes(), lower, upper),
+		})
+	}
+
+	return derivations
+}
+
+func packedInt24(v Tick) []byte {
+	b := make([]byte, 3)
+	v.putInt24(b)
+	return b
+}
+
+// DiagnoseKey reads q's pool under every derivation of keyDerivations in one
+// batch and returns those with a nonempty position. For debugging forks where
+// the standard key reads an empty position that should exist.
+func (c *Client) DiagnoseKey(ctx context.Context, q PositionQuery, block *big.Int, salts []common.Hash) ([]KeyMatch, error) {
+	var npm *common.Address
+	if ch, err := c.chain(ctx); err == nil {
+		npm = &ch.NPM
+	}
+
+	derivations := keyDerivations(q, npm, salts)
+	calls := make([]call, len(derivations))
+	for i, d := range derivations {
+		calldata, err := c.abis.PoolABI().Pack(positionsMethod, d.Key)
+		if err != nil {
+			return nil, fmt.Errorf("pack positions: %w", err)
+		}
+		calls[i] = call{Target: q.Pool, CallData: calldata}
+	}
+
+	responses, err := c.aggregate(ctx, calls, block)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []KeyMatch
+	for i, response := range responses {
+		position, err := c.unpackPosition(response)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", derivations[i].Name, err)
+		}
+		if !position.empty() {
+			matches = append(matches, KeyMatch{derivations[i], position})
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// saltedFork is a pool of a fork that keys positions by
+// keccak256(abi.encodePacked(owner, tickLower, tickUpper, salt)).
+func saltedFork(t *testing.T, q PositionQuery, salt common.Hash) *fakeNode {
+	pool := newFakePool(0, 1000)
+	key := crypto.Keccak256Hash(q.Owner.Bytes(), packedInt24(q.TickLower), packedInt24(q.TickUpper), salt.Bytes())
+	pool.positions[key] = Position{Liquidity: big.NewInt(1000)}
+
+	return &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)}
+}
+
+func TestDiagnoseKeySalted(t *testing.T) {
+	q := testQueries[0]
+	salt := common.HexToHash("0x2a")
+	client := newFakeClient(t, saltedFork(t, q, salt))
+
+	// the standard key reads an empty position
+	positions, err := client.Positions(context.Background(), []PositionQuery{q}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !positions[0].empty() {
+		t.Fatalf("standard key read %+v", positions[0])
+	}
+
+	matches, err := client.DiagnoseKey(context.Background(), q, nil, []common.Hash{salt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("matches = %+v, want the salted key", matches)
+	}
+	if want := "encodePacked(owner, tickLower, tickUpper, " + salt.Hex() + ")"; matches[0].Name != want || matches[0].Position.Liquidity.Int64() != 1000 {
+		t.Errorf("match = %s with %+v, want %s", matches[0].Name, matches[0].Position, want)
+	}
+
+	// without the salt no derivation matches
+	matches, err = client.DiagnoseKey(context.Background(), q, nil, nil)
+	if err != nil || len(matches) != 0 {
+		t.Errorf("without the salt: matches = %+v, %v", matches, err)
+	}
+}
+
+func TestRunDiagnoseKey(t *testing.T) {
+	q := testQueries[0]
+	node := saltedFork(t, q, common.HexToHash("0x2a"))
+	args := []string{"-diagnose-key", "-node", node.serve(t), "-max-lag", "0",
+		"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60"}
+
+	code, stdout, stderr := runOutput(t, append(args, "-key-salt", "0x2a")...)
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	if !strings.Contains(stdout, common.HexToHash("0x2a").Hex()+") key=") || !strings.Contains(stdout, "liquidity=1000") {
+		t.Errorf("stdout = %q, want the salted derivation", stdout)
+	}
+
+	if code, _, stderr := runOutput(t, args...); code != exitNotFound {
+		t.Errorf("without -key-salt: exit code %d, want %d; stderr:\n%s", code, exitNotFound, stderr)
+	}
+	if code, _, _ := runOutput(t, append(args, "-key-salt", "0xnothex")...); code != exitBadInput {
+		t.Errorf("invalid -key-salt: exit code %d, want %d", code, exitBadInput)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// KeyRow is a row of a -keys file with the position key computed for it, or
+// the reason the row is invalid.
+type KeyRow struct {
+	Line      int            `json:"line"`
+	Owner     common.Address `json:"owner"`
+	TickLower Tick           `json:"tickLower"`
+	TickUpper Tick           `json:"tickUpper"`
+	Key       *common.Hash   `json:"key,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// readKeyRows reads a CSV of owner,tickLower,tickUpper rows, with an optional
+// header row starting with owner, and computes the key of every valid row in
+// one batch. An invalid row is returned with its Error set; only an
+// unreadable file fails.
+func readKeyRows(path string) ([]KeyRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	var (
+		rows   []KeyRow
+		inputs []KeyInput
+		valid  []int
+	)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, KeyRow{Line: parseErr.Line, Error: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		if len(rows) == 0 && strings.EqualFold(record[0], "owner") {
+			continue
+		}
+
+		row, err := parseKeyRow(record)
+		row.Line, _ = reader.FieldPos(0)
+		if err != nil {
+			row.Error = err.Error()
+		} else {
+			inputs = append(inputs, KeyInput{Owner: row.Owner, TickLower: row.TickLower, TickUpper: row.TickUpper})
+			valid = append(valid, len(rows))
+		}
+		rows = append(rows, row)
+	}
+
+	for i, key := range calcPositionKeys(inputs) {
+		key := key
+		rows[valid[i]].Key = &key
+	}
+
+	return rows, nil
+}
+
+func parseKeyRow(record []string) (KeyRow, error) {
+	if len(record) != 3 {
+		return KeyRow{}, fmt.Errorf("want 3 fields owner,tickLower,tickUpper, got %d", len(record))
+	}
+
+	owner, err := parseAddress(strings.TrimSpace(record[0]))
+	if err != nil {
+		return KeyRow{}, err
+	}
+	row := KeyRow{Owner: owner}
+
+	ticks := [2]*Tick{&row.TickLower, &row.TickUpper}
+	for i, name := range []string{"tickLower", "tickUpper"} {
+		tick, err := strconv.ParseInt(strings.TrimSpace(record[i+1]), 10, 32)
+		if err != nil {
+			return row, fmt.Errorf("invalid %s %q", name, record[i+1])
+		}
+		*ticks[i] = Tick(tick)
+	}
+
+	return row, validateTicks(row.TickLower, row.TickUpper)
+}
+
+// writeKeyRows prints rows as JSON or as a CSV of the input columns followed
+// by key and error.
+func writeKeyRows(w io.Writer, format string, rows []KeyRow) error {
+	switch format {
+	case formatJSON:
+		return json.NewEncoder(w).Encode(rows)
+	case formatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, row := range rows {
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"line", "owner", "tickLower", "tickUpper", "key", "error"})
+	for _, row := range rows {
+		if row.Key == nil {
+			out.Write([]string{strconv.Itoa(row.Line), "", "", "", "", row.Error})
+			continue
+		}
+		out.Write([]string{
+			strconv.Itoa(row.Line),
+			row.Owner.Hex(),
+			strconv.Itoa(int(row.TickLower)),
+			strconv.Itoa(int(row.TickUpper)),
+			row.Key.Hex(),
+			"",
+		})
+	}
+	out.Flush()
+
+	return out.Error()
+}
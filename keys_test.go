@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const keysCSV = `owner,tickLower,tickUpper
+0x3333333333333333333333333333333333333333,-60,60
+# a comment
+0x3333333333333333333333333333333333333333, -887272, 887272
+0x33333333333333333333333333333333333333,-60,60
+0x3333333333333333333333333333333333333333,60,-60
+0x3333333333333333333333333333333333333333,-60
+`
+
+func TestReadKeyRows(t *testing.T) {
+	rows, err := readKeyRows(writeInput(t, keysCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		line         int
+		lower, upper Tick
+		err          string
+	}{
+		{2, -60, 60, ""},
+		{4, MinTick, MaxTick, ""},
+		{5, 0, 0, "has 38 hex digits"},
+		{6, 60, -60, "must be below"},
+		{7, 0, 0, "want 3 fields"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i, w := range want {
+		row := rows[i]
+		if row.Line != w.line {
+			t.Errorf("row %d is from line %d, want %d", i, row.Line, w.line)
+		}
+		if w.err != "" {
+			if row.Key != nil || !strings.Contains(row.Error, w.err) {
+				t.Errorf("line %d: key %v, error %q, want one containing %q", w.line, row.Key, row.Error, w.err)
+			}
+			continue
+		}
+
+		key, err := calcPositionKey(testOwner, w.lower, w.upper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row.Error != "" || row.Key == nil || *row.Key != key || row.TickLower != w.lower || row.TickUpper != w.upper {
+			t.Errorf("line %d = %+v, want key %s", w.line, row, key)
+		}
+	}
+}
+
+func TestWriteKeyRows(t *testing.T) {
+	rows, err := readKeyRows(writeInput(t, keysCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeKeyRows(&buf, formatTable, rows); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1+len(rows) || strings.Join(records[0], ",") != "line,owner,tickLower,tickUpper,key,error" {
+		t.Fatalf("CSV = %q", records)
+	}
+	if got := records[1]; got[1] != testOwner.Hex() || got[4] != rows[0].Key.Hex() || got[5] != "" {
+		t.Errorf("valid row = %q", got)
+	}
+	if got := records[3]; got[4] != "" || got[5] != rows[2].Error {
+		t.Errorf("invalid row = %q", got)
+	}
+
+	buf.Reset()
+	if err := writeKeyRows(&buf, formatJSON, rows); err != nil {
+		t.Fatal(err)
+	}
+	var back []KeyRow
+	if err := json.Unmarshal(buf.Bytes(), &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back) != len(rows) || *back[1].Key != *rows[1].Key || back[4].Error != rows[4].Error {
+		t.Errorf("JSON rows = %+v", back)
+	}
+}
+
+func TestRunKeys(t *testing.T) {
+	code, stdout, stderr := runOutput(t, "-keys", writeInput(t, keysCSV))
+	if code != exitBadInput || !strings.Contains(stderr, "3 of 5 rows") {
+		t.Errorf("exit code %d, want %d for invalid rows; stderr:\n%s", code, exitBadInput, stderr)
+	}
+	if n := strings.Count(stdout, "\n"); n != 6 {
+		t.Errorf("printed %d lines, want a header and every row:\n%s", n, stdout)
+	}
+
+	// no node is needed
+	valid := "0x3333333333333333333333333333333333333333,-60,60\n"
+	if code, stdout, stderr := runOutput(t, "-keys", writeInput(t, valid), "-node", "http://127.0.0.1:1"); code != exitOK || !strings.Contains(stdout, testOwner.Hex()) {
+		t.Errorf("exit code %d, want %d; stdout:\n%s\nstderr:\n%s", code, exitOK, stdout, stderr)
+	}
+}
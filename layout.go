@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// PositionLayout is an alternate return layout of a pool's positions(bytes32)
+// for forks that add or drop fields. Outputs named like a Position field
+// (liquidity, feeGrowthInside0LastX128, feeGrowthInside1LastX128, tokensOwed0,
+// tokensOwed1) fill it; other outputs are decoded and ignored, and Position
+// fields without an output are left zero.
+type PositionLayout struct {
+	Name    string
+	Outputs abi.Arguments
+}
+
+// algebraLayout is Algebra's pool positions(bytes32), used by QuickSwap V3
+// and other Algebra deployments: a timestamp after liquidity.
+// https://github.com/cryptoalgebra/AlgebraV1/blob/main/src/core/contracts/interfaces/pool/IAlgebraPoolState.sol
+var algebraLayout = mustPositionLayout("algebra", `[{"internalType":"uint128","name":"liquidity","type":"uint128"},{"internalType":"uint32","name":"lastLiquidityAddTimestamp","type":"uint32"},{"internalType":"uint256","name":"feeGrowthInside0LastX128","type":"uint256"},{"internalType":"uint256","name":"feeGrowthInside1LastX128","type":"uint256"},{"internalType":"uint128","name":"tokensOwed0","type":"uint128"},{"internalType":"uint128","name":"tokensOwed1","type":"uint128"}]`)
+
+// defaultPositionLayouts are tried when positions() doesn't return the
+// canonical five words.
+var defaultPositionLayouts = []PositionLayout{algebraLayout}
+
+// mustPositionLayout builds a layout from the JSON outputs array of a
+// positions(bytes32) ABI entry.
+func mustPositionLayout(name, outputs string) PositionLayout {
+	layout, err := parsePositionLayout(name, outputs)
+	if err != nil {
+		panic(err)
+	}
+
+	return layout
+}
+
+// parsePositionLayout is mustPositionLayout returning its error.
+func parsePositionLayout(name, outputs string) (PositionLayout, error) {
+	parsed, err := abi.JSON(strings.NewReader(`[{"inputs":[],"name":"positions","outputs":` + outputs + `,"stateMutability":"view","type":"function"}]`))
+	if err != nil {
+		return PositionLayout{}, err
+	}
+	if len(parsed.Methods[positionsMethod].Outputs) == 0 {
+		return PositionLayout{}, fmt.Errorf("layout %s has no outputs", name)
+	}
+
+	return PositionLayout{Name: name, Outputs: parsed.Methods[positionsMethod].Outputs}, nil
+}
+
+// readPositionLayout reads a -position-layout file, the JSON outputs array
+// of a fork's positions(bytes32), as a layout named after the file.
+func readPositionLayout(path string) (PositionLayout, error) {
+	outputs, err := os.ReadFile(path)
+	if err != nil {
+		return PositionLayout{}, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	layout, err := parsePositionLayout(name, string(outputs))
+	if err != nil {
+		return PositionLayout{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return layout, nil
+}
+
+// WithPositionLayouts replaces the alternate positions() layouts tried when
+// a pool's response isn't the canonical one, e.g. to add a fork's layout.
+func WithPositionLayouts(layouts ...PositionLayout) Option {
+	return func(o *clientOptions) {
+		o.layouts = layouts
+	}
+}
+
+// decode fills a Position from a response of this layout.
+func (l PositionLayout) decode(response []byte) (Position, error) {
+	out, err := l.Outputs.Unpack(response)
+	if err != nil {
+		return Position{}, err
+	}
+
+	var position Position
+	fields := map[string]**big.Int{
+		"liquidity":                &position.Liquidity,
+		"feeGrowthInside0LastX128": &position.FeeGrowthInside0LastX128,
+		"feeGrowthInside1LastX128": &position.FeeGrowthInside1LastX128,
+		"tokensOwed0":              &position.TokensOwed0,
+		"tokensOwed1":              &position.TokensOwed1,
+	}
+	for i, arg := range l.Outputs {
+		field, ok := fields[arg.Name]
+		if !ok {
+			continue
+		}
+		v, ok := out[i].(*big.Int)
+		if !ok {
+			return Position{}, fmt.Errorf("layout %s: output %s is %s, want an integer wider than 64 bits", l.Name, arg.Name, arg.Type)
+		}
+		*field = v
+	}
+
+	return position, nil
+}
+
+// layoutLog reports each alternate layout once per client.
+type layoutLog struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (l *layoutLog) used(layout PositionLayout, words int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.seen[layout.Name] {
+		return
+	}
+	if l.seen == nil {
+		l.seen = make(map[string]bool)
+	}
+	l.seen[layout.Name] = true
+
+	log.Printf("warning: positions() returned %d words instead of 5, decoded with the %s layout", words, layout.Name)
+}
+
+// unpackLayouts decodes response with the first alternate layout of the
+// same size.
+func (c *Client) unpackLayouts(response []byte) (Position, error) {
+	names := []string{"canonical"}
+
+	for _, layout := range c.layouts {
+		names = append(names, layout.Name)
+		if len(layout.Outputs)*32 != len(response) {
+			continue
+		}
+
+		position, err := layout.decode(response)
+		if err != nil {
+			return Position{}, err
+		}
+		c.layoutLog.used(layout, len(response)/32)
+
+		return position, nil
+	}
+
+	return Position{}, fmt.Errorf("positions() returned %d bytes, which matches none of the layouts %s", len(response), strings.Join(names, ", "))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// wordsNode answers positions with words, one 32-byte word each, and every
+// other pool call with zeros.
+func wordsNode(t *testing.T, words ...int64) *fakeNode {
+	var response []byte
+	for _, w := range words {
+		response = append(response, common.BigToHash(big.NewInt(w)).Bytes()...)
+	}
+
+	return &fakeNode{head: 100, handle: func(to common.Address, data []byte) ([]byte, error) {
+		if methodOf(t, poolABI, data).Name == positionsMethod {
+			return response, nil
+		}
+		return make([]byte, 32), nil
+	}}
+}
+
+// rewardLayout is a fork's positions(bytes32) with a reward debt after the
+// owed tokens.
+const rewardLayout = `[{"name":"liquidity","type":"uint128"},{"name":"feeGrowthInside0LastX128","type":"uint256"},{"name":"feeGrowthInside1LastX128","type":"uint256"},{"name":"tokensOwed0","type":"uint128"},{"name":"tokensOwed1","type":"uint128"},{"name":"rewardDebt","type":"uint256"},{"name":"rewardGrowthLast","type":"uint256"}]`
+
+func TestAlgebraLayout(t *testing.T) {
+	logs := captureLog(t)
+	// liquidity, lastLiquidityAddTimestamp, fee growths and owed tokens
+	client := newFakeClient(t, wordsNode(t, 1000, 1_700_000_000, 11, 12, 7, 8))
+
+	position, err := client.Position(context.Background(), testQueries[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position.Liquidity.Int64() != 1000 || position.FeeGrowthInside0LastX128.Int64() != 11 || position.FeeGrowthInside1LastX128.Int64() != 12 ||
+		position.TokensOwed0.Int64() != 7 || position.TokensOwed1.Int64() != 8 {
+		t.Errorf("position = %+v", position)
+	}
+	if !strings.Contains(logs.String(), "6 words instead of 5, decoded with the algebra layout") {
+		t.Errorf("log = %q, want the layout warning", logs.String())
+	}
+}
+
+func TestWithPositionLayouts(t *testing.T) {
+	layout, err := parsePositionLayout("reward", rewardLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	captureLog(t)
+	node := wordsNode(t, 1000, 11, 12, 7, 8, 99, 98)
+
+	if _, err := newFakeClient(t, node).Position(context.Background(), testQueries[0], nil); err == nil || !strings.Contains(err.Error(), "canonical, algebra") {
+		t.Errorf("err = %v, want no layout of 7 words", err)
+	}
+
+	client := newFakeClient(t, node, WithPositionLayouts(layout))
+	position, err := client.Position(context.Background(), testQueries[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position.Liquidity.Int64() != 1000 || position.TokensOwed1.Int64() != 8 {
+		t.Errorf("position = %+v", position)
+	}
+}
+
+func TestParsePositionLayout(t *testing.T) {
+	for _, outputs := range []string{"", "[]", `[{"name":"liquidity","type":"decimal"}]`} {
+		if _, err := parsePositionLayout("bad", outputs); err == nil {
+			t.Errorf("parsePositionLayout(%q) succeeded", outputs)
+		}
+	}
+
+	// a field named like a Position field must decode to a big integer
+	layout, err := parsePositionLayout("narrow", `[{"name":"liquidity","type":"uint32"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := layout.decode(common.BigToHash(big.NewInt(1)).Bytes()); err == nil {
+		t.Error("want an error decoding liquidity as uint32")
+	}
+}
+
+func TestRunPositionLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reward.json")
+	if err := os.WriteFile(path, []byte(rewardLayout), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runOutput(t, "-node", wordsNode(t, 1000, 11, 12, 7, 8, 99, 98).serve(t), "-max-lag", "0", "-position-layout", path, "-format", "json")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"tokensOwed1": "8"`) {
+		t.Errorf("output:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "decoded with the reward layout") {
+		t.Errorf("stderr doesn't name the layout:\n%s", stderr)
+	}
+
+	if code, _, _ := runOutput(t, "-position-layout", filepath.Join(t.TempDir(), "missing.json")); code != exitBadInput {
+		t.Errorf("missing layout file: exit code = %d, want %d", code, exitBadInput)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PositionLink is what a Uniswap app or block explorer URL identifies.
+type PositionLink struct {
+	ChainID uint64
+	// TokenID is set for a position NFT, Pool for a pool contract.
+	TokenID *big.Int
+	Pool    *common.Address
+	// NPM is the NFT contract of an explorer NFT page, checked against the
+	// registry once the chain is known.
+	NPM *common.Address
+}
+
+// linkChains maps the chain names of app.uniswap.org URLs to chain ids.
+var linkChains = map[string]uint64{
+	"ethereum": 1,
+	"mainnet":  1,
+	"arbitrum": 42161,
+	"sepolia":  11155111,
+}
+
+// explorerChains maps block explorer hosts to chain ids.
+var explorerChains = map[string]uint64{
+	"etherscan.io":         1,
+	"arbiscan.io":          42161,
+	"sepolia.etherscan.io": 11155111,
+}
+
+// parsePositionLink accepts these URL shapes:
+//
+//	https://app.uniswap.org/positions/v3/arbitrum/123456
+//	https://app.uniswap.org/pools/123456?chain=arbitrum
+//	https://app.uniswap.org/#/pool/123456?chain=arbitrum
+//	https://arbiscan.io/nft/0xC36442b4a4522E871399CD717aBDD847Ab11FE88/123456
+//	https://arbiscan.io/token/0xC36442b4a4522E871399CD717aBDD847Ab11FE88?a=123456
+//	https://arbiscan.io/address/0xC6962004f452bE9203591991D15f6b388e09E8D0
+func parsePositionLink(raw string) (PositionLink, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return PositionLink{}, fmt.Errorf("invalid URL %q: %w", raw, err)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+
+	if host == "app.uniswap.org" {
+		return parseAppLink(u)
+	}
+	if chainID, ok := explorerChains[host]; ok {
+		return parseExplorerLink(u, chainID)
+	}
+
+	return PositionLink{}, fmt.Errorf("unrecognized URL %q: expected an app.uniswap.org position or a block explorer page", raw)
+}
+
+func parseAppLink(u *url.URL) (PositionLink, error) {
+	// hash routed URLs of the old app keep the route in the fragment
+	path, query := u.Path, u.Query()
+	if strings.HasPrefix(u.Fragment, "/") {
+		route, err := url.Parse(u.Fragment)
+		if err != nil {
+			return PositionLink{}, fmt.Errorf("invalid app route %q: %w", u.Fragment, err)
+		}
+		path, query = route.Path, route.Query()
+	}
+
+	var chainName, tokenID string
+	switch parts := strings.Split(strings.Trim(path, "/"), "/"); {
+	case len(parts) == 4 && parts[0] == "positions" && parts[1] == "v3":
+		chainName, tokenID = parts[2], parts[3]
+	case len(parts) == 2 && (parts[0] == "pools" || parts[0] == "pool"):
+		chainName, tokenID = query.Get("chain"), parts[1]
+		if chainName == "" {
+			chainName = "ethereum"
+		}
+	default:
+		return PositionLink{}, fmt.Errorf("unrecognized app URL path %q: expected a v3 position", path)
+	}
+
+	chainID, ok := linkChains[chainName]
+	if !ok {
+		return PositionLink{}, fmt.Errorf("unsupported chain %q in app URL, see -chains", chainName)
+	}
+	id, err := parseTokenID(tokenID)
+	if err != nil {
+		return PositionLink{}, err
+	}
+
+	return PositionLink{ChainID: chainID, TokenID: id}, nil
+}
+
+func parseExplorerLink(u *url.URL, chainID uint64) (PositionLink, error) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 {
+		return PositionLink{}, fmt.Errorf("unrecognized explorer URL path %q", u.Path)
+	}
+
+	contract, err := parseAddress(parts[1])
+	if err != nil {
+		return PositionLink{}, fmt.Errorf("explorer URL: %w", err)
+	}
+
+	var tokenID string
+	switch {
+	case parts[0] == "address" && len(parts) == 2:
+		return PositionLink{ChainID: chainID, Pool: &contract}, nil
+	case parts[0] == "nft" && len(parts) == 3:
+		tokenID = parts[2]
+	case parts[0] == "token" && len(parts) == 2 && u.Query().Get("a") != "":
+		tokenID = u.Query().Get("a")
+	default:
+		return PositionLink{}, fmt.Errorf("unrecognized explorer URL path %q: expected an NFT or a pool address page", u.Path)
+	}
+
+	id, err := parseTokenID(tokenID)
+	if err != nil {
+		return PositionLink{}, err
+	}
+
+	return PositionLink{ChainID: chainID, TokenID: id, NPM: &contract}, nil
+}
+
+func parseTokenID(s string) (*big.Int, error) {
+	id, ok := new(big.Int).SetString(s, 10)
+	if !ok || id.Sign() < 0 {
+		return nil, fmt.Errorf("invalid token id %q in URL", s)
+	}
+
+	return id, nil
+}
+
+// check verifies the link against the node's chain.
+func (l PositionLink) check(ch Chain) error {
+	switch {
+	case l.ChainID != ch.ID:
+		return fmt.Errorf("URL is for chain %d but the node is on %s (%d)", l.ChainID, ch.Name, ch.ID)
+	case l.NPM != nil && *l.NPM != ch.NPM:
+		return fmt.Errorf("URL is an NFT of %s, not of the Uniswap V3 position manager %s", l.NPM.Hex(), ch.NPM.Hex())
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParsePositionLink(t *testing.T) {
+	npm := common.HexToAddress("0xC36442b4a4522E871399CD717aBDD847Ab11FE88")
+	pool := common.HexToAddress("0xC6962004f452bE9203591991D15f6b388e09E8D0")
+
+	for _, tc := range []struct {
+		url     string
+		chainID uint64
+		tokenID int64
+		pool    *common.Address
+		npm     *common.Address
+	}{
+		{"https://app.uniswap.org/positions/v3/arbitrum/123456", 42161, 123456, nil, nil},
+		{"https://app.uniswap.org/pools/123456?chain=arbitrum", 42161, 123456, nil, nil},
+		{"https://app.uniswap.org/pools/7", 1, 7, nil, nil},
+		{"https://app.uniswap.org/#/pool/123456?chain=sepolia", 11155111, 123456, nil, nil},
+		{"https://arbiscan.io/nft/0xC36442b4a4522E871399CD717aBDD847Ab11FE88/123456", 42161, 123456, nil, &npm},
+		{"https://www.arbiscan.io/token/0xc36442b4a4522e871399cd717abdd847ab11fe88?a=42", 42161, 42, nil, &npm},
+		{"https://arbiscan.io/address/0xC6962004f452bE9203591991D15f6b388e09E8D0", 42161, -1, &pool, nil},
+	} {
+		link, err := parsePositionLink(tc.url)
+		if err != nil {
+			t.Errorf("%s: %v", tc.url, err)
+			continue
+		}
+		if link.ChainID != tc.chainID {
+			t.Errorf("%s: chain %d, want %d", tc.url, link.ChainID, tc.chainID)
+		}
+		if tc.tokenID < 0 && link.TokenID != nil || tc.tokenID >= 0 && (link.TokenID == nil || link.TokenID.Int64() != tc.tokenID) {
+			t.Errorf("%s: token id %v, want %d", tc.url, link.TokenID, tc.tokenID)
+		}
+		for _, pair := range [][2]*common.Address{{link.Pool, tc.pool}, {link.NPM, tc.npm}} {
+			if (pair[0] == nil) != (pair[1] == nil) || pair[0] != nil && *pair[0] != *pair[1] {
+				t.Errorf("%s: link %+v", tc.url, link)
+			}
+		}
+	}
+}
+
+func TestParsePositionLinkInvalid(t *testing.T) {
+	for url, want := range map[string]string{
+		"https://example.com/positions/v3/arbitrum/1":                          "unrecognized URL",
+		"https://app.uniswap.org/swap":                                         "unrecognized app URL path",
+		"https://app.uniswap.org/positions/v3/base/1":                          "unsupported chain",
+		"https://app.uniswap.org/positions/v3/arbitrum/0x1":                    "invalid token id",
+		"https://arbiscan.io/tx/0xabc":                                         "explorer URL",
+		"https://arbiscan.io/nft/0xC36442b4a4522E871399CD717aBDD847Ab11FE88":   "expected an NFT",
+		"https://arbiscan.io/token/0xC36442b4a4522E871399CD717aBDD847Ab11FE88": "expected an NFT",
+		"://": "invalid URL",
+	} {
+		if _, err := parsePositionLink(url); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", url, err, want)
+		}
+	}
+}
+
+func TestPositionLinkCheck(t *testing.T) {
+	arbitrum, _ := chainByID(42161)
+	other := common.HexToAddress("0x4444444444444444444444444444444444444444")
+
+	for _, tc := range []struct {
+		link PositionLink
+		ok   bool
+	}{
+		{PositionLink{ChainID: 42161, TokenID: big.NewInt(1)}, true},
+		{PositionLink{ChainID: 42161, TokenID: big.NewInt(1), NPM: &arbitrum.NPM}, true},
+		{PositionLink{ChainID: 1, TokenID: big.NewInt(1)}, false},
+		{PositionLink{ChainID: 42161, TokenID: big.NewInt(1), NPM: &other}, false},
+	} {
+		if err := tc.link.check(arbitrum); (err == nil) != tc.ok {
+			t.Errorf("check(%+v) = %v, want ok %t", tc.link, err, tc.ok)
+		}
+	}
+}
+
+func TestRunURL(t *testing.T) {
+	npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {5}}}
+	node := listNode(t, npm)
+
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-format", "json",
+		"-url", "https://app.uniswap.org/positions/v3/arbitrum/5")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	if ids := tokenIDs(t, stdout); len(ids) != 1 || ids[0] != 5 {
+		t.Errorf("read token ids %v, want [5]", ids)
+	}
+
+	if code, _, _ := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-url", "https://etherscan.io/nft/0xC36442b4a4522E871399CD717aBDD847Ab11FE88/5"); code != exitBadInput {
+		t.Errorf("URL of another chain: exit code %d, want %d", code, exitBadInput)
+	}
+}
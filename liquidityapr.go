@@ -0,0 +1,232 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// LiquiditySegment is a stretch of an APR window in which the position's
+// liquidity didn't change.
+type LiquiditySegment struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Elapsed   time.Duration
+	Liquidity *big.Int
+	Fees0     *big.Int
+	Fees1     *big.Int
+}
+
+// SegmentedAPR is a FeeAccrual summed over the window's liquidity segments.
+// Its APR weighs each segment's capital by the segment's duration.
+type SegmentedAPR struct {
+	FeeAccrual
+	Segments []LiquiditySegment
+}
+
+// liquidityChange is an IncreaseLiquidity (positive Delta) or
+// DecreaseLiquidity (negative Delta) of an NFT.
+type liquidityChange struct {
+	Block uint64
+	Delta *big.Int
+}
+
+// AccurateAPR returns the fees NFT tokenID earned in the window of length
+// since that ends at block, nil meaning latest, and their APR, allowing for
+// liquidity added or removed inside the window. Unlike FeesSince it doesn't
+// need the position untouched: the window is cut at every IncreaseLiquidity
+// or DecreaseLiquidity of the token and each segment earns its liquidity
+// times the growth of the range's feeGrowthInside over it. Liquidity at each
+// segment is replayed backwards from the end of the window through the
+// events, so a position minted inside the window starts at zero.
+//
+// Fees and capital are valued in token1 at the price of the window's last
+// block, like FeesSince; each segment's capital counts for its share of the
+// window. Changes are resolved per block, so fees earned in the block of a
+// change are credited to the liquidity before it.
+func (c *Client) AccurateAPR(ctx context.Context, tokenID *big.Int, since time.Duration, block *big.Int) (SegmentedAPR, error) {
+	from, to, err := c.BlockSince(ctx, since, block)
+	if err != nil {
+		return SegmentedAPR{}, err
+	}
+
+	positions, err := c.TokenPositions(ctx, []*big.Int{tokenID}, to.Number)
+	if err != nil {
+		return SegmentedAPR{}, err
+	}
+	p := positions[0]
+
+	changes, err := c.liquidityChanges(ctx, tokenID, from.Number.Uint64()+1, to.Number.Uint64())
+	if err != nil {
+		return SegmentedAPR{}, err
+	}
+
+	// segment i runs from boundaries[i] to boundaries[i+1] with liquidity[i],
+	// what the position held at the end of boundaries[i]
+	boundaries := []uint64{from.Number.Uint64()}
+	for _, change := range changes {
+		if change.Block != boundaries[len(boundaries)-1] {
+			boundaries = append(boundaries, change.Block)
+		}
+	}
+	if boundaries[len(boundaries)-1] != to.Number.Uint64() {
+		boundaries = append(boundaries, to.Number.Uint64())
+	}
+
+	liquidity := make([]*big.Int, len(boundaries)-1)
+	current := new(big.Int).Set(p.Position.Liquidity)
+	next := len(changes) - 1
+	for i := len(liquidity) - 1; i >= 0; i-- {
+		for ; next >= 0 && changes[next].Block > boundaries[i]; next-- {
+			current.Sub(current, changes[next].Delta)
+		}
+		if current.Sign() < 0 {
+			return SegmentedAPR{}, errors.New("liquidity events don't add up to the position's liquidity")
+		}
+		liquidity[i] = new(big.Int).Set(current)
+	}
+
+	states := make([]rangeFees, len(boundaries))
+	var times []uint64
+	for i, number := range boundaries {
+		header := to
+		if i == 0 {
+			header = from
+		} else if number != to.Number.Uint64() {
+			if header, err = c.eth.HeaderByNumber(ctx, new(big.Int).SetUint64(number)); err != nil {
+				return SegmentedAPR{}, fmt.Errorf("get block %d: %w", number, err)
+			}
+		}
+		times = append(times, header.Time)
+
+		calls, err := c.rangeFeeCalls(p.Pool, p.TickLower, p.TickUpper)
+		if err != nil {
+			return SegmentedAPR{}, err
+		}
+		responses, err := c.aggregate(ctx, calls, header.Number)
+		if err != nil {
+			return SegmentedAPR{}, fmt.Errorf("read fee growth at block %d: %w", number, err)
+		}
+		if states[i], err = c.unpackRangeFees(responses, p.TickLower, p.TickUpper); err != nil {
+			return SegmentedAPR{}, err
+		}
+	}
+
+	last := states[len(states)-1]
+	result := SegmentedAPR{FeeAccrual: FeeAccrual{
+		FromBlock: from.Number.Uint64(),
+		ToBlock:   to.Number.Uint64(),
+		Elapsed:   time.Duration(to.Time-from.Time) * time.Second,
+		Fees0:     new(big.Int),
+		Fees1:     new(big.Int),
+	}}
+	capitalTime := new(big.Int) // sum of capital * seconds
+	for i, l := range liquidity {
+		segment := LiquiditySegment{
+			FromBlock: boundaries[i],
+			ToBlock:   boundaries[i+1],
+			Elapsed:   time.Duration(times[i+1]-times[i]) * time.Second,
+			Liquidity: l,
+			Fees0:     uncollected(l, new(big.Int), states[i+1].inside0, states[i].inside0),
+			Fees1:     uncollected(l, new(big.Int), states[i+1].inside1, states[i].inside1),
+		}
+		result.Segments = append(result.Segments, segment)
+		result.Fees0.Add(result.Fees0, segment.Fees0)
+		result.Fees1.Add(result.Fees1, segment.Fees1)
+
+		amount0, amount1 := AmountsForLiquidity(last.sqrtPriceX96, p.TickLower, p.TickUpper, l)
+		capital := valueInToken1(amount0, amount1, last.sqrtPriceX96)
+		capitalTime.Add(capitalTime, capital.Mul(capital, new(big.Int).SetUint64(times[i+1]-times[i])))
+	}
+
+	if seconds := int64(result.Elapsed / time.Second); seconds > 0 {
+		// the time-weighted capital over the whole window
+		principal := capitalTime.Quo(capitalTime, big.NewInt(seconds))
+		earned := valueInToken1(result.Fees0, result.Fees1, last.sqrtPriceX96)
+		if apr, err := EstimateAPR(earned, principal, result.Elapsed); err == nil {
+			result.APR = apr
+		}
+	}
+
+	return result, nil
+}
+
+// liquidityChanges returns the IncreaseLiquidity and DecreaseLiquidity events
+// of tokenID in [fromBlock, toBlock], in block order.
+func (c *Client) liquidityChanges(ctx context.Context, tokenID *big.Int, fromBlock, toBlock uint64) ([]liquidityChange, error) {
+	ch, err := c.chain(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	npmABI := c.abis.NPMABI()
+	increase, decrease := npmABI.Events[increaseLiquidityEvent], npmABI.Events[decreaseLiquidityEvent]
+
+	var changes []liquidityChange
+	for start := fromBlock; start <= toBlock; start += discoverChunk {
+		end := min(start+discoverChunk-1, toBlock)
+
+		logs, err := c.eth.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{ch.NPM},
+			Topics:    [][]common.Hash{{increase.ID, decrease.ID}, {common.BigToHash(tokenID)}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("filter liquidity logs %d-%d: %w", start, end, err)
+		}
+
+		for _, l := range logs {
+			if len(l.Topics) != 2 {
+				return nil, fmt.Errorf("liquidity log %s:%d has %d topics", l.TxHash, l.Index, len(l.Topics))
+			}
+			event := increase
+			if l.Topics[0] == decrease.ID {
+				event = decrease
+			}
+			out, err := event.Inputs.NonIndexed().Unpack(l.Data)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s log %s:%d: %w", event.Name, l.TxHash, l.Index, err)
+			}
+
+			delta := new(big.Int).Set(out[0].(*big.Int))
+			if event.ID == decrease.ID {
+				delta.Neg(delta)
+			}
+			changes = append(changes, liquidityChange{Block: l.BlockNumber, Delta: delta})
+		}
+	}
+
+	slices.SortStableFunc(changes, func(a, b liquidityChange) int { return cmp.Compare(a.Block, b.Block) })
+
+	return changes, nil
+}
+
+// writeSegmentedAPR prints a as writeAccrual does, followed by a table of its
+// segments.
+func writeSegmentedAPR(w io.Writer, format string, a SegmentedAPR) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(a)
+	}
+
+	if err := writeAccrual(w, format, a.FeeAccrual); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BLOCKS\tELAPSED\tLIQUIDITY\tFEES0\tFEES1")
+	for _, s := range a.Segments {
+		fmt.Fprintf(tw, "%d-%d\t%s\t%s\t%s\t%s\n", s.FromBlock, s.ToBlock, s.Elapsed, s.Liquidity, s.Fees0, s.Fees1)
+	}
+
+	return tw.Flush()
+}
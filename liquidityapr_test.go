@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// aprNode serves NFT 1 with 1e15 liquidity in [-60, 60) of a pool at tick 0
+// whose fee growth is the block number in token0 per unit of liquidity; 4e14
+// of the liquidity was added at block 80.
+func aprNode(t *testing.T) *fakeNode {
+	pool := newFakePool(0, 1e18)
+	npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {1}}, liquidity: map[int64]int64{1: 1e15}}
+	npm.next = poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)
+	positions := npm.handler(t)
+
+	event := npmABI.Events[increaseLiquidityEvent]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(4e14), new(big.Int), new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &fakeNode{head: 100, logs: []types.Log{{
+		Address:     arbitrumNPM,
+		Topics:      []common.Hash{event.ID, common.BigToHash(big.NewInt(1))},
+		Data:        data,
+		BlockNumber: 80,
+	}}}
+	node.handle = func(to common.Address, data []byte) ([]byte, error) {
+		node.mu.Lock()
+		block := node.blocks[len(node.blocks)-1]
+		node.mu.Unlock()
+
+		number := node.head
+		if block != "latest" {
+			number = hexutil.MustDecodeUint64(block)
+		}
+		pool.global0 = new(big.Int).Mul(q128, new(big.Int).SetUint64(number))
+		return positions(to, data)
+	}
+
+	return node
+}
+
+func TestAccurateAPR(t *testing.T) {
+	client := newFakeClient(t, aprNode(t))
+
+	// 7 minutes of 12 second blocks: 65 to 100
+	apr, err := client.AccurateAPR(context.Background(), big.NewInt(1), 7*time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []LiquiditySegment{
+		{FromBlock: 65, ToBlock: 80, Elapsed: 3 * time.Minute, Liquidity: big.NewInt(6e14), Fees0: big.NewInt(6e14 * 15), Fees1: new(big.Int)},
+		{FromBlock: 80, ToBlock: 100, Elapsed: 4 * time.Minute, Liquidity: big.NewInt(1e15), Fees0: big.NewInt(1e15 * 20), Fees1: new(big.Int)},
+	}
+	if len(apr.Segments) != len(want) {
+		t.Fatalf("segments = %+v, want %+v", apr.Segments, want)
+	}
+	for i, w := range want {
+		s := apr.Segments[i]
+		if s.FromBlock != w.FromBlock || s.ToBlock != w.ToBlock || s.Elapsed != w.Elapsed || s.Liquidity.Cmp(w.Liquidity) != 0 || s.Fees0.Cmp(w.Fees0) != 0 || s.Fees1.Sign() != 0 {
+			t.Errorf("segment %d = %+v, want %+v", i, s, w)
+		}
+	}
+	if apr.FromBlock != 65 || apr.ToBlock != 100 || apr.Fees0.Int64() != 29e15 || apr.Fees1.Sign() != 0 {
+		t.Errorf("accrual = %+v, want 29e15 token0 in blocks 65-100", apr.FeeAccrual)
+	}
+
+	// the capital counts 6e14 liquidity for 3 of the 7 minutes and 1e15 for 4
+	amount0, amount1 := AmountsForLiquidity(q96, -60, 60, big.NewInt(1e15))
+	capital, _ := new(big.Float).SetInt(new(big.Int).Add(amount0, amount1)).Float64()
+	principal := capital * (0.6*3 + 4) / 7
+	wantAPR := 29e15 / principal * float64(year) / float64(7*time.Minute)
+	if got, _ := apr.APR.Float64(); apr.APR == nil || got < wantAPR*(1-1e-6) || got > wantAPR*(1+1e-6) {
+		t.Errorf("APR = %v, want %g", apr.APR, wantAPR)
+	}
+}
+
+func TestRunAccurateAPR(t *testing.T) {
+	url := "https://app.uniswap.org/positions/v3/arbitrum/1"
+
+	code, stdout, stderr := runOutput(t, "-node", aprNode(t).serve(t), "-max-lag", "0", "-since", "7m", "-url", url)
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	for _, want := range []string{"fees in blocks 65-100 (7m0s): 29000000000000000 token0 0 token1 apr=", "\n65-80   3m0s     600000000000000   9000000000000000   0\n", "\n80-100  4m0s     1000000000000000  20000000000000000  0\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %q:\n%s", want, stdout)
+		}
+	}
+
+	code, stdout, stderr = runOutput(t, "-node", aprNode(t).serve(t), "-max-lag", "0", "-since", "7m", "-url", url, "-format", "json")
+	if code != exitOK {
+		t.Fatalf("json: exit code = %d; stderr:\n%s", code, stderr)
+	}
+	var out struct {
+		Fees0    string `json:"fees0"`
+		Segments []struct {
+			Seconds   uint64 `json:"seconds"`
+			Liquidity string `json:"liquidity"`
+		} `json:"segments"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, stdout)
+	}
+	if out.Fees0 != "29000000000000000" || len(out.Segments) != 2 || out.Segments[0].Seconds != 180 || out.Segments[1].Liquidity != "1000000000000000" {
+		t.Errorf("output = %+v", out)
+	}
+}
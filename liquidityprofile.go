@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// LiquidityStep is the combined liquidity active from Tick up to the Tick of
+// the next step.
+type LiquidityStep struct {
+	Tick      Tick     `json:"tick"`
+	Liquidity *big.Int `json:"liquidity"`
+}
+
+// LiquidityProfile is the combined liquidity of several positions as a step
+// function over ticks, in ascending tick order. The last step always has
+// zero liquidity, and consecutive steps never hold the same liquidity.
+type LiquidityProfile []LiquidityStep
+
+// CombinedLiquidity sums the liquidity of positions of one pool at each tick.
+// Overlapping ranges add up; adjacent ranges with equal liquidity merge into
+// one step. Positions with an Error are skipped.
+func CombinedLiquidity(results []PositionResult) (LiquidityProfile, error) {
+	net := make(map[Tick]*big.Int)
+	add := func(tick Tick, delta *big.Int) {
+		if net[tick] == nil {
+			net[tick] = new(big.Int)
+		}
+		net[tick].Add(net[tick], delta)
+	}
+
+	var pool *common.Address
+	for i, r := range results {
+		if r.Error != "" || r.Position.Liquidity == nil || r.Position.Liquidity.Sign() == 0 {
+			continue
+		}
+		if pool == nil {
+			pool = &results[i].Pool
+		} else if r.Pool != *pool {
+			return nil, fmt.Errorf("position %d is in pool %s, not %s", i, r.Pool, *pool)
+		}
+		if err := validateTicks(r.TickLower, r.TickUpper); err != nil {
+			return nil, fmt.Errorf("position %d: %w", i, err)
+		}
+
+		add(r.TickLower, r.Position.Liquidity)
+		add(r.TickUpper, new(big.Int).Neg(r.Position.Liquidity))
+	}
+
+	ticks := make([]Tick, 0, len(net))
+	for tick := range net {
+		ticks = append(ticks, tick)
+	}
+	sort.Slice(ticks, func(i, j int) bool { return ticks[i] < ticks[j] })
+
+	var profile LiquidityProfile
+	active := new(big.Int)
+	for _, tick := range ticks {
+		active.Add(active, net[tick])
+		if n := len(profile); n > 0 && profile[n-1].Liquidity.Cmp(active) == 0 {
+			continue
+		}
+		profile = append(profile, LiquidityStep{Tick: tick, Liquidity: new(big.Int).Set(active)})
+	}
+
+	return profile, nil
+}
+
+// PoolProfile is the combined liquidity of the positions of one pool.
+type PoolProfile struct {
+	Pool    common.Address   `json:"pool"`
+	Profile LiquidityProfile `json:"profile"`
+}
+
+// poolProfiles is CombinedLiquidity of the results of each pool, in the
+// order the pools first appear.
+func poolProfiles(results []PositionResult) ([]PoolProfile, error) {
+	var pools []common.Address
+	byPool := make(map[common.Address][]PositionResult)
+	for _, r := range results {
+		if _, ok := byPool[r.Pool]; !ok {
+			pools = append(pools, r.Pool)
+		}
+		byPool[r.Pool] = append(byPool[r.Pool], r)
+	}
+
+	profiles := make([]PoolProfile, 0, len(pools))
+	for _, pool := range pools {
+		profile, err := CombinedLiquidity(byPool[pool])
+		if err != nil {
+			return nil, err
+		}
+		if len(profile) > 0 {
+			profiles = append(profiles, PoolProfile{Pool: pool, Profile: profile})
+		}
+	}
+
+	return profiles, nil
+}
+
+// writeProfiles prints each pool's profile as a table of the liquidity
+// from each tick on, or as JSON.
+func writeProfiles(w io.Writer, format string, profiles []PoolProfile) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(profiles)
+	case formatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, p := range profiles {
+			if err := enc.Encode(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i, p := range profiles {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "pool %s\n", p.Pool.Hex())
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "FROM TICK\tLIQUIDITY")
+		for _, step := range p.Profile {
+			fmt.Fprintf(tw, "%d\t%s\n", step.Tick, step.Liquidity)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// At returns the combined liquidity active at tick.
+func (p LiquidityProfile) At(tick Tick) *big.Int {
+	i := sort.Search(len(p), func(i int) bool { return p[i].Tick > tick })
+	if i == 0 {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Set(p[i-1].Liquidity)
+}
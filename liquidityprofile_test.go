@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func profileResult(pool common.Address, lower, upper Tick, liquidity int64) PositionResult {
+	return PositionResult{Pool: pool, Owner: testOwner, TickLower: lower, TickUpper: upper, Position: Position{Liquidity: big.NewInt(liquidity)}}
+}
+
+func checkProfile(t *testing.T, got LiquidityProfile, want []LiquidityStep) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("profile = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].Tick != want[i].Tick || got[i].Liquidity.Cmp(want[i].Liquidity) != 0 {
+			t.Errorf("step %d = %d: %s, want %d: %s", i, got[i].Tick, got[i].Liquidity, want[i].Tick, want[i].Liquidity)
+		}
+	}
+}
+
+func TestCombinedLiquidity(t *testing.T) {
+	profile, err := CombinedLiquidity([]PositionResult{
+		profileResult(testPool, -120, 60, 1000),
+		profileResult(testPool, 0, 180, 500),
+		// skipped
+		{Pool: testPool, TickLower: -600, TickUpper: 600, Error: "revert"},
+		profileResult(testPool, -600, 600, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkProfile(t, profile, []LiquidityStep{
+		{-120, big.NewInt(1000)},
+		{0, big.NewInt(1500)},
+		{60, big.NewInt(500)},
+		{180, new(big.Int)},
+	})
+	for tick, want := range map[Tick]int64{-121: 0, -120: 1000, 30: 1500, 60: 500, 180: 0} {
+		if got := profile.At(tick); got.Int64() != want {
+			t.Errorf("At(%d) = %s, want %d", tick, got, want)
+		}
+	}
+}
+
+func TestCombinedLiquidityPools(t *testing.T) {
+	other := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	results := []PositionResult{profileResult(testPool, -60, 60, 1000), profileResult(other, 0, 60, 1000)}
+
+	if _, err := CombinedLiquidity(results); err == nil {
+		t.Error("CombinedLiquidity of two pools succeeded")
+	}
+
+	profiles, err := poolProfiles(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].Pool != testPool || profiles[1].Pool != other {
+		t.Fatalf("profiles = %+v, want %s then %s", profiles, testPool.Hex(), other.Hex())
+	}
+	checkProfile(t, profiles[1].Profile, []LiquidityStep{{0, big.NewInt(1000)}, {60, new(big.Int)}})
+}
+
+func TestRunLiquidityProfile(t *testing.T) {
+	entry := `{"pool": "%s", "owner": "%s", "tickLower": %d, "tickUpper": %d}`
+	path := writeInput(t, "["+fmt.Sprintf(entry, testPool.Hex(), testOwner.Hex(), -60, 60)+","+fmt.Sprintf(entry, testPool.Hex(), testOwner.Hex(), 0, 120)+"]")
+
+	code, stdout, stderr := runOutput(t, "-node", positionNode(t, 1000).serve(t), "-max-lag", "0", "-input", path, "-liquidity-profile")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	for _, want := range []string{"pool " + testPool.Hex() + "\n", "-60        1000\n", "0          2000\n", "60         1000\n", "120        0\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %q:\n%s", want, stdout)
+		}
+	}
+
+	if code, _, _ := runOutput(t, "-liquidity-profile", "-group"); code != exitBadInput {
+		t.Errorf("-liquidity-profile -group: exit code = %d, want %d", code, exitBadInput)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Load balancing strategies for -lb.
+const (
+	LBRoundRobin = "round-robin"
+)
+
+// defaultEjectFor is how long a RoundRobinBackend leaves out a backend that
+// failed a request.
+const defaultEjectFor = 30 * time.Second
+
+// RoundRobinBackend spreads requests over its backends in turn, so that a
+// large scan stays under each node's rate limit. Unlike FallbackBackend every
+// backend serves its share, not just the first.
+//
+// A backend that fails a request is ejected for ejectFor and the request is
+// retried on the next healthy one; an ejected backend is only tried again
+// before its time is up when every other backend failed too. A revert, or a
+// partial answer whose failed reads all reverted, is returned right away
+// since the same call reverts everywhere.
+type RoundRobinBackend struct {
+	backends []Backend
+	ejectFor time.Duration
+	now      func() time.Time
+
+	mu           sync.Mutex
+	next         int
+	ejectedUntil []time.Time
+}
+
+// NewRoundRobinBackend balances over backends, ejecting a failing one for
+// ejectFor.
+func NewRoundRobinBackend(ejectFor time.Duration, backends ...Backend) *RoundRobinBackend {
+	return &RoundRobinBackend{
+		backends:     backends,
+		ejectFor:     ejectFor,
+		now:          time.Now,
+		ejectedUntil: make([]time.Time, len(backends)),
+	}
+}
+
+func (r *RoundRobinBackend) Positions(ctx context.Context, queries []PositionQuery, block *big.Int) ([]Position, error) {
+	if len(r.backends) == 0 {
+		return nil, errors.New("no backends")
+	}
+
+	var (
+		errs    []error
+		partial []Position
+		partErr error
+	)
+
+	for _, i := range r.order() {
+		positions, err := r.backends[i].Positions(ctx, queries, block)
+		if err == nil {
+			return positions, nil
+		}
+
+		if isRevert(err) {
+			return nil, err
+		}
+		var batchErr *BatchError
+		if errors.As(err, &batchErr) && batchErr.reverted() {
+			return positions, err
+		}
+
+		r.eject(i)
+		if errors.As(err, &batchErr) && partial == nil {
+			partial, partErr = positions, err
+		}
+		errs = append(errs, fmt.Errorf("backend %d: %w", i, err))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if partial != nil {
+		return partial, partErr
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// order advances the rotation and returns the backends to try for one
+// request: the healthy ones from the current turn on, then the ejected ones.
+func (r *RoundRobinBackend) order() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	healthy := make([]int, 0, len(r.backends))
+	var ejected []int
+	for k := 0; k < len(r.backends); k++ {
+		i := (r.next + k) % len(r.backends)
+		if now.Before(r.ejectedUntil[i]) {
+			ejected = append(ejected, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+	r.next = (r.next + 1) % len(r.backends)
+
+	return append(healthy, ejected...)
+}
+
+func (r *RoundRobinBackend) eject(i int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ejectedUntil[i] = r.now().Add(r.ejectFor)
+}
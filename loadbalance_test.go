@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestRoundRobinBackend(t *testing.T) {
+	found := []Position{{Liquidity: big.NewInt(1000)}}
+	transport := &CallError{Kind: KindTransport, Err: errors.New("connection refused")}
+	revert := &CallError{Kind: KindRevert, Err: errRevert{}}
+
+	backends := []*stubBackend{{positions: found}, {positions: found}, {positions: found}}
+	rr := NewRoundRobinBackend(time.Minute, backends[0], backends[1], backends[2])
+	now := time.Unix(1_700_000_000, 0)
+	rr.now = func() time.Time { return now }
+
+	calls := func() []int {
+		return []int{backends[0].calls, backends[1].calls, backends[2].calls}
+	}
+	read := func() ([]Position, error) {
+		return rr.Positions(context.Background(), testQueries[:1], nil)
+	}
+
+	// every backend serves its turn
+	for i := 0; i < 3; i++ {
+		if _, err := read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := calls(); !slices.Equal(got, []int{1, 1, 1}) {
+		t.Errorf("requests = %v after a round, want one each", got)
+	}
+
+	// backend 0 fails its turn, backend 1 takes over
+	backends[0].err = transport
+	if positions, err := read(); err != nil || positions[0].Liquidity.Int64() != 1000 {
+		t.Fatalf("read with a failing backend = %+v, %v", positions, err)
+	}
+	if got := calls(); !slices.Equal(got, []int{2, 2, 1}) {
+		t.Errorf("requests = %v, want the failed one retried on the next", got)
+	}
+
+	// the ejected backend is left out of its turns
+	backends[0].err = nil
+	for i := 0; i < 3; i++ {
+		if _, err := read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := calls(); got[0] != 2 {
+		t.Errorf("requests = %v, want none to the ejected backend", got)
+	}
+
+	// and is back once its time is up
+	now = now.Add(time.Minute)
+	before := backends[0].calls
+	for i := 0; i < 3; i++ {
+		if _, err := read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backends[0].calls != before+1 {
+		t.Errorf("backend 0 got %d requests after its ejection, want 1", backends[0].calls-before)
+	}
+
+	// a revert isn't retried elsewhere
+	for _, b := range backends {
+		b.err = revert
+	}
+	before = backends[0].calls + backends[1].calls + backends[2].calls
+	if _, err := read(); !isRevert(err) {
+		t.Errorf("err = %v, want the revert", err)
+	}
+	if n := backends[0].calls + backends[1].calls + backends[2].calls - before; n != 1 {
+		t.Errorf("a revert was tried on %d backends, want 1", n)
+	}
+
+	// when every backend fails each is tried, the ejected ones last
+	for _, b := range backends {
+		b.err = transport
+	}
+	before = backends[0].calls + backends[1].calls + backends[2].calls
+	if _, err := read(); err == nil || !strings.Contains(err.Error(), "backend 2") {
+		t.Errorf("err = %v, want every backend's error", err)
+	}
+	if n := backends[0].calls + backends[1].calls + backends[2].calls - before; n != 3 {
+		t.Errorf("a failing read was tried on %d backends, want 3", n)
+	}
+
+	if _, err := NewRoundRobinBackend(time.Minute).Positions(context.Background(), testQueries[:1], nil); err == nil {
+		t.Error("empty RoundRobinBackend answered")
+	}
+}
+
+func TestRunNodes(t *testing.T) {
+	first := positionNode(t, 1000)
+	pools := first.handle
+	first.handle = func(to common.Address, data []byte) ([]byte, error) {
+		if methodOf(t, poolABI, data).Name == positionsMethod {
+			return nil, errRateLimited{}
+		}
+		return pools(to, data)
+	}
+	second := positionNode(t, 1000)
+	nodes := first.serve(t) + "," + second.serve(t)
+
+	code, _, stderr := runOutput(t, "-nodes", nodes, "-retries", "0", "-max-lag", "0")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	if second.count("eth_call") == 0 {
+		t.Error("the second node got no calls after the first failed")
+	}
+
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"-nodes", nodes, "-node", second.serve(t)}, "-nodes can't be combined with -node"},
+		{[]string{"-nodes", nodes, "-lb", "random"}, "invalid -lb"},
+	} {
+		if code, _, stderr := runOutput(t, append(tc.args, "-max-lag", "0")...); code != exitBadInput || !strings.Contains(stderr, tc.err) {
+			t.Errorf("run(%q) = %d, want %d; stderr:\n%s", tc.args, code, exitBadInput, stderr)
+		}
+	}
+}
@@ -3,32 +3,50 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
-	"strings"
+	"os"
+	"os/signal"
+	"time"
 
-	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 type Position struct {
-	Liquidity                *big.Int
-	FeeGrowthInside0LastX128 *big.Int
-	FeeGrowthInside1LastX128 *big.Int
-	TokensOwed0              *big.Int
-	TokensOwed1              *big.Int
+	Liquidity                *big.Int `json:"liquidity"`
+	FeeGrowthInside0LastX128 *big.Int `json:"feeGrowthInside0LastX128"`
+	FeeGrowthInside1LastX128 *big.Int `json:"feeGrowthInside1LastX128"`
+	TokensOwed0              *big.Int `json:"tokensOwed0"`
+	TokensOwed1              *big.Int `json:"tokensOwed1"`
 }
 
 const (
 	// public node from https://chainlist.org/chain/42161
 	nodeAddr = "https://arbitrum.llamarpc.com"
 
-	abiUniV3Pool    = `[{"inputs":[{"internalType":"bytes32","name":"","type":"bytes32"}],"name":"positions","outputs":[{"internalType":"uint128","name":"liquidity","type":"uint128"},{"internalType":"uint256","name":"feeGrowthInside0LastX128","type":"uint256"},{"internalType":"uint256","name":"feeGrowthInside1LastX128","type":"uint256"},{"internalType":"uint128","name":"tokensOwed0","type":"uint128"},{"internalType":"uint128","name":"tokensOwed1","type":"uint128"}],"stateMutability":"view","type":"function"}]`
+	abiUniV3Pool    = `[{"inputs":[{"internalType":"bytes32","name":"","type":"bytes32"}],"name":"positions","outputs":[{"internalType":"uint128","name":"liquidity","type":"uint128"},{"internalType":"uint256","name":"feeGrowthInside0LastX128","type":"uint256"},{"internalType":"uint256","name":"feeGrowthInside1LastX128","type":"uint256"},{"internalType":"uint128","name":"tokensOwed0","type":"uint128"},{"internalType":"uint128","name":"tokensOwed1","type":"uint128"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"liquidity","outputs":[{"internalType":"uint128","name":"","type":"uint128"}],"stateMutability":"view","type":"function"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"owner","type":"address"},{"indexed":false,"internalType":"address","name":"recipient","type":"address"},{"indexed":true,"internalType":"int24","name":"tickLower","type":"int24"},{"indexed":true,"internalType":"int24","name":"tickUpper","type":"int24"},{"indexed":false,"internalType":"uint128","name":"amount0","type":"uint128"},{"indexed":false,"internalType":"uint128","name":"amount1","type":"uint128"}],"name":"Collect","type":"event"},{"inputs":[],"name":"fee","outputs":[{"internalType":"uint24","name":"","type":"uint24"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"token0","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"token1","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"slot0","outputs":[{"internalType":"uint160","name":"sqrtPriceX96","type":"uint160"},{"internalType":"int24","name":"tick","type":"int24"},{"internalType":"uint16","name":"observationIndex","type":"uint16"},{"internalType":"uint16","name":"observationCardinality","type":"uint16"},{"internalType":"uint16","name":"observationCardinalityNext","type":"uint16"},{"internalType":"uint8","name":"feeProtocol","type":"uint8"},{"internalType":"bool","name":"unlocked","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"feeGrowthGlobal0X128","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"feeGrowthGlobal1X128","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"int24","name":"","type":"int24"}],"name":"ticks","outputs":[{"internalType":"uint128","name":"liquidityGross","type":"uint128"},{"internalType":"int128","name":"liquidityNet","type":"int128"},{"internalType":"uint256","name":"feeGrowthOutside0X128","type":"uint256"},{"internalType":"uint256","name":"feeGrowthOutside1X128","type":"uint256"},{"internalType":"int56","name":"tickCumulativeOutside","type":"int56"},{"internalType":"uint160","name":"secondsPerLiquidityOutsideX128","type":"uint160"},{"internalType":"uint32","name":"secondsOutside","type":"uint32"},{"internalType":"bool","name":"initialized","type":"bool"}],"stateMutability":"view","type":"function"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"address","name":"sender","type":"address"},{"indexed":true,"internalType":"address","name":"owner","type":"address"},{"indexed":true,"internalType":"int24","name":"tickLower","type":"int24"},{"indexed":true,"internalType":"int24","name":"tickUpper","type":"int24"},{"indexed":false,"internalType":"uint128","name":"amount","type":"uint128"},{"indexed":false,"internalType":"uint256","name":"amount0","type":"uint256"},{"indexed":false,"internalType":"uint256","name":"amount1","type":"uint256"}],"name":"Mint","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"owner","type":"address"},{"indexed":true,"internalType":"int24","name":"tickLower","type":"int24"},{"indexed":true,"internalType":"int24","name":"tickUpper","type":"int24"},{"indexed":false,"internalType":"uint128","name":"amount","type":"uint128"},{"indexed":false,"internalType":"uint256","name":"amount0","type":"uint256"},{"indexed":false,"internalType":"uint256","name":"amount1","type":"uint256"}],"name":"Burn","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"sender","type":"address"},{"indexed":true,"internalType":"address","name":"recipient","type":"address"},{"indexed":false,"internalType":"int256","name":"amount0","type":"int256"},{"indexed":false,"internalType":"int256","name":"amount1","type":"int256"},{"indexed":false,"internalType":"uint160","name":"sqrtPriceX96","type":"uint160"},{"indexed":false,"internalType":"uint128","name":"liquidity","type":"uint128"},{"indexed":false,"internalType":"int24","name":"tick","type":"int24"}],"name":"Swap","type":"event"},{"inputs":[],"name":"tickSpacing","outputs":[{"internalType":"int24","name":"","type":"int24"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"int24","name":"tickLower","type":"int24"},{"internalType":"int24","name":"tickUpper","type":"int24"}],"name":"snapshotCumulativesInside","outputs":[{"internalType":"int56","name":"tickCumulativeInside","type":"int56"},{"internalType":"uint160","name":"secondsPerLiquidityInsideX128","type":"uint160"},{"internalType":"uint32","name":"secondsInside","type":"uint32"}],"stateMutability":"view","type":"function"}]`
 	positionsMethod = "positions"
+	liquidityMethod = "liquidity"
+	collectEvent    = "Collect"
+	mintEvent       = "Mint"
+	burnEvent       = "Burn"
+	swapEvent       = "Swap"
+	feeMethod       = "fee"
+	token0Method    = "token0"
+	token1Method    = "token1"
+	slot0Method     = "slot0"
+	ticksMethod     = "ticks"
+
+	tickSpacingMethod    = "tickSpacing"
+	snapshotInsideMethod = "snapshotCumulativesInside"
+
+	feeGrowthGlobal0Method = "feeGrowthGlobal0X128"
+	feeGrowthGlobal1Method = "feeGrowthGlobal1X128"
 )
 
 // https://app.uniswap.org/explore/pools
@@ -37,39 +55,359 @@ var poolAddress = common.HexToAddress("0xc6962004f452be9203591991d15f6b388e09e8d
 
 var (
 	//Random minter from logs pool
-	ownerPositionAddress       = common.HexToAddress("0xF829c130478599E4EF49F6e02EDaA1F8736E9B00")
-	tickLower            int32 = -197740
-	tickUpper            int32 = -197640
+	ownerPositionAddress      = common.HexToAddress("0xF829c130478599E4EF49F6e02EDaA1F8736E9B00")
+	tickLower            Tick = -197740
+	tickUpper            Tick = -197640
+)
+
+// https://github.com/Uniswap/v3-core/blob/d8b1c635c275d2a9450bd6a78f3fa2484fef73eb/contracts/libraries/TickMath.sol
+// packed as int24: MinTick is 0xf27618, MaxTick is 0x0d89e8
+const (
+	MinTick Tick = -887272
+	MaxTick Tick = -MinTick
+)
+
+const (
+	minInt24 = -1 << 23
+	maxInt24 = 1<<23 - 1
 )
 
 func main() {
-	positionKey, err := calcPositionKey(ownerPositionAddress, tickLower, tickUpper)
+	os.Exit(run(os.Args[1:]))
+}
+
+// run is the whole command; it returns the process exit code, see exitCode.
+func run(args []string) int {
+	err := execute(args)
+	if err != nil && !reported(err) {
+		log.New(os.Stderr, "", log.LstdFlags).Print(err)
+	}
+
+	return exitCode(err)
+}
+
+// cli holds the flag values of a run.
+type cli struct {
+	node, pool, inputPath, linkURL string
+	format                         string
+	scaled, fullAddr, group        bool
+	profile                        bool
+	pushURL, metricsAddr           string
+	list                           bool
+	minLiq, summary                string
+	offset, limit                  int
+	timeout                        time.Duration
+	retries                        int
+	retryCap                       int64
+	maxLag                         time.Duration
+	verbose                        bool
+	batch, proxy                   string
+	signKey, flagged               string
+	watch, dashboard, follow       bool
+	feeLimit0, feeLimit1           string
+	minDelta0, minDelta1           string
+	decimals0, decimals1           int
+	spacing, digits                int
+	aprWindow, blockTime           time.Duration
+	backend, archive               string
+	confirms, atBlock, atL1Block   uint64
+	pin, config, decode            string
+	priceTick, poolOf, dex         string
+	diagnose, discover             bool
+	scanDepth                      uint64
+	since, backfill                string
+	bfStep                         uint64
+	workers                        int
+	keysPath, reconcile            string
+	baseToken, quoteTok            string
+	cpuProf, memProf               string
+	schema, listChain              bool
+	auditPath                      string
+	dryRun, hexTrace, quiet        bool
+	share, amounts, report         bool
+	exitImpact                     bool
+	feeRate                        bool
+	reinvest                       string
+	collects, breakEven, activity  string
+	project                        string
+	tokenURI, tokenImage           string
+	poolABIPath, npmABIPath        string
+	overridePath                   string
+	prices                         priceList
+	simulatePath                   string
+
+	owners                           addressList
+	lower, upper                     tickFlag
+	fallbackNodes, lbNodes, keySalts stringList
+	labels, layoutPaths              stringList
+	lb                               string
+}
+
+// newFlagSet defines the flags of the command, bound to the returned cli.
+func newFlagSet() (*flag.FlagSet, *cli) {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.Usage = func() { printUsage(fs.Output(), fs) }
+
+	o := &cli{lower: tickFlag{tick: tickLower}, upper: tickFlag{tick: tickUpper}}
+	fs.StringVar(&o.node, "node", nodeAddr, "node RPC URL, or the IPC socket path of a local node")
+	fs.StringVar(&o.pool, "pool", poolAddress.Hex(), "pool address")
+	fs.StringVar(&o.inputPath, "input", "", "JSON file with a list of positions to fetch")
+	fs.StringVar(&o.linkURL, "url", "", "read the position of a Uniswap app position URL or block explorer NFT page, or the -owner's position in the pool of an explorer address page")
+	fs.StringVar(&o.format, "format", formatText, "output format: text, json, ndjson (one JSON result per line, streamed with -list), table or csv")
+	fs.BoolVar(&o.scaled, "scaled", false, "with -format json or ndjson, also give owed amounts in whole tokens, as tokensOwed0Scaled next to tokensOwed0")
+	fs.IntVar(&o.digits, "price-precision", defaultPriceDigits, "significant digits of printed prices, price impacts and rates")
+	fs.BoolVar(&o.fullAddr, "full-addresses", false, "print full addresses in -format table and -dashboard instead of 0x1234…abcd")
+	fs.BoolVar(&o.group, "group", false, "group positions by pool with per-pool liquidity, value and fee subtotals and a grand total")
+	fs.BoolVar(&o.profile, "liquidity-profile", false, "print the combined liquidity of the positions of each pool as steps over ticks instead of the positions")
+	fs.StringVar(&o.pushURL, "pushgateway", "", "push position metrics to this Prometheus Pushgateway URL at the end of the run")
+	fs.BoolVar(&o.list, "list", false, "list all NFT positions of every -owner")
+	fs.StringVar(&o.minLiq, "min-liquidity", "1", "with -list, hide positions with less liquidity")
+	fs.StringVar(&o.summary, "summary", "", "with -list, print totals of the portfolio instead of its positions, valued in this quote token address")
+	fs.IntVar(&o.offset, "offset", 0, "with -list, skip this many positions in enumeration order")
+	fs.IntVar(&o.limit, "limit", 0, "with -list, read at most this many positions (default all)")
+	fs.DurationVar(&o.timeout, "timeout", 30*time.Second, "timeout for the whole command")
+	fs.IntVar(&o.retries, "retries", 2, "retries for calls failing with a transport or rate limit error")
+	fs.Int64Var(&o.retryCap, "retry-budget", 0, "most retries of all calls of the command together, after which calls fail on their first error; 0 for no limit")
+	fs.DurationVar(&o.maxLag, "max-lag", defaultMaxLag, "warn when reading latest from a node whose head block is older than this; 0 disables the check")
+	fs.BoolVar(&o.verbose, "verbose", false, "log every eth_call")
+	fs.StringVar(&o.poolABIPath, "pool-abi", "", "JSON ABI file of the pool contract, for forks whose pools differ from Uniswap V3's; it must keep the methods the tool calls")
+	fs.StringVar(&o.npmABIPath, "npm-abi", "", "JSON ABI file of the position manager, see -pool-abi")
+	fs.StringVar(&o.batch, "batch", BatchMulticall, "batch backend: multicall or native JSON-RPC batch")
+	fs.StringVar(&o.proxy, "proxy", "", "proxy URL for node requests (default from HTTP_PROXY/HTTPS_PROXY/NO_PROXY)")
+	fs.StringVar(&o.signKey, "sign-key", "", "hex private key to sign an EIP-712 snapshot of the position")
+	fs.StringVar(&o.flagged, "flagged-tokens", "", "comma separated fee-on-transfer or rebasing tokens to warn about, in addition to the built-in list")
+	fs.BoolVar(&o.watch, "watch", false, "print the position at every new block (needs a ws or ipc node)")
+	fs.StringVar(&o.metricsAddr, "metrics-addr", "", "with -watch, serve OpenMetrics gauges of the position at http://ADDR/metrics, updated at every new block, e.g. :9101")
+	fs.DurationVar(&o.blockTime, "block-time", 0, "with -watch or -follow, the block time to wait 10 of for a new head before resubscribing, and to back off by; 0 for that of the node's chain")
+	fs.BoolVar(&o.dashboard, "dashboard", false, "print a panel with the range, amounts, fees and value of the position; with -watch, redraw it at every new block")
+	fs.BoolVar(&o.follow, "follow", false, "print a compact line with fee deltas at every new block (needs a ws or ipc node)")
+	fs.StringVar(&o.feeLimit0, "fee-threshold0", "", "with -follow, alert when uncollected token0 fees reach this amount in token units")
+	fs.StringVar(&o.feeLimit1, "fee-threshold1", "", "with -follow, alert when uncollected token1 fees reach this amount in token units")
+	fs.StringVar(&o.minDelta0, "min-change0", "", "with -follow, skip blocks where liquidity and range are unchanged and token0 fees moved by less than this amount in token units since the last line")
+	fs.StringVar(&o.minDelta1, "min-change1", "", "with -follow, skip blocks where liquidity and range are unchanged and token1 fees moved by less than this amount in token units since the last line")
+	fs.IntVar(&o.decimals0, "token0-decimals", -1, "decimals of token0 for -fee-threshold0 and -min-change0 (default read from the token)")
+	fs.IntVar(&o.decimals1, "token1-decimals", -1, "decimals of token1 for -fee-threshold1 and -min-change1 (default read from the token)")
+	fs.DurationVar(&o.aprWindow, "apr-window", 0, "with -follow, show the fee APR over this trailing window, e.g. 24h")
+	fs.StringVar(&o.backend, "backend", BackendRPC, "where reads come from: rpc (the -node) or archive (captured responses in -archive-dir)")
+	fs.StringVar(&o.archive, "archive-dir", "", "directory of captured eth_call responses for -backend archive; with -backend rpc, position reads fall back to it last")
+	fs.Uint64Var(&o.confirms, "confirmations", 0, "read one-shot results this many blocks below the head instead of at the head")
+	fs.Uint64Var(&o.atBlock, "block", 0, "read one-shot results at this block; on Arbitrum an L2 block number, see -l1-block (default latest)")
+	fs.Uint64Var(&o.atL1Block, "l1-block", 0, "on Arbitrum, read one-shot results at the last L2 block of this L1 block")
+	fs.StringVar(&o.pin, "at", "", "pin every read of the command to one block: latest (the head at startup, or the -block, -l1-block or -confirmations block) or a block number")
+	fs.StringVar(&o.config, "config", "", "file with default flag values as key = value lines; explicit flags take precedence")
+	fs.StringVar(&o.decode, "decode-calldata", "", "print the position key encoded in hex positions(bytes32) calldata and exit")
+	fs.StringVar(&o.priceTick, "price-tick", "", "print the sqrtPriceX96 and tick of this price, whole token1 per token0 with -token0-decimals and -token1-decimals or raw units without, and exit")
+	fs.IntVar(&o.spacing, "tick-spacing", 0, "with -price-tick, also print the tick usable at this tick spacing nearest to the price's, e.g. 60 for a 0.3% pool")
+	fs.StringVar(&o.poolOf, "pool-of", "", "print the address of the -dex pool of TOKEN_A,TOKEN_B,FEE, with the fee in hundredths of a basis point, e.g. 0xTokenA,0xTokenB,500, on the chain of CHAIN_ID (default Arbitrum) and exit")
+	fs.StringVar(&o.dex, "dex", uniswapV3.Name, "with -pool-of, the exchange: "+dexNames())
+	fs.BoolVar(&o.diagnose, "diagnose-key", false, "try nonstandard position key derivations for -owner and the ticks and print those with a position")
+	fs.BoolVar(&o.discover, "discover", false, "find the NFT positions -owner received or sent in NPM Transfer logs and read them")
+	fs.Uint64Var(&o.scanDepth, "discover-blocks", 100_000, "with -discover, how many blocks below the head to scan")
+	fs.StringVar(&o.since, "since", "", "print the fees the position earned and their APR over this trailing window, e.g. 24h or 7d; with the -url of a position NFT, allow for liquidity added or removed inside the window; with -discover, scan this window instead of -discover-blocks")
+	fs.BoolVar(&o.feeRate, "fee-rate", false, "with -since, also print how long the price was in the position's range and the fees over the position's value, annualized over the time in range; the ticks must be initialized")
+	fs.StringVar(&o.reinvest, "reinvest", "", "with -since, also print the APR compounded as if the fees were collected and reinvested this often, e.g. 1d or 1w, assuming the window's fee rate holds all year")
+	fs.StringVar(&o.backfill, "backfill", "", "read the position at every -backfill-step-th block of this FROM-TO block range, e.g. 250000000-251000000, and print the series")
+	fs.Uint64Var(&o.bfStep, "backfill-step", 1000, "with -backfill, blocks between two reads")
+	fs.IntVar(&o.workers, "workers", 4, "with -backfill, reads in flight at once")
+	fs.StringVar(&o.keysPath, "keys", "", "compute the pool position key of every owner,tickLower,tickUpper row of this CSV file offline and print the rows with their keys")
+	fs.StringVar(&o.reconcile, "reconcile", "", "compare the uncollected fees computed for this NFT token id with an eth_call of collect from its -owner, and fail if they differ")
+	fs.BoolVar(&o.share, "share", false, "print the position's share of the pool's active liquidity, while it is in range")
+	fs.BoolVar(&o.amounts, "amounts", false, "print the token amounts the position's liquidity is worth, without fees, at -block or the head; past blocks need an archive node")
+	fs.BoolVar(&o.report, "report", false, "print everything about the position from one block: its pool and tokens, prices, amounts, uncollected fees and value in token1")
+	fs.BoolVar(&o.exitImpact, "exit-impact", false, "print how much withdrawing the position and swapping its token0 into token1 would move the pool price, at the head")
+	fs.StringVar(&o.collects, "collects", "", "print the Collect events of the position in this FROM-TO block range, e.g. 250000000-251000000, with their totals")
+	fs.StringVar(&o.activity, "activity", "", "print the Mint and Burn events of the position and the pool's swaps through its range in this FROM-TO block range; every swap of the pool is fetched, so keep it short on busy pools")
+	fs.StringVar(&o.project, "project", "", "print what the position's liquidity, without fees, is worth in whole token1 now and if the price moved to this one, whole token1 per token0, e.g. 5000")
+	fs.StringVar(&o.breakEven, "break-even", "", "print the prices, raw token1 per raw token0, at which the position with its fees is worth as much as holding the AMOUNT0,AMOUNT1 raw amounts deposited")
+	fs.StringVar(&o.tokenURI, "token-uri", "", "print the name and description of the tokenURI metadata of this NFT token id")
+	fs.StringVar(&o.tokenImage, "token-image", "", "with -token-uri, write the NFT's image, an SVG for the Uniswap NPM, to this file")
+	fs.StringVar(&o.simulatePath, "simulate", "", "read the positions after executing the calls of this JSON array, each with to, data and optionally from, gas and value, on top of the block; the node must support eth_simulateV1")
+	fs.StringVar(&o.overridePath, "override", "", "read the positions as if the accounts of this JSON file, an eth_call state override set by address, had its code, balance or storage; the node must support state overrides")
+	fs.StringVar(&o.baseToken, "base", "", "base token address; with -quote, also show owed amounts as base/quote whatever the pool's token order")
+	fs.StringVar(&o.quoteTok, "quote", "", "quote token address, see -base")
+	fs.StringVar(&o.cpuProf, "cpuprofile", "", "write a pprof CPU profile of the run to this file")
+	fs.StringVar(&o.memProf, "memprofile", "", "write a pprof heap profile at the end of the run to this file")
+	fs.BoolVar(&o.schema, "schema", false, "print the JSON Schema of -format json output and exit")
+	fs.BoolVar(&o.listChain, "chains", false, "print the supported chains with their contract addresses and default RPC and exit")
+	fs.StringVar(&o.auditPath, "audit-log", "", "append a JSON line per eth_call with its block, block time and request and result hashes to this file")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "don't dial the node: print the JSON-RPC requests the command would send as JSON lines on stderr, answering every read with zeros")
+	fs.BoolVar(&o.hexTrace, "hex-intermediates", false, "print every intermediate value of the fee and amount math on stderr as 0x-padded 256-bit hex, for bit-exact comparison with a contract trace")
+	fs.BoolVar(&o.quiet, "quiet", false, "print only results and errors")
+	fs.Var(&o.lower, "tick-lower", "lower tick of the position, or min for the lowest tick usable at the pool's tick spacing")
+	fs.Var(&o.upper, "tick-upper", "upper tick of the position, or max for the highest tick usable at the pool's tick spacing")
+	fs.Var(&o.keySalts, "key-salt", "with -diagnose-key, also try keys salted with this hex bytes32; repeat or separate with commas")
+	fs.Var(&o.prices, "price", "value TOKEN at PRICE per whole token instead of the pool price in -report, -project, -group and -summary, given as TOKEN=PRICE; the prices of a pool's two tokens are in one unit, such as USD, and a price given for only one is in whole units of the other; repeat or separate with commas for several")
+	fs.Var(&o.labels, "label", "label every position KEY=VALUE in -format json and ndjson output; repeat or separate with commas for several")
+	fs.Var(&o.layoutPaths, "position-layout", "JSON file with the outputs array of a fork's pool positions(bytes32), to decode responses that aren't the canonical five words; repeat for several")
+	fs.Var(&o.fallbackNodes, "fallback-node", "node RPC URL or IPC path to read positions from when -node fails; repeat or separate with commas to try several in order")
+	fs.Var(&o.lbNodes, "nodes", "node RPC URLs or IPC paths to spread position reads over with -lb, instead of -node; the first also serves every other read")
+	fs.StringVar(&o.lb, "lb", LBRoundRobin, "with -nodes, how position reads are spread: round-robin, leaving out a node for a while after it fails")
+	fs.Var(&o.owners, "owner", "position owner address; repeat or separate with commas to read several (default "+ownerPositionAddress.Hex()+")")
+
+	return fs, o
+}
+
+func execute(args []string) error {
+	fs, o := newFlagSet()
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		// the flag package already printed the error and usage
+		return &inputError{err: err, reported: true}
+	}
+
+	if o.config != "" {
+		if err := loadConfig(fs, o.config); err != nil {
+			return badInput("load config: %w", err)
+		}
+	}
+	if err := checkFlags(fs, len(o.owners)); err != nil {
+		return err
+	}
+
+	if o.digits < 1 {
+		return badInput("invalid -price-precision: %d, want at least 1", o.digits)
+	}
+	priceDigits = o.digits
+	defer func() { priceDigits = defaultPriceDigits }()
+
+	wantChain, chainSet, err := envChain()
 	if err != nil {
-		log.Fatal("calc position key:", err)
+		return badInput("%w", err)
+	}
+	if len(o.lbNodes) > 0 {
+		if o.lb != LBRoundRobin {
+			return badInput("invalid -lb: %s", o.lb)
+		}
+		o.node = o.lbNodes[0]
+	} else if chainSet && !flagSet(fs, "node") {
+		o.node = wantChain.RPC
+	}
+
+	if o.quiet {
+		log.SetOutput(io.Discard)
+	}
+	if len(o.owners) == 0 {
+		o.owners = addressList{ownerPositionAddress}
+	}
+
+	// modes that don't read from a node
+	switch {
+	case o.listChain:
+		return writeChains(os.Stdout, o.format)
+	case o.schema:
+		return printSchema(os.Stdout)
+	case o.keysPath != "":
+		return computeKeys(os.Stdout, o.keysPath, o.format)
+	case o.decode != "":
+		return decodeCalldata(os.Stdout, o.decode)
+	case o.priceTick != "":
+		return convertPrice(os.Stdout, o.priceTick, o.decimals0, o.decimals1, o.spacing)
+	case o.poolOf != "":
+		chainID := uint64(defaultDEXChain)
+		if chainSet {
+			chainID = wantChain.ID
+		}
+		return computePool(os.Stdout, o.poolOf, o.dex, chainID)
 	}
-	contract, _ := abi.JSON(strings.NewReader(abiUniV3Pool))
-	calldata, _ := contract.Pack(positionsMethod, positionKey)
 
-	client, err := ethclient.Dial(nodeAddr)
+	s, err := newSession(fs, o)
 	if err != nil {
-		log.Fatal("conenct to node:", err)
+		return err
 	}
 
-	response, err := client.CallContract(context.Background(), ethereum.CallMsg{To: &poolAddress, Data: calldata}, nil)
+	stopProfiles, err := startProfiles(o.cpuProf, o.memProf)
 	if err != nil {
-		log.Fatal("call contract:", err)
+		return err
+	}
+	defer func() {
+		if err := stopProfiles(); err != nil {
+			log.Print("warning: ", err)
+		}
+	}()
+
+	if o.hexTrace {
+		intermediates = os.Stderr
+		defer func() { intermediates = nil }()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if !o.watch && !o.follow {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
 	}
 
-	var position Position
+	defer s.close()
+	if err := s.connect(ctx, wantChain, chainSet); err != nil {
+		return err
+	}
+	if err := s.resolve(ctx); err != nil {
+		return err
+	}
 
-	if err := contract.UnpackIntoInterface(&position, positionsMethod, response); err != nil {
-		log.Fatal("parse result contract: ", err, "response: ", string(response))
+	var results []PositionResult
+	switch {
+	case o.dashboard:
+		return s.runDashboard(ctx)
+	case o.watch:
+		return s.runWatch(ctx)
+	case o.follow:
+		return s.runFollow(ctx)
+	case o.signKey != "":
+		return s.runSign(ctx)
+	case o.diagnose:
+		return s.runDiagnoseKey(ctx)
+	case o.discover:
+		results, err = s.discoverPositions(ctx)
+	case s.window > 0:
+		return s.runSince(ctx)
+	case s.reconcileID != nil:
+		return s.runReconcile(ctx)
+	case o.backfill != "":
+		return s.runBackfill(ctx)
+	case o.share:
+		return s.runShare(ctx)
+	case o.amounts:
+		return s.runAmounts(ctx)
+	case o.report:
+		return s.runReport(ctx)
+	case o.exitImpact:
+		return s.runExitImpact(ctx)
+	case o.collects != "":
+		return s.runCollects(ctx)
+	case o.activity != "":
+		return s.runActivity(ctx)
+	case o.breakEven != "":
+		return s.runBreakEven(ctx)
+	case s.projected != nil:
+		return s.runProject(ctx)
+	case s.tokenID != nil:
+		return s.runTokenURI(ctx)
+	case s.link.TokenID != nil:
+		results, err = s.linkedPosition(ctx)
+	case o.list && o.summary != "":
+		return s.runSummary(ctx)
+	case o.list && o.format == formatNDJSON:
+		return s.runStreamList(ctx)
+	case o.list:
+		results, err = s.listPositions(ctx)
+	case o.inputPath != "":
+		results, err = s.inputPositions(ctx)
+	default:
+		results, err = s.ownerPositions(ctx)
+	}
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("%+v", position)
+	return s.printResults(ctx, results)
 }
 
-func calcPositionKey(address common.Address, tickLower, tickUpper int32) (common.Hash, error) {
+func calcPositionKey(address common.Address, tickLower, tickUpper Tick) (common.Hash, error) {
 	callData, err := encodePacked(address, tickLower, tickUpper)
 	if err != nil {
 		return common.Hash{}, err
@@ -89,10 +427,18 @@ func encodePacked(args ...interface{}) ([]byte, error) {
 		case common.Address:
 			buffer.Write(v.Bytes())
 		case *big.Int:
-			buffer.Write(int24Bytes(v))
-		case int32:
-			bigInt := big.NewInt(int64(v))
-			buffer.Write(int24Bytes(bigInt))
+			if !v.IsInt64() || v.Int64() < minInt24 || v.Int64() > maxInt24 {
+				return nil, fmt.Errorf("value %s overflows int24", v)
+			}
+			b := make([]byte, 3)
+			Tick(v.Int64()).putInt24(b)
+			buffer.Write(b)
+		case Tick:
+			b, err := v.int24Bytes()
+			if err != nil {
+				return nil, err
+			}
+			buffer.Write(b)
 		case string:
 			buffer.Write([]byte(v))
 		case []byte:
@@ -105,16 +451,15 @@ func encodePacked(args ...interface{}) ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
-func int24Bytes(n *big.Int) []byte {
-	bytes := make([]byte, 3)
-
-	//adding "f" before a number
-	if n.Sign() == -1 {
-		n = big.NewInt(0).Sub(big.NewInt(0), n)
-		n = big.NewInt(0).Sub(big.NewInt(1<<24), n)
+func validateTicks(lower, upper Tick) error {
+	switch {
+	case lower < MinTick:
+		return fmt.Errorf("tick lower %d is below MIN_TICK %d", lower, MinTick)
+	case upper > MaxTick:
+		return fmt.Errorf("tick upper %d is above MAX_TICK %d", upper, MaxTick)
+	case lower >= upper:
+		return fmt.Errorf("tick lower %d must be below tick upper %d", lower, upper)
 	}
 
-	copy(bytes, n.Bytes()[max(0, len(n.Bytes())-3):])
-
-	return bytes
+	return nil
 }
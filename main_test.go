@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var keyArgs = abi.Arguments{
+	{Type: mustNewType("address")},
+	{Type: mustNewType("int24")},
+	{Type: mustNewType("int24")},
+}
+
+// packedReference is abi.encodePacked(owner, lower, upper) built from the
+// padded abi.encode words: the address is the low 20 bytes of its word and
+// each int24 the low 3 bytes of its.
+func packedReference(t *testing.T, owner common.Address, lower, upper Tick) []byte {
+	t.Helper()
+
+	words, err := keyArgs.Pack(owner, big.NewInt(int64(lower)), big.NewInt(int64(upper)))
+	if err != nil {
+		t.Fatalf("abi encode: %v", err)
+	}
+
+	packed := append([]byte{}, words[32-common.AddressLength:32]...)
+	packed = append(packed, words[64-3:64]...)
+	return append(packed, words[96-3:96]...)
+}
+
+func FuzzEncodePacked(f *testing.F) {
+	for _, seed := range int24Seeds {
+		f.Add(testOwner.Bytes(), seed, -seed)
+	}
+	f.Add([]byte{}, int32(MinTick), int32(MaxTick))
+	f.Add(bytes.Repeat([]byte{0xff}, 20), int32(minInt24), int32(maxInt24))
+
+	f.Fuzz(func(t *testing.T, ownerBytes []byte, lower, upper int32) {
+		owner := common.BytesToAddress(ownerBytes)
+		lowerTick, upperTick := Tick(lower), Tick(upper)
+
+		packed, err := encodePacked(owner, lowerTick, upperTick)
+		fromBig, bigErr := encodePacked(owner, big.NewInt(int64(lower)), big.NewInt(int64(upper)))
+		if !lowerTick.fitsInt24() || !upperTick.fitsInt24() {
+			if err == nil || bigErr == nil {
+				t.Fatalf("encodePacked(%d, %d) = %x, %x, want overflow errors", lower, upper, packed, fromBig)
+			}
+			return
+		}
+
+		if err != nil || bigErr != nil {
+			t.Fatalf("encodePacked(%d, %d): %v, %v", lower, upper, err, bigErr)
+		}
+		if len(packed) != packedKeyLength {
+			t.Fatalf("encodePacked(%d, %d) has %d bytes, want %d", lower, upper, len(packed), packedKeyLength)
+		}
+		if !bytes.Equal(packed, fromBig) {
+			t.Fatalf("Tick and *big.Int encodings differ: %x, %x", packed, fromBig)
+		}
+		if ref := packedReference(t, owner, lowerTick, upperTick); !bytes.Equal(packed, ref) {
+			t.Fatalf("encodePacked(%s, %d, %d) = %x, want %x", owner, lower, upper, packed, ref)
+		}
+	})
+}
+
+// runOutput runs the command with args and returns its exit code with what
+// it printed on stdout and stderr, the log included.
+func runOutput(t *testing.T, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	outFile, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outFile.Close()
+	errFile, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer errFile.Close()
+
+	savedOut, savedErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = outFile, errFile
+	log.SetOutput(errFile)
+	defer func() {
+		os.Stdout, os.Stderr = savedOut, savedErr
+		// -quiet discards the log
+		log.SetOutput(savedErr)
+	}()
+
+	code = run(args)
+
+	out, err := os.ReadFile(outFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	errOut, err := os.ReadFile(errFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code, string(out), string(errOut)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"math/big"
+)
+
+var (
+	q96        = new(big.Int).Lsh(big.NewInt(1), 96)
+	q128       = new(big.Int).Lsh(big.NewInt(1), 128)
+	q32        = new(big.Int).Lsh(big.NewInt(1), 32)
+	maxUint    = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	maxUint128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	tickMuls   = mustBigs(
+		"fffcb933bd6fad37aa2d162d1a594001",
+		"fff97272373d413259a46990580e213a",
+		"fff2e50f5f656932ef12357cf3c7fdcc",
+		"ffe5caca7e10e4e61c3624eaa0941cd0",
+		"ffcb9843d60f6159c9db58835c926644",
+		"ff973b41fa98c081472e6896dfb254c0",
+		"ff2ea16466c96a3843ec78b326b52861",
+		"fe5dee046a99a2a811c461f1969c3053",
+		"fcbe86c7900a88aedcffc83b479aa3a4",
+		"f987a7253ac413176f2b074cf7815e54",
+		"f3392b0822b70005940c7a398e4b70f3",
+		"e7159475a2c29b7443b29c7fa6e889d9",
+		"d097f3bdfd2022b8845ad8f792aa5825",
+		"a9f746462d870fdf8a65dc1f90e061e5",
+		"70d869a156d2a1b890bb3df62baf32f7",
+		"31be135f97d08fd981231505542fcfa6",
+		"9aa508b5b7a84e1c677de54f3e99bc9",
+		"5d6af8dedb81196699c329225ee604",
+		"2216e584f5fa1ea926041bedfe98",
+		"48a170391f7dc42444e8fa2",
+	)
+)
+
+// SqrtRatio is TickMath.getSqrtRatioAtTick: sqrt(1.0001^tick) as a Q64.96.
+// tick must be within [MinTick, MaxTick].
+// https://github.com/Uniswap/v3-core/blob/d8b1c635c275d2a9450bd6a78f3fa2484fef73eb/contracts/libraries/TickMath.sol
+func (tick Tick) SqrtRatio() *big.Int {
+	absTick := tick
+	if absTick < 0 {
+		absTick = -absTick
+	}
+
+	ratio := new(big.Int).Set(q128)
+	if absTick&1 != 0 {
+		ratio.Set(tickMuls[0])
+	}
+	for i := 1; i < len(tickMuls); i++ {
+		if absTick&(1<<i) != 0 {
+			ratio.Mul(ratio, tickMuls[i])
+			ratio.Rsh(ratio, 128)
+		}
+	}
+
+	if tick > 0 {
+		ratio.Quo(maxUint, ratio)
+	}
+
+	// round up so that getTickAtSqrtRatio of the result is tick
+	remainder := new(big.Int).Mod(ratio, q32)
+	ratio.Rsh(ratio, 32)
+	if remainder.Sign() != 0 {
+		ratio.Add(ratio, big.NewInt(1))
+	}
+
+	return ratio
+}
+
+// AmountsForLiquidity is LiquidityAmounts.getAmountsForLiquidity: the token
+// amounts that liquidity is worth at sqrtPriceX96 on [tickLower, tickUpper).
+//
+// For any uint128 liquidity the amounts stay below 2^192, well inside
+// uint256: amount0 is at most L*2^96/MIN_SQRT_RATIO with MIN_SQRT_RATIO > 2^32
+// and amount1 at most L*MAX_SQRT_RATIO/2^96 with MAX_SQRT_RATIO < 2^160.
+// https://github.com/Uniswap/v3-periphery/blob/main/contracts/libraries/LiquidityAmounts.sol
+func AmountsForLiquidity(sqrtPriceX96 *big.Int, tickLower, tickUpper Tick, liquidity *big.Int) (amount0, amount1 *big.Int) {
+	sqrtA, sqrtB := tickLower.SqrtRatio(), tickUpper.SqrtRatio()
+	traceHex("amounts.sqrtRatioA", sqrtA)
+	traceHex("amounts.sqrtRatioB", sqrtB)
+	traceHex("amounts.sqrtPriceX96", sqrtPriceX96)
+	traceHex("amounts.liquidity", liquidity)
+
+	switch {
+	case sqrtPriceX96.Cmp(sqrtA) <= 0:
+		amount0, amount1 = amount0ForLiquidity(sqrtA, sqrtB, liquidity), new(big.Int)
+	case sqrtPriceX96.Cmp(sqrtB) < 0:
+		amount0, amount1 = amount0ForLiquidity(sqrtPriceX96, sqrtB, liquidity), amount1ForLiquidity(sqrtA, sqrtPriceX96, liquidity)
+	default:
+		amount0, amount1 = new(big.Int), amount1ForLiquidity(sqrtA, sqrtB, liquidity)
+	}
+	traceHex("amounts.amount0", amount0)
+	traceHex("amounts.amount1", amount1)
+
+	return amount0, amount1
+}
+
+// LiquidityForAmounts is LiquidityAmounts.getLiquidityForAmounts: the most
+// liquidity that amount0 and amount1 can mint at sqrtPriceX96 on
+// [tickLower, tickUpper). Unlike the contract it doesn't revert when the
+// result overflows uint128.
+func LiquidityForAmounts(sqrtPriceX96 *big.Int, tickLower, tickUpper Tick, amount0, amount1 *big.Int) *big.Int {
+	sqrtA, sqrtB := tickLower.SqrtRatio(), tickUpper.SqrtRatio()
+
+	switch {
+	case sqrtPriceX96.Cmp(sqrtA) <= 0:
+		return liquidityForAmount0(sqrtA, sqrtB, amount0)
+	case sqrtPriceX96.Cmp(sqrtB) < 0:
+		liquidity0 := liquidityForAmount0(sqrtPriceX96, sqrtB, amount0)
+		liquidity1 := liquidityForAmount1(sqrtA, sqrtPriceX96, amount1)
+		if liquidity0.Cmp(liquidity1) < 0 {
+			return liquidity0
+		}
+		return liquidity1
+	default:
+		return liquidityForAmount1(sqrtA, sqrtB, amount1)
+	}
+}
+
+func liquidityForAmount0(sqrtA, sqrtB, amount0 *big.Int) *big.Int {
+	sqrtA, sqrtB = sortSqrt(sqrtA, sqrtB)
+	intermediate := mulDiv(sqrtA, sqrtB, q96)
+
+	return mulDiv(amount0, intermediate, new(big.Int).Sub(sqrtB, sqrtA))
+}
+
+func liquidityForAmount1(sqrtA, sqrtB, amount1 *big.Int) *big.Int {
+	sqrtA, sqrtB = sortSqrt(sqrtA, sqrtB)
+
+	return mulDiv(amount1, q96, new(big.Int).Sub(sqrtB, sqrtA))
+}
+
+func amount0ForLiquidity(sqrtA, sqrtB, liquidity *big.Int) *big.Int {
+	sqrtA, sqrtB = sortSqrt(sqrtA, sqrtB)
+	shifted := new(big.Int).Lsh(liquidity, 96)
+
+	return new(big.Int).Quo(mulDiv(shifted, new(big.Int).Sub(sqrtB, sqrtA), sqrtB), sqrtA)
+}
+
+func amount1ForLiquidity(sqrtA, sqrtB, liquidity *big.Int) *big.Int {
+	sqrtA, sqrtB = sortSqrt(sqrtA, sqrtB)
+
+	return mulDiv(liquidity, new(big.Int).Sub(sqrtB, sqrtA), q96)
+}
+
+func sortSqrt(a, b *big.Int) (*big.Int, *big.Int) {
+	if a.Cmp(b) > 0 {
+		return b, a
+	}
+
+	return a, b
+}
+
+// mulDiv is FullMath.mulDiv for non-negative operands: floor(a*b/denominator).
+// big.Int products don't overflow, so the full 512-bit intermediate that
+// FullMath builds by hand is exact here, e.g. a 2^256-1 fee growth delta
+// times 2^128-1 liquidity.
+func mulDiv(a, b, denominator *big.Int) *big.Int {
+	product := new(big.Int).Mul(a, b)
+	traceHex("mulDiv.product", product)
+	traceHex("mulDiv.denominator", denominator)
+
+	return product.Quo(product, denominator)
+}
+
+func mustBigs(hexes ...string) []*big.Int {
+	values := make([]*big.Int, len(hexes))
+	for i, h := range hexes {
+		v, ok := new(big.Int).SetString(h, 16)
+		if !ok {
+			panic("invalid hex constant " + h)
+		}
+		values[i] = v
+	}
+
+	return values
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+// maxAmountBits is the documented bound of AmountsForLiquidity: amounts of
+// any uint128 liquidity stay below 2^192.
+const maxAmountBits = 192
+
+func TestAmountsForLiquidityMaxLiquidity(t *testing.T) {
+	sqrtA, sqrtB := MinTick.SqrtRatio(), MaxTick.SqrtRatio()
+
+	for _, tc := range []struct {
+		name         string
+		sqrtPriceX96 *big.Int
+	}{
+		{"below range", sqrtA},
+		{"tick 0", q96},
+		{"near MIN_SQRT_RATIO", new(big.Int).Add(sqrtA, big.NewInt(1))},
+		{"near MAX_SQRT_RATIO", new(big.Int).Sub(sqrtB, big.NewInt(1))},
+		{"above range", sqrtB},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			amount0, amount1 := AmountsForLiquidity(tc.sqrtPriceX96, MinTick, MaxTick, maxUint128)
+
+			// one division each, where the implementation divides twice
+			price := clampBig(tc.sqrtPriceX96, sqrtA, sqrtB)
+			want0 := new(big.Int).Lsh(maxUint128, 96)
+			want0.Mul(want0, new(big.Int).Sub(sqrtB, price))
+			want0.Quo(want0, new(big.Int).Mul(sqrtB, price))
+			want1 := new(big.Int).Mul(maxUint128, new(big.Int).Sub(price, sqrtA))
+			want1.Rsh(want1, 96)
+
+			if amount0.Cmp(want0) != 0 {
+				t.Errorf("amount0 = %s, want %s", amount0, want0)
+			}
+			if amount1.Cmp(want1) != 0 {
+				t.Errorf("amount1 = %s, want %s", amount1, want1)
+			}
+			for i, amount := range []*big.Int{amount0, amount1} {
+				if amount.Sign() < 0 || amount.BitLen() > maxAmountBits {
+					t.Errorf("amount%d = %s is outside [0, 2^%d)", i, amount, maxAmountBits)
+				}
+			}
+		})
+	}
+}
+
+func clampBig(v, lo, hi *big.Int) *big.Int {
+	switch {
+	case v.Cmp(lo) < 0:
+		return lo
+	case v.Cmp(hi) > 0:
+		return hi
+	}
+
+	return v
+}
+
+func TestLiquidityForAmountsOverflowsUint128(t *testing.T) {
+	huge := new(big.Int).Lsh(big.NewInt(1), 200)
+
+	liquidity := LiquidityForAmounts(q96, -60, 60, huge, huge)
+	if liquidity.Cmp(maxUint128) <= 0 {
+		t.Errorf("liquidity = %s, want more than max uint128 for 2^200 of each token", liquidity)
+	}
+}
+
+// FullMath.mulDiv cases of the v3-core test suite whose product overflows
+// uint256.
+// https://github.com/Uniswap/v3-core/blob/d8b1c635c275d2a9450bd6a78f3fa2484fef73eb/test/FullMath.spec.ts
+func TestMulDivPhantomOverflow(t *testing.T) {
+	mul := func(x *big.Int, n int64) *big.Int { return new(big.Int).Mul(x, big.NewInt(n)) }
+	div := func(x *big.Int, n int64) *big.Int { return new(big.Int).Quo(x, big.NewInt(n)) }
+
+	for _, tc := range []struct {
+		name          string
+		a, b, d, want *big.Int
+	}{
+		{"all max inputs", maxUint, maxUint, maxUint, maxUint},
+		{"accurate without phantom overflow", q128, div(mul(q128, 50), 100), div(mul(q128, 150), 100), div(q128, 3)},
+		{"accurate with phantom overflow", q128, mul(q128, 35), mul(q128, 8), div(mul(q128, 4375), 1000)},
+		{"accurate with phantom overflow and repeating decimal", q128, mul(q128, 1000), mul(q128, 3000), div(q128, 3)},
+		{"fee growth wrap times max liquidity", maxUint, maxUint128, q128, mustBigs("fffffffffffffffffffffffffffffffeffffffffffffffffffffffffffffffff")[0]},
+	} {
+		if got := mulDiv(tc.a, tc.b, tc.d); got.Cmp(tc.want) != 0 {
+			t.Errorf("%s: mulDiv = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestUncollectedMaxLiquidity(t *testing.T) {
+	one := big.NewInt(1)
+
+	for _, tc := range []struct {
+		name                       string
+		tokensOwed, inside, before *big.Int
+		want                       string
+	}{
+		// inside wrapped past 2^256 to just below insideLast
+		{"max fee growth delta", new(big.Int), new(big.Int), one, "ffffffffffffffffffffffffffffffff"},
+		{"accrual past uint128 is truncated", new(big.Int), new(big.Int).Lsh(one, 200), new(big.Int), "ffffffffffffff000000000000000000"},
+		{"tokensOwed plus accrual wraps", maxUint128, q128, new(big.Int), "fffffffffffffffffffffffffffffffe"},
+		{"no growth", maxUint128, one, one, "ffffffffffffffffffffffffffffffff"},
+	} {
+		got := uncollected(maxUint128, tc.tokensOwed, tc.inside, tc.before)
+		if want := mustBigs(tc.want)[0]; got.Cmp(want) != 0 {
+			t.Errorf("%s: uncollected = %x, want %x", tc.name, got, want)
+		}
+		if got.Sign() < 0 || got.Cmp(maxUint128) > 0 {
+			t.Errorf("%s: uncollected = %s is not a uint128", tc.name, got)
+		}
+	}
+}
+
+func TestSqrtRatio(t *testing.T) {
+	for _, tc := range []struct {
+		tick Tick
+		want string
+	}{
+		// TickMath.MIN_SQRT_RATIO and MAX_SQRT_RATIO
+		{MinTick, "4295128739"},
+		{MaxTick, "1461446703485210103287273052203988822378723970342"},
+		{0, q96.String()},
+	} {
+		if got := tc.tick.SqrtRatio(); got.String() != tc.want {
+			t.Errorf("SqrtRatio(%d) = %s, want %s", tc.tick, got, tc.want)
+		}
+	}
+}
+
+func TestSqrtRatioMatchesFloat(t *testing.T) {
+	prev := MinTick.SqrtRatio()
+	for _, tick := range []Tick{-887271, -500000, -100000, -60, -1, 1, 60, 100000, 500000, 887271} {
+		got := tick.SqrtRatio()
+		if got.Cmp(prev) <= 0 {
+			t.Errorf("SqrtRatio(%d) = %s isn't above the previous tick's %s", tick, got, prev)
+		}
+		prev = got
+
+		want := math.Pow(1.0001, float64(tick)/2) * math.Exp2(96)
+		ratio, _ := new(big.Float).Quo(new(big.Float).SetInt(got), big.NewFloat(want)).Float64()
+		if math.Abs(ratio-1) > 1e-9 {
+			t.Errorf("SqrtRatio(%d) = %s, want about %g", tick, got, want)
+		}
+	}
+}
+
+func TestAmountsForLiquidity(t *testing.T) {
+	liquidity := big.NewInt(1e18)
+	lower, upper := Tick(-600), Tick(600)
+
+	for _, tc := range []struct {
+		name   string
+		tick   Tick
+		a0, a1 bool // whether the amount is non-zero
+	}{
+		{"below range", -1200, true, false},
+		{"at the lower tick", lower, true, false},
+		{"in range", 0, true, true},
+		{"at the upper tick", upper, false, true},
+		{"above range", 1200, false, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			amount0, amount1 := AmountsForLiquidity(tc.tick.SqrtRatio(), lower, upper, liquidity)
+			if (amount0.Sign() > 0) != tc.a0 || (amount1.Sign() > 0) != tc.a1 {
+				t.Fatalf("amounts = %s, %s", amount0, amount1)
+			}
+
+			// L * (1/sqrt(p) - 1/sqrt(pb)) and L * (sqrt(p) - sqrt(pa))
+			sqrt := func(tick Tick) float64 { return math.Pow(1.0001, float64(tick)/2) }
+			p := math.Min(math.Max(sqrt(tc.tick), sqrt(lower)), sqrt(upper))
+			want0 := 1e18 * (1/p - 1/sqrt(upper))
+			want1 := 1e18 * (p - sqrt(lower))
+			for i, c := range []struct {
+				got  *big.Int
+				want float64
+			}{{amount0, want0}, {amount1, want1}} {
+				got, _ := new(big.Float).SetInt(c.got).Float64()
+				if math.Abs(got-c.want) > 1e-6*math.Max(c.want, 1) {
+					t.Errorf("amount%d = %s, want about %g", i, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLiquidityForAmountsRoundTrip(t *testing.T) {
+	amount0, amount1 := big.NewInt(5e17), big.NewInt(3e18)
+	lower, upper := Tick(-600), Tick(600)
+
+	for _, tick := range []Tick{-1200, -600, -60, 0, 60, 599, 600, 1200} {
+		sqrtPrice := tick.SqrtRatio()
+		liquidity := LiquidityForAmounts(sqrtPrice, lower, upper, amount0, amount1)
+		if liquidity.Sign() <= 0 {
+			t.Errorf("tick %d: liquidity = %s", tick, liquidity)
+			continue
+		}
+
+		// minting the liquidity takes no more than what was given
+		needed0, needed1 := AmountsForLiquidity(sqrtPrice, lower, upper, liquidity)
+		if needed0.Cmp(amount0) > 0 || needed1.Cmp(amount1) > 0 {
+			t.Errorf("tick %d: liquidity %s needs %s, %s, more than %s, %s", tick, liquidity, needed0, needed1, amount0, amount1)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// openMetricsType is the Content-Type of the OpenMetrics text format.
+const openMetricsType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// pollsMetric counts the polls of a watched position. OpenMetrics only allows
+// exemplars on counters and histograms, so its exemplar, rather than the
+// gauges, carries the block of the last poll.
+const pollsMetric = "uniswap_position_polls"
+
+// feeMetrics are the gauges -metrics-addr exports per watched position on top
+// of positionMetrics.
+var feeMetrics = []struct {
+	name  string
+	help  string
+	value func(PositionFees) *big.Int
+}{
+	{"uniswap_position_uncollected_fees0", "Fees collect would pay out in raw token0 units.", func(f PositionFees) *big.Int { return f.Fees0 }},
+	{"uniswap_position_uncollected_fees1", "Fees collect would pay out in raw token1 units.", func(f PositionFees) *big.Int { return f.Fees1 }},
+	{"uniswap_position_in_range", "1 while the pool's price is in the position's range, 0 otherwise.", func(f PositionFees) *big.Int {
+		if f.InRange {
+			return big.NewInt(1)
+		}
+		return new(big.Int)
+	}},
+}
+
+// positionSample is the last poll of a watched position.
+type positionSample struct {
+	result PositionResult
+	fees   PositionFees
+	block  uint64
+	polls  uint64
+}
+
+// metricsRegistry holds the last poll of every watched position and serves
+// them to scrapes.
+type metricsRegistry struct {
+	mu      sync.Mutex
+	samples []positionSample
+}
+
+// record reads the fees of update, a poll of q, and makes it the sample of q.
+func (m *metricsRegistry) record(ctx context.Context, client *Client, q PositionQuery, update PositionUpdate) error {
+	block := new(big.Int).SetUint64(update.Block)
+	fees, err := client.feesOf(ctx, q, update.Position, block)
+	if err != nil {
+		return fmt.Errorf("read fees at block %d: %w", update.Block, err)
+	}
+
+	sample := positionSample{
+		result: PositionResult{Pool: q.Pool, Owner: q.Owner, TickLower: q.TickLower, TickUpper: q.TickUpper, Position: update.Position},
+		fees:   fees,
+		block:  update.Block,
+		polls:  1,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, s := range m.samples {
+		if s.result.Pool == q.Pool && s.result.Owner == q.Owner && s.result.TickLower == q.TickLower && s.result.TickUpper == q.TickUpper {
+			sample.polls += s.polls
+			m.samples[i] = sample
+			return nil
+		}
+	}
+	m.samples = append(m.samples, sample)
+
+	return nil
+}
+
+// serveMetrics serves metrics at /metrics on addr until stop is called.
+func serveMetrics(addr string, metrics *metricsRegistry) (stop func(), err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	log.Printf("watch: serving metrics at http://%s/metrics", listener.Addr())
+
+	return func() { server.Close() }, nil
+}
+
+func (m *metricsRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	samples := append([]positionSample(nil), m.samples...)
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", openMetricsType)
+	// a failed write means the scraper is gone; there's no one to tell
+	writeOpenMetrics(w, samples)
+}
+
+// writeOpenMetrics writes samples in the OpenMetrics text format: the gauges
+// of positionMetrics and feeMetrics, and the polls counter with the block of
+// the last poll as its exemplar.
+// https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md
+func writeOpenMetrics(w io.Writer, samples []positionSample) error {
+	var b strings.Builder
+	gauge := func(name, help string, value func(positionSample) *big.Int) {
+		fmt.Fprintf(&b, "# TYPE %s gauge\n# HELP %s %s\n", name, name, help)
+		for _, s := range samples {
+			if v := value(s); v != nil {
+				fmt.Fprintf(&b, "%s{%s} %s\n", name, metricLabels(s.result), v)
+			}
+		}
+	}
+
+	for _, m := range positionMetrics {
+		gauge(m.name, m.help, func(s positionSample) *big.Int { return m.value(s.result.Position) })
+	}
+	for _, m := range feeMetrics {
+		gauge(m.name, m.help, func(s positionSample) *big.Int { return m.value(s.fees) })
+	}
+
+	fmt.Fprintf(&b, "# TYPE %s counter\n# HELP %s Polls of the position.\n", pollsMetric, pollsMetric)
+	for _, s := range samples {
+		fmt.Fprintf(&b, "%s_total{%s} %d # {block=\"%d\"} 1\n", pollsMetric, metricLabels(s.result), s.polls, s.block)
+	}
+	b.WriteString("# EOF\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
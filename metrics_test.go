@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestMetricsRegistry(t *testing.T) {
+	pool := newFakePool(120, 1e18)
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	pool.setPosition(t, q, Position{Liquidity: big.NewInt(1e18), TokensOwed0: big.NewInt(5), TokensOwed1: big.NewInt(0)})
+	client := newFakeClient(t, &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)})
+
+	metrics := &metricsRegistry{}
+	scrape := httptest.NewServer(metrics)
+	t.Cleanup(scrape.Close)
+
+	// at block 99 the price is above the range; at block 100 it is back in
+	// it, which grew 2 token0 of fees per unit of liquidity
+	for _, poll := range []struct {
+		block  uint64
+		tick   Tick
+		growth int64
+	}{{99, 120, 0}, {100, 0, 2}} {
+		pool.tick, pool.sqrtPriceX96 = poll.tick, poll.tick.SqrtRatio()
+		pool.global0 = new(big.Int).Mul(q128, big.NewInt(poll.growth))
+
+		position, err := client.Position(context.Background(), q, new(big.Int).SetUint64(poll.block))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := metrics.record(context.Background(), client, q, PositionUpdate{Block: poll.block, Position: position}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := http.Get(scrape.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/openmetrics-text; version=1.0.0") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	labels := `{pool="` + testPool.Hex() + `",owner="` + testOwner.Hex() + `",tick_lower="-60",tick_upper="60"}`
+	for _, want := range []string{
+		"# TYPE uniswap_position_liquidity gauge\n",
+		"\nuniswap_position_liquidity" + labels + " 1000000000000000000\n",
+		"\nuniswap_position_tokens_owed0" + labels + " 5\n",
+		"\n# TYPE uniswap_position_uncollected_fees0 gauge\n",
+		"\nuniswap_position_uncollected_fees0" + labels + " 2000000000000000005\n",
+		"\nuniswap_position_uncollected_fees1" + labels + " 0\n",
+		"\nuniswap_position_in_range" + labels + " 1\n",
+		"\n# TYPE uniswap_position_polls counter\n",
+		"\nuniswap_position_polls_total" + labels + ` 2 # {block="100"} 1` + "\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("scrape lacks %q:\n%s", want, body)
+		}
+	}
+	if !strings.HasSuffix(string(body), "\n# EOF\n") {
+		t.Errorf("scrape doesn't end with # EOF:\n%s", body)
+	}
+	if n := strings.Count(string(body), "uniswap_position_in_range{"); n != 1 {
+		t.Errorf("got %d in-range samples, want the last poll's only", n)
+	}
+}
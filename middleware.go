@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+// Middleware wraps a ContractCaller to add a cross-cutting concern.
+type Middleware func(next ethereum.ContractCaller) ethereum.ContractCaller
+
+// CallerFunc adapts a function to ethereum.ContractCaller.
+type CallerFunc func(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error)
+
+func (f CallerFunc) CallContract(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	return f(ctx, msg, block)
+}
+
+// chain wraps caller so that middleware[0] runs first.
+func chain(caller ethereum.ContractCaller, middleware ...Middleware) ethereum.ContractCaller {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		caller = middleware[i](caller)
+	}
+
+	return caller
+}
+
+// classifying is the innermost layer: it turns node errors into *CallError so
+// the middleware above it can look at the Kind.
+func classifying(next ethereum.ContractCaller) ethereum.ContractCaller {
+	return CallerFunc(func(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+		response, err := next.CallContract(ctx, msg, block)
+		if err != nil {
+			return nil, &CallError{Kind: classifyError(err), Err: err}
+		}
+
+		return response, nil
+	})
+}
+
+// LoggingMiddleware logs every call with its duration and outcome.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	return func(next ethereum.ContractCaller) ethereum.ContractCaller {
+		return CallerFunc(func(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+			start := time.Now()
+			response, err := next.CallContract(ctx, msg, block)
+			logger.Printf("eth_call to=%s block=%s bytes=%d took=%s err=%v", msg.To, blockString(block), len(msg.Data), time.Since(start), err)
+
+			return response, err
+		})
+	}
+}
+
+// CountingMiddleware adds one to calls for every call that goes through it.
+func CountingMiddleware(calls *atomic.Int64) Middleware {
+	return func(next ethereum.ContractCaller) ethereum.ContractCaller {
+		return CallerFunc(func(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+			calls.Add(1)
+			return next.CallContract(ctx, msg, block)
+		})
+	}
+}
+
+// ErrRetryBudgetExhausted matches, with errors.Is, a call that failed with a
+// retryable error after the retries of the whole run were used up.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetryMiddleware resends calls that failed with a retryable error up to
+// retries times, doubling the delay after each attempt.
+func RetryMiddleware(retries int, backoff time.Duration) Middleware {
+	return BudgetedRetryMiddleware(retries, backoff, nil)
+}
+
+// BudgetedRetryMiddleware is RetryMiddleware with every resend also taken
+// from budget, the retries left for all calls together, so that a flaky node
+// can't multiply the requests of a large scan. Once budget is spent, calls
+// fail on their first retryable error, wrapped with ErrRetryBudgetExhausted.
+// A nil budget is unlimited.
+func BudgetedRetryMiddleware(retries int, backoff time.Duration, budget *atomic.Int64) Middleware {
+	return func(next ethereum.ContractCaller) ethereum.ContractCaller {
+		return CallerFunc(func(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+			delay := backoff
+
+			for attempt := 0; ; attempt++ {
+				response, err := next.CallContract(ctx, msg, block)
+				if err == nil || attempt == retries || !errorKind(err).Retryable() {
+					return response, err
+				}
+				if budget != nil && budget.Add(-1) < 0 {
+					return nil, fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, err)
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil, err
+				case <-time.After(delay):
+				}
+				delay *= 2
+			}
+		})
+	}
+}
+
+func errorKind(err error) ErrorKind {
+	var callErr *CallError
+	if errors.As(err, &callErr) {
+		return callErr.Kind
+	}
+
+	return classifyError(err)
+}
+
+func blockString(block *big.Int) string {
+	if block == nil {
+		return "latest"
+	}
+
+	return block.String()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// scripted is a ContractCaller that fails with errs in turn, then succeeds.
+type scripted struct {
+	errs  []error
+	calls int
+}
+
+func (s *scripted) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	s.calls++
+	if s.calls <= len(s.errs) {
+		return nil, s.errs[s.calls-1]
+	}
+
+	return []byte{1}, nil
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	layer := func(name string) Middleware {
+		return func(next ethereum.ContractCaller) ethereum.ContractCaller {
+			return CallerFunc(func(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+				order = append(order, name)
+				return next.CallContract(ctx, msg, block)
+			})
+		}
+	}
+
+	caller := chain(&scripted{}, layer("a"), layer("b"), layer("c"))
+	if _, err := caller.CallContract(context.Background(), ethereum.CallMsg{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(order, ""); got != "abc" {
+		t.Errorf("middleware ran in order %s, want abc", got)
+	}
+}
+
+func TestLoggingAndRetry(t *testing.T) {
+	next := &scripted{errs: []error{errRateLimited{}, errRateLimited{}}}
+	var logs bytes.Buffer
+	caller := chain(classifying(next), LoggingMiddleware(log.New(&logs, "", 0)), RetryMiddleware(3, time.Millisecond))
+
+	response, err := caller.CallContract(context.Background(), ethereum.CallMsg{Data: []byte{1, 2}}, big.NewInt(7))
+	if err != nil || len(response) != 1 {
+		t.Fatalf("call = %x, %v", response, err)
+	}
+	if next.calls != 3 {
+		t.Errorf("sent %d calls, want 3", next.calls)
+	}
+	out := logs.String()
+	if strings.Count(out, "eth_call ") != 1 || !strings.Contains(out, "block=7 bytes=2") || !strings.Contains(out, "err=<nil>") {
+		t.Errorf("log = %q, want one successful call at block 7", out)
+	}
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		errs  []error
+		calls int
+		// kind is the kind of the returned error, KindUnknown for none
+		kind ErrorKind
+	}{
+		{"revert", []error{errRevert{}}, 1, KindRevert},
+		{"rate limit", []error{errRateLimited{}, errRateLimited{}}, 3, KindUnknown},
+		{"out of retries", []error{errRateLimited{}, errRateLimited{}, errRateLimited{}, errRateLimited{}}, 3, KindRateLimit},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			next := &scripted{errs: tc.errs}
+			caller := chain(classifying(next), RetryMiddleware(2, time.Millisecond))
+
+			_, err := caller.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+			if next.calls != tc.calls {
+				t.Errorf("sent %d calls, want %d", next.calls, tc.calls)
+			}
+			if tc.kind == KindUnknown {
+				if err != nil {
+					t.Errorf("err = %v, want success", err)
+				}
+				return
+			}
+			if kind := errorKind(err); kind != tc.kind {
+				t.Errorf("err = %v of kind %s, want %s", err, kind, tc.kind)
+			}
+		})
+	}
+}
+
+func TestBudgetedRetryMiddleware(t *testing.T) {
+	var budget atomic.Int64
+	budget.Store(1)
+	var calls atomic.Int64
+	next := &scripted{errs: []error{errRateLimited{}, errRateLimited{}, errRateLimited{}}}
+	caller := chain(classifying(next), CountingMiddleware(&calls), BudgetedRetryMiddleware(5, time.Millisecond, &budget))
+
+	_, err := caller.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("err = %v, want ErrRetryBudgetExhausted", err)
+	}
+	if errorKind(err) != KindRateLimit {
+		t.Errorf("err = %v, want the rate limit error wrapped", err)
+	}
+	// the first call and the one retry of the budget
+	if next.calls != 2 || calls.Load() != 1 {
+		t.Errorf("sent %d calls, counted %d, want 2 and 1", next.calls, calls.Load())
+	}
+}
+
+func TestRetryMiddlewareCanceled(t *testing.T) {
+	next := &scripted{errs: []error{errRateLimited{}, errRateLimited{}}}
+	caller := chain(classifying(next), RetryMiddleware(2, time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := caller.CallContract(ctx, ethereum.CallMsg{}, nil); err == nil || next.calls != 1 {
+		t.Errorf("call = %v after %d calls, want the first error", err, next.calls)
+	}
+}
+
+func TestRunRetryBudget(t *testing.T) {
+	node := &fakeNode{head: 100, handle: func(common.Address, []byte) ([]byte, error) {
+		return nil, errRateLimited{}
+	}}
+
+	code, _, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-retries", "5", "-retry-budget", "1")
+	if code != exitNode || !strings.Contains(stderr, "retry budget exhausted") {
+		t.Errorf("exit code %d, want %d; stderr:\n%s", code, exitNode, stderr)
+	}
+	// the read and the one retry of the budget
+	if n := node.count("eth_call"); n != 2 {
+		t.Errorf("node got %d eth_calls, want 2", n)
+	}
+
+	if code, _, stderr := runOutput(t, "-retry-budget", "-1"); code != exitBadInput || !strings.Contains(stderr, "must not be negative") {
+		t.Errorf("negative budget: exit code %d, want %d; stderr:\n%s", code, exitBadInput, stderr)
+	}
+}
@@ -0,0 +1,1209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// session is a run past flag parsing: the values parsed from the flags, and
+// once connected the client, the query and the block every mode reads.
+type session struct {
+	*cli
+
+	poolAddr     common.Address
+	link         PositionLink
+	minLiquidity *big.Int
+	window       time.Duration
+	// compound is the reinvest period of -reinvest
+	compound     time.Duration
+	bfFrom, bfTo uint64
+	reconcileID  *big.Int
+	summaryQuote common.Address
+	base, quote  common.Address
+	warnTokens   map[common.Address]string
+	retryBudget  *atomic.Int64
+	processors   []PositionProcessor
+	abis         ABIProvider
+	layouts      []PositionLayout
+	overrides    map[common.Address]OverrideAccount
+	bundle       []ethereum.CallMsg
+	// collectFrom and collectTo are the block range of -collects, and
+	// activityFrom and activityTo of -activity
+	collectFrom, collectTo   uint64
+	activityFrom, activityTo uint64
+	// entry0 and entry1 are the deposit of -break-even
+	entry0, entry1 *big.Int
+	// projected is the price of -project
+	projected *big.Float
+	// tokenID is the NFT of -token-uri
+	tokenID *big.Int
+
+	client *Client
+	// source serves the position reads of the default mode, through
+	// -nodes, -fallback-node and -archive-dir
+	source    Backend
+	auditFile *os.File
+	// calls counts every RPC call attempt, for the -backfill summary
+	calls atomic.Int64
+
+	query PositionQuery
+	block *big.Int
+	// stale is the warning of a node whose head lags, if any
+	stale string
+}
+
+// newSession parses the flag values checkFlags can't judge by being set.
+func newSession(fs *flag.FlagSet, o *cli) (*session, error) {
+	s := &session{cli: o}
+
+	var ok bool
+	if s.minLiquidity, ok = new(big.Int).SetString(o.minLiq, 10); !ok || s.minLiquidity.Sign() < 0 {
+		return nil, badInput("invalid -min-liquidity: %s", o.minLiq)
+	}
+	if o.lower.keyword == tickMax || o.upper.keyword == tickMin {
+		return nil, badInput("-tick-lower takes min and -tick-upper takes max")
+	}
+	if o.offset < 0 || o.limit < 0 {
+		return nil, badInput("-offset and -limit must not be negative")
+	}
+
+	var err error
+	if o.reinvest != "" {
+		if s.compound, err = parseSince(o.reinvest); err != nil {
+			return nil, badInput("invalid -reinvest: %w", err)
+		}
+	}
+	if o.since != "" {
+		if s.window, err = parseSince(o.since); err != nil {
+			return nil, badInput("invalid -since: %w", err)
+		}
+	}
+	if o.backfill != "" {
+		if s.bfFrom, s.bfTo, err = parseBlockRange(o.backfill); err != nil {
+			return nil, badInput("invalid -backfill: %w", err)
+		}
+		if o.bfStep == 0 || o.workers < 1 {
+			return nil, badInput("-backfill-step and -workers must be positive")
+		}
+	}
+	if o.collects != "" {
+		if s.collectFrom, s.collectTo, err = parseBlockRange(o.collects); err != nil {
+			return nil, badInput("invalid -collects: %w", err)
+		}
+	}
+	if o.activity != "" {
+		if s.activityFrom, s.activityTo, err = parseBlockRange(o.activity); err != nil {
+			return nil, badInput("invalid -activity: %w", err)
+		}
+	}
+	if o.breakEven != "" {
+		if s.entry0, s.entry1, err = parseEntry(o.breakEven); err != nil {
+			return nil, badInput("invalid -break-even: %w", err)
+		}
+	}
+	if o.project != "" {
+		if s.projected, ok = new(big.Float).SetPrec(pricePrec).SetString(o.project); !ok || s.projected.Sign() <= 0 {
+			return nil, badInput("invalid -project: want a positive price, got %q", o.project)
+		}
+	}
+	if o.tokenURI != "" {
+		if s.tokenID, ok = new(big.Int).SetString(o.tokenURI, 10); !ok || s.tokenID.Sign() < 0 {
+			return nil, badInput("invalid -token-uri: want a token id, got %q", o.tokenURI)
+		}
+	}
+	if o.reconcile != "" {
+		if s.reconcileID, ok = new(big.Int).SetString(o.reconcile, 10); !ok || s.reconcileID.Sign() < 0 {
+			return nil, badInput("invalid -reconcile: want a token id, got %q", o.reconcile)
+		}
+	}
+	if _, ok := lookupFormatter(o.format); !ok {
+		return nil, badInput("invalid -format %q: want one of %s", o.format, strings.Join(formatterNames(), ", "))
+	}
+	if o.blockTime < 0 {
+		return nil, badInput("invalid -block-time %s: want a positive duration, or 0 for the chain's", o.blockTime)
+	}
+	if flagGiven(fs, "block-time") && !o.watch && !o.follow {
+		return nil, badInput("-block-time needs -watch or -follow")
+	}
+	if o.list && o.format == formatNDJSON {
+		if o.group || o.profile || o.pushURL != "" {
+			return nil, badInput("-group, -liquidity-profile and -pushgateway print positions once, not streamed with -list -format ndjson")
+		}
+	}
+
+	if o.pin != "" && o.pin != "latest" {
+		n, err := strconv.ParseUint(o.pin, 10, 64)
+		if err != nil {
+			return nil, badInput("invalid -at: want latest or a block number, got %q", o.pin)
+		}
+		if o.atBlock != 0 || o.atL1Block != 0 || o.confirms != 0 {
+			return nil, badInput("-at with a block number can't be combined with -block, -l1-block or -confirmations")
+		}
+		o.atBlock = n
+	}
+
+	if s.poolAddr, err = parseAddress(o.pool); err != nil {
+		return nil, badInput("invalid -pool: %w", err)
+	}
+	if o.linkURL != "" {
+		if s.link, err = parsePositionLink(o.linkURL); err != nil {
+			return nil, badInput("invalid -url: %w", err)
+		}
+		if s.link.Pool != nil {
+			if flagSet(fs, "pool") {
+				return nil, badInput("-url names a pool already, drop -pool")
+			}
+			s.poolAddr = *s.link.Pool
+		} else if name := firstGiven(fs, "watch", "follow", "dashboard", "sign-key", "diagnose-key"); name != "" {
+			return nil, badInput("-url of a position NFT only works with one-shot reads, not -%s", name)
+		}
+	}
+	if err := notAPool(s.poolAddr); err != nil {
+		return nil, badInput("invalid pool: %w", err)
+	}
+
+	if o.summary != "" {
+		if s.summaryQuote, err = parseAddress(o.summary); err != nil {
+			return nil, badInput("invalid -summary: %w", err)
+		}
+	}
+	if o.baseToken != "" {
+		if s.base, err = parseAddress(o.baseToken); err != nil {
+			return nil, badInput("invalid -base: %w", err)
+		}
+		if s.quote, err = parseAddress(o.quoteTok); err != nil {
+			return nil, badInput("invalid -quote: %w", err)
+		}
+	}
+	if s.warnTokens, err = flaggedTokens(o.flagged); err != nil {
+		return nil, badInput("invalid -flagged-tokens: %w", err)
+	}
+
+	if len(o.labels) > 0 {
+		label, err := labelProcessor(o.labels)
+		if err != nil {
+			return nil, badInput("invalid -label: %w", err)
+		}
+		s.processors = append(s.processors, label)
+	}
+
+	if o.poolABIPath != "" || o.npmABIPath != "" {
+		if s.abis, err = readABIs(o.poolABIPath, o.npmABIPath); err != nil {
+			return nil, badInput("read ABI: %w", err)
+		}
+	}
+
+	for _, path := range o.layoutPaths {
+		layout, err := readPositionLayout(path)
+		if err != nil {
+			return nil, badInput("read -position-layout: %w", err)
+		}
+		s.layouts = append(s.layouts, layout)
+	}
+
+	if o.overridePath != "" {
+		if s.overrides, err = readOverrides(o.overridePath); err != nil {
+			return nil, badInput("read -override: %w", err)
+		}
+		if o.backend != BackendRPC || o.dryRun {
+			return nil, badInput("-override needs a node to call, not -backend %s or -dry-run", o.backend)
+		}
+	}
+
+	if o.simulatePath != "" {
+		if s.bundle, err = readTransactions(o.simulatePath); err != nil {
+			return nil, badInput("read -simulate: %w", err)
+		}
+		if o.backend != BackendRPC || o.dryRun {
+			return nil, badInput("-simulate needs a node to call, not -backend %s or -dry-run", o.backend)
+		}
+	}
+
+	if o.retryCap < 0 {
+		return nil, badInput("-retry-budget must not be negative")
+	} else if o.retryCap > 0 {
+		s.retryBudget = new(atomic.Int64)
+		s.retryBudget.Store(o.retryCap)
+	}
+	switch o.backend {
+	case BackendRPC:
+	case BackendArchive:
+		if o.archive == "" {
+			return nil, badInput("-backend archive needs -archive-dir")
+		}
+		if len(o.lbNodes) > 0 {
+			return nil, badInput("-nodes reads from nodes, it can't be combined with -backend archive")
+		}
+		if o.dryRun {
+			return nil, badInput("-dry-run replaces the node, it can't be combined with -backend %s", o.backend)
+		}
+	default:
+		return nil, badInput("invalid -backend: %s", o.backend)
+	}
+
+	return s, nil
+}
+
+// connect dials the node with the middleware and options the flags ask for,
+// checks it is on the chain CHAIN_ID and -url name, and sets up the
+// backends of position reads.
+func (s *session) connect(ctx context.Context, wantChain Chain, chainSet bool) error {
+	var middleware []Middleware
+	if s.verbose {
+		middleware = append(middleware, LoggingMiddleware(log.Default()))
+	}
+	middleware = append(middleware, BudgetedRetryMiddleware(s.retries, 500*time.Millisecond, s.retryBudget))
+	// after the retries, so every attempt counts
+	middleware = append(middleware, CountingMiddleware(&s.calls))
+
+	opts := []Option{WithMiddleware(middleware...), WithBatch(s.batch), WithProxy(s.proxy), WithProcessor(s.processors...)}
+	if s.abis != nil {
+		opts = append(opts, WithABIProvider(s.abis))
+	}
+	if len(s.prices) > 0 {
+		opts = append(opts, WithPriceOverrides(PriceOverrides(s.prices)))
+	}
+	if s.blockTime > 0 {
+		opts = append(opts, WithBlockTime(s.blockTime))
+	}
+	if len(s.layouts) > 0 {
+		opts = append(opts, WithPositionLayouts(append(slices.Clone(defaultPositionLayouts), s.layouts...)...))
+	}
+	if s.auditPath != "" {
+		auditFile, err := os.OpenFile(s.auditPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return badInput("open audit log: %w", err)
+		}
+		s.auditFile = auditFile
+		opts = append(opts, WithAuditLog(auditFile))
+	}
+	primaryOpts := opts
+	if s.backend == BackendArchive {
+		primaryOpts = append(slices.Clip(opts), WithArchive(s.archive))
+	}
+	if s.dryRun {
+		chainID := dryRunChainID(s.node)
+		if chainSet {
+			chainID = wantChain.ID
+		}
+		primaryOpts = append(slices.Clip(opts), WithDryRun(os.Stderr, chainID))
+	}
+
+	client, err := NewClient(ctx, s.node, primaryOpts...)
+	if err != nil {
+		return nodeFailure("conenct to node: %w", err)
+	}
+	s.client = client
+
+	if chainSet && s.backend == BackendRPC {
+		ch, err := client.chain(ctx)
+		if err != nil {
+			return nodeFailure("get chain: %w", err)
+		}
+		if ch.ID != wantChain.ID {
+			return badInput("-node is on %s (%d) but %s is %d", ch.Name, ch.ID, chainIDEnv, wantChain.ID)
+		}
+	}
+
+	if s.linkURL != "" {
+		ch, err := client.chain(ctx)
+		if err != nil {
+			return nodeFailure("get chain: %w", err)
+		}
+		if err := s.link.check(ch); err != nil {
+			return badInput("-url: %w", err)
+		}
+	}
+
+	s.source = client
+	if !s.dryRun && len(s.lbNodes) > 1 {
+		balanced := []Backend{client}
+		for _, url := range s.lbNodes[1:] {
+			c, err := NewClient(ctx, url, primaryOpts...)
+			if err != nil {
+				return nodeFailure("connect to node %s: %w", url, err)
+			}
+			balanced = append(balanced, c)
+		}
+		s.source = NewRoundRobinBackend(defaultEjectFor, balanced...)
+	}
+	if !s.dryRun && (len(s.fallbackNodes) > 0 || s.backend == BackendRPC && s.archive != "") {
+		fallback := FallbackBackend{s.source}
+		for _, url := range s.fallbackNodes {
+			c, err := NewClient(ctx, url, opts...)
+			if err != nil {
+				return nodeFailure("connect to fallback node: %w", err)
+			}
+			fallback = append(fallback, c)
+		}
+		if s.backend == BackendRPC && s.archive != "" {
+			c, err := NewClient(ctx, "", append(slices.Clip(opts), WithArchive(s.archive))...)
+			if err != nil {
+				return nodeFailure("open archive: %w", err)
+			}
+			fallback = append(fallback, c)
+		}
+		s.source = fallback
+	}
+
+	return nil
+}
+
+// close releases what connect opened.
+func (s *session) close() {
+	if s.auditFile != nil {
+		s.auditFile.Close()
+	}
+}
+
+// resolve sets the query and the block of the run: it pins every read to
+// the block for -at, turns min and max ticks into the pool's usable ticks
+// and checks the node's lag.
+func (s *session) resolve(ctx context.Context) error {
+	s.query = PositionQuery{
+		Pool:      s.poolAddr,
+		Owner:     s.owners[0],
+		TickLower: s.lower.tick,
+		TickUpper: s.upper.tick,
+	}
+
+	block, err := s.client.readBlock(ctx, s.atBlock, s.atL1Block, s.confirms)
+	if err != nil {
+		return nodeFailure("get read block: %w", err)
+	}
+	if s.atL1Block != 0 {
+		log.Printf("L1 block %d maps to L2 block %s", s.atL1Block, block)
+	}
+	if s.pin != "" {
+		if block == nil {
+			head, err := s.client.eth.BlockNumber(ctx)
+			if err != nil {
+				return nodeFailure("get block number: %w", err)
+			}
+			block = new(big.Int).SetUint64(head)
+		}
+		s.client.Pin(block)
+		if s.verbose {
+			log.Printf("pinned all reads to block %s", block)
+		}
+	}
+	s.block = block
+
+	if s.lower.keyword != "" || s.upper.keyword != "" {
+		spacing, err := s.client.TickSpacing(ctx, s.poolAddr, block)
+		if err != nil {
+			return nodeFailure("get tick spacing: %w", err)
+		}
+		s.query.TickLower, s.query.TickUpper = s.lower.resolve(spacing), s.upper.resolve(spacing)
+	}
+	if s.verbose && s.atBlock != 0 {
+		// only Arbitrum has the mapping; elsewhere -block is the only number
+		if l1Block, err := s.client.L1BlockNumber(ctx, s.atBlock); err == nil {
+			log.Printf("L2 block %d has L1 block number %d", s.atBlock, l1Block)
+		}
+	}
+
+	// A node that stopped syncing answers latest with old state and no error.
+	if s.maxLag > 0 && s.backend == BackendRPC && !s.dryRun && s.atBlock == 0 && s.atL1Block == 0 {
+		head, lag, err := s.client.HeadLag(ctx)
+		if err != nil {
+			log.Printf("warning: check node lag: %v", err)
+		} else if s.stale = staleWarning(head, lag, s.maxLag); s.stale != "" {
+			log.Print("warning: ", s.stale)
+		}
+	}
+
+	return nil
+}
+
+// checkQuery validates the ticks of the query of the single position modes.
+func (s *session) checkQuery() error {
+	if err := validateTicks(s.query.TickLower, s.query.TickUpper); err != nil {
+		return badInput("invalid ticks: %w", err)
+	}
+
+	return nil
+}
+
+// prepare adds pool details and runs the processors on fetched results.
+func (s *session) prepare(ctx context.Context, results []PositionResult) ([]PositionResult, error) {
+	if err := annotatePools(ctx, s.client, results, s.warnTokens, s.block); err != nil {
+		return nil, nodeFailure("get pools: %w", err)
+	}
+
+	if s.baseToken != "" {
+		orientResults(results, s.base, s.quote)
+	}
+	if s.scaled {
+		if err := annotateDecimals(ctx, s.client, results, s.block); err != nil {
+			return nil, nodeFailure("get token decimals: %w", err)
+		}
+	}
+	if s.stale != "" {
+		for i := range results {
+			results[i].Warnings = append(results[i].Warnings, s.stale)
+		}
+	}
+
+	results, err := s.client.processResults(ctx, results)
+	if err != nil {
+		return nil, fmt.Errorf("process positions: %w", err)
+	}
+
+	return results, nil
+}
+
+// printResults prepares fetched results and prints them, grouped for
+// -group, then pushes their metrics for -pushgateway.
+func (s *session) printResults(ctx context.Context, results []PositionResult) error {
+	results, err := s.prepare(ctx, results)
+	if err != nil {
+		return err
+	}
+
+	if !anyFound(results) {
+		return ErrNotFound
+	}
+
+	if s.profile {
+		profiles, err := poolProfiles(results)
+		if err != nil {
+			return badInput("liquidity profile: %w", err)
+		}
+		if err := writeProfiles(os.Stdout, s.format, profiles); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+	} else if s.group {
+		groups, total, err := s.client.GroupByPool(ctx, results, s.block)
+		if err != nil {
+			return nodeFailure("group positions: %w", err)
+		}
+		if err := writeGroups(os.Stdout, s.format, groups, total, s.fullAddr); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+	} else if err := writeResults(os.Stdout, s.format, results, s.fullAddr); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+
+	if s.pushURL != "" {
+		if err := pushMetrics(ctx, s.pushURL, results); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printSchema is -schema.
+func printSchema(w io.Writer) error {
+	schema, err := outputSchema()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
+// computeKeys is -keys: it fails when any row is invalid, after printing
+// them all.
+func computeKeys(w io.Writer, path, format string) error {
+	rows, err := readKeyRows(path)
+	if err != nil {
+		return badInput("read -keys: %w", err)
+	}
+	if err := writeKeyRows(w, format, rows); err != nil {
+		return err
+	}
+
+	invalid := 0
+	for _, row := range rows {
+		if row.Error != "" {
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return badInput("%d of %d rows of %s are invalid", invalid, len(rows), path)
+	}
+
+	return nil
+}
+
+// decodeCalldata is -decode-calldata.
+func decodeCalldata(w io.Writer, hex string) error {
+	calldata, err := hexutil.Decode(hex)
+	if err != nil {
+		return badInput("invalid -decode-calldata: %w", err)
+	}
+
+	key, err := decodePositionKey(calldata)
+	if err != nil {
+		return badInput("decode calldata: %w", err)
+	}
+	_, err = fmt.Fprintln(w, key.Hex())
+
+	return err
+}
+
+// convertPrice is -price-tick; decimals below zero, not given, are 0.
+func convertPrice(w io.Writer, price string, decimals0, decimals1, tickSpacing int) error {
+	p, ok := new(big.Float).SetPrec(pricePrec).SetString(price)
+	if !ok {
+		return badInput("invalid -price-tick: %q is not a number", price)
+	}
+	if decimals0 > math.MaxUint8 || decimals1 > math.MaxUint8 {
+		return badInput("token decimals must be at most %d", math.MaxUint8)
+	}
+	if tickSpacing < 0 || tickSpacing > maxTickSpacing {
+		return badInput("-tick-spacing must be between 1 and %d", maxTickSpacing)
+	}
+
+	sqrtPriceX96, tick := SqrtPriceX96FromPrice(p, uint8(max(decimals0, 0)), uint8(max(decimals1, 0)))
+	if _, err := fmt.Fprintf(w, "sqrtPriceX96 %s\ntick %d\n", sqrtPriceX96, tick); err != nil || tickSpacing == 0 {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "usable tick %d\n", NearestUsableTick(tick, int32(tickSpacing)))
+
+	return err
+}
+
+// computePool is -pool-of.
+func computePool(w io.Writer, spec, dexName string, chainID uint64) error {
+	dex, ok := dexByName(dexName)
+	if !ok {
+		return badInput("invalid -dex %q: want one of %s", dexName, dexNames())
+	}
+	tokenA, tokenB, fee, err := parsePoolSpec(spec)
+	if err != nil {
+		return badInput("invalid -pool-of: %w", err)
+	}
+
+	pool, err := dex.PoolAddress(chainID, tokenA, tokenB, fee)
+	if err != nil {
+		return badInput("-pool-of: %w", err)
+	}
+	_, err = fmt.Fprintln(w, pool.Hex())
+
+	return err
+}
+
+// runDashboard is -dashboard, redrawn at every block with -watch.
+func (s *session) runDashboard(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	if err := showDashboard(ctx, s.client, s.query, s.block, s.watch, s.fullAddr); err != nil && ctx.Err() == nil {
+		return nodeFailure("show dashboard: %w", err)
+	}
+	return nil
+}
+
+// runWatch is -watch, serving the position's metrics with -metrics-addr.
+func (s *session) runWatch(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	var metrics *metricsRegistry
+	if s.metricsAddr != "" {
+		metrics = &metricsRegistry{}
+		stop, err := serveMetrics(s.metricsAddr, metrics)
+		if err != nil {
+			return badInput("invalid -metrics-addr: %w", err)
+		}
+		defer stop()
+	}
+
+	if err := printUpdates(ctx, s.client, s.query, s.format, metrics); err != nil && ctx.Err() == nil {
+		return nodeFailure("watch position: %w", err)
+	}
+	return nil
+}
+
+// runFollow is -follow, with its fee thresholds and change filter.
+func (s *session) runFollow(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	decimals := [2]int{s.decimals0, s.decimals1}
+	thresholds, err := feeThresholds(ctx, s.client, s.query.Pool, [2]string{s.feeLimit0, s.feeLimit1}, decimals)
+	if err != nil {
+		return badInput("invalid fee threshold: %w", err)
+	}
+
+	var filter *ChangeFilter
+	if s.minDelta0 != "" || s.minDelta1 != "" {
+		minFees, err := feeThresholds(ctx, s.client, s.query.Pool, [2]string{s.minDelta0, s.minDelta1}, decimals)
+		if err != nil {
+			return badInput("invalid minimum change: %w", err)
+		}
+		filter = &ChangeFilter{MinFees0: minFees[0], MinFees1: minFees[1]}
+	}
+
+	if err := followPosition(ctx, s.client, s.query, thresholds, s.aprWindow, filter); err != nil && ctx.Err() == nil {
+		return nodeFailure("follow position: %w", err)
+	}
+	return nil
+}
+
+// runSign is -sign-key: it prints the signed snapshot of the position.
+func (s *session) runSign(ctx context.Context) error {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(s.signKey, "0x"))
+	if err != nil {
+		return badInput("invalid -sign-key: not a hex secp256k1 private key")
+	}
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	attestation, err := s.client.Attest(ctx, s.query, key)
+	if err != nil {
+		return nodeFailure("attest position: %w", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(attestation); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runDiagnoseKey is -diagnose-key.
+func (s *session) runDiagnoseKey(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	salts := make([]common.Hash, len(s.keySalts))
+	for i, salt := range s.keySalts {
+		b, err := hexutil.Decode(salt)
+		if err != nil || len(b) > common.HashLength {
+			return badInput("invalid -key-salt %q: want hex of at most 32 bytes", salt)
+		}
+		salts[i] = common.BytesToHash(b)
+	}
+
+	matches, err := s.client.DiagnoseKey(ctx, s.query, s.block, salts)
+	if err != nil {
+		return nodeFailure("diagnose key: %w", err)
+	}
+	if len(matches) == 0 {
+		return ErrNotFound
+	}
+	for _, m := range matches {
+		fmt.Printf("%s key=%s liquidity=%s\n", m.Name, m.Key.Hex(), m.Position.Liquidity)
+	}
+	return nil
+}
+
+// discoverPositions is -discover: the NFTs of the owners' Transfer logs in
+// the -discover-blocks below the head, or in the -since window.
+func (s *session) discoverPositions(ctx context.Context) ([]PositionResult, error) {
+	head := s.block
+	if head == nil {
+		number, err := s.client.eth.BlockNumber(ctx)
+		if err != nil {
+			return nil, nodeFailure("get block number: %w", err)
+		}
+		head = new(big.Int).SetUint64(number)
+	}
+	from := head.Uint64() - min(s.scanDepth, head.Uint64())
+	if s.window > 0 {
+		first, _, err := s.client.BlockSince(ctx, s.window, head)
+		if err != nil {
+			return nil, nodeFailure("find -since block: %w", err)
+		}
+		from = first.Number.Uint64()
+	}
+
+	tokenIDs, err := s.client.DiscoverTokenIDs(ctx, s.owners, from, head.Uint64())
+	if err != nil {
+		return nil, nodeFailure("discover positions: %w", err)
+	}
+	log.Printf("found %d position NFTs in blocks %d-%d", len(tokenIDs), from, head)
+
+	var batchErr *BatchError
+	positions, err := s.client.TokenPositions(ctx, tokenIDs, s.block)
+	if errors.As(err, &batchErr) {
+		log.Print("warning: partial results: ", err)
+	} else if err != nil {
+		return nil, nodeFailure("get positions: %w", err)
+	}
+
+	return newTokenPositionResults(positions, err), nil
+}
+
+// runSince is -since of a single position.
+func (s *session) runSince(ctx context.Context) error {
+	if s.link.TokenID != nil {
+		return s.runAccurateAPR(ctx)
+	}
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	if s.feeRate {
+		return s.runFeeRate(ctx)
+	}
+
+	accrual, err := s.client.FeesSince(ctx, s.query, s.window, s.block)
+	if errors.Is(err, ErrPositionChanged) {
+		return err
+	} else if err != nil {
+		return nodeFailure("get fees since: %w", err)
+	}
+	if s.compound > 0 {
+		return s.writeReinvested(accrual)
+	}
+	if err := writeAccrual(os.Stdout, s.format, accrual); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// writeReinvested prints accrual with its APR compounded for -reinvest.
+func (s *session) writeReinvested(accrual FeeAccrual) error {
+	r := ReinvestedAccrual{FeeAccrual: accrual, Period: s.compound}
+	if accrual.APR != nil {
+		var err error
+		if r.ReinvestedAPR, err = ReinvestedAPR(accrual.APR, s.compound); err != nil {
+			return badInput("-reinvest: %w", err)
+		}
+	}
+	if err := writeReinvested(os.Stdout, s.format, r); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runAccurateAPR is -since of the NFT of -url, through its liquidity
+// changes.
+func (s *session) runAccurateAPR(ctx context.Context) error {
+	apr, err := s.client.AccurateAPR(ctx, s.link.TokenID, s.window, s.block)
+	if isRevert(err) {
+		return ErrNotFound
+	} else if err != nil {
+		return nodeFailure("get fees since: %w", err)
+	}
+	if err := writeSegmentedAPR(os.Stdout, s.format, apr); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runFeeRate is -since with -fee-rate.
+func (s *session) runFeeRate(ctx context.Context) error {
+	rate, err := s.client.RealizedFeeRate(ctx, s.query, s.window, s.block)
+	if errors.Is(err, ErrPositionChanged) {
+		return err
+	} else if err != nil {
+		return nodeFailure("get fee rate: %w", err)
+	}
+	if err := writeFeeRate(os.Stdout, s.format, rate); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runReconcile is -reconcile: it fails with ErrFeeMismatch when the
+// prediction and the simulated collect differ.
+func (s *session) runReconcile(ctx context.Context) error {
+	rec, err := s.client.ReconcileCollect(ctx, s.reconcileID, s.owners[0], s.block)
+	if errors.Is(err, ErrNotOwner) {
+		return badInput("-reconcile: %w", err)
+	} else if err != nil {
+		return nodeFailure("reconcile fees: %w", err)
+	}
+	if err := writeReconciliation(os.Stdout, s.format, rec); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	if !rec.Agrees() {
+		return ErrFeeMismatch
+	}
+	return nil
+}
+
+// runShare is -share: the part of the pool's active liquidity the
+// position provides, read at one block.
+func (s *session) runShare(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	block, err := s.client.fixedBlock(ctx, s.block)
+	if err != nil {
+		return nodeFailure("get read block: %w", err)
+	}
+	fees, err := s.client.ComputeUncollectedFees(ctx, s.query, block)
+	if err != nil {
+		return nodeFailure("get position: %w", err)
+	}
+	if fees.Liquidity.Sign() == 0 {
+		return ErrNotFound
+	}
+
+	share := PoolShare{Block: block, Tick: fees.Tick, InRange: fees.InRange, Liquidity: fees.Liquidity}
+	if fees.InRange {
+		if share.Share, err = s.client.LiquidityShare(ctx, s.query.Pool, fees.Liquidity, block); err != nil {
+			return nodeFailure("get liquidity share: %w", err)
+		}
+	}
+	if err := writeShare(os.Stdout, s.format, share); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runAmounts is -amounts.
+func (s *session) runAmounts(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	block, err := s.client.fixedBlock(ctx, s.block)
+	if err != nil {
+		return nodeFailure("get read block: %w", err)
+	}
+	amounts, err := s.client.PositionAmountsAt(ctx, s.query, block)
+	if errors.Is(err, ErrStateUnavailable) {
+		return nodeFailure("the node has no state at block %s, -amounts needs an archive node there: %w", block, err)
+	} else if err != nil {
+		return nodeFailure("get position amounts: %w", err)
+	}
+	if amounts.Liquidity.Sign() == 0 {
+		return ErrNotFound
+	}
+	if err := writeAmounts(os.Stdout, s.format, amounts); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runReport is -report.
+func (s *session) runReport(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	r, err := s.client.Report(ctx, s.query, s.block)
+	if err != nil {
+		return nodeFailure("get position report: %w", err)
+	}
+	if r.Position.Liquidity.Sign() == 0 {
+		return ErrNotFound
+	}
+	for _, warning := range r.Warnings {
+		log.Print("warning: ", warning)
+	}
+	if err := writeReport(os.Stdout, s.format, r); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runProject is -project.
+func (s *session) runProject(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	r, err := s.client.Report(ctx, s.query, s.block)
+	if err != nil {
+		return nodeFailure("get position: %w", err)
+	}
+	if r.Position.Liquidity.Sign() == 0 {
+		return ErrNotFound
+	}
+	for _, warning := range r.Warnings {
+		log.Print("warning: ", warning)
+	}
+	p, err := projectReport(r, s.projected)
+	if err != nil {
+		return nodeFailure("project value: %w", err)
+	}
+	if err := writeProjection(os.Stdout, s.format, p); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runExitImpact is -exit-impact, read at the head like
+// WithdrawalPriceImpact.
+func (s *session) runExitImpact(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	q := s.query
+	position, err := s.client.Position(ctx, q, nil)
+	if err != nil {
+		return nodeFailure("get position: %w", err)
+	}
+	if position.Liquidity.Sign() == 0 {
+		return ErrNotFound
+	}
+	impact, err := s.client.WithdrawalPriceImpact(ctx, q.Pool, position.Liquidity, q.TickLower, q.TickUpper)
+	if err != nil {
+		return nodeFailure("get price impact: %w", err)
+	}
+	if err := writeExitImpact(os.Stdout, s.format, ExitImpact{Liquidity: position.Liquidity, Impact: impact}); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runCollects is -collects.
+func (s *session) runCollects(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	q := s.query
+	events, err := s.client.CollectHistory(ctx, q.Pool, q.Owner, q.TickLower, q.TickUpper,
+		new(big.Int).SetUint64(s.collectFrom), new(big.Int).SetUint64(s.collectTo))
+	if err != nil {
+		return nodeFailure("get collect history: %w", err)
+	}
+	if err := writeCollects(os.Stdout, s.format, events); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runActivity is -activity.
+func (s *session) runActivity(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	q := s.query
+	events, err := s.client.PositionActivity(ctx, q.Pool, q.Owner, q.TickLower, q.TickUpper,
+		new(big.Int).SetUint64(s.activityFrom), new(big.Int).SetUint64(s.activityTo))
+	if err != nil {
+		return nodeFailure("get position activity: %w", err)
+	}
+	if err := writeActivity(os.Stdout, s.format, events); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runBreakEven is -break-even: the position's fees are what collect would
+// pay now.
+func (s *session) runBreakEven(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	fees, err := s.client.ComputeUncollectedFees(ctx, s.query, s.block)
+	if err != nil {
+		return nodeFailure("get position: %w", err)
+	}
+	if fees.Liquidity.Sign() == 0 {
+		return ErrNotFound
+	}
+
+	prices, err := BreakEvenPrice(fees.Liquidity, s.query.TickLower, s.query.TickUpper, s.entry0, s.entry1, fees.Fees0, fees.Fees1)
+	if err != nil {
+		return badInput("break-even price: %w", err)
+	}
+	b := BreakEven{Price: PriceFromSqrtPriceX96(fees.SqrtPriceX96, 0, 0), Prices: prices}
+	if err := writeBreakEven(os.Stdout, s.format, b); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runTokenURI is -token-uri, writing the image for -token-image.
+func (s *session) runTokenURI(ctx context.Context) error {
+	meta, err := s.client.PositionTokenURI(ctx, s.tokenID, s.block)
+	if isRevert(err) {
+		// the NPM reverts for tokens never minted or burned
+		return fmt.Errorf("token %s: %w", s.tokenID, ErrNotFound)
+	} else if err != nil {
+		return nodeFailure("get token uri: %w", err)
+	}
+	if s.tokenImage != "" {
+		if meta.ImageData == nil {
+			return fmt.Errorf("token %s has no inline image to write, its image is %q", s.tokenID, meta.Image)
+		}
+		if err := os.WriteFile(s.tokenImage, meta.ImageData, 0o644); err != nil {
+			return fmt.Errorf("write image: %w", err)
+		}
+	}
+	if err := writeTokenURI(os.Stdout, s.format, meta); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runBackfill is -backfill, with its progress on stderr.
+func (s *session) runBackfill(ctx context.Context) error {
+	if err := s.checkQuery(); err != nil {
+		return err
+	}
+
+	var live io.Writer
+	if !s.quiet && isTerminal(os.Stderr) {
+		live = os.Stderr
+	}
+	progress := NewProgress("backfill", live, &s.calls)
+	points, err := s.client.Backfill(ctx, s.query, s.bfFrom, s.bfTo, s.bfStep, s.workers, progress)
+	progress.Finish()
+	if err != nil {
+		return nodeFailure("backfill: %w", err)
+	}
+	if err := writeBackfill(os.Stdout, s.format, points); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// linkedPosition is the NFT position of -url.
+func (s *session) linkedPosition(ctx context.Context) ([]PositionResult, error) {
+	positions, err := s.client.TokenPositions(ctx, []*big.Int{s.link.TokenID}, s.block)
+	if err != nil {
+		return nil, nodeFailure("get position: %w", err)
+	}
+
+	return []PositionResult{newTokenPositionResult(positions[0])}, nil
+}
+
+// runSummary is -list -summary.
+func (s *session) runSummary(ctx context.Context) error {
+	portfolio, err := s.client.PortfolioSummary(ctx, s.owners, s.summaryQuote, s.block)
+	if err != nil {
+		return nodeFailure("summarize positions: %w", err)
+	}
+	if err := writeSummary(os.Stdout, s.format, portfolio); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// runStreamList is -list -format ndjson, printed batch by batch.
+func (s *session) runStreamList(ctx context.Context) error {
+	prepare := func(results []PositionResult) ([]PositionResult, error) { return s.prepare(ctx, results) }
+
+	return streamList(ctx, s.client, s.owners, s.block, Page{Offset: s.offset, Limit: s.limit}, s.minLiquidity, prepare)
+}
+
+// listPositions is -list: the NFT positions of the owners with at least
+// -min-liquidity.
+func (s *session) listPositions(ctx context.Context) ([]PositionResult, error) {
+	positions, err := s.client.ListOwnersPositions(ctx, s.owners, s.block, Page{Offset: s.offset, Limit: s.limit})
+	if err != nil {
+		return nil, nodeFailure("list positions: %w", err)
+	}
+
+	results := make([]PositionResult, 0, len(positions))
+	for _, p := range positions {
+		results = append(results, newTokenPositionResult(p))
+	}
+	total := len(results)
+	results = filterByLiquidity(results, s.minLiquidity)
+	log.Printf("found %d positions, %d with liquidity >= %s", total, len(results), s.minLiquidity)
+
+	return results, nil
+}
+
+// inputPositions are the positions of the -input file.
+func (s *session) inputPositions(ctx context.Context) ([]PositionResult, error) {
+	entries, err := readInput(s.inputPath)
+	if err != nil {
+		return nil, badInput("read input: %w", err)
+	}
+
+	var batchErr *BatchError
+	results, err := fetchEntries(ctx, s.client, entries, s.block)
+	if errors.As(err, &batchErr) {
+		log.Print("warning: partial results: ", err)
+	} else if err != nil {
+		return nil, nodeFailure("get positions: %w", err)
+	}
+
+	return results, nil
+}
+
+// ownerPositions is the default mode: the position of the query for every
+// -owner.
+func (s *session) ownerPositions(ctx context.Context) ([]PositionResult, error) {
+	if err := s.checkQuery(); err != nil {
+		return nil, err
+	}
+
+	queries := make([]PositionQuery, len(s.owners))
+	for i, owner := range s.owners {
+		queries[i] = s.query
+		queries[i].Owner = owner
+	}
+
+	if s.bundle != nil {
+		return s.simulatedPositions(ctx, queries)
+	}
+	if s.overrides != nil {
+		return s.overriddenPositions(ctx, queries)
+	}
+
+	var batchErr *BatchError
+	positions, err := s.source.Positions(ctx, queries, s.block)
+	if errors.As(err, &batchErr) {
+		log.Print("warning: partial results: ", err)
+	} else if err != nil {
+		return nil, nodeFailure("get position: %w", err)
+	}
+
+	return newPositionResults(queries, positions, err), nil
+}
+
+// overriddenPositions are the positions of queries with the state of
+// -override, read one eth_call each since a batch can't carry overrides.
+func (s *session) overriddenPositions(ctx context.Context, queries []PositionQuery) ([]PositionResult, error) {
+	results := make([]PositionResult, len(queries))
+	for i, q := range queries {
+		position, err := s.client.GetPositionWithOverrides(ctx, q, s.block, s.overrides)
+		if err != nil {
+			return nil, nodeFailure("get position with overrides: %w", err)
+		}
+		results[i] = newPositionResult(q, position)
+	}
+
+	return results, nil
+}
+
+// simulatedPositions are the positions of queries after the calls of
+// -simulate, on top of the state of -override if given.
+func (s *session) simulatedPositions(ctx context.Context, queries []PositionQuery) ([]PositionResult, error) {
+	results := make([]PositionResult, len(queries))
+	for i, q := range queries {
+		position, err := s.client.SimulatePosition(ctx, q, s.bundle, s.block, s.overrides)
+		if err != nil {
+			return nil, nodeFailure("simulate position: %w", err)
+		}
+		results[i] = newPositionResult(q, position)
+	}
+
+	return results, nil
+}
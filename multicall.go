@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	abiMulticall3    = `[{"inputs":[{"components":[{"internalType":"address","name":"target","type":"address"},{"internalType":"bool","name":"allowFailure","type":"bool"},{"internalType":"bytes","name":"callData","type":"bytes"}],"internalType":"struct Multicall3.Call3[]","name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"internalType":"bool","name":"success","type":"bool"},{"internalType":"bytes","name":"returnData","type":"bytes"}],"internalType":"struct Multicall3.Result[]","name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+	aggregate3Method = "aggregate3"
+)
+
+// https://www.multicall3.com/deployments
+var multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+var multicall3ABI = mustParseABI(abiMulticall3)
+
+type call struct {
+	Target   common.Address
+	CallData []byte
+}
+
+type call3 struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type call3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// aggregate executes calls with the configured batch backend.
+func (c *Client) aggregate(ctx context.Context, calls []call, block *big.Int) ([][]byte, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	if c.batch == BatchNative {
+		return c.batchNative(ctx, calls, c.at(block))
+	}
+
+	return c.multicall(ctx, calls, block)
+}
+
+// aggregateChunked is aggregate over consecutive chunks of at most size
+// calls, for batches too large for one request or one call's gas limit. The
+// *BatchErrors of the chunks are merged into one indexed like calls.
+func (c *Client) aggregateChunked(ctx context.Context, calls []call, block *big.Int, size int) ([][]byte, error) {
+	responses := make([][]byte, 0, len(calls))
+	var errs []error
+	for start := 0; start < len(calls); start += size {
+		chunk, err := c.aggregate(ctx, calls[start:min(start+size, len(calls))], block)
+		var batchErr *BatchError
+		if errors.As(err, &batchErr) {
+			if errs == nil {
+				errs = make([]error, len(calls))
+			}
+			copy(errs[start:], batchErr.Errs)
+		} else if err != nil {
+			return nil, err
+		}
+		responses = append(responses, chunk...)
+	}
+
+	if errs != nil {
+		return responses, &BatchError{Errs: errs}
+	}
+
+	return responses, nil
+}
+
+// multicall executes calls through Multicall3, or one by one when the chain
+// has no Multicall3 at the canonical address or didn't at block yet. Every
+// call may fail on its own: the ones that revert are left nil and reported
+// in a *BatchError.
+func (c *Client) multicall(ctx context.Context, calls []call, block *big.Int) ([][]byte, error) {
+	deployed, err := c.multicallDeployed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !deployed {
+		return c.callSequential(ctx, calls, block)
+	}
+
+	args := make([]call3, len(calls))
+	for i, cl := range calls {
+		args[i] = call3{Target: cl.Target, AllowFailure: true, CallData: cl.CallData}
+	}
+
+	calldata, err := multicall3ABI.Pack(aggregate3Method, args)
+	if err != nil {
+		return nil, fmt.Errorf("pack multicall: %w", err)
+	}
+
+	response, err := c.callContract(ctx, ethereum.CallMsg{To: &multicall3Address, Data: calldata}, block)
+	if err != nil {
+		return nil, fmt.Errorf("call multicall: %w", err)
+	}
+	if len(response) == 0 {
+		// Multicall3 had no code yet at block: deployed checks the pinned
+		// or latest block, not every historical one
+		c.warnMulticallMissingAt(block)
+		return c.callSequential(ctx, calls, block)
+	}
+
+	out, err := multicall3ABI.Unpack(aggregate3Method, response)
+	if err != nil {
+		return nil, fmt.Errorf("parse multicall result: %w", err)
+	}
+
+	results := *abi.ConvertType(out[0], new([]call3Result)).(*[]call3Result)
+
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("parse multicall result: got %d results for %d calls", len(results), len(calls))
+	}
+
+	responses := make([][]byte, len(results))
+	var errs []error
+	for i, result := range results {
+		if !result.Success {
+			if errs == nil {
+				errs = make([]error, len(calls))
+			}
+			errs[i] = fmt.Errorf("call %s: %w", calls[i].Target, revertError(result.ReturnData))
+			continue
+		}
+		responses[i] = result.ReturnData
+	}
+
+	if errs != nil {
+		return responses, &BatchError{Errs: errs}
+	}
+
+	return responses, nil
+}
+
+// revertError is the *CallError of a call that Multicall3 reports as failed,
+// with the revert reason when returnData is an Error(string).
+func revertError(returnData []byte) error {
+	if reason, err := abi.UnpackRevert(returnData); err == nil {
+		return &CallError{Kind: KindRevert, Err: fmt.Errorf("execution reverted: %s", reason)}
+	}
+	if len(returnData) > 0 {
+		return &CallError{Kind: KindRevert, Err: fmt.Errorf("execution reverted: %#x", returnData)}
+	}
+
+	return &CallError{Kind: KindRevert, Err: errors.New("execution reverted")}
+}
+
+// callSequential sends calls one by one, each with its share of ctx's
+// deadline. Calls that revert or run out of their share, with
+// ErrCallTimeout, are skipped and the others are returned along with a
+// *BatchError; any other failure fails the whole batch.
+func (c *Client) callSequential(ctx context.Context, calls []call, block *big.Int) ([][]byte, error) {
+	responses := make([][]byte, len(calls))
+	var errs []error
+
+	for i, cl := range calls {
+		callCtx, cancel := callBudget(ctx, len(calls)-i)
+		response, err := c.callContract(callCtx, ethereum.CallMsg{To: &cl.Target, Data: cl.CallData}, block)
+		timedOut := callCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+		cancel()
+
+		switch {
+		case err != nil && (timedOut || isRevert(err)):
+			if errs == nil {
+				errs = make([]error, len(calls))
+			}
+			if timedOut {
+				err = ErrCallTimeout
+			}
+			errs[i] = fmt.Errorf("call %s: %w", cl.Target, err)
+		case err != nil:
+			return nil, fmt.Errorf("call %s: %w", cl.Target, err)
+		default:
+			responses[i] = response
+		}
+	}
+
+	if errs != nil {
+		return responses, &BatchError{Errs: errs}
+	}
+
+	return responses, nil
+}
+
+// multicallDeployed checks once per client whether Multicall3 has code at the
+// pinned or latest block. Older blocks may predate the deployment; multicall
+// notices those from their empty response.
+func (c *Client) multicallDeployed(ctx context.Context) (bool, error) {
+	c.multicallMu.Lock()
+	defer c.multicallMu.Unlock()
+
+	if c.multicallChecked {
+		return c.multicallOK, nil
+	}
+
+	code, err := c.eth.CodeAt(ctx, multicall3Address, c.pinned)
+	if err != nil {
+		return false, fmt.Errorf("check multicall3: %w", err)
+	}
+
+	c.multicallChecked = true
+	c.multicallOK = len(code) > 0
+	if !c.multicallOK {
+		log.Printf("warning: multicall3 is not deployed at %s, falling back to sequential calls", multicall3Address)
+	}
+
+	return c.multicallOK, nil
+}
+
+// warnMulticallMissingAt logs, once per client, that Multicall3 didn't exist
+// yet at a block being read.
+func (c *Client) warnMulticallMissingAt(block *big.Int) {
+	c.multicallMu.Lock()
+	defer c.multicallMu.Unlock()
+
+	if !c.multicallHistoryWarned {
+		c.multicallHistoryWarned = true
+		log.Printf("warning: multicall3 has no code at block %s, falling back to sequential calls for blocks before its deployment", blockString(c.at(block)))
+	}
+}
+
+func mustParseABI(s string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	testPool    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	badPool     = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testOwner   = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	testQueries = []PositionQuery{
+		{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60},
+		{Pool: badPool, Owner: testOwner, TickLower: -60, TickUpper: 60},
+		{Pool: testPool, Owner: testOwner, TickLower: -120, TickUpper: 120},
+	}
+)
+
+// positionsHandler answers positions(bytes32) on testPool with a liquidity
+// of 1000 and reverts everything on any other address.
+func positionsHandler(t *testing.T) func(common.Address, []byte) ([]byte, error) {
+	return func(to common.Address, data []byte) ([]byte, error) {
+		if to != testPool {
+			return nil, errRevert{}
+		}
+		if method := methodOf(t, poolABI, data); method.Name != positionsMethod {
+			t.Errorf("unexpected call of %s", method.Name)
+		}
+
+		return packOutputs(t, poolABI, positionsMethod, big.NewInt(1000), new(big.Int), new(big.Int), big.NewInt(7), big.NewInt(8)), nil
+	}
+}
+
+func TestPositionsSequentialFallback(t *testing.T) {
+	node := &fakeNode{head: 100, multicallFrom: -1, handle: positionsHandler(t)}
+	client := newFakeClient(t, node)
+
+	queries := []PositionQuery{testQueries[0], testQueries[2]}
+	positions, err := client.Positions(context.Background(), queries, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, p := range positions {
+		if p.Liquidity.Int64() != 1000 || p.TokensOwed0.Int64() != 7 || p.TokensOwed1.Int64() != 8 {
+			t.Errorf("position %d = %+v", i, p)
+		}
+	}
+	if got := node.count("eth_call"); got != len(queries) {
+		t.Errorf("sent %d eth_calls, want one per query: %d", got, len(queries))
+	}
+
+	// the fallback is decided once per client
+	if _, err := client.Positions(context.Background(), queries, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := node.count("eth_getCode"); got != 1 {
+		t.Errorf("checked Multicall3 code %d times, want 1", got)
+	}
+}
+
+func TestPositionsRevertedEntry(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		multicallFrom int64
+		batch         string
+	}{
+		{"multicall", 0, BatchMulticall},
+		{"sequential", -1, BatchMulticall},
+		{"native", -1, BatchNative},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &fakeNode{head: 100, multicallFrom: tc.multicallFrom, handle: positionsHandler(t)}
+			client := newFakeClient(t, node, WithBatch(tc.batch))
+
+			positions, err := client.Positions(context.Background(), testQueries, nil)
+			var batchErr *BatchError
+			if !errors.As(err, &batchErr) {
+				t.Fatalf("err = %v, want a *BatchError", err)
+			}
+			if len(batchErr.Errs) != len(testQueries) {
+				t.Fatalf("got %d errors for %d queries", len(batchErr.Errs), len(testQueries))
+			}
+			if !isRevert(batchErr.Errs[1]) {
+				t.Errorf("error of the bad pool = %v, want a revert", batchErr.Errs[1])
+			}
+			if !batchErr.reverted() {
+				t.Errorf("reverted() = false for %v", batchErr)
+			}
+
+			for _, i := range []int{0, 2} {
+				if batchErr.Errs[i] != nil {
+					t.Errorf("query %d failed: %v", i, batchErr.Errs[i])
+				}
+				if positions[i].Liquidity == nil || positions[i].Liquidity.Int64() != 1000 {
+					t.Errorf("position %d = %+v", i, positions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateChunkedMergesBatchErrors(t *testing.T) {
+	node := &fakeNode{head: 100, handle: positionsHandler(t)}
+	client := newFakeClient(t, node)
+
+	calls := make([]call, 5)
+	for i := range calls {
+		calls[i] = call{Target: testPool}
+		if i%2 == 1 {
+			calls[i].Target = badPool
+		}
+		calldata, err := client.positionCalldata(testQueries[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		calls[i].CallData = calldata
+	}
+
+	responses, err := client.aggregateChunked(context.Background(), calls, nil, 2)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("err = %v, want a *BatchError", err)
+	}
+	if len(responses) != len(calls) || len(batchErr.Errs) != len(calls) {
+		t.Fatalf("got %d responses and %d errors for %d calls", len(responses), len(batchErr.Errs), len(calls))
+	}
+	for i := range calls {
+		if failed := batchErr.Errs[i] != nil; failed != (i%2 == 1) {
+			t.Errorf("call %d: error %v", i, batchErr.Errs[i])
+		}
+	}
+	if got := node.count("eth_call"); got != 3 {
+		t.Errorf("sent %d eth_calls, want one per chunk: 3", got)
+	}
+}
+
+func TestPositionsBeforeMulticallDeployment(t *testing.T) {
+	node := &fakeNode{head: 100, multicallFrom: 50, handle: positionsHandler(t)}
+	client := newFakeClient(t, node)
+	queries := []PositionQuery{testQueries[0], testQueries[2]}
+
+	for _, tc := range []struct {
+		block int64
+		calls int
+	}{
+		{10, 1 + len(queries)}, // the empty aggregate3, then one by one
+		{60, 1},
+	} {
+		before := node.count("eth_call")
+		positions, err := client.Positions(context.Background(), queries, big.NewInt(tc.block))
+		if err != nil {
+			t.Fatalf("block %d: %v", tc.block, err)
+		}
+		for i, p := range positions {
+			if p.Liquidity == nil || p.Liquidity.Int64() != 1000 {
+				t.Errorf("block %d: position %d = %+v", tc.block, i, p)
+			}
+		}
+		if got := node.count("eth_call") - before; got != tc.calls {
+			t.Errorf("block %d: sent %d eth_calls, want %d", tc.block, got, tc.calls)
+		}
+	}
+}
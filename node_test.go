@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// fakeNode is an in-process JSON-RPC node for tests. Contract calls go to
+// handle; Multicall3 is emulated on top of it from block multicallFrom on,
+// or not at all when multicallFrom is negative.
+type fakeNode struct {
+	chainID       uint64
+	head          uint64
+	multicallFrom int64
+	handle        func(to common.Address, data []byte) ([]byte, error)
+	logs          []types.Log
+	// blockTime is the timestamp of a block, by default 12 seconds apart
+	blockTime func(number uint64) uint64
+	// prunedBelow is the first block with state; calls at older blocks fail
+	// like on a full node
+	prunedBelow uint64
+
+	mu sync.Mutex
+	// requests counts the eth_call and eth_getCode requests by method, and
+	// the JSON-RPC batches as batch
+	requests map[string]int
+	// blocks are the block tags of the calls, in order
+	blocks []string
+	// aggregates are the numbers of calls of the aggregate3 calls, in order
+	aggregates []int
+	// overrides are the state override sets of the calls that had one, in
+	// order
+	overrides []map[common.Address]json.RawMessage
+	// simulations are the calls of the eth_simulateV1 requests, in order
+	simulations [][]simulateCall
+}
+
+// errRevert is a node's execution reverted error.
+type errRevert struct{}
+
+func (errRevert) Error() string  { return "execution reverted" }
+func (errRevert) ErrorCode() int { return codeExecutionReverted }
+
+// newFakeClient starts n and returns a Client reading from it with opts.
+func newFakeClient(t *testing.T, n *fakeNode, opts ...Option) *Client {
+	t.Helper()
+
+	rpcClient, err := rpc.Dial(n.serve(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rpcClient.Close)
+
+	options := clientOptions{batch: BatchMulticall, abis: ABIs{}, layouts: defaultPositionLayouts}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return newClient(ethclient.NewClient(rpcClient), options)
+}
+
+// serve starts n and returns its HTTP URL.
+func (n *fakeNode) serve(t *testing.T) string {
+	t.Helper()
+
+	server := n.server(t)
+
+	// over HTTP to see the JSON-RPC batches
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+			n.mu.Lock()
+			n.requests["batch"]++
+			n.mu.Unlock()
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+
+	return httpServer.URL
+}
+
+// serveIPC starts n on a unix socket and returns its path.
+func (n *fakeNode) serveIPC(t *testing.T) string {
+	t.Helper()
+
+	// a short directory, since socket paths are limited to about 100 bytes
+	dir, err := os.MkdirTemp("", "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "geth.ipc")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	server := n.server(t)
+	go server.ServeListener(listener)
+	t.Cleanup(func() { listener.Close() })
+
+	return path
+}
+
+func (n *fakeNode) server(t *testing.T) *rpc.Server {
+	t.Helper()
+
+	if n.chainID == 0 {
+		n.chainID = 42161
+	}
+	n.requests = make(map[string]int)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &fakeEth{n}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+
+	return server
+}
+
+func (n *fakeNode) count(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.requests[method]
+}
+
+// deployed reports whether Multicall3 has code at the block tag.
+func (n *fakeNode) deployed(block string) bool {
+	if n.multicallFrom < 0 {
+		return false
+	}
+	number := n.head
+	if block != "latest" && block != "pending" {
+		number = hexutil.MustDecodeUint64(block)
+	}
+
+	return number >= uint64(n.multicallFrom)
+}
+
+type fakeEth struct {
+	n *fakeNode
+}
+
+func (e *fakeEth) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(new(big.Int).SetUint64(e.n.chainID))
+}
+
+func (e *fakeEth) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(e.n.head)
+}
+
+func (e *fakeEth) GetBlockByNumber(block string, _ bool) (*types.Header, error) {
+	number := e.n.head
+	if block != "latest" && block != "pending" && block != "safe" && block != "finalized" {
+		var err error
+		if number, err = hexutil.DecodeUint64(block); err != nil {
+			return nil, err
+		}
+	}
+	if number > e.n.head {
+		return nil, nil
+	}
+
+	blockTime := number * 12
+	if e.n.blockTime != nil {
+		blockTime = e.n.blockTime(number)
+	}
+
+	return &types.Header{Number: new(big.Int).SetUint64(number), Time: blockTime, Difficulty: new(big.Int)}, nil
+}
+
+func (e *fakeEth) GetCode(address common.Address, block string) hexutil.Bytes {
+	e.n.mu.Lock()
+	e.n.requests["eth_getCode"]++
+	e.n.mu.Unlock()
+
+	if address == multicall3Address && e.n.deployed(block) {
+		return hexutil.Bytes{0xfe}
+	}
+
+	return nil
+}
+
+func (e *fakeEth) Call(args archiveCallArgs, block string, overrides *map[common.Address]json.RawMessage) (hexutil.Bytes, error) {
+	e.n.mu.Lock()
+	e.n.requests["eth_call"]++
+	e.n.blocks = append(e.n.blocks, block)
+	if overrides != nil {
+		e.n.overrides = append(e.n.overrides, *overrides)
+	}
+	e.n.mu.Unlock()
+
+	calldata := args.Input
+	if calldata == nil {
+		calldata = args.Data
+	}
+	if args.To == nil {
+		return nil, errors.New("call without a target")
+	}
+	if block != "latest" && hexutil.MustDecodeUint64(block) < e.n.prunedBelow {
+		return nil, errors.New("missing trie node 0x1234 (path ) state is not available")
+	}
+	if *args.To != multicall3Address {
+		return e.n.handle(*args.To, calldata)
+	}
+	if !e.n.deployed(block) {
+		// an account without code returns nothing
+		return nil, nil
+	}
+
+	aggregate3 := multicall3ABI.Methods[aggregate3Method]
+	in, err := aggregate3.Inputs.Unpack(calldata[4:])
+	if err != nil {
+		return nil, err
+	}
+	calls := *abi.ConvertType(in[0], new([]call3)).(*[]call3)
+	e.n.mu.Lock()
+	e.n.aggregates = append(e.n.aggregates, len(calls))
+	e.n.mu.Unlock()
+
+	results := make([]call3Result, len(calls))
+	for i, cl := range calls {
+		response, err := e.n.handle(cl.Target, cl.CallData)
+		switch {
+		case errors.As(err, new(errRevert)) && cl.AllowFailure:
+			results[i] = call3Result{}
+		case err != nil:
+			return nil, err
+		default:
+			results[i] = call3Result{Success: true, ReturnData: response}
+		}
+	}
+
+	return aggregate3.Outputs.Pack(results)
+}
+
+// fakeSimulatedCall is a call result of eth_simulateV1.
+type fakeSimulatedCall struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	Status     hexutil.Uint64 `json:"status"`
+	Error      *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// fakeSimulateOptions are eth_simulateV1's options, with the state override
+// sets kept as JSON like eth_call's.
+type fakeSimulateOptions struct {
+	BlockStateCalls []struct {
+		StateOverrides map[common.Address]json.RawMessage `json:"stateOverrides"`
+		Calls          []simulateCall                     `json:"calls"`
+	} `json:"blockStateCalls"`
+}
+
+// SimulateV1 runs the calls of each block through handle in order; a revert
+// fails its call, not the request.
+func (e *fakeEth) SimulateV1(options fakeSimulateOptions, block string) ([]map[string][]fakeSimulatedCall, error) {
+	e.n.mu.Lock()
+	e.n.requests["eth_simulateV1"]++
+	e.n.blocks = append(e.n.blocks, block)
+	for _, b := range options.BlockStateCalls {
+		e.n.simulations = append(e.n.simulations, b.Calls)
+		if b.StateOverrides != nil {
+			e.n.overrides = append(e.n.overrides, b.StateOverrides)
+		}
+	}
+	e.n.mu.Unlock()
+
+	blocks := make([]map[string][]fakeSimulatedCall, len(options.BlockStateCalls))
+	for i, b := range options.BlockStateCalls {
+		calls := make([]fakeSimulatedCall, len(b.Calls))
+		for j, cl := range b.Calls {
+			response, err := e.n.handle(*cl.To, cl.Data)
+			switch {
+			case errors.As(err, new(errRevert)):
+				calls[j].Error = &struct {
+					Message string `json:"message"`
+				}{err.Error()}
+			case err != nil:
+				return nil, err
+			default:
+				calls[j] = fakeSimulatedCall{ReturnData: response, Status: 1}
+			}
+		}
+		blocks[i] = map[string][]fakeSimulatedCall{"calls": calls}
+	}
+
+	return blocks, nil
+}
+
+type fakeFilter struct {
+	FromBlock hexutil.Uint64  `json:"fromBlock"`
+	ToBlock   hexutil.Uint64  `json:"toBlock"`
+	Topics    [][]common.Hash `json:"topics"`
+}
+
+func (e *fakeEth) GetLogs(filter fakeFilter) []types.Log {
+	logs := []types.Log{}
+	for _, l := range e.n.logs {
+		if l.BlockNumber >= uint64(filter.FromBlock) && l.BlockNumber <= uint64(filter.ToBlock) && matchTopics(l.Topics, filter.Topics) {
+			logs = append(logs, l)
+		}
+	}
+
+	return logs
+}
+
+func matchTopics(topics []common.Hash, filter [][]common.Hash) bool {
+	for i, alternatives := range filter {
+		if len(alternatives) == 0 {
+			continue
+		}
+		if i >= len(topics) || !slices.Contains(alternatives, topics[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// packOutputs encodes the outputs of method of parsed, as a contract returns
+// them.
+func packOutputs(t *testing.T, parsed abi.ABI, method string, values ...interface{}) []byte {
+	t.Helper()
+
+	data, err := parsed.Methods[method].Outputs.Pack(values...)
+	if err != nil {
+		t.Fatalf("pack %s outputs: %v", method, err)
+	}
+
+	return data
+}
+
+// methodOf returns the method of parsed that data calls.
+func methodOf(t *testing.T, parsed abi.ABI, data []byte) *abi.Method {
+	t.Helper()
+
+	method, err := parsed.MethodById(data)
+	if err != nil {
+		t.Fatalf("unknown selector %x: %v", data[:4], err)
+	}
+
+	return method
+}
+
+// fakePool is the state of a Uniswap V3 pool for fakeNode.
+type fakePool struct {
+	sqrtPriceX96 *big.Int
+	tick         Tick
+	liquidity    *big.Int
+	fee          int64
+	tickSpacing  int64
+	token0       common.Address
+	token1       common.Address
+	global0      *big.Int
+	global1      *big.Int
+	ticks        map[Tick]fakeTick
+	positions    map[common.Hash]Position
+}
+
+type fakeTick struct {
+	liquidityGross *big.Int
+	liquidityNet   *big.Int
+	outside0       *big.Int
+	outside1       *big.Int
+}
+
+// newFakePool is a pool at tick with liquidity and no fee growth yet.
+func newFakePool(tick Tick, liquidity int64) *fakePool {
+	return &fakePool{
+		sqrtPriceX96: tick.SqrtRatio(),
+		tick:         tick,
+		liquidity:    big.NewInt(liquidity),
+		fee:          3000,
+		tickSpacing:  60,
+		token0:       common.HexToAddress("0x000000000000000000000000000000000000000a"),
+		token1:       common.HexToAddress("0x000000000000000000000000000000000000000b"),
+		global0:      new(big.Int),
+		global1:      new(big.Int),
+		ticks:        make(map[Tick]fakeTick),
+		positions:    make(map[common.Hash]Position),
+	}
+}
+
+// bigOr is v, or zero when v is nil.
+func bigOr(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+
+	return v
+}
+
+// call answers a pool view call.
+func (p *fakePool) call(t *testing.T, data []byte) ([]byte, error) {
+	t.Helper()
+
+	method := methodOf(t, poolABI, data)
+	in, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	switch method.Name {
+	case slot0Method:
+		return packOutputs(t, poolABI, slot0Method, p.sqrtPriceX96, big.NewInt(int64(p.tick)), uint16(0), uint16(1), uint16(1), uint8(0), true), nil
+	case liquidityMethod:
+		return packOutputs(t, poolABI, liquidityMethod, p.liquidity), nil
+	case feeMethod:
+		return packOutputs(t, poolABI, feeMethod, big.NewInt(p.fee)), nil
+	case tickSpacingMethod:
+		return packOutputs(t, poolABI, tickSpacingMethod, big.NewInt(p.tickSpacing)), nil
+	case token0Method:
+		return packOutputs(t, poolABI, token0Method, p.token0), nil
+	case token1Method:
+		return packOutputs(t, poolABI, token1Method, p.token1), nil
+	case feeGrowthGlobal0Method:
+		return packOutputs(t, poolABI, feeGrowthGlobal0Method, p.global0), nil
+	case feeGrowthGlobal1Method:
+		return packOutputs(t, poolABI, feeGrowthGlobal1Method, p.global1), nil
+	case ticksMethod:
+		tick, ok := p.ticks[Tick(in[0].(*big.Int).Int64())]
+		return packOutputs(t, poolABI, ticksMethod,
+			bigOr(tick.liquidityGross), bigOr(tick.liquidityNet), bigOr(tick.outside0), bigOr(tick.outside1),
+			new(big.Int), new(big.Int), uint32(0), ok), nil
+	case positionsMethod:
+		position := p.positions[common.Hash(in[0].([32]byte))]
+		return packOutputs(t, poolABI, positionsMethod,
+			bigOr(position.Liquidity), bigOr(position.FeeGrowthInside0LastX128), bigOr(position.FeeGrowthInside1LastX128),
+			bigOr(position.TokensOwed0), bigOr(position.TokensOwed1)), nil
+	}
+
+	t.Errorf("unexpected pool call of %s", method.Name)
+	return nil, errRevert{}
+}
+
+// setPosition stores position under the key of q.
+func (p *fakePool) setPosition(t *testing.T, q PositionQuery, position Position) {
+	t.Helper()
+
+	key, err := calcPositionKey(q.Owner, q.TickLower, q.TickUpper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.positions[key] = position
+}
+
+// poolsHandler answers calls to pools and decimals() of the tokens in
+// decimals, and reverts everything else.
+func poolsHandler(t *testing.T, pools map[common.Address]*fakePool, decimals map[common.Address]uint8) func(common.Address, []byte) ([]byte, error) {
+	return func(to common.Address, data []byte) ([]byte, error) {
+		if pool, ok := pools[to]; ok {
+			return pool.call(t, data)
+		}
+		if d, ok := decimals[to]; ok {
+			return packOutputs(t, erc20ABI, decimalsMethod, d), nil
+		}
+
+		return nil, errRevert{}
+	}
+}
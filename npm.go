@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	abiNPM                    = `[{"inputs":[{"internalType":"uint256","name":"tokenId","type":"uint256"}],"name":"positions","outputs":[{"internalType":"uint96","name":"nonce","type":"uint96"},{"internalType":"address","name":"operator","type":"address"},{"internalType":"address","name":"token0","type":"address"},{"internalType":"address","name":"token1","type":"address"},{"internalType":"uint24","name":"fee","type":"uint24"},{"internalType":"int24","name":"tickLower","type":"int24"},{"internalType":"int24","name":"tickUpper","type":"int24"},{"internalType":"uint128","name":"liquidity","type":"uint128"},{"internalType":"uint256","name":"feeGrowthInside0LastX128","type":"uint256"},{"internalType":"uint256","name":"feeGrowthInside1LastX128","type":"uint256"},{"internalType":"uint128","name":"tokensOwed0","type":"uint128"},{"internalType":"uint128","name":"tokensOwed1","type":"uint128"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"uint256","name":"tokenId","type":"uint256"}],"name":"ownerOf","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"owner","type":"address"}],"name":"balanceOf","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"owner","type":"address"},{"internalType":"uint256","name":"index","type":"uint256"}],"name":"tokenOfOwnerByIndex","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"uint256","name":"tokenId","type":"uint256"}],"name":"tokenURI","outputs":[{"internalType":"string","name":"","type":"string"}],"stateMutability":"view","type":"function"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"from","type":"address"},{"indexed":true,"internalType":"address","name":"to","type":"address"},{"indexed":true,"internalType":"uint256","name":"tokenId","type":"uint256"}],"name":"Transfer","type":"event"},{"inputs":[{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"recipient","type":"address"},{"internalType":"uint128","name":"amount0Max","type":"uint128"},{"internalType":"uint128","name":"amount1Max","type":"uint128"}],"internalType":"struct INonfungiblePositionManager.CollectParams","name":"params","type":"tuple"}],"name":"collect","outputs":[{"internalType":"uint256","name":"amount0","type":"uint256"},{"internalType":"uint256","name":"amount1","type":"uint256"}],"stateMutability":"payable","type":"function"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"uint256","name":"tokenId","type":"uint256"},{"indexed":false,"internalType":"uint128","name":"liquidity","type":"uint128"},{"indexed":false,"internalType":"uint256","name":"amount0","type":"uint256"},{"indexed":false,"internalType":"uint256","name":"amount1","type":"uint256"}],"name":"IncreaseLiquidity","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"uint256","name":"tokenId","type":"uint256"},{"indexed":false,"internalType":"uint128","name":"liquidity","type":"uint128"},{"indexed":false,"internalType":"uint256","name":"amount0","type":"uint256"},{"indexed":false,"internalType":"uint256","name":"amount1","type":"uint256"}],"name":"DecreaseLiquidity","type":"event"}]`
+	ownerOfMethod             = "ownerOf"
+	balanceOfMethod           = "balanceOf"
+	tokenOfOwnerByIndexMethod = "tokenOfOwnerByIndex"
+	tokenURIMethod            = "tokenURI"
+	collectMethod             = "collect"
+	transferEvent             = "Transfer"
+	increaseLiquidityEvent    = "IncreaseLiquidity"
+	decreaseLiquidityEvent    = "DecreaseLiquidity"
+	abiV3Factory              = `[{"inputs":[{"internalType":"address","name":"","type":"address"},{"internalType":"address","name":"","type":"address"},{"internalType":"uint24","name":"","type":"uint24"}],"name":"getPool","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"}]`
+	getPoolMethod             = "getPool"
+)
+
+// enumerationChunk caps the calls of one batch when walking large
+// portfolios; a wallet with thousands of positions would otherwise need a
+// single multicall of tens of thousands of calls.
+const enumerationChunk = 500
+
+var (
+	npmABI     = mustParseABI(abiNPM)
+	factoryABI = mustParseABI(abiV3Factory)
+)
+
+// TokenPosition is a position held by the NonfungiblePositionManager on behalf
+// of an NFT. Its Position fields are the NPM's own per-token accounting.
+type TokenPosition struct {
+	TokenID   *big.Int
+	Owner     common.Address
+	Pool      common.Address
+	Token0    common.Address
+	Token1    common.Address
+	Fee       uint32
+	TickLower Tick
+	TickUpper Tick
+	Position
+}
+
+type npmPosition struct {
+	Nonce                    *big.Int
+	Operator                 common.Address
+	Token0                   common.Address
+	Token1                   common.Address
+	Fee                      *big.Int
+	TickLower                *big.Int
+	TickUpper                *big.Int
+	Liquidity                *big.Int
+	FeeGrowthInside0LastX128 *big.Int
+	FeeGrowthInside1LastX128 *big.Int
+	TokensOwed0              *big.Int
+	TokensOwed1              *big.Int
+}
+
+// TokenPositions reads NFT positions by token id, together with their owner
+// and pool, in two multicalls. A token whose reads revert, such as a burned
+// or never minted one, is left with only its TokenID set and reported in a
+// *BatchError indexed like tokenIDs, returned with the other positions.
+func (c *Client) TokenPositions(ctx context.Context, tokenIDs []*big.Int, block *big.Int) ([]TokenPosition, error) {
+	if len(tokenIDs) == 0 {
+		return nil, nil
+	}
+
+	ch, err := c.chain(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	calls := make([]call, 0, 2*len(tokenIDs))
+	for _, tokenID := range tokenIDs {
+		positionsData, err := c.abis.NPMABI().Pack(positionsMethod, tokenID)
+		if err != nil {
+			return nil, fmt.Errorf("pack positions: %w", err)
+		}
+		ownerData, err := c.abis.NPMABI().Pack(ownerOfMethod, tokenID)
+		if err != nil {
+			return nil, fmt.Errorf("pack ownerOf: %w", err)
+		}
+		calls = append(calls, call{Target: ch.NPM, CallData: positionsData}, call{Target: ch.NPM, CallData: ownerData})
+	}
+
+	responses, err := c.aggregateChunked(ctx, calls, block, enumerationChunk)
+	var batchErr *BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		return nil, err
+	}
+
+	var errs []error
+	result := make([]TokenPosition, len(tokenIDs))
+	poolCalls := make([]call, 0, len(tokenIDs))
+	poolIdx := make([]int, 0, len(tokenIDs))
+	for i, tokenID := range tokenIDs {
+		result[i].TokenID = tokenID
+		if batchErr != nil {
+			if err := errors.Join(batchErr.Errs[2*i], batchErr.Errs[2*i+1]); err != nil {
+				if errs == nil {
+					errs = make([]error, len(tokenIDs))
+				}
+				errs[i] = fmt.Errorf("token %s: %w", tokenID, err)
+				continue
+			}
+		}
+
+		var raw npmPosition
+		if err := c.abis.NPMABI().UnpackIntoInterface(&raw, positionsMethod, responses[2*i]); err != nil {
+			return nil, fmt.Errorf("parse positions of token %s: %w", tokenID, err)
+		}
+		out, err := c.abis.NPMABI().Unpack(ownerOfMethod, responses[2*i+1])
+		if err != nil {
+			return nil, fmt.Errorf("parse owner of token %s: %w", tokenID, err)
+		}
+
+		result[i] = TokenPosition{
+			TokenID:   tokenID,
+			Owner:     out[0].(common.Address),
+			Token0:    raw.Token0,
+			Token1:    raw.Token1,
+			Fee:       uint32(raw.Fee.Uint64()),
+			TickLower: Tick(raw.TickLower.Int64()),
+			TickUpper: Tick(raw.TickUpper.Int64()),
+			Position: Position{
+				Liquidity:                raw.Liquidity,
+				FeeGrowthInside0LastX128: raw.FeeGrowthInside0LastX128,
+				FeeGrowthInside1LastX128: raw.FeeGrowthInside1LastX128,
+				TokensOwed0:              raw.TokensOwed0,
+				TokensOwed1:              raw.TokensOwed1,
+			},
+		}
+
+		getPoolData, err := c.abis.FactoryABI().Pack(getPoolMethod, raw.Token0, raw.Token1, raw.Fee)
+		if err != nil {
+			return nil, fmt.Errorf("pack getPool: %w", err)
+		}
+		poolCalls = append(poolCalls, call{Target: ch.Factory, CallData: getPoolData})
+		poolIdx = append(poolIdx, i)
+	}
+
+	responses, err = c.aggregateChunked(ctx, poolCalls, block, enumerationChunk)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, response := range responses {
+		out, err := c.abis.FactoryABI().Unpack(getPoolMethod, response)
+		if err != nil {
+			return nil, fmt.Errorf("parse pool of token %s: %w", tokenIDs[poolIdx[j]], err)
+		}
+		result[poolIdx[j]].Pool = out[0].(common.Address)
+	}
+
+	if errs != nil {
+		return result, &BatchError{Errs: errs}
+	}
+
+	return result, nil
+}
+
+// Page selects a window of positions by enumeration order. A zero Limit
+// means every position from Offset on.
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+// ListOwnerPositions returns the NFT positions owned by owner in page,
+// including closed ones.
+func (c *Client) ListOwnerPositions(ctx context.Context, owner common.Address, block *big.Int, page Page) ([]TokenPosition, error) {
+	return c.ListOwnersPositions(ctx, []common.Address{owner}, block, page)
+}
+
+// ListOwnersPositions returns the NFT positions of all owners, grouped by
+// owner in the given order, with page applied to the combined list. Balances
+// are read in one batch, token ids and positions in batches of at most
+// enumerationChunk calls.
+func (c *Client) ListOwnersPositions(ctx context.Context, owners []common.Address, block *big.Int, page Page) ([]TokenPosition, error) {
+	ch, err := c.chain(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	calls := make([]call, len(owners))
+	for i, owner := range owners {
+		if calls[i].CallData, err = c.abis.NPMABI().Pack(balanceOfMethod, owner); err != nil {
+			return nil, fmt.Errorf("pack balanceOf: %w", err)
+		}
+		calls[i].Target = ch.NPM
+	}
+
+	responses, err := c.aggregate(ctx, calls, block)
+	if err != nil {
+		return nil, err
+	}
+
+	skip, left := int64(page.Offset), int64(page.Limit)
+	calls = calls[:0]
+	for i, response := range responses {
+		out, err := c.abis.NPMABI().Unpack(balanceOfMethod, response)
+		if err != nil {
+			return nil, fmt.Errorf("parse balanceOf %s: %w", owners[i], err)
+		}
+
+		balance := out[0].(*big.Int).Int64()
+		start := min(skip, balance)
+		skip -= start
+		end := balance
+		if page.Limit > 0 {
+			end = min(balance, start+left)
+			left -= end - start
+		}
+
+		for j := start; j < end; j++ {
+			calldata, err := c.abis.NPMABI().Pack(tokenOfOwnerByIndexMethod, owners[i], big.NewInt(j))
+			if err != nil {
+				return nil, fmt.Errorf("pack tokenOfOwnerByIndex: %w", err)
+			}
+			calls = append(calls, call{Target: ch.NPM, CallData: calldata})
+		}
+	}
+
+	responses, err = c.aggregateChunked(ctx, calls, block, enumerationChunk)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenIDs := make([]*big.Int, len(responses))
+	for i, response := range responses {
+		out, err := c.abis.NPMABI().Unpack(tokenOfOwnerByIndexMethod, response)
+		if err != nil {
+			return nil, fmt.Errorf("parse tokenOfOwnerByIndex: %w", err)
+		}
+		tokenIDs[i] = out[0].(*big.Int)
+	}
+
+	return c.TokenPositions(ctx, tokenIDs, block)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// fakeNPM is the NonfungiblePositionManager state for fakeNode: the token
+// ids of each owner, in enumeration order, with the liquidity of a token id
+// defaulting to 500. Every other token id reverts, like a burned one, and
+// every factory getPool returns testPool. Calls to other contracts go to
+// next, or revert without it.
+type fakeNPM struct {
+	tokens    map[common.Address][]int64
+	liquidity map[int64]int64
+	next      func(common.Address, []byte) ([]byte, error)
+}
+
+var arbitrumFactory = common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984")
+
+func (n *fakeNPM) owner(id int64) (common.Address, bool) {
+	for owner, ids := range n.tokens {
+		if slices.Contains(ids, id) {
+			return owner, true
+		}
+	}
+
+	return common.Address{}, false
+}
+
+func (n *fakeNPM) handler(t *testing.T) func(common.Address, []byte) ([]byte, error) {
+	token0 := common.HexToAddress("0x000000000000000000000000000000000000000a")
+	token1 := common.HexToAddress("0x000000000000000000000000000000000000000b")
+
+	return func(to common.Address, data []byte) ([]byte, error) {
+		switch {
+		case to == arbitrumFactory:
+			if method := methodOf(t, factoryABI, data); method.Name != getPoolMethod {
+				t.Errorf("unexpected factory call of %s", method.Name)
+			}
+			return packOutputs(t, factoryABI, getPoolMethod, testPool), nil
+		case to != arbitrumNPM && n.next != nil:
+			return n.next(to, data)
+		case to != arbitrumNPM:
+			return nil, errRevert{}
+		}
+
+		method := methodOf(t, npmABI, data)
+		in, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch method.Name {
+		case balanceOfMethod:
+			return packOutputs(t, npmABI, balanceOfMethod, big.NewInt(int64(len(n.tokens[in[0].(common.Address)])))), nil
+		case tokenOfOwnerByIndexMethod:
+			ids, index := n.tokens[in[0].(common.Address)], in[1].(*big.Int).Int64()
+			if index >= int64(len(ids)) {
+				return nil, errRevert{}
+			}
+			return packOutputs(t, npmABI, tokenOfOwnerByIndexMethod, big.NewInt(ids[index])), nil
+		}
+
+		id := in[0].(*big.Int).Int64()
+		owner, ok := n.owner(id)
+		if !ok {
+			return nil, errRevert{}
+		}
+		switch method.Name {
+		case positionsMethod:
+			liquidity, ok := n.liquidity[id]
+			if !ok {
+				liquidity = 500
+			}
+			return packOutputs(t, npmABI, positionsMethod,
+				new(big.Int), common.Address{}, token0, token1, big.NewInt(3000), big.NewInt(-60), big.NewInt(60),
+				big.NewInt(liquidity), new(big.Int), new(big.Int), new(big.Int), new(big.Int)), nil
+		case ownerOfMethod:
+			return packOutputs(t, npmABI, ownerOfMethod, owner), nil
+		}
+		t.Errorf("unexpected NPM call of %s", method.Name)
+		return nil, errRevert{}
+	}
+}
+
+func TestTokenPositionsBurnedID(t *testing.T) {
+	node := &fakeNode{head: 100, handle: (&fakeNPM{tokens: map[common.Address][]int64{testOwner: {1, 3}}}).handler(t)}
+	client := newFakeClient(t, node)
+
+	tokenIDs := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+	positions, err := client.TokenPositions(context.Background(), tokenIDs, nil)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("err = %v, want a *BatchError", err)
+	}
+	if len(positions) != len(tokenIDs) || len(batchErr.Errs) != len(tokenIDs) {
+		t.Fatalf("got %d positions and %d errors for %d token ids", len(positions), len(batchErr.Errs), len(tokenIDs))
+	}
+
+	if !isRevert(batchErr.Errs[1]) {
+		t.Errorf("error of the burned token = %v, want a revert", batchErr.Errs[1])
+	}
+	if positions[1].TokenID.Int64() != 2 {
+		t.Errorf("burned position has token id %s, want 2", positions[1].TokenID)
+	}
+	for _, i := range []int{0, 2} {
+		p := positions[i]
+		if batchErr.Errs[i] != nil {
+			t.Errorf("token %s failed: %v", p.TokenID, batchErr.Errs[i])
+		}
+		if p.Owner != testOwner || p.Pool != testPool || p.Liquidity.Int64() != 500 || p.TickLower != -60 || p.TickUpper != 60 {
+			t.Errorf("position %d = %+v", i, p)
+		}
+	}
+
+	results := newTokenPositionResults(positions, err)
+	if results[1].Error == "" || results[0].Error != "" {
+		t.Errorf("result errors = %q, %q", results[0].Error, results[1].Error)
+	}
+}
+
+func TestListOwnersPositions(t *testing.T) {
+	other := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {5, 1}, other: {7}}}
+	node := &fakeNode{head: 100, handle: npm.handler(t)}
+	client := newFakeClient(t, node)
+
+	positions, err := client.ListOwnersPositions(context.Background(), []common.Address{testOwner, other}, nil, Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// grouped by owner in the given order, each in enumeration order
+	want := []struct {
+		id    int64
+		owner common.Address
+	}{{5, testOwner}, {1, testOwner}, {7, other}}
+	if len(positions) != len(want) {
+		t.Fatalf("got %d positions, want %d", len(positions), len(want))
+	}
+	for i, w := range want {
+		if p := positions[i]; p.TokenID.Int64() != w.id || p.Owner != w.owner || p.Pool != testPool {
+			t.Errorf("position %d = token %s of %s in %s, want token %d of %s", i, p.TokenID, p.Owner, p.Pool, w.id, w.owner)
+		}
+	}
+	// the balances in one batch, then the token ids, the positions and
+	// their pools
+	if n := node.count("eth_call"); n != 4 {
+		t.Errorf("sent %d eth_calls, want 4", n)
+	}
+}
+
+func TestListTwoOwners(t *testing.T) {
+	other := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {1, 2}, other: {3}}}
+
+	code, stdout, stderr := runOutput(t, "-node", listNode(t, npm).serve(t), "-max-lag", "0", "-list", "-owner", testOwner.Hex()+","+other.Hex(), "-format", "json")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+
+	var results []PositionResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, stdout)
+	}
+	owners := make(map[int64]common.Address)
+	for _, r := range results {
+		owners[r.TokenID.Int64()] = r.Owner
+	}
+	if len(results) != 3 || owners[1] != testOwner || owners[2] != testOwner || owners[3] != other {
+		t.Errorf("listed token owners %v", owners)
+	}
+}
+
+// tokenRange is the token ids from, from+1, ... n of them.
+func tokenRange(from, n int64) []int64 {
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = from + int64(i)
+	}
+
+	return ids
+}
+
+func TestListOwnersPositionsPage(t *testing.T) {
+	other := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: tokenRange(1000, 250), other: tokenRange(5000, 10)}}
+	client := newFakeClient(t, &fakeNode{head: 100, handle: npm.handler(t)})
+
+	for _, tc := range []struct {
+		page Page
+		want []int64
+	}{
+		{Page{}, append(tokenRange(1000, 250), tokenRange(5000, 10)...)},
+		{Page{Offset: 100, Limit: 120}, tokenRange(1100, 120)},
+		// across the two owners
+		{Page{Offset: 245, Limit: 10}, append(tokenRange(1245, 5), tokenRange(5000, 5)...)},
+		{Page{Offset: 255}, tokenRange(5005, 5)},
+		{Page{Offset: 260}, nil},
+		{Page{Offset: 1000, Limit: 5}, nil},
+	} {
+		positions, err := client.ListOwnersPositions(context.Background(), []common.Address{testOwner, other}, nil, tc.page)
+		if err != nil {
+			t.Fatalf("%+v: %v", tc.page, err)
+		}
+		got := make([]int64, len(positions))
+		for i, p := range positions {
+			got[i] = p.TokenID.Int64()
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%+v: token ids %v, want %v", tc.page, got, tc.want)
+		}
+	}
+}
+
+func TestListOwnerPositionsChunks(t *testing.T) {
+	const balance = 1200
+	npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: tokenRange(1, balance)}}
+	node := &fakeNode{head: 100, handle: npm.handler(t)}
+	client := newFakeClient(t, node)
+
+	positions, err := client.ListOwnerPositions(context.Background(), testOwner, nil, Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != balance {
+		t.Fatalf("got %d positions, want %d", len(positions), balance)
+	}
+
+	// the balance, then the token ids in chunks
+	if got := node.aggregates[:4]; !slices.Equal(got, []int{1, enumerationChunk, enumerationChunk, balance - 2*enumerationChunk}) {
+		t.Errorf("first multicalls have %v calls", got)
+	}
+	for i, n := range node.aggregates {
+		if n > enumerationChunk {
+			t.Errorf("multicall %d has %d calls, more than %d", i, n, enumerationChunk)
+		}
+	}
+}
+
+func TestListPageFlags(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		code int
+		ids  []int64
+	}{
+		{[]string{"-offset", "1", "-limit", "2"}, exitOK, []int64{2, 3}},
+		{[]string{"-offset", "1", "-limit", "2", "-format", "ndjson"}, exitOK, []int64{2, 3}},
+		{[]string{"-offset", "4"}, exitNotFound, nil},
+		{[]string{"-offset", "-1"}, exitBadInput, nil},
+		{[]string{"-limit", "-1"}, exitBadInput, nil},
+	} {
+		npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {1, 2, 3, 4}}}
+		args := append([]string{"-node", listNode(t, npm).serve(t), "-max-lag", "0", "-list", "-owner", testOwner.Hex(), "-format", "json"}, tc.args...)
+
+		code, stdout, stderr := runOutput(t, args...)
+		if code != tc.code {
+			t.Errorf("%q: exit code %d, want %d; stderr:\n%s", tc.args, code, tc.code, stderr)
+			continue
+		}
+		if got := tokenIDs(t, stdout); !slices.Equal(got, tc.ids) {
+			t.Errorf("%q: listed token ids %v, want %v", tc.args, got, tc.ids)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Orientation maps a pool's token0/token1 onto the base/quote pair a user
+// thinks in, e.g. WETH/USDC whichever of them sorts first.
+type Orientation struct {
+	Base  common.Address
+	Quote common.Address
+	// Flipped means base is token1.
+	Flipped bool
+}
+
+// NewOrientation orients a pool with token0 and token1 for base/quote.
+func NewOrientation(token0, token1, base, quote common.Address) (Orientation, error) {
+	switch {
+	case base == token0 && quote == token1:
+		return Orientation{Base: base, Quote: quote}, nil
+	case base == token1 && quote == token0:
+		return Orientation{Base: base, Quote: quote, Flipped: true}, nil
+	default:
+		return Orientation{}, fmt.Errorf("pool tokens %s/%s are not %s/%s", token0, token1, base, quote)
+	}
+}
+
+// Amounts returns token0 and token1 amounts as base and quote amounts.
+func (o Orientation) Amounts(amount0, amount1 *big.Int) (base, quote *big.Int) {
+	if o.Flipped {
+		return amount1, amount0
+	}
+
+	return amount0, amount1
+}
+
+// Price is the raw quote per base price at sqrtPriceX96, no decimals applied.
+func (o Orientation) Price(sqrtPriceX96 *big.Int) *big.Float {
+	sqrtPrice := new(big.Float).SetInt(sqrtPriceX96)
+	price := new(big.Float).Mul(sqrtPrice, sqrtPrice)
+	price.Quo(price, new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 192)))
+
+	if o.Flipped {
+		return price.Quo(big.NewFloat(1), price)
+	}
+
+	return price
+}
+
+// BaseQuote is a result's owed amounts in base/quote terms.
+type BaseQuote struct {
+	Base      common.Address `json:"base"`
+	Quote     common.Address `json:"quote"`
+	OwedBase  *big.Int       `json:"owedBase"`
+	OwedQuote *big.Int       `json:"owedQuote"`
+}
+
+func (b *BaseQuote) String() string {
+	return fmt.Sprintf("{Base:%s Quote:%s OwedBase:%s OwedQuote:%s}", b.Base, b.Quote, b.OwedBase, b.OwedQuote)
+}
+
+// orientResults fills BaseQuote of results whose pool trades base against
+// quote and warns on the others. Pool tokens must already be annotated.
+func orientResults(results []PositionResult, base, quote common.Address) {
+	for i := range results {
+		r := &results[i]
+
+		o, err := NewOrientation(r.Token0, r.Token1, base, quote)
+		if err != nil {
+			r.Warnings = append(r.Warnings, err.Error())
+			continue
+		}
+
+		owedBase, owedQuote := o.Amounts(r.Position.TokensOwed0, r.Position.TokensOwed1)
+		r.BaseQuote = &BaseQuote{Base: base, Quote: quote, OwedBase: owedBase, OwedQuote: owedQuote}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// USDC sorts before WETH on Arbitrum, so a WETH/USDC pool has USDC as
+	// token0
+	arbUSDC = common.HexToAddress("0xaf88d065e77c8cC2239327C5EDb3A432268e5831")
+	arbWETH = common.HexToAddress("0x82aF49447D8a07e3bd95BD0d56f35241523fBab1")
+)
+
+func TestOrientationBaseIsToken1(t *testing.T) {
+	o, err := NewOrientation(arbUSDC, arbWETH, arbWETH, arbUSDC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !o.Flipped || o.Base != arbWETH || o.Quote != arbUSDC {
+		t.Errorf("orientation = %+v", o)
+	}
+
+	// 2 WETH (token1) and 3000 USDC (token0) owed
+	base, quote := o.Amounts(big.NewInt(3000e6), big.NewInt(2e18))
+	if base.Int64() != 2e18 || quote.Int64() != 3000e6 {
+		t.Errorf("base, quote = %s, %s", base, quote)
+	}
+
+	// token1 per token0 is 4, so 0.25 of token0 per token1
+	sqrtPriceX96 := new(big.Int).Lsh(big.NewInt(2), 96)
+	if price, _ := o.Price(sqrtPriceX96).Float64(); price != 0.25 {
+		t.Errorf("flipped price = %g, want 0.25", price)
+	}
+	unflipped, err := NewOrientation(arbUSDC, arbWETH, arbUSDC, arbWETH)
+	if err != nil || unflipped.Flipped {
+		t.Fatalf("orientation = %+v, %v", unflipped, err)
+	}
+	if price, _ := unflipped.Price(sqrtPriceX96).Float64(); price != 4 {
+		t.Errorf("price = %g, want 4", price)
+	}
+}
+
+func TestOrientResults(t *testing.T) {
+	results := []PositionResult{
+		{Token0: arbUSDC, Token1: arbWETH, Position: Position{TokensOwed0: big.NewInt(5), TokensOwed1: big.NewInt(7)}},
+		{Token0: arbUSDC, Token1: common.HexToAddress("0x000000000000000000000000000000000000000b")},
+	}
+	orientResults(results, arbWETH, arbUSDC)
+
+	if bq := results[0].BaseQuote; bq == nil || bq.OwedBase.Int64() != 7 || bq.OwedQuote.Int64() != 5 || bq.Base != arbWETH {
+		t.Errorf("base/quote = %v", bq)
+	}
+	if results[1].BaseQuote != nil || len(results[1].Warnings) != 1 || !strings.Contains(results[1].Warnings[0], "are not") {
+		t.Errorf("result in another pool = %v, %q", results[1].BaseQuote, results[1].Warnings)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// overrideSlot is a storage slot of testPool the tests override.
+var overrideSlot = common.HexToHash("0x01")
+
+const testOverrides = `{"0x1111111111111111111111111111111111111111": {
+	"balance": "0x10",
+	"nonce": "0x2",
+	"code": "0xfe",
+	"stateDiff": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x00000000000000000000000000000000000000000000000000000000000003e8"}
+}}`
+
+// overrideNode answers positions with liquidity 1000 when called with a
+// state override set and 0 without one.
+func overrideNode(t *testing.T) *fakeNode {
+	node := &fakeNode{head: 100}
+	node.handle = func(to common.Address, data []byte) ([]byte, error) {
+		if methodOf(t, poolABI, data).Name != positionsMethod {
+			return make([]byte, 32), nil
+		}
+		node.mu.Lock()
+		overridden := len(node.overrides) > 0
+		node.mu.Unlock()
+
+		liquidity := new(big.Int)
+		if overridden {
+			liquidity.SetInt64(1000)
+		}
+		return packOutputs(t, poolABI, positionsMethod, liquidity, new(big.Int), new(big.Int), new(big.Int), new(big.Int)), nil
+	}
+
+	return node
+}
+
+func TestReadOverrides(t *testing.T) {
+	overrides, err := readOverrides(writeInput(t, testOverrides))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, ok := overrides[testPool]
+	if !ok || len(overrides) != 1 {
+		t.Fatalf("overrides = %+v, want testPool's", overrides)
+	}
+	if o.Balance.Int64() != 16 || o.Nonce != 2 || len(o.Code) != 1 || o.Code[0] != 0xfe || o.State != nil {
+		t.Errorf("override = %+v", o)
+	}
+	if got := o.StateDiff[overrideSlot]; got != common.BigToHash(big.NewInt(1000)) {
+		t.Errorf("slot 1 = %s, want 1000", got)
+	}
+
+	for _, content := range []string{"{", "{}", `{"0x1234": {}}`} {
+		if _, err := readOverrides(writeInput(t, content)); err == nil {
+			t.Errorf("readOverrides(%s) succeeded", content)
+		}
+	}
+}
+
+func TestGetPositionWithOverrides(t *testing.T) {
+	overrides, err := readOverrides(writeInput(t, testOverrides))
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := overrideNode(t)
+	client := newFakeClient(t, node)
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+
+	position, err := client.GetPositionWithOverrides(context.Background(), q, big.NewInt(90), overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position.Liquidity.Int64() != 1000 {
+		t.Errorf("liquidity = %s, want the overridden 1000", position.Liquidity)
+	}
+
+	if len(node.overrides) != 1 {
+		t.Fatalf("sent %d override sets, want 1", len(node.overrides))
+	}
+	var sent struct {
+		StateDiff map[common.Hash]common.Hash `json:"stateDiff"`
+	}
+	if err := json.Unmarshal(node.overrides[0][testPool], &sent); err != nil {
+		t.Fatal(err)
+	}
+	if sent.StateDiff[overrideSlot] != common.BigToHash(big.NewInt(1000)) {
+		t.Errorf("sent %s, want the slot override", node.overrides[0][testPool])
+	}
+	if node.blocks[0] != "0x5a" {
+		t.Errorf("read block %s, want 0x5a", node.blocks[0])
+	}
+}
+
+func TestRunOverride(t *testing.T) {
+	path := writeInput(t, testOverrides)
+
+	code, stdout, stderr := runOutput(t, "-node", overrideNode(t).serve(t), "-max-lag", "0", "-override", path, "-format", "json")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"liquidity": "1000"`) {
+		t.Errorf("output lacks the overridden liquidity:\n%s", stdout)
+	}
+
+	// without the overrides the position doesn't exist
+	if code, _, _ := runOutput(t, "-node", overrideNode(t).serve(t), "-max-lag", "0"); code != exitNotFound {
+		t.Errorf("without -override: exit code = %d, want %d", code, exitNotFound)
+	}
+	if code, _, _ := runOutput(t, "-override", path, "-list"); code != exitBadInput {
+		t.Errorf("-override -list: exit code = %d, want %d", code, exitBadInput)
+	}
+}
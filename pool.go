@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ActiveLiquidity reads the pool's in-range liquidity at the current tick.
+func (c *Client) ActiveLiquidity(ctx context.Context, pool common.Address, block *big.Int) (*big.Int, error) {
+	out, err := c.callPool(ctx, pool, liquidityMethod, block)
+	if err != nil {
+		return nil, err
+	}
+
+	return out[0].(*big.Int), nil
+}
+
+// TickSpacing reads the pool's tick spacing.
+func (c *Client) TickSpacing(ctx context.Context, pool common.Address, block *big.Int) (int32, error) {
+	out, err := c.callPool(ctx, pool, tickSpacingMethod, block)
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(out[0].(*big.Int).Int64()), nil
+}
+
+// LiquidityShare returns positionLiquidity / pool active liquidity.
+//
+// The result only means something while the position is in range: an
+// out-of-range position does not contribute to the active liquidity at all.
+func (c *Client) LiquidityShare(ctx context.Context, pool common.Address, positionLiquidity *big.Int, block *big.Int) (*big.Float, error) {
+	active, err := c.ActiveLiquidity(ctx, pool, block)
+	if err != nil {
+		return nil, err
+	}
+	if active.Sign() == 0 {
+		return nil, errors.New("pool has no active liquidity")
+	}
+
+	return new(big.Float).Quo(new(big.Float).SetInt(positionLiquidity), new(big.Float).SetInt(active)), nil
+}
+
+// PoolShare is a position's part of its pool's active liquidity at one
+// block, for -share.
+type PoolShare struct {
+	Block     *big.Int `json:"block"`
+	Tick      Tick     `json:"tick"`
+	InRange   bool     `json:"inRange"`
+	Liquidity *big.Int `json:"liquidity"`
+	// Share is nil out of range, where the position has no part in it
+	Share *big.Float `json:"share"`
+}
+
+// writeShare prints s as a line, or as JSON.
+func writeShare(w io.Writer, format string, s PoolShare) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(s)
+	}
+
+	if !s.InRange {
+		_, err := fmt.Fprintf(w, "out of range at tick %d: no share of the active liquidity\n", s.Tick)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "liquidity %s is %s of the active liquidity at tick %d\n", s.Liquidity, formatPercent(s.Share), s.Tick)
+
+	return err
+}
+
+// PoolInfo is the immutable configuration of a pool.
+type PoolInfo struct {
+	Fee         uint32
+	Token0      common.Address
+	Token1      common.Address
+	TickSpacing int32
+}
+
+// PoolInfos reads fee, token0, token1 and tickSpacing of every pool in one
+// batch. The spacing is read rather than looked up by fee, so pools of a fee
+// tier enabled later, or of a fork's factory, are handled alike.
+func (c *Client) PoolInfos(ctx context.Context, pools []common.Address, block *big.Int) (map[common.Address]PoolInfo, error) {
+	methods := []string{feeMethod, token0Method, token1Method, tickSpacingMethod}
+
+	calls := make([]call, 0, len(methods)*len(pools))
+	for _, pool := range pools {
+		for _, method := range methods {
+			calldata, err := c.abis.PoolABI().Pack(method)
+			if err != nil {
+				return nil, fmt.Errorf("pack %s: %w", method, err)
+			}
+			calls = append(calls, call{Target: pool, CallData: calldata})
+		}
+	}
+
+	responses, err := c.aggregate(ctx, calls, block)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make(map[common.Address]PoolInfo, len(pools))
+	for i, pool := range pools {
+		var out [4]interface{}
+		for j, method := range methods {
+			values, err := c.abis.PoolABI().Unpack(method, responses[len(methods)*i+j])
+			if err != nil {
+				return nil, fmt.Errorf("parse %s of %s: %w", method, pool, err)
+			}
+			out[j] = values[0]
+		}
+
+		infos[pool] = PoolInfo{
+			Fee:         uint32(out[0].(*big.Int).Uint64()),
+			Token0:      out[1].(common.Address),
+			Token1:      out[2].(common.Address),
+			TickSpacing: int32(out[3].(*big.Int).Int64()),
+		}
+	}
+
+	return infos, nil
+}
+
+// annotatePools fills the pool fields of results and flags their tokens.
+func annotatePools(ctx context.Context, client *Client, results []PositionResult, flagged map[common.Address]string, block *big.Int) error {
+	var pools []common.Address
+	seen := make(map[common.Address]bool)
+	for _, r := range results {
+		if !seen[r.Pool] {
+			seen[r.Pool] = true
+			pools = append(pools, r.Pool)
+		}
+	}
+
+	infos, err := client.PoolInfos(ctx, pools, block)
+	if err != nil {
+		return err
+	}
+
+	for i := range results {
+		info := infos[results[i].Pool]
+		results[i].Token0 = info.Token0
+		results[i].Token1 = info.Token1
+		results[i].setPool(info)
+		if r := results[i]; r.TickSpacing > 0 && !(r.TickLower.AlignedTo(r.TickSpacing) && r.TickUpper.AlignedTo(r.TickSpacing)) {
+			// the pool rejects such a mint, so the read can only be empty
+			results[i].Warnings = append(results[i].Warnings, fmt.Sprintf("ticks [%d, %d) are not multiples of the pool's tick spacing %d", r.TickLower, r.TickUpper, r.TickSpacing))
+		}
+		results[i].Warnings = append(results[i].Warnings, tokenWarnings(flagged, info.Token0, info.Token1)...)
+	}
+
+	return nil
+}
+
+// Slot0 is the part of the pool's slot0 the tool uses.
+type Slot0 struct {
+	SqrtPriceX96 *big.Int
+	Tick         Tick
+}
+
+// Slot0 reads the current price and tick of the pool.
+func (c *Client) Slot0(ctx context.Context, pool common.Address, block *big.Int) (Slot0, error) {
+	out, err := c.callPool(ctx, pool, slot0Method, block)
+	if err != nil {
+		return Slot0{}, err
+	}
+
+	return Slot0{
+		SqrtPriceX96: out[0].(*big.Int),
+		Tick:         Tick(out[1].(*big.Int).Int64()),
+	}, nil
+}
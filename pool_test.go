@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestPoolInfos(t *testing.T) {
+	tiers := []struct {
+		fee, tickSpacing int64
+		percent          string
+	}{
+		{100, 1, "0.01%"},
+		{500, 10, "0.05%"},
+		{3000, 60, "0.3%"},
+		{10000, 200, "1%"},
+		// tiers the factory didn't start with, or a fork's, spaced by the pool
+		{2500, 50, "0.25%"},
+		{3000, 1, "0.3%"},
+	}
+
+	pools := make(map[common.Address]*fakePool)
+	var addresses []common.Address
+	for i, tier := range tiers {
+		address := common.BigToAddress(big.NewInt(int64(0x100 + i)))
+		pool := newFakePool(0, 1)
+		pool.fee, pool.tickSpacing = tier.fee, tier.tickSpacing
+		pools[address] = pool
+		addresses = append(addresses, address)
+	}
+	node := &fakeNode{head: 100, handle: poolsHandler(t, pools, nil)}
+	client := newFakeClient(t, node)
+
+	infos, err := client.PoolInfos(context.Background(), addresses, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := node.count("eth_call"); n != 1 {
+		t.Errorf("sent %d eth_calls, want one batch", n)
+	}
+
+	for i, tier := range tiers {
+		info := infos[addresses[i]]
+		if int64(info.Fee) != tier.fee || int64(info.TickSpacing) != tier.tickSpacing || info.Token0 != pools[addresses[i]].token0 {
+			t.Errorf("pool info of the %d tier = %+v", tier.fee, info)
+		}
+
+		r := PositionResult{TickLower: -600, TickUpper: 600}
+		r.setPool(info)
+		if r.FeePercent != tier.percent || int64(r.TickSpacing) != tier.tickSpacing {
+			t.Errorf("fee %d shows as %s with spacing %d, want %s and %d", tier.fee, r.FeePercent, r.TickSpacing, tier.percent, tier.tickSpacing)
+		}
+	}
+}
+
+func TestLiquidityShare(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		active int64
+		want   string
+	}{
+		{"quarter", 4000, "0.25"},
+		{"all", 1000, "1"},
+		{"no active liquidity", 0, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pools := map[common.Address]*fakePool{testPool: newFakePool(0, tc.active)}
+			client := newFakeClient(t, &fakeNode{head: 100, handle: poolsHandler(t, pools, nil)})
+
+			share, err := client.LiquidityShare(context.Background(), testPool, big.NewInt(1000), big.NewInt(90))
+			if tc.want == "" {
+				if err == nil {
+					t.Errorf("share = %s, want an error", share)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := share.Text('g', 10); got != tc.want {
+				t.Errorf("share = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunShare(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		tick Tick
+		want string
+	}{
+		{"in range", 0, "liquidity 1000 is 25% of the active liquidity at tick 0\n"},
+		{"out of range", 120, "out of range at tick 120: no share of the active liquidity\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pool := newFakePool(tc.tick, 4000)
+			q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+			pool.setPosition(t, q, Position{Liquidity: big.NewInt(1000)})
+			node := &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)}
+
+			code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-share",
+				"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60")
+			if code != exitOK {
+				t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+			}
+			if stdout != tc.want {
+				t.Errorf("output = %q, want %q", stdout, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunShareNoPosition(t *testing.T) {
+	pools := map[common.Address]*fakePool{testPool: newFakePool(0, 4000)}
+	node := &fakeNode{head: 100, handle: poolsHandler(t, pools, nil)}
+
+	code, _, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-share", "-pool", testPool.Hex(), "-owner", testOwner.Hex())
+	if code != exitNotFound {
+		t.Errorf("exit code = %d, want %d; stderr:\n%s", code, exitNotFound, stderr)
+	}
+}
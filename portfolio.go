@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Portfolio aggregates NFT positions for a -summary.
+type Portfolio struct {
+	Quote common.Address
+	// Positions counts positions with liquidity or uncollected fees; closed
+	// and fully collected ones are skipped.
+	Positions  int
+	InRange    int
+	OutOfRange int
+	// Value is what the counted positions are worth in raw Quote units,
+	// liquidity plus uncollected fees, at each pool's current price or the
+	// price overrides of its tokens. It only
+	// covers pools that trade Quote; the others are counted in Unpriced.
+	Value    *big.Int
+	Unpriced int
+	// Fees is the uncollected fees per token in raw units.
+	Fees map[common.Address]*big.Int
+}
+
+// PortfolioSummary lists the NFT positions of owners and sums their value in
+// quote, uncollected fees and range status, reading every pool's fee state
+// in batches of at most enumerationChunk calls. The batches are separate
+// eth_calls, so latest is resolved to the head's number first for all of them
+// to see one block.
+func (c *Client) PortfolioSummary(ctx context.Context, owners []common.Address, quote common.Address, block *big.Int) (Portfolio, error) {
+	block, err := c.fixedBlock(ctx, block)
+	if err != nil {
+		return Portfolio{}, err
+	}
+
+	positions, err := c.ListOwnersPositions(ctx, owners, block, Page{})
+	if err != nil {
+		return Portfolio{}, err
+	}
+
+	var calls []call
+	for _, p := range positions {
+		stateCalls, err := c.rangeFeeCalls(p.Pool, p.TickLower, p.TickUpper)
+		if err != nil {
+			return Portfolio{}, err
+		}
+		calls = append(calls, stateCalls...)
+	}
+
+	responses, err := c.aggregateChunked(ctx, calls, block, enumerationChunk)
+	if err != nil {
+		return Portfolio{}, err
+	}
+
+	states := make([]rangeFees, len(positions))
+	for i, p := range positions {
+		if states[i], err = c.unpackRangeFees(responses[i*rangeFeeCallCount:], p.TickLower, p.TickUpper); err != nil {
+			return Portfolio{}, err
+		}
+	}
+
+	pairs := make([][2]common.Address, len(positions))
+	for i, p := range positions {
+		pairs[i] = [2]common.Address{p.Token0, p.Token1}
+	}
+	decimals, err := c.overrideDecimals(ctx, block, pairs)
+	if err != nil {
+		return Portfolio{}, err
+	}
+	valuePrices := make([]*big.Int, len(positions))
+	for i, p := range positions {
+		valuePrices[i] = c.valuationPrice(p.Token0, p.Token1, decimals[p.Token0], decimals[p.Token1], states[i].sqrtPriceX96)
+	}
+
+	return summarize(positions, states, quote, valuePrices), nil
+}
+
+// summarize sums positions with the fee states of their ranges. valuePrices,
+// when not nil, are the sqrtPriceX96 each position is valued at instead of
+// its pool's.
+func summarize(positions []TokenPosition, states []rangeFees, quote common.Address, valuePrices []*big.Int) Portfolio {
+	summary := Portfolio{Quote: quote, Value: new(big.Int), Fees: make(map[common.Address]*big.Int)}
+
+	for i, p := range positions {
+		fees := states[i].fees(p.Position, p.TickLower, p.TickUpper)
+		if fees.Liquidity.Sign() == 0 && fees.Fees0.Sign() == 0 && fees.Fees1.Sign() == 0 {
+			continue
+		}
+
+		summary.Positions++
+		if fees.InRange {
+			summary.InRange++
+		} else {
+			summary.OutOfRange++
+		}
+
+		summary.addFees(p.Token0, fees.Fees0)
+		summary.addFees(p.Token1, fees.Fees1)
+
+		amount0, amount1 := AmountsForLiquidity(fees.SqrtPriceX96, p.TickLower, p.TickUpper, fees.Liquidity)
+		amount0.Add(amount0, fees.Fees0)
+		amount1.Add(amount1, fees.Fees1)
+
+		valuePrice := fees.SqrtPriceX96
+		if valuePrices != nil {
+			valuePrice = valuePrices[i]
+		}
+		switch quote {
+		case p.Token1:
+			summary.Value.Add(summary.Value, valueInToken1(amount0, amount1, valuePrice))
+		case p.Token0:
+			summary.Value.Add(summary.Value, valueInToken0(amount0, amount1, valuePrice))
+		default:
+			summary.Unpriced++
+		}
+	}
+
+	return summary
+}
+
+func (p *Portfolio) addFees(token common.Address, amount *big.Int) {
+	addTo(p.Fees, token, amount)
+}
+
+// writeSummary prints a portfolio as key: value lines, or as JSON.
+func writeSummary(w io.Writer, format string, p Portfolio) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(p)
+	}
+
+	tokens := make([]common.Address, 0, len(p.Fees))
+	for token := range p.Fees {
+		tokens = append(tokens, token)
+	}
+	slices.SortFunc(tokens, func(a, b common.Address) int { return a.Cmp(b) })
+
+	fmt.Fprintf(w, "positions: %d (%d in range, %d out of range)\n", p.Positions, p.InRange, p.OutOfRange)
+	fmt.Fprintf(w, "value: %s %s (%d positions unpriced)\n", p.Value, p.Quote.Hex(), p.Unpriced)
+	for _, token := range tokens {
+		fmt.Fprintf(w, "fees: %s %s\n", p.Fees[token], token.Hex())
+	}
+
+	return nil
+}
+
+// valueInToken0 is valueInToken1 the other way around: amount1 priced at
+// sqrtPriceX96 plus amount0, in raw token0 units.
+func valueInToken0(amount0, amount1, sqrtPriceX96 *big.Int) *big.Int {
+	priceX192 := new(big.Int).Mul(sqrtPriceX96, sqrtPriceX96)
+	value := mulDiv(amount1, new(big.Int).Lsh(big.NewInt(1), 192), priceX192)
+
+	return value.Add(value, amount0)
+}
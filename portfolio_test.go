@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSummarize(t *testing.T) {
+	token0 := common.HexToAddress("0x000000000000000000000000000000000000000a")
+	token1 := common.HexToAddress("0x000000000000000000000000000000000000000b")
+	other := common.HexToAddress("0x000000000000000000000000000000000000000c")
+
+	position := func(t0, t1 common.Address, liquidity, owed0, owed1 int64) TokenPosition {
+		return TokenPosition{Token0: t0, Token1: t1, TickLower: -60, TickUpper: 60, Position: Position{
+			Liquidity:                big.NewInt(liquidity),
+			FeeGrowthInside0LastX128: new(big.Int),
+			FeeGrowthInside1LastX128: new(big.Int),
+			TokensOwed0:              big.NewInt(owed0),
+			TokensOwed1:              big.NewInt(owed1),
+		}}
+	}
+	state := func(tick Tick) rangeFees {
+		return rangeFees{sqrtPriceX96: tick.SqrtRatio(), tick: tick, inside0: new(big.Int), inside1: new(big.Int)}
+	}
+
+	positions := []TokenPosition{
+		position(token0, token1, 1e6, 7, 9),
+		// out of range, nothing owed
+		position(token0, token1, 1e6, 0, 0),
+		// closed and collected
+		position(token0, token1, 0, 0, 0),
+		// closed with fees left
+		position(token1, token0, 0, 3, 4),
+		// a pool without the quote token
+		position(token0, other, 1e6, 5, 0),
+	}
+	states := []rangeFees{state(0), state(120), state(0), state(0), state(0)}
+
+	for _, tc := range []struct {
+		quote    common.Address
+		unpriced int
+	}{
+		{token1, 1},
+		{token0, 0},
+		{other, 3},
+	} {
+		summary := summarize(positions, states, tc.quote, nil)
+
+		if summary.Positions != 4 || summary.InRange != 3 || summary.OutOfRange != 1 {
+			t.Errorf("quote %s: counted %d positions, %d in range, %d out of range, want 4, 3, 1", tc.quote, summary.Positions, summary.InRange, summary.OutOfRange)
+		}
+		if summary.Unpriced != tc.unpriced {
+			t.Errorf("quote %s: %d unpriced, want %d", tc.quote, summary.Unpriced, tc.unpriced)
+		}
+		for token, want := range map[common.Address]int64{token0: 7 + 4 + 5, token1: 9 + 3, other: 0} {
+			if got := bigOr(summary.Fees[token]); got.Int64() != want {
+				t.Errorf("quote %s: fees of %s = %s, want %d", tc.quote, token, got, want)
+			}
+		}
+	}
+
+	// the value is every amount of the pools of token0 and token1 together:
+	// priced at 1, or all token1 above the range
+	amount0, amount1 := AmountsForLiquidity(Tick(0).SqrtRatio(), -60, 60, big.NewInt(1e6))
+	above0, above1 := AmountsForLiquidity(Tick(120).SqrtRatio(), -60, 60, big.NewInt(1e6))
+	want := new(big.Int).Add(amount0, amount1)
+	want.Add(want, above0).Add(want, above1).Add(want, big.NewInt(7+9+3+4))
+	if got := summarize(positions, states, token1, nil).Value; got.Cmp(want) != 0 {
+		t.Errorf("value in token1 = %s, want %s", got, want)
+	}
+}
+
+func TestPortfolioSummary(t *testing.T) {
+	token1 := common.HexToAddress("0x000000000000000000000000000000000000000b")
+	npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {1, 2, 3}}, liquidity: map[int64]int64{2: 0}}
+	node := listNode(t, npm)
+	client := newFakeClient(t, node)
+
+	summary, err := client.PortfolioSummary(context.Background(), []common.Address{testOwner}, token1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount0, amount1 := AmountsForLiquidity(Tick(0).SqrtRatio(), -60, 60, big.NewInt(500))
+	want := new(big.Int).Add(amount0, amount1)
+	want.Mul(want, big.NewInt(2))
+	if summary.Positions != 2 || summary.InRange != 2 || summary.Unpriced != 0 || summary.Value.Cmp(want) != 0 {
+		t.Errorf("summary = %+v, want 2 positions in range worth %s", summary, want)
+	}
+	// every read at the head's number
+	for i, block := range node.blocks {
+		if block != "0x64" {
+			t.Errorf("call %d read block %s, want 0x64", i, block)
+		}
+	}
+
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-list", "-owner", testOwner.Hex(), "-summary", token1.Hex())
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	for _, line := range []string{
+		"positions: 2 (2 in range, 0 out of range)",
+		"value: " + want.String() + " " + token1.Hex() + " (0 positions unpriced)",
+	} {
+		if !strings.Contains(stdout, line+"\n") {
+			t.Errorf("-summary output lacks %q:\n%s", line, stdout)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math/big"
+	"sort"
+)
+
+// TickMath.MIN_SQRT_RATIO and MAX_SQRT_RATIO, the sqrt prices of MinTick and
+// MaxTick.
+var (
+	minSqrtRatio = MinTick.SqrtRatio()
+	maxSqrtRatio = MaxTick.SqrtRatio()
+)
+
+// pricePrec is the big.Float precision of price conversions, enough for the
+// 160 bits of a sqrtPriceX96.
+const pricePrec = 256
+
+// SqrtPriceX96FromPrice converts a human price, token1 per token0 in whole
+// tokens, into the pool's sqrtPriceX96 and the tick the pool would report at
+// that price. dec0 and dec1 are the tokens' decimals. Prices outside what a
+// pool can represent, including zero and negative ones, are clamped to
+// [MIN_SQRT_RATIO, MAX_SQRT_RATIO).
+func SqrtPriceX96FromPrice(price *big.Float, dec0, dec1 uint8) (*big.Int, Tick) {
+	if price.Sign() <= 0 {
+		return new(big.Int).Set(minSqrtRatio), MinTick
+	}
+
+	// raw token1 units per raw token0 unit
+	raw := shiftDecimals(new(big.Float).SetPrec(pricePrec).Set(price), int64(dec1)-int64(dec0))
+
+	sqrtPrice := new(big.Float).SetPrec(pricePrec).Sqrt(raw)
+	sqrtPrice.Mul(sqrtPrice, new(big.Float).SetInt(q96))
+	sqrtPriceX96, _ := sqrtPrice.Int(nil)
+
+	switch {
+	case sqrtPriceX96.Cmp(minSqrtRatio) < 0:
+		sqrtPriceX96.Set(minSqrtRatio)
+	case sqrtPriceX96.Cmp(maxSqrtRatio) >= 0:
+		sqrtPriceX96.Sub(maxSqrtRatio, big.NewInt(1))
+	}
+
+	return sqrtPriceX96, tickAtSqrtRatio(sqrtPriceX96)
+}
+
+// PriceFromSqrtPriceX96 is the inverse of SqrtPriceX96FromPrice: token1 per
+// token0 in whole tokens.
+func PriceFromSqrtPriceX96(sqrtPriceX96 *big.Int, dec0, dec1 uint8) *big.Float {
+	sqrtPrice := new(big.Float).SetPrec(pricePrec).SetInt(sqrtPriceX96)
+	price := new(big.Float).SetPrec(pricePrec).Mul(sqrtPrice, sqrtPrice)
+	price.Quo(price, new(big.Float).SetPrec(pricePrec).SetInt(new(big.Int).Lsh(big.NewInt(1), 192)))
+
+	return shiftDecimals(price, int64(dec0)-int64(dec1))
+}
+
+// defaultPriceDigits is the -price-precision default.
+const defaultPriceDigits = 8
+
+// priceDigits is how many significant digits prices, price impacts and rates
+// print with, set by -price-precision.
+var priceDigits = defaultPriceDigits
+
+// formatPrice renders a price, price impact or rate with priceDigits
+// significant digits, rounded to nearest even from the exact value of x.
+func formatPrice(x *big.Float) string {
+	return x.Text('g', priceDigits)
+}
+
+// formatPercent renders a fraction, such as a price impact or an APR, as a
+// percentage through formatPrice.
+func formatPercent(x *big.Float) string {
+	return formatPrice(new(big.Float).Mul(x, big.NewFloat(100))) + "%"
+}
+
+// shiftDecimals multiplies x by 10^exp in place.
+func shiftDecimals(x *big.Float, exp int64) *big.Float {
+	scale := big.NewInt(exp)
+	scale.Abs(scale)
+	scale.Exp(big.NewInt(10), scale, nil)
+
+	if exp < 0 {
+		return x.Quo(x, new(big.Float).SetPrec(pricePrec).SetInt(scale))
+	}
+
+	return x.Mul(x, new(big.Float).SetPrec(pricePrec).SetInt(scale))
+}
+
+// tickAtSqrtRatio is TickMath.getTickAtSqrtRatio: the greatest tick whose
+// sqrt price is at most sqrtPriceX96, found by bisecting Tick.SqrtRatio.
+// sqrtPriceX96 must be within [MIN_SQRT_RATIO, MAX_SQRT_RATIO).
+func tickAtSqrtRatio(sqrtPriceX96 *big.Int) Tick {
+	n := int(MaxTick - MinTick + 1)
+	i := sort.Search(n, func(i int) bool {
+		return (MinTick + Tick(i)).SqrtRatio().Cmp(sqrtPriceX96) > 0
+	})
+
+	return MinTick + Tick(i) - 1
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestSqrtPriceX96FromPrice(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		price      string
+		dec0, dec1 uint8
+		tick       Tick
+	}{
+		{"one", "1", 0, 0, 0},
+		{"WETH/USDC", "2000", 18, 6, -200312},
+		{"raw tick 60", "1.0060180", 0, 0, 60},
+		{"below tick 1", "1.00009", 0, 0, 0},
+		{"negative tick", "0.5", 0, 0, -6932},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			price, _ := new(big.Float).SetPrec(pricePrec).SetString(tc.price)
+			sqrtPriceX96, tick := SqrtPriceX96FromPrice(price, tc.dec0, tc.dec1)
+			if tick != tc.tick {
+				t.Errorf("tick = %d, want %d", tick, tc.tick)
+			}
+			// the tick is the greatest at or below the price
+			if tick.SqrtRatio().Cmp(sqrtPriceX96) > 0 || (tick+1).SqrtRatio().Cmp(sqrtPriceX96) <= 0 {
+				t.Errorf("sqrtPriceX96 %s is outside tick %d", sqrtPriceX96, tick)
+			}
+
+			back := PriceFromSqrtPriceX96(sqrtPriceX96, tc.dec0, tc.dec1)
+			diff := new(big.Float).Quo(new(big.Float).Sub(back, price), price)
+			if f, _ := diff.Float64(); f > 1e-20 || f < -1e-20 {
+				t.Errorf("round trip = %s, want %s", back.Text('g', 30), tc.price)
+			}
+		})
+	}
+}
+
+func TestSqrtPriceX96FromPriceClamps(t *testing.T) {
+	for _, tc := range []struct {
+		price *big.Float
+		want  *big.Int
+		tick  Tick
+	}{
+		{big.NewFloat(0), minSqrtRatio, MinTick},
+		{big.NewFloat(-1), minSqrtRatio, MinTick},
+		{big.NewFloat(1e-300), minSqrtRatio, MinTick},
+		{big.NewFloat(1e300), new(big.Int).Sub(maxSqrtRatio, big.NewInt(1)), MaxTick - 1},
+	} {
+		sqrtPriceX96, tick := SqrtPriceX96FromPrice(tc.price, 0, 0)
+		if sqrtPriceX96.Cmp(tc.want) != 0 || tick != tc.tick {
+			t.Errorf("SqrtPriceX96FromPrice(%g) = %s, %d, want %s, %d", tc.price, sqrtPriceX96, tick, tc.want, tc.tick)
+		}
+	}
+}
+
+func TestConvertPrice(t *testing.T) {
+	var out bytes.Buffer
+	if err := convertPrice(&out, "1", -1, -1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if want := "sqrtPriceX96 79228162514264337593543950336\ntick 0\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	for _, tc := range []struct {
+		price               string
+		dec0, dec1, spacing int
+	}{
+		{"two", 0, 0, 0},
+		{"1", 256, 0, 0},
+		{"1", 0, 0, -60},
+		{"1", 0, 0, 16384},
+	} {
+		if err := convertPrice(&out, tc.price, tc.dec0, tc.dec1, tc.spacing); exitCode(err) != exitBadInput {
+			t.Errorf("convertPrice(%s, %d, %d, %d) = %v, want bad input", tc.price, tc.dec0, tc.dec1, tc.spacing, err)
+		}
+	}
+}
+
+func TestRunPriceTick(t *testing.T) {
+	code, stdout, stderr := runOutput(t, "-price-tick", "2000", "-token0-decimals", "18", "-token1-decimals", "6")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if !strings.HasSuffix(stdout, "\ntick -200312\n") {
+		t.Errorf("output = %q", stdout)
+	}
+
+	code, stdout, stderr = runOutput(t, "-price-tick", "2000", "-token0-decimals", "18", "-token1-decimals", "6", "-tick-spacing", "60")
+	if code != exitOK {
+		t.Fatalf("-tick-spacing: exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if !strings.HasSuffix(stdout, "\ntick -200312\nusable tick -200340\n") {
+		t.Errorf("-tick-spacing output = %q", stdout)
+	}
+	if code, _, _ := runOutput(t, "-tick-spacing", "60"); code != exitBadInput {
+		t.Errorf("-tick-spacing without -price-tick: exit code = %d, want %d", code, exitBadInput)
+	}
+}
+
+// significantDigits counts the digits of a formatPrice mantissa, from its
+// first nonzero one.
+func significantDigits(s string) int {
+	mantissa, _, _ := strings.Cut(strings.TrimPrefix(s, "-"), "e")
+	return len(strings.TrimLeft(strings.Replace(mantissa, ".", "", 1), "0"))
+}
+
+func TestFormatPrice(t *testing.T) {
+	// 1.0001^-60 with 6 decimals shifted, 994018.26223949...
+	price := PriceFromSqrtPriceX96(Tick(-60).SqrtRatio(), 6, 0)
+
+	for _, tc := range []struct {
+		digits int
+		want   string
+	}{
+		{defaultPriceDigits, "994018.26"},
+		{3, "9.94e+05"},
+		{12, "994018.262239"},
+	} {
+		priceDigits = tc.digits
+		got := formatPrice(price)
+		if got != tc.want || significantDigits(got) != tc.digits {
+			t.Errorf("%d digits: %s, want %s", tc.digits, got, tc.want)
+		}
+	}
+	priceDigits = defaultPriceDigits
+
+	if got := formatPercent(big.NewFloat(0.25)); got != "25%" {
+		t.Errorf("formatPercent(0.25) = %s, want 25%%", got)
+	}
+}
+
+func TestRunPricePrecision(t *testing.T) {
+	node, _ := reportNode(t)
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-pool", testPool.Hex(), "-owner", testOwner.Hex(),
+		"-tick-lower", "-60", "-tick-upper", "60", "-report", "-price-precision", "4")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if want := "price:     1, range 0.994 to 1.006\n"; !strings.Contains(stdout, want) {
+		t.Errorf("output lacks %q:\n%s", want, stdout)
+	}
+
+	if code, _, _ := runOutput(t, "-price-precision", "0"); code != exitBadInput {
+		t.Errorf("-price-precision 0: exit code = %d, want %d", code, exitBadInput)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PositionProcessor is called for every fetched result, for custom
+// enrichment or filtering. It may modify r; returning false drops it.
+//
+// Processors run in registration order after the built-in steps: results
+// have already passed -min-liquidity and carry their pool's fee, tokens and
+// warnings.
+type PositionProcessor func(ctx context.Context, r *PositionResult) (keep bool, err error)
+
+// WithProcessor registers processors for the results of list and batch
+// reads.
+func WithProcessor(processors ...PositionProcessor) Option {
+	return func(o *clientOptions) {
+		o.processors = append(o.processors, processors...)
+	}
+}
+
+// labelProcessor is the processor of -label: it sets the labels, KEY=VALUE
+// pairs, on every result.
+func labelProcessor(labels []string) (PositionProcessor, error) {
+	pairs := make([][2]string, len(labels))
+	for i, label := range labels {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q is not KEY=VALUE", label)
+		}
+		pairs[i] = [2]string{key, value}
+	}
+
+	return func(_ context.Context, r *PositionResult) (bool, error) {
+		for _, pair := range pairs {
+			r.SetLabel(pair[0], pair[1])
+		}
+		return true, nil
+	}, nil
+}
+
+// processResults runs the registered processors over results.
+func (c *Client) processResults(ctx context.Context, results []PositionResult) ([]PositionResult, error) {
+	if len(c.processors) == 0 {
+		return results, nil
+	}
+
+	kept := results[:0]
+	for i := range results {
+		keep := true
+		for _, process := range c.processors {
+			var err error
+			if keep, err = process(ctx, &results[i]); err != nil {
+				return nil, fmt.Errorf("process position %s/%s: %w", results[i].Pool, results[i].Owner, err)
+			}
+			if !keep {
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, results[i])
+		}
+	}
+
+	return kept, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestProcessResults(t *testing.T) {
+	tag := func(_ context.Context, r *PositionResult) (bool, error) {
+		r.SetLabel("desk", "treasury")
+		return true, nil
+	}
+	dropEmpty := func(_ context.Context, r *PositionResult) (bool, error) {
+		return r.Position.Liquidity.Sign() > 0, nil
+	}
+	client := newFakeClient(t, &fakeNode{head: 100}, WithProcessor(tag), WithProcessor(dropEmpty))
+
+	results := []PositionResult{
+		{Owner: testOwner, Position: Position{Liquidity: big.NewInt(1)}},
+		{Owner: testOwner, Position: Position{Liquidity: new(big.Int)}},
+	}
+	kept, err := client.processResults(context.Background(), results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 1 || kept[0].Position.Liquidity.Int64() != 1 {
+		t.Fatalf("kept %+v, want the position with liquidity", kept)
+	}
+	if kept[0].Labels["desk"] != "treasury" {
+		t.Errorf("labels = %v, want the tag", kept[0].Labels)
+	}
+
+	failing := newFakeClient(t, &fakeNode{head: 100}, WithProcessor(func(context.Context, *PositionResult) (bool, error) {
+		return false, errors.New("no price")
+	}))
+	if _, err := failing.processResults(context.Background(), results); err == nil {
+		t.Error("want the processor's error")
+	}
+}
+
+func TestLabelProcessor(t *testing.T) {
+	label, err := labelProcessor([]string{"desk=treasury", "note="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r PositionResult
+	if keep, err := label(context.Background(), &r); !keep || err != nil {
+		t.Errorf("label = %t, %v, want the result kept", keep, err)
+	}
+	if len(r.Labels) != 2 || r.Labels["desk"] != "treasury" || r.Labels["note"] != "" {
+		t.Errorf("labels = %v", r.Labels)
+	}
+
+	for _, bad := range []string{"desk", "=treasury"} {
+		if _, err := labelProcessor([]string{bad}); err == nil {
+			t.Errorf("labelProcessor(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRunLabel(t *testing.T) {
+	code, stdout, stderr := runOutput(t, "-node", positionNode(t, 1000).serve(t), "-max-lag", "0", "-format", "json", "-label", "desk=treasury", "-label", "env=prod")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+
+	var results []PositionResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, stdout)
+	}
+	if len(results) != 1 || results[0].Labels["desk"] != "treasury" || results[0].Labels["env"] != "prod" {
+		t.Errorf("results = %+v, want both labels", results)
+	}
+
+	if code, _, _ := runOutput(t, "-label", "desk"); code != exitBadInput {
+		t.Errorf("-label desk: exit code = %d, want %d", code, exitBadInput)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfiles starts a CPU profile written to cpuPath and returns a stop
+// function that finishes it and writes a heap profile to memPath. Empty paths
+// disable either profile.
+func startProfiles(cpuPath, memPath string) (stop func() error, err error) {
+	var cpuFile *os.File
+	if cpuPath != "" {
+		if cpuFile, err = os.Create(cpuPath); err != nil {
+			return nil, fmt.Errorf("create cpu profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(cpuFile); err != nil {
+			cpuFile.Close()
+			return nil, fmt.Errorf("start cpu profile: %w", err)
+		}
+	}
+
+	return func() error {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			if err := cpuFile.Close(); err != nil {
+				return fmt.Errorf("write cpu profile: %w", err)
+			}
+		}
+
+		if memPath == "" {
+			return nil
+		}
+
+		memFile, err := os.Create(memPath)
+		if err != nil {
+			return fmt.Errorf("create memory profile: %w", err)
+		}
+		defer memFile.Close()
+
+		// up-to-date allocation statistics
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(memFile); err != nil {
+			return fmt.Errorf("write memory profile: %w", err)
+		}
+
+		return memFile.Close()
+	}, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval is how often Progress logs a line when it can't redraw
+// one in place.
+const progressInterval = 10 * time.Second
+
+// Progress reports how far a batch of reads got: a line redrawn in place on
+// a terminal, or a log line every progressInterval otherwise, and a summary
+// at the end. A nil *Progress reports nothing.
+type Progress struct {
+	label string
+	// live is the terminal to redraw on, nil to log instead
+	live  io.Writer
+	calls *atomic.Int64
+	start time.Time
+
+	mu      sync.Mutex
+	total   int
+	done    int
+	failed  int
+	logged  time.Time
+	drawing bool
+}
+
+// NewProgress reports the progress of reads labelled label. With live set,
+// usually a terminal stderr, the line is redrawn there; otherwise it goes to
+// the standard logger. calls, if not nil, counts the RPC calls made for the
+// summary, e.g. through CountingMiddleware.
+func NewProgress(label string, live io.Writer, calls *atomic.Int64) *Progress {
+	now := time.Now()
+	return &Progress{label: label, live: live, calls: calls, start: now, logged: now}
+}
+
+// setTotal sets how many reads the batch has.
+func (p *Progress) setTotal(total int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+}
+
+// add counts one finished read, failed or not.
+func (p *Progress) add(failed bool) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	if failed {
+		p.failed++
+	}
+
+	switch {
+	case p.live != nil:
+		fmt.Fprintf(p.live, "\r%s\x1b[K", p.line())
+		p.drawing = true
+	case time.Since(p.logged) >= progressInterval:
+		log.Print(p.line())
+		p.logged = time.Now()
+	}
+}
+
+// Finish clears the live line and logs the summary.
+func (p *Progress) Finish() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.drawing {
+		fmt.Fprint(p.live, "\r\x1b[K")
+		p.drawing = false
+	}
+	log.Print(p.summary())
+}
+
+// line is the progress so far, e.g. "backfill: 120/1001 positions, 2 failed".
+func (p *Progress) line() string {
+	return fmt.Sprintf("%s: %d/%d positions, %d failed", p.label, p.done, p.total, p.failed)
+}
+
+// summary is the line with the elapsed time and, when counted, the number of
+// RPC calls.
+func (p *Progress) summary() string {
+	s := fmt.Sprintf("%s in %s", p.line(), time.Since(p.start).Round(time.Millisecond))
+	if p.calls != nil {
+		s += fmt.Sprintf(", %d RPC calls", p.calls.Load())
+	}
+
+	return s
+}
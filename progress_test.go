@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// captureLog sends the standard logger to a buffer for the rest of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	flags := log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	})
+
+	return &buf
+}
+
+func TestProgressLive(t *testing.T) {
+	logs := captureLog(t)
+	var live bytes.Buffer
+	var calls atomic.Int64
+	calls.Store(7)
+
+	p := NewProgress("backfill", &live, &calls)
+	p.setTotal(3)
+	p.add(false)
+	p.add(true)
+	p.add(false)
+	p.Finish()
+
+	want := "\rbackfill: 1/3 positions, 0 failed\x1b[K" +
+		"\rbackfill: 2/3 positions, 1 failed\x1b[K" +
+		"\rbackfill: 3/3 positions, 1 failed\x1b[K" +
+		"\r\x1b[K"
+	if live.String() != want {
+		t.Errorf("live output = %q, want %q", live.String(), want)
+	}
+	if summary := regexp.MustCompile(`^backfill: 3/3 positions, 1 failed in \S+, 7 RPC calls\n$`); !summary.MatchString(logs.String()) {
+		t.Errorf("log = %q, want the summary alone", logs.String())
+	}
+}
+
+func TestProgressLogged(t *testing.T) {
+	logs := captureLog(t)
+
+	p := NewProgress("backfill", nil, nil)
+	p.setTotal(2)
+	p.add(false)
+	// the first line is logged once progressInterval has passed
+	p.logged = p.logged.Add(-progressInterval)
+	p.add(false)
+	p.Finish()
+
+	lines := strings.Split(strings.TrimSuffix(logs.String(), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != "backfill: 2/2 positions, 0 failed" || !strings.HasPrefix(lines[1], "backfill: 2/2 positions, 0 failed in ") || strings.Contains(lines[1], "RPC calls") {
+		t.Errorf("log = %q", logs.String())
+	}
+}
+
+func TestProgressNil(t *testing.T) {
+	var p *Progress
+	p.setTotal(1)
+	p.add(true)
+	p.Finish()
+}
+
+func TestRunBackfillSummary(t *testing.T) {
+	node := positionNode(t, 1000)
+	code, _, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-backfill", "40-60", "-backfill-step", "10")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	// stderr isn't a terminal, so there is no live line
+	if strings.Contains(stderr, "\r") {
+		t.Errorf("stderr has a live line:\n%q", stderr)
+	}
+	summary := regexp.MustCompile(`backfill: 3/3 positions, 0 failed in \S+, (\d+) RPC calls\n`)
+	m := summary.FindStringSubmatch(stderr)
+	if m == nil {
+		t.Fatalf("stderr lacks the summary:\n%s", stderr)
+	}
+	if m[1] == "0" {
+		t.Errorf("summary counted no RPC calls: %s", m[0])
+	}
+}
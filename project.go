@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// ProjectValue is what liquidity on [tickLower, tickUpper) would be worth if
+// the pool's price moved to sqrtPriceX96: the token amounts at that price, in
+// whole tokens of dec0 and dec1 decimals, times quote0 and quote1, the quote
+// price of one token0 and one token1 in that scenario. Uncollected fees are
+// not included.
+//
+// For "what if ETH hits $5000" on a WETH/USDC pool, take sqrtPriceX96 from
+// SqrtPriceX96FromPrice(5000, 18, 6) and quote both tokens in dollars: quote0
+// 5000 and quote1 1.
+func ProjectValue(liquidity *big.Int, tickLower, tickUpper Tick, sqrtPriceX96 *big.Int, dec0, dec1 uint8, quote0, quote1 *big.Float) (*big.Float, error) {
+	if err := validateTicks(tickLower, tickUpper); err != nil {
+		return nil, err
+	}
+	if liquidity.Sign() < 0 {
+		return nil, errors.New("negative liquidity")
+	}
+	if sqrtPriceX96.Cmp(minSqrtRatio) < 0 || sqrtPriceX96.Cmp(maxSqrtRatio) >= 0 {
+		return nil, fmt.Errorf("sqrt price %s is outside [MIN_SQRT_RATIO, MAX_SQRT_RATIO)", sqrtPriceX96)
+	}
+	if quote0.Sign() < 0 || quote1.Sign() < 0 {
+		return nil, errors.New("negative quote price")
+	}
+
+	amount0, amount1 := AmountsForLiquidity(sqrtPriceX96, tickLower, tickUpper, liquidity)
+
+	value := shiftDecimals(new(big.Float).SetPrec(pricePrec).SetInt(amount0), -int64(dec0))
+	value.Mul(value, quote0)
+	value1 := shiftDecimals(new(big.Float).SetPrec(pricePrec).SetInt(amount1), -int64(dec1))
+	value1.Mul(value1, quote1)
+
+	return value.Add(value, value1), nil
+}
+
+// Projection is a position's value in whole token1 at its pool's price and
+// at a projected one, without fees.
+type Projection struct {
+	Price          *big.Float `json:"price"`
+	Value          *big.Float `json:"value"`
+	ProjectedPrice *big.Float `json:"projectedPrice"`
+	ProjectedValue *big.Float `json:"projectedValue"`
+}
+
+// projectReport values the liquidity of r now, at its ValuePrice, and if the
+// price, token1 per token0 in whole tokens, moved to price.
+func projectReport(r *PositionReport, price *big.Float) (Projection, error) {
+	p := Projection{Price: r.ValuePrice, ProjectedPrice: price}
+	one := big.NewFloat(1)
+	q := r.Query
+
+	var err error
+	if p.Value, err = ProjectValue(r.Position.Liquidity, q.TickLower, q.TickUpper, r.SqrtPriceX96, r.Token0.Decimals, r.Token1.Decimals, r.ValuePrice, one); err != nil {
+		return Projection{}, err
+	}
+	sqrtPriceX96, _ := SqrtPriceX96FromPrice(price, r.Token0.Decimals, r.Token1.Decimals)
+	if p.ProjectedValue, err = ProjectValue(r.Position.Liquidity, q.TickLower, q.TickUpper, sqrtPriceX96, r.Token0.Decimals, r.Token1.Decimals, price, one); err != nil {
+		return Projection{}, err
+	}
+
+	return p, nil
+}
+
+// writeProjection prints p as lines, or as JSON.
+func writeProjection(w io.Writer, format string, p Projection) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(p)
+	}
+
+	fmt.Fprintf(w, "at price %s: %s token1\n", formatPrice(p.Price), p.Value.Text('f', 6))
+	_, err := fmt.Fprintf(w, "at price %s: %s token1\n", formatPrice(p.ProjectedPrice), p.ProjectedValue.Text('f', 6))
+
+	return err
+}
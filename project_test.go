@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestProjectValue(t *testing.T) {
+	liquidity := big.NewInt(1e18)
+	quote0, quote1 := big.NewFloat(2), big.NewFloat(1)
+
+	for _, tc := range []struct {
+		name string
+		tick Tick
+	}{
+		// all token0
+		{"below", -120},
+		{"inside", 0},
+		// all token1
+		{"above", 120},
+	} {
+		sqrtPriceX96 := tc.tick.SqrtRatio()
+		amount0, amount1 := AmountsForLiquidity(sqrtPriceX96, -60, 60, liquidity)
+		if tc.name == "below" && amount1.Sign() != 0 || tc.name == "above" && amount0.Sign() != 0 || tc.name == "inside" && (amount0.Sign() == 0 || amount1.Sign() == 0) {
+			t.Fatalf("%s: amounts = %s, %s", tc.name, amount0, amount1)
+		}
+
+		// token0 has 18 decimals and token1 6
+		got, err := ProjectValue(liquidity, -60, 60, sqrtPriceX96, 18, 6, quote0, quote1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a0, _ := new(big.Float).SetInt(amount0).Float64()
+		a1, _ := new(big.Float).SetInt(amount1).Float64()
+		want := a0/1e18*2 + a1/1e6
+		if v, _ := got.Float64(); !closeTo(v, want) {
+			t.Errorf("%s: value = %g, want %g", tc.name, v, want)
+		}
+	}
+
+	for name, fn := range map[string]func() (*big.Float, error){
+		"ticks":     func() (*big.Float, error) { return ProjectValue(liquidity, 60, -60, q96, 0, 0, quote0, quote1) },
+		"liquidity": func() (*big.Float, error) { return ProjectValue(big.NewInt(-1), -60, 60, q96, 0, 0, quote0, quote1) },
+		"sqrt price": func() (*big.Float, error) {
+			return ProjectValue(liquidity, -60, 60, maxSqrtRatio, 0, 0, quote0, quote1)
+		},
+		"negative quote": func() (*big.Float, error) {
+			return ProjectValue(liquidity, -60, 60, q96, 0, 0, big.NewFloat(-1), quote1)
+		},
+	} {
+		if _, err := fn(); err == nil {
+			t.Errorf("invalid %s: ProjectValue succeeded", name)
+		}
+	}
+}
+
+func TestRunProject(t *testing.T) {
+	node, q := reportNode(t)
+	amount0, amount1 := AmountsForLiquidity(q96, q.TickLower, q.TickUpper, big.NewInt(1e18))
+	// both tokens have 18 decimals, one assumed; at 4 the price is above the
+	// range and the position all token1
+	_, above := AmountsForLiquidity(Tick(120).SqrtRatio(), q.TickLower, q.TickUpper, big.NewInt(1e18))
+	now := new(big.Float).SetInt(new(big.Int).Add(amount0, amount1))
+	projected := new(big.Float).SetInt(above)
+
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-pool", testPool.Hex(), "-owner", testOwner.Hex(),
+		"-tick-lower", "-60", "-tick-upper", "60", "-project", "4")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	for _, want := range []string{
+		"at price 1: " + shiftDecimals(now, -18).Text('f', 6) + " token1\n",
+		"at price 4: " + shiftDecimals(projected, -18).Text('f', 6) + " token1\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %q:\n%s", want, stdout)
+		}
+	}
+
+	if code, _, _ := runOutput(t, "-project", "-5"); code != exitBadInput {
+		t.Errorf("-project -5: exit code = %d, want %d", code, exitBadInput)
+	}
+}
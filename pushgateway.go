@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// pushJob is the job label runs push their metrics under. Every push replaces
+// the metrics of the previous run.
+const pushJob = "uniswap_position"
+
+// positionMetrics are the gauges a run exports per position.
+var positionMetrics = []struct {
+	name  string
+	help  string
+	value func(Position) *big.Int
+}{
+	{"uniswap_position_liquidity", "Liquidity of the position.", func(p Position) *big.Int { return p.Liquidity }},
+	{"uniswap_position_tokens_owed0", "tokensOwed0 of the position in raw token0 units.", func(p Position) *big.Int { return p.TokensOwed0 }},
+	{"uniswap_position_tokens_owed1", "tokensOwed1 of the position in raw token1 units.", func(p Position) *big.Int { return p.TokensOwed1 }},
+}
+
+// writeMetrics writes results in the Prometheus text exposition format.
+// Positions that couldn't be read are left out.
+// https://prometheus.io/docs/instrumenting/exposition_formats/#text-based-format
+func writeMetrics(w io.Writer, results []PositionResult) error {
+	var b strings.Builder
+	for _, m := range positionMetrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for _, r := range results {
+			value := m.value(r.Position)
+			if r.Error != "" || value == nil {
+				continue
+			}
+
+			fmt.Fprintf(&b, "%s{%s} %s\n", m.name, metricLabels(r), value)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// metricLabels are the labels that tell the metrics of r from those of the
+// other positions.
+func metricLabels(r PositionResult) string {
+	labels := fmt.Sprintf(`pool="%s",owner="%s",tick_lower="%d",tick_upper="%d"`, r.Pool.Hex(), r.Owner.Hex(), r.TickLower, r.TickUpper)
+	if r.TokenID != nil {
+		labels += fmt.Sprintf(`,token_id="%s"`, r.TokenID)
+	}
+	return labels
+}
+
+// pushMetrics sends the metrics of results to a Prometheus Pushgateway at
+// gateway, e.g. http://localhost:9091, for runs too short to be scraped.
+// https://github.com/prometheus/pushgateway#api
+func pushMetrics(ctx context.Context, gateway string, results []PositionResult) error {
+	var body bytes.Buffer
+	if err := writeMetrics(&body, results); err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(gateway, "/") + "/metrics/job/" + pushJob
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, &body)
+	if err != nil {
+		return fmt.Errorf("invalid pushgateway URL %q: %w", gateway, err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push metrics: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
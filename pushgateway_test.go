@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	results := []PositionResult{
+		{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60, Position: Position{Liquidity: big.NewInt(1000), TokensOwed0: big.NewInt(7), TokensOwed1: big.NewInt(8)}},
+		{Pool: testPool, Owner: testOwner, TokenID: big.NewInt(42), TickLower: -120, TickUpper: 120, Position: Position{Liquidity: big.NewInt(5)}},
+		{Pool: badPool, Owner: testOwner, TickLower: -60, TickUpper: 60, Error: "execution reverted"},
+	}
+
+	var out bytes.Buffer
+	if err := writeMetrics(&out, results); err != nil {
+		t.Fatal(err)
+	}
+
+	labels := `pool="` + testPool.Hex() + `",owner="` + testOwner.Hex() + `"`
+	want := strings.Join([]string{
+		"# HELP uniswap_position_liquidity Liquidity of the position.",
+		"# TYPE uniswap_position_liquidity gauge",
+		"uniswap_position_liquidity{" + labels + `,tick_lower="-60",tick_upper="60"} 1000`,
+		"uniswap_position_liquidity{" + labels + `,tick_lower="-120",tick_upper="120",token_id="42"} 5`,
+		"# HELP uniswap_position_tokens_owed0 tokensOwed0 of the position in raw token0 units.",
+		"# TYPE uniswap_position_tokens_owed0 gauge",
+		"uniswap_position_tokens_owed0{" + labels + `,tick_lower="-60",tick_upper="60"} 7`,
+		"# HELP uniswap_position_tokens_owed1 tokensOwed1 of the position in raw token1 units.",
+		"# TYPE uniswap_position_tokens_owed1 gauge",
+		"uniswap_position_tokens_owed1{" + labels + `,tick_lower="-60",tick_upper="60"} 8`,
+	}, "\n") + "\n"
+	if out.String() != want {
+		t.Errorf("metrics:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+// stubGateway records the pushes it gets and answers them with status.
+type stubGateway struct {
+	status int
+
+	mu     sync.Mutex
+	pushes []*http.Request
+	bodies []string
+}
+
+func (g *stubGateway) serve(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		g.mu.Lock()
+		g.pushes = append(g.pushes, r)
+		g.bodies = append(g.bodies, string(body))
+		g.mu.Unlock()
+		if g.status != 0 {
+			http.Error(w, "push rejected", g.status)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func TestRunPushgateway(t *testing.T) {
+	gateway := &stubGateway{}
+	code, _, stderr := runOutput(t, "-node", positionNode(t, 1000).serve(t), "-max-lag", "0", "-pushgateway", gateway.serve(t)+"/")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+
+	if len(gateway.pushes) != 1 {
+		t.Fatalf("got %d pushes, want 1", len(gateway.pushes))
+	}
+	push := gateway.pushes[0]
+	if push.Method != http.MethodPut || push.URL.Path != "/metrics/job/"+pushJob {
+		t.Errorf("push was %s %s, want PUT /metrics/job/%s", push.Method, push.URL.Path, pushJob)
+	}
+	if ct := push.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(gateway.bodies[0], "# TYPE uniswap_position_liquidity gauge\n") || !strings.Contains(gateway.bodies[0], "} 1000\n") {
+		t.Errorf("push body:\n%s", gateway.bodies[0])
+	}
+}
+
+func TestRunPushgatewayRejected(t *testing.T) {
+	gateway := &stubGateway{status: http.StatusBadRequest}
+	code, _, stderr := runOutput(t, "-node", positionNode(t, 1000).serve(t), "-max-lag", "0", "-pushgateway", gateway.serve(t))
+	if code == exitOK || !strings.Contains(stderr, "push rejected") {
+		t.Errorf("exit code %d, want a failure with the gateway's message; stderr:\n%s", code, stderr)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrFeeMismatch means the fees the tool predicted and those a simulated
+// collect pays out differ.
+var ErrFeeMismatch = errors.New("predicted fees don't match the simulated collect")
+
+// ErrNotOwner means the NFT isn't owned by the address given for it.
+var ErrNotOwner = errors.New("position is not owned by the given address")
+
+// Reconciliation compares the fees the tool computes for an NFT position with
+// what the NonfungiblePositionManager's collect pays out for it at the same
+// block.
+type Reconciliation struct {
+	TokenID *big.Int
+	Owner   common.Address
+	Block   *big.Int
+	// Predicted0 and Predicted1 are tokensOwed plus the fees replayed from
+	// the pool's fee growth, as in -list and -summary.
+	Predicted0 *big.Int
+	Predicted1 *big.Int
+	// Collected0 and Collected1 are what collect returns to the owner.
+	Collected0 *big.Int
+	Collected1 *big.Int
+}
+
+// Agrees reports whether the prediction is exactly what collect pays.
+func (r Reconciliation) Agrees() bool {
+	return r.Predicted0.Cmp(r.Collected0) == 0 && r.Predicted1.Cmp(r.Collected1) == 0
+}
+
+type collectParams struct {
+	TokenId    *big.Int
+	Recipient  common.Address
+	Amount0Max *big.Int
+	Amount1Max *big.Int
+}
+
+// ReconcileCollect predicts the uncollected fees of NFT tokenID and checks
+// them against an eth_call of collect sent from owner, which pokes the pool
+// with a zero burn and so pays out exactly what the contract's own fee math
+// gives; nothing is committed. Both read the same block, resolved to a
+// number first. It fails with ErrNotOwner when owner doesn't hold the NFT,
+// as collect would revert.
+func (c *Client) ReconcileCollect(ctx context.Context, tokenID *big.Int, owner common.Address, block *big.Int) (Reconciliation, error) {
+	block, err := c.fixedBlock(ctx, block)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+
+	positions, err := c.TokenPositions(ctx, []*big.Int{tokenID}, block)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	p := positions[0]
+	if p.Owner != owner {
+		return Reconciliation{}, fmt.Errorf("%w: token %s is owned by %s", ErrNotOwner, tokenID, p.Owner.Hex())
+	}
+
+	stateCalls, err := c.rangeFeeCalls(p.Pool, p.TickLower, p.TickUpper)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	responses, err := c.aggregate(ctx, stateCalls, block)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	state, err := c.unpackRangeFees(responses, p.TickLower, p.TickUpper)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	fees := state.fees(p.Position, p.TickLower, p.TickUpper)
+
+	ch, err := c.chain(ctx)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	calldata, err := c.abis.NPMABI().Pack(collectMethod, collectParams{
+		TokenId:    tokenID,
+		Recipient:  owner,
+		Amount0Max: maxUint128,
+		Amount1Max: maxUint128,
+	})
+	if err != nil {
+		return Reconciliation{}, fmt.Errorf("pack collect: %w", err)
+	}
+	response, err := c.callContract(ctx, ethereum.CallMsg{From: owner, To: &ch.NPM, Data: calldata}, block)
+	if err != nil {
+		return Reconciliation{}, fmt.Errorf("simulate collect: %w", err)
+	}
+	out, err := c.abis.NPMABI().Unpack(collectMethod, response)
+	if err != nil {
+		return Reconciliation{}, fmt.Errorf("parse collect result: %w", err)
+	}
+
+	return Reconciliation{
+		TokenID:    tokenID,
+		Owner:      owner,
+		Block:      block,
+		Predicted0: fees.Fees0,
+		Predicted1: fees.Fees1,
+		Collected0: out[0].(*big.Int),
+		Collected1: out[1].(*big.Int),
+	}, nil
+}
+
+// writeReconciliation prints r as lines with the differences, collected
+// minus predicted, or as JSON.
+func writeReconciliation(w io.Writer, format string, r Reconciliation) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(r)
+	}
+
+	fmt.Fprintf(w, "token %s at block %s\n", r.TokenID, r.Block)
+	fmt.Fprintf(w, "predicted: %s token0 %s token1\n", r.Predicted0, r.Predicted1)
+	fmt.Fprintf(w, "collect:   %s token0 %s token1\n", r.Collected0, r.Collected1)
+	if r.Agrees() {
+		_, err := fmt.Fprintln(w, "agree")
+		return err
+	}
+	diff0 := new(big.Int).Sub(r.Collected0, r.Predicted0)
+	diff1 := new(big.Int).Sub(r.Collected1, r.Predicted1)
+	_, err := fmt.Fprintf(w, "differ:    %s token0 %s token1\n", diff0, diff1)
+
+	return err
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// reconcileNode serves the NFTs of npm over a pool that accrued 2 token0 per
+// unit of liquidity, 1000 for token 1's 500, and answers collect with
+// collected.
+func reconcileNode(t *testing.T, npm *fakeNPM, collected0, collected1 int64) *fakeNode {
+	pool := newFakePool(0, 1000)
+	pool.global0 = new(big.Int).Mul(q128, big.NewInt(2))
+	npm.next = poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)
+	positions := npm.handler(t)
+
+	return &fakeNode{head: 100, handle: func(to common.Address, data []byte) ([]byte, error) {
+		if to != arbitrumNPM || methodOf(t, npmABI, data).Name != collectMethod {
+			return positions(to, data)
+		}
+		in, err := npmABI.Methods[collectMethod].Inputs.Unpack(data[4:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		params := *abi.ConvertType(in[0], new(collectParams)).(*collectParams)
+		if params.Recipient != testOwner || params.Amount0Max.Cmp(maxUint128) != 0 || params.Amount1Max.Cmp(maxUint128) != 0 {
+			t.Errorf("collect params = %+v", params)
+		}
+		return packOutputs(t, npmABI, collectMethod, big.NewInt(collected0), big.NewInt(collected1)), nil
+	}}
+}
+
+func TestReconcileCollect(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		collected0 int64
+		agrees     bool
+	}{
+		{"agree", 1000, true},
+		{"differ", 1003, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {1}}}
+			node := reconcileNode(t, npm, tc.collected0, 0)
+			client := newFakeClient(t, node)
+
+			rec, err := client.ReconcileCollect(context.Background(), big.NewInt(1), testOwner, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rec.Predicted0.Int64() != 1000 || rec.Predicted1.Sign() != 0 || rec.Collected0.Int64() != tc.collected0 {
+				t.Errorf("reconciliation = %+v", rec)
+			}
+			if rec.Agrees() != tc.agrees {
+				t.Errorf("Agrees() = %t, want %t", rec.Agrees(), tc.agrees)
+			}
+			// the latest block is pinned for both reads
+			if rec.Block == nil || rec.Block.Int64() != 100 {
+				t.Errorf("block = %v, want 100", rec.Block)
+			}
+			for i, block := range node.blocks {
+				if block != "0x64" {
+					t.Errorf("call %d read block %s, want 0x64", i, block)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileCollectNotOwner(t *testing.T) {
+	other := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	npm := &fakeNPM{tokens: map[common.Address][]int64{other: {1}}}
+	client := newFakeClient(t, reconcileNode(t, npm, 1000, 0))
+
+	if _, err := client.ReconcileCollect(context.Background(), big.NewInt(1), testOwner, nil); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("err = %v, want ErrNotOwner", err)
+	}
+}
+
+func TestRunReconcile(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		collected0 int64
+		want       int
+		line       string
+	}{
+		{"agree", 1000, exitOK, "agree"},
+		{"differ", 997, exitFailure, "differ:    -3 token0 0 token1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {1}}}
+			node := reconcileNode(t, npm, tc.collected0, 0)
+
+			code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-reconcile", "1", "-owner", testOwner.Hex())
+			if code != tc.want {
+				t.Fatalf("exit code = %d, want %d; stderr:\n%s", code, tc.want, stderr)
+			}
+			if !strings.Contains(stdout, "predicted: 1000 token0 0 token1\n") || !strings.Contains(stdout, tc.line+"\n") {
+				t.Errorf("output lacks %q:\n%s", tc.line, stdout)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TokenInfo is what a report knows about a pool token.
+type TokenInfo struct {
+	Address  common.Address `json:"address"`
+	Decimals uint8          `json:"decimals"`
+	// Assumed is set when the token's decimals() reverted and Decimals is
+	// defaultDecimals.
+	Assumed bool `json:"assumed,omitempty"`
+}
+
+// warning is the warning for a token whose decimals are assumed.
+func (t TokenInfo) warning() string {
+	return fmt.Sprintf("token %s: decimals() reverted, assuming %d", t.Address, defaultDecimals)
+}
+
+// PositionReport is everything about one position at one block.
+type PositionReport struct {
+	Query    PositionQuery `json:"query"`
+	Block    *big.Int      `json:"block"`
+	Position Position      `json:"position"`
+
+	Token0      TokenInfo `json:"token0"`
+	Token1      TokenInfo `json:"token1"`
+	Fee         uint32    `json:"fee"`
+	TickSpacing int32     `json:"tickSpacing"`
+
+	// PriceLower, PriceUpper and Price are token1 per token0 in whole
+	// tokens, at the range bounds and at the pool's current price.
+	PriceLower   *big.Float `json:"priceLower"`
+	PriceUpper   *big.Float `json:"priceUpper"`
+	Price        *big.Float `json:"price"`
+	SqrtPriceX96 *big.Int   `json:"sqrtPriceX96"`
+	Tick         Tick       `json:"tick"`
+	InRange      bool       `json:"inRange"`
+	FullRange    bool       `json:"fullRange"`
+
+	// Amount0 and Amount1 are what the liquidity is worth at the current
+	// price; Fees0 and Fees1 are what collect would pay out on top.
+	Amount0 *big.Int `json:"amount0"`
+	Amount1 *big.Int `json:"amount1"`
+	Fees0   *big.Int `json:"fees0"`
+	Fees1   *big.Int `json:"fees1"`
+	// Value is amounts plus fees in raw token1 units at ValuePrice, token1
+	// per token0 in whole tokens: Price, or the rate of the tokens' price
+	// overrides.
+	Value      *big.Int   `json:"value"`
+	ValuePrice *big.Float `json:"valuePrice"`
+
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// reportPoolMethods are the pool's immutables a report reads, in the order
+// of its first batch.
+var reportPoolMethods = []string{feeMethod, token0Method, token1Method, tickSpacingMethod}
+
+// Report reads q and derives every figure of a PositionReport from one block:
+// the head's number when block is nil and no block is pinned. The reads take
+// two batches, the pool's immutables with the position and its fee state,
+// then the tokens' decimals.
+func (c *Client) Report(ctx context.Context, q PositionQuery, block *big.Int) (*PositionReport, error) {
+	if err := validateTicks(q.TickLower, q.TickUpper); err != nil {
+		return nil, err
+	}
+
+	block, err := c.fixedBlock(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+
+	calls := make([]call, 0, len(reportPoolMethods)+1+rangeFeeCallCount)
+	for _, method := range reportPoolMethods {
+		calldata, err := c.abis.PoolABI().Pack(method)
+		if err != nil {
+			return nil, fmt.Errorf("pack %s: %w", method, err)
+		}
+		calls = append(calls, call{Target: q.Pool, CallData: calldata})
+	}
+	positionData, err := c.positionCalldata(q)
+	if err != nil {
+		return nil, err
+	}
+	calls = append(calls, call{Target: q.Pool, CallData: positionData})
+	stateCalls, err := c.rangeFeeCalls(q.Pool, q.TickLower, q.TickUpper)
+	if err != nil {
+		return nil, err
+	}
+	calls = append(calls, stateCalls...)
+
+	responses, err := c.aggregate(ctx, calls, block)
+	if err != nil {
+		return nil, fmt.Errorf("read position at block %s: %w", block, err)
+	}
+
+	var immutables [4]interface{}
+	for i, method := range reportPoolMethods {
+		out, err := c.abis.PoolABI().Unpack(method, responses[i])
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", method, err)
+		}
+		immutables[i] = out[0]
+	}
+	responses = responses[len(reportPoolMethods):]
+
+	position, err := c.unpackPosition(responses[0])
+	if err != nil {
+		return nil, err
+	}
+	state, err := c.unpackRangeFees(responses[1:], q.TickLower, q.TickUpper)
+	if err != nil {
+		return nil, err
+	}
+	fees := state.fees(position, q.TickLower, q.TickUpper)
+
+	r := &PositionReport{
+		Query:        q,
+		Block:        block,
+		Position:     position,
+		Token0:       TokenInfo{Address: immutables[1].(common.Address)},
+		Token1:       TokenInfo{Address: immutables[2].(common.Address)},
+		Fee:          uint32(immutables[0].(*big.Int).Uint64()),
+		TickSpacing:  int32(immutables[3].(*big.Int).Int64()),
+		SqrtPriceX96: fees.SqrtPriceX96,
+		Tick:         fees.Tick,
+		InRange:      fees.InRange,
+		Fees0:        fees.Fees0,
+		Fees1:        fees.Fees1,
+		Warnings:     tokenWarnings(defaultFlaggedTokens, immutables[1].(common.Address), immutables[2].(common.Address)),
+	}
+	r.FullRange = IsFullRange(q.TickLower, q.TickUpper, r.TickSpacing)
+
+	warnings, err := c.readDecimals(ctx, block, &r.Token0, &r.Token1)
+	if err != nil {
+		return nil, err
+	}
+	r.Warnings = append(r.Warnings, warnings...)
+
+	r.Amount0, r.Amount1 = AmountsForLiquidity(fees.SqrtPriceX96, q.TickLower, q.TickUpper, fees.Liquidity)
+	r.Price = PriceFromSqrtPriceX96(fees.SqrtPriceX96, r.Token0.Decimals, r.Token1.Decimals)
+	valuePrice := c.valuationPrice(r.Token0.Address, r.Token1.Address, r.Token0.Decimals, r.Token1.Decimals, fees.SqrtPriceX96)
+	r.Value = valueInToken1(new(big.Int).Add(r.Amount0, r.Fees0), new(big.Int).Add(r.Amount1, r.Fees1), valuePrice)
+	r.ValuePrice = r.Price
+	if rate, ok := c.prices.rate(r.Token0.Address, r.Token1.Address); ok {
+		r.ValuePrice = rate
+	}
+	r.PriceLower = PriceFromSqrtPriceX96(q.TickLower.SqrtRatio(), r.Token0.Decimals, r.Token1.Decimals)
+	r.PriceUpper = PriceFromSqrtPriceX96(q.TickUpper.SqrtRatio(), r.Token0.Decimals, r.Token1.Decimals)
+
+	return r, nil
+}
+
+// readDecimals fills the decimals of tokens in one batch. decimals() is
+// optional in ERC-20, so a token whose call reverts gets defaultDecimals and
+// a warning instead of failing the read.
+func (c *Client) readDecimals(ctx context.Context, block *big.Int, tokens ...*TokenInfo) (warnings []string, err error) {
+	calldata, err := c.abis.ERC20ABI().Pack(decimalsMethod)
+	if err != nil {
+		return nil, fmt.Errorf("pack decimals: %w", err)
+	}
+
+	calls := make([]call, len(tokens))
+	for i, token := range tokens {
+		calls[i] = call{Target: token.Address, CallData: calldata}
+	}
+
+	responses, err := c.aggregate(ctx, calls, block)
+	var batchErr *BatchError
+	if err != nil && !(errors.As(err, &batchErr) && batchErr.reverted()) {
+		return nil, fmt.Errorf("read token decimals: %w", err)
+	}
+
+	for i, token := range tokens {
+		if batchErr != nil && batchErr.Errs[i] != nil {
+			token.Decimals, token.Assumed = defaultDecimals, true
+			warnings = append(warnings, token.warning())
+			continue
+		}
+
+		out, err := c.abis.ERC20ABI().Unpack(decimalsMethod, responses[i])
+		if err != nil {
+			return nil, fmt.Errorf("parse decimals of %s: %w", token.Address, err)
+		}
+		token.Decimals = out[0].(uint8)
+	}
+
+	return warnings, nil
+}
+
+// writeReport prints r as labeled lines, or as JSON.
+func writeReport(w io.Writer, format string, r *PositionReport) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(r)
+	}
+
+	status := "out of range"
+	if r.InRange {
+		status = "in range"
+	}
+	if r.FullRange {
+		status += ", full range"
+	}
+
+	fmt.Fprintf(w, "block %s, tick %d, %s\n", r.Block, r.Tick, status)
+	fmt.Fprintf(w, "pool:      %s, fee %d, tick spacing %d\n", r.Query.Pool.Hex(), r.Fee, r.TickSpacing)
+	fmt.Fprintf(w, "tokens:    %s (%d decimals) / %s (%d decimals)\n", r.Token0.Address.Hex(), r.Token0.Decimals, r.Token1.Address.Hex(), r.Token1.Decimals)
+	fmt.Fprintf(w, "price:     %s, range %s to %s\n", formatPrice(r.Price), formatPrice(r.PriceLower), formatPrice(r.PriceUpper))
+	fmt.Fprintf(w, "liquidity: %s\n", r.Position.Liquidity)
+	fmt.Fprintf(w, "amounts:   %s token0 %s token1\n", r.Amount0, r.Amount1)
+	fmt.Fprintf(w, "fees:      %s token0 %s token1\n", r.Fees0, r.Fees1)
+	_, err := fmt.Fprintf(w, "value:     %s token1 at price %s\n", r.Value, formatPrice(r.ValuePrice))
+
+	return err
+}
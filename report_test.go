@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// reportNode serves a pool at tick 0 whose token1 has no decimals(), with a
+// position of 1e18 liquidity in [-60, 60) that earned 4 token0 per unit of
+// liquidity and is owed 7 more.
+func reportNode(t *testing.T) (*fakeNode, PositionQuery) {
+	pool := newFakePool(0, 1e18)
+	pool.global0 = new(big.Int).Mul(q128, big.NewInt(5))
+	pool.ticks[-60] = fakeTick{outside0: new(big.Int).Set(q128)}
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	pool.setPosition(t, q, Position{Liquidity: big.NewInt(1e18), TokensOwed0: big.NewInt(7)})
+
+	decimals := map[common.Address]uint8{pool.token0: 18}
+	return &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, decimals)}, q
+}
+
+func TestReport(t *testing.T) {
+	node, q := reportNode(t)
+	client := newFakeClient(t, node)
+
+	r, err := client.Report(context.Background(), q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every figure is read or derived, none is left out
+	v := reflect.ValueOf(*r)
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Field(i); field.Kind() == reflect.Pointer && field.IsNil() || field.Kind() == reflect.Slice && field.Len() == 0 {
+			t.Errorf("%s is not populated", v.Type().Field(i).Name)
+		}
+	}
+
+	if r.Block.Int64() != 100 || r.Fee != 3000 || r.TickSpacing != 60 || !r.InRange || r.FullRange {
+		t.Errorf("report = %+v", r)
+	}
+	if r.Token0.Decimals != 18 || r.Token0.Assumed || r.Token1.Decimals != defaultDecimals || !r.Token1.Assumed {
+		t.Errorf("tokens = %+v, %+v, want token1's decimals assumed", r.Token0, r.Token1)
+	}
+	if r.Fees0.Cmp(big.NewInt(4e18+7)) != 0 || r.Fees1.Sign() != 0 {
+		t.Errorf("fees = %s, %s, want %d, 0", r.Fees0, r.Fees1, int64(4e18+7))
+	}
+	if r.Amount0.Sign() <= 0 || r.Amount1.Sign() <= 0 {
+		t.Errorf("amounts = %s, %s, want both tokens in range", r.Amount0, r.Amount1)
+	}
+	// at price 1, the value is the sum of the amounts and fees
+	want := new(big.Int).Add(r.Amount0, r.Amount1)
+	if want.Add(want, r.Fees0); r.Value.Cmp(want) != 0 {
+		t.Errorf("value = %s, want %s", r.Value, want)
+	}
+	if r.PriceLower.Cmp(r.Price) >= 0 || r.Price.Cmp(r.PriceUpper) >= 0 {
+		t.Errorf("price %s is not inside %s to %s", r.Price, r.PriceLower, r.PriceUpper)
+	}
+	for i, block := range node.blocks {
+		if block != "0x64" {
+			t.Errorf("call %d read block %s, want 0x64", i, block)
+		}
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	node, _ := reportNode(t)
+
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-report", "-tick-lower", "-60", "-tick-upper", "60")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	for _, want := range []string{"block 100, tick 0, in range\n", "pool:      " + testPool.Hex() + ", fee 3000, tick spacing 60\n", "liquidity: 1000000000000000000\n", "fees:      4000000000000000007 token0 0 token1\n"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %q:\n%s", want, stdout)
+		}
+	}
+	if !strings.Contains(stderr, "warning: token 0x000000000000000000000000000000000000000b: decimals() reverted") {
+		t.Errorf("stderr lacks the decimals warning:\n%s", stderr)
+	}
+
+	node, _ = reportNode(t)
+	code, stdout, stderr = runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-report", "-tick-lower", "-60", "-tick-upper", "60", "-format", "json")
+	if code != exitOK {
+		t.Fatalf("json: exit code = %d; stderr:\n%s", code, stderr)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, stdout)
+	}
+	// uint256 figures are decimal strings, like every other big integer
+	for _, field := range []string{"block", "sqrtPriceX96", "amount0", "amount1", "fees0", "fees1", "value"} {
+		if _, ok := out[field].(string); !ok {
+			t.Errorf("%s = %#v, want a decimal string", field, out[field])
+		}
+	}
+	if out["block"] != "100" || out["fees0"] != "4000000000000000007" {
+		t.Errorf("block, fees0 = %v, %v, want 100, 4000000000000000007", out["block"], out["fees0"])
+	}
+
+	// no position at the default ticks
+	node, _ = reportNode(t)
+	if code, _, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-report"); code != exitNotFound {
+		t.Errorf("no position: exit code = %d, want %d; stderr:\n%s", code, exitNotFound, stderr)
+	}
+}
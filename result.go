@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatTable = "table"
+	// formatNDJSON is one compact JSON result per line, flushed per line.
+	formatNDJSON = "ndjson"
+)
+
+// PositionResult is a fetched position together with what identifies it.
+type PositionResult struct {
+	Pool      common.Address `json:"pool"`
+	Owner     common.Address `json:"owner"`
+	TokenID   *big.Int       `json:"tokenId,omitempty"`
+	TickLower Tick           `json:"tickLower"`
+	TickUpper Tick           `json:"tickUpper"`
+	Position  Position       `json:"position"`
+
+	Token0      common.Address `json:"token0"`
+	Token1      common.Address `json:"token1"`
+	Fee         uint32         `json:"fee"`
+	FeePercent  string         `json:"feePercent"`
+	TickSpacing int32          `json:"tickSpacing,omitempty"`
+	// Decimals0 and Decimals1 are set with -scaled; JSON output then also
+	// carries the owed amounts scaled by them.
+	Decimals0 *uint8 `json:"decimals0,omitempty"`
+	Decimals1 *uint8 `json:"decimals1,omitempty"`
+	// FullRange is set when the ticks cover the whole usable range of the
+	// pool's tick spacing.
+	FullRange bool `json:"fullRange,omitempty"`
+
+	// BaseQuote is set when the user named the pair's base and quote.
+	BaseQuote *BaseQuote `json:"baseQuote,omitempty"`
+
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Error is set when this position couldn't be read while the rest of
+	// the batch could.
+	Error string `json:"error,omitempty"`
+
+	// Labels are free-form annotations, e.g. set by a PositionProcessor.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// SetLabel sets a label, allocating Labels as needed.
+func (r *PositionResult) SetLabel(key, value string) {
+	if r.Labels == nil {
+		r.Labels = make(map[string]string)
+	}
+	r.Labels[key] = value
+}
+
+func (r *PositionResult) setPool(info PoolInfo) {
+	r.Fee = info.Fee
+	r.FeePercent = feePercent(info.Fee)
+	r.TickSpacing = info.TickSpacing
+	r.FullRange = IsFullRange(r.TickLower, r.TickUpper, r.TickSpacing)
+}
+
+func newPositionResult(q PositionQuery, position Position) PositionResult {
+	return PositionResult{
+		Pool:      q.Pool,
+		Owner:     q.Owner,
+		TickLower: q.TickLower,
+		TickUpper: q.TickUpper,
+		Position:  position,
+	}
+}
+
+// newPositionResults pairs queries with positions read by Positions, marking
+// the reads that failed in a partial batch. err is what Positions returned.
+func newPositionResults(queries []PositionQuery, positions []Position, err error) []PositionResult {
+	var batchErr *BatchError
+	errors.As(err, &batchErr)
+
+	results := make([]PositionResult, len(queries))
+	for i, q := range queries {
+		results[i] = newPositionResult(q, positions[i])
+		if batchErr != nil && batchErr.Errs[i] != nil {
+			results[i].Error = batchErr.Errs[i].Error()
+		}
+	}
+
+	return results
+}
+
+// newTokenPositionResults is newPositionResults for positions read by
+// TokenPositions.
+func newTokenPositionResults(positions []TokenPosition, err error) []PositionResult {
+	var batchErr *BatchError
+	errors.As(err, &batchErr)
+
+	results := make([]PositionResult, len(positions))
+	for i, p := range positions {
+		results[i] = newTokenPositionResult(p)
+		if batchErr != nil && batchErr.Errs[i] != nil {
+			results[i].Error = batchErr.Errs[i].Error()
+		}
+	}
+
+	return results
+}
+
+func newTokenPositionResult(p TokenPosition) PositionResult {
+	return PositionResult{
+		Pool:      p.Pool,
+		Owner:     p.Owner,
+		TokenID:   p.TokenID,
+		TickLower: p.TickLower,
+		TickUpper: p.TickUpper,
+		Position:  p.Position,
+	}
+}
+
+// writeResults writes results with the Formatter registered as format.
+func writeResults(w io.Writer, format string, results []PositionResult, fullAddresses bool) error {
+	formatter, ok := lookupFormatter(format)
+	if !ok {
+		return fmt.Errorf("unsupported format: %s (registered: %s)", format, strings.Join(formatterNames(), ", "))
+	}
+
+	return formatter.Format(w, results, FormatOptions{FullAddresses: fullAddresses})
+}
+
+// writeNDJSON writes one line per result and flushes w after each one if it
+// buffers, so a consumer sees results as they are written.
+func writeNDJSON(w io.Writer, results []PositionResult) error {
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(interface{ Flush() error })
+
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+		if flusher != nil {
+			if err := flusher.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeTable prints results as aligned columns, with shortened addresses
+// unless fullAddresses is set.
+func writeTable(w io.Writer, results []PositionResult, fullAddresses bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "POOL\tOWNER\tPAIR\tTOKEN ID\tRANGE\tFEE\tLIQUIDITY\tOWED0\tOWED1")
+	for _, r := range results {
+		tokenID := "-"
+		if r.TokenID != nil {
+			tokenID = r.TokenID.String()
+		}
+
+		pair := "-"
+		if r.Token0 != (common.Address{}) {
+			pair = displayAddress(r.Token0, fullAddresses) + "/" + displayAddress(r.Token1, fullAddresses)
+		}
+
+		ticks := fmt.Sprintf("[%d, %d)", r.TickLower, r.TickUpper)
+		if r.FullRange {
+			ticks = "full range"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			displayAddress(r.Pool, fullAddresses), displayAddress(r.Owner, fullAddresses), pair, tokenID, ticks, r.FeePercent,
+			r.Position.Liquidity, r.Position.TokensOwed0, r.Position.TokensOwed1)
+	}
+
+	return tw.Flush()
+}
+
+// shortAddress abbreviates an address as 0x1234…abcd.
+func shortAddress(address common.Address) string {
+	hex := address.Hex()
+	return hex[:6] + "…" + hex[len(hex)-4:]
+}
+
+// displayAddress is how tables and panels print an address: shortened, or in
+// full with -full-addresses.
+func displayAddress(address common.Address, full bool) string {
+	if full {
+		return address.Hex()
+	}
+
+	return shortAddress(address)
+}
+
+// anyFound reports whether a result holds a position that exists: the pool
+// returns all zeros for keys it never saw.
+func anyFound(results []PositionResult) bool {
+	for _, r := range results {
+		if !r.Position.empty() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// empty reports whether every field of p is zero.
+func (p Position) empty() bool {
+	for _, v := range []*big.Int{p.Liquidity, p.FeeGrowthInside0LastX128, p.FeeGrowthInside1LastX128, p.TokensOwed0, p.TokensOwed1} {
+		if v != nil && v.Sign() != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterByLiquidity keeps the results with at least minLiquidity.
+func filterByLiquidity(results []PositionResult, minLiquidity *big.Int) []PositionResult {
+	var kept []PositionResult
+
+	for _, r := range results {
+		if r.Position.Liquidity.Cmp(minLiquidity) >= 0 {
+			kept = append(kept, r)
+		}
+	}
+
+	return kept
+}
+
+// printUpdates writes every WatchPosition update as soon as it arrives and,
+// with a metrics registry, records it there.
+func printUpdates(ctx context.Context, client *Client, q PositionQuery, format string, metrics *metricsRegistry) error {
+	updates := make(chan PositionUpdate)
+	errc := make(chan error, 1)
+
+	go func() {
+		errc <- client.WatchPosition(ctx, q, updates)
+	}()
+
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		select {
+		case err := <-errc:
+			return err
+		case update := <-updates:
+			var err error
+			switch format {
+			case formatJSON, formatNDJSON:
+				err = enc.Encode(update)
+			default:
+				_, err = fmt.Printf("%+v\n", update)
+			}
+			if err != nil {
+				return err
+			}
+
+			if metrics != nil {
+				if err := metrics.record(ctx, client, q, update); err != nil {
+					log.Printf("watch: record metrics block=%d err=%q", update.Block, err)
+				}
+			}
+		}
+	}
+}
+
+// streamList is -list -format ndjson: it reads the owners' positions a batch
+// of enumerationChunk at a time and writes each batch as soon as it is read
+// and prepared, instead of after the whole portfolio.
+func streamList(ctx context.Context, client *Client, owners []common.Address, block *big.Int, page Page, minLiquidity *big.Int, prepare func([]PositionResult) ([]PositionResult, error)) error {
+	found := false
+
+	for offset, read := page.Offset, 0; page.Limit == 0 || read < page.Limit; {
+		size := enumerationChunk
+		if page.Limit > 0 {
+			size = min(size, page.Limit-read)
+		}
+
+		positions, err := client.ListOwnersPositions(ctx, owners, block, Page{Offset: offset, Limit: size})
+		if err != nil {
+			return nodeFailure("list positions: %w", err)
+		}
+		offset, read = offset+len(positions), read+len(positions)
+
+		results := make([]PositionResult, len(positions))
+		for i, p := range positions {
+			results[i] = newTokenPositionResult(p)
+		}
+		if results, err = prepare(filterByLiquidity(results, minLiquidity)); err != nil {
+			return err
+		}
+		found = found || anyFound(results)
+
+		if err := writeNDJSON(os.Stdout, results); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+
+		if len(positions) < size {
+			break
+		}
+	}
+
+	if !found {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestFilterByLiquidity(t *testing.T) {
+	results := make([]PositionResult, 4)
+	for i, liquidity := range []int64{0, 1, 999, 1000} {
+		results[i].Position.Liquidity = big.NewInt(liquidity)
+	}
+
+	for _, tc := range []struct {
+		min  int64
+		want int
+	}{
+		{0, 4},
+		{1, 3},
+		{1000, 1},
+		{1001, 0},
+	} {
+		kept := filterByLiquidity(results, big.NewInt(tc.min))
+		if len(kept) != tc.want {
+			t.Errorf("min %d kept %d results, want %d", tc.min, len(kept), tc.want)
+		}
+		for _, r := range kept {
+			if r.Position.Liquidity.Int64() < tc.min {
+				t.Errorf("min %d kept liquidity %s", tc.min, r.Position.Liquidity)
+			}
+		}
+	}
+}
+
+// listNode serves the NFT positions of npm, all in testPool.
+func listNode(t *testing.T, npm *fakeNPM) *fakeNode {
+	npm.next = poolsHandler(t, map[common.Address]*fakePool{testPool: newFakePool(0, 1000)}, nil)
+
+	return &fakeNode{head: 100, handle: npm.handler(t)}
+}
+
+// tokenIDs returns the token ids of -format json or ndjson output.
+func tokenIDs(t *testing.T, out string) []int64 {
+	t.Helper()
+
+	var results []PositionResult
+	if strings.HasPrefix(out, "[") {
+		if err := json.Unmarshal([]byte(out), &results); err != nil {
+			t.Fatalf("parse output: %v\n%s", err, out)
+		}
+	} else {
+		for scanner := bufio.NewScanner(strings.NewReader(out)); scanner.Scan(); {
+			var r PositionResult
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				t.Fatalf("parse line: %v\n%s", err, scanner.Text())
+			}
+			results = append(results, r)
+		}
+	}
+
+	ids := make([]int64, len(results))
+	for i, r := range results {
+		ids[i] = r.TokenID.Int64()
+	}
+
+	return ids
+}
+
+func TestListMinLiquidity(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		code int
+		ids  []int64
+	}{
+		// closed positions are hidden by default
+		{nil, exitOK, []int64{1, 3}},
+		{[]string{"-format", "ndjson"}, exitOK, []int64{1, 3}},
+		{[]string{"-min-liquidity", "0"}, exitOK, []int64{1, 2, 3}},
+		{[]string{"-min-liquidity", "600"}, exitOK, []int64{3}},
+		{[]string{"-min-liquidity", "10000"}, exitNotFound, nil},
+		{[]string{"-min-liquidity", "-1"}, exitBadInput, nil},
+		{[]string{"-min-liquidity", "1e3"}, exitBadInput, nil},
+	} {
+		npm := &fakeNPM{
+			tokens:    map[common.Address][]int64{testOwner: {1, 2, 3}},
+			liquidity: map[int64]int64{2: 0, 3: 800},
+		}
+		args := append([]string{"-node", listNode(t, npm).serve(t), "-max-lag", "0", "-list", "-owner", testOwner.Hex(), "-format", "json"}, tc.args...)
+
+		code, out, stderr := runOutput(t, args...)
+		if code != tc.code {
+			t.Errorf("%q: exit code %d, want %d; stderr:\n%s", tc.args, code, tc.code, stderr)
+			continue
+		}
+		if got := tokenIDs(t, out); !slices.Equal(got, tc.ids) {
+			t.Errorf("%q: listed token ids %v, want %v", tc.args, got, tc.ids)
+		}
+	}
+}
+
+func TestListReportsFilteredCount(t *testing.T) {
+	npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {1, 2, 3}}, liquidity: map[int64]int64{2: 0}}
+
+	_, _, stderr := runOutput(t, "-node", listNode(t, npm).serve(t), "-max-lag", "0", "-list", "-owner", testOwner.Hex())
+	if want := "found 3 positions, 2 with liquidity >= 1"; !strings.Contains(stderr, want) {
+		t.Errorf("log lacks %q:\n%s", want, stderr)
+	}
+}
+
+// columnStarts returns the rune offsets where the columns of a table line
+// start, columns being separated by two spaces or more.
+func columnStarts(line string) []int {
+	runes := []rune(line)
+	starts := []int{0}
+	for i := 2; i < len(runes); i++ {
+		if runes[i] != ' ' && runes[i-1] == ' ' && runes[i-2] == ' ' {
+			starts = append(starts, i)
+		}
+	}
+
+	return starts
+}
+
+func TestWriteTable(t *testing.T) {
+	pool := newFakePool(0, 1)
+	results := []PositionResult{
+		{
+			Pool: testPool, Owner: testOwner, Token0: pool.token0, Token1: pool.token1,
+			TickLower: -887220, TickUpper: 887220, FullRange: true, FeePercent: "0.3%",
+			Position: Position{Liquidity: maxUint128, TokensOwed0: big.NewInt(0), TokensOwed1: big.NewInt(123456789)},
+		},
+		{
+			Pool: badPool, Owner: testOwner, TokenID: big.NewInt(42),
+			TickLower: -60, TickUpper: 60, FeePercent: "0.05%",
+			Position: Position{Liquidity: big.NewInt(7), TokensOwed0: big.NewInt(1), TokensOwed1: big.NewInt(0)},
+		},
+	}
+
+	var out strings.Builder
+	if err := writeTable(&out, results, false); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("table has %d lines, want a header and 2 rows:\n%s", len(lines), out.String())
+	}
+
+	header := columnStarts(lines[0])
+	if len(header) != 9 {
+		t.Fatalf("header has %d columns: %q", len(header), lines[0])
+	}
+	for _, line := range lines[1:] {
+		if starts := columnStarts(line); !slices.Equal(starts, header) {
+			t.Errorf("columns of %q start at %v, header's at %v", line, starts, header)
+		}
+	}
+
+	for _, want := range []string{"0x1111…1111", shortAddress(pool.token0) + "/" + shortAddress(pool.token1), "full range", "[-60, 60)", maxUint128.String(), "  -  "} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("table lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestShortAddress(t *testing.T) {
+	address := common.HexToAddress("0xc36442b4a4522e871399cd717abdd847ab11fe88")
+	if got, want := shortAddress(address), "0xC364…FE88"; got != want {
+		t.Errorf("shortAddress = %s, want %s", got, want)
+	}
+	if got := displayAddress(address, false); got != "0xC364…FE88" {
+		t.Errorf("displayAddress = %s, want the short form", got)
+	}
+	if got := displayAddress(address, true); got != address.Hex() {
+		t.Errorf("displayAddress with full addresses = %s, want %s", got, address.Hex())
+	}
+}
+
+func TestTableFullAddresses(t *testing.T) {
+	npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: {1}}}
+	args := []string{"-max-lag", "0", "-list", "-owner", testOwner.Hex(), "-format", "table"}
+	token0 := common.HexToAddress("0x000000000000000000000000000000000000000a")
+
+	for _, full := range []bool{false, true} {
+		args := append([]string{"-node", listNode(t, npm).serve(t)}, args...)
+		if full {
+			args = append(args, "-full-addresses")
+		}
+		code, stdout, stderr := runOutput(t, args...)
+		if code != exitOK {
+			t.Fatalf("%q: exit code %d; stderr:\n%s", args, code, stderr)
+		}
+
+		// pool, owner and token columns alike
+		for _, address := range []common.Address{testPool, testOwner, token0} {
+			if got := strings.Contains(stdout, address.Hex()); got != full {
+				t.Errorf("full addresses %t: table has %s: %t\n%s", full, address.Hex(), got, stdout)
+			}
+			if got := strings.Contains(stdout, shortAddress(address)); got == full {
+				t.Errorf("full addresses %t: table has %s: %t\n%s", full, shortAddress(address), got, stdout)
+			}
+		}
+	}
+}
+
+// flushCounter is a buffer that counts the lines it holds at each Flush.
+type flushCounter struct {
+	bytes.Buffer
+	flushed []int
+}
+
+func (f *flushCounter) Flush() error {
+	f.flushed = append(f.flushed, strings.Count(f.String(), "\n"))
+	return nil
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	results := []PositionResult{
+		{Pool: testPool, Owner: testOwner, TokenID: big.NewInt(1), Position: Position{Liquidity: big.NewInt(10)}},
+		{Pool: testPool, Owner: testOwner, TokenID: big.NewInt(2)},
+		{Pool: badPool, Owner: testOwner, TokenID: big.NewInt(3), Error: "execution reverted"},
+	}
+
+	var out flushCounter
+	if err := writeNDJSON(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	if got := tokenIDs(t, out.String()); !slices.Equal(got, []int64{1, 2, 3}) {
+		t.Errorf("streamed token ids %v, want [1 2 3]:\n%s", got, out.String())
+	}
+	// one compact object per line, each flushed on its own
+	for i, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		if !strings.HasPrefix(line, "{") || !strings.HasSuffix(line, "}") {
+			t.Errorf("line %d = %s", i, line)
+		}
+	}
+	if !slices.Equal(out.flushed, []int{1, 2, 3}) {
+		t.Errorf("flushed at %v lines, want after every line", out.flushed)
+	}
+}
+
+func TestStreamListWritesEachBatch(t *testing.T) {
+	stdout := filepath.Join(t.TempDir(), "stdout")
+	// lines holds how much output was written when the second batch started
+	lines := -1
+
+	npm := &fakeNPM{tokens: map[common.Address][]int64{testOwner: tokenRange(1, enumerationChunk+100)}}
+	node := listNode(t, npm)
+	list := node.handle
+	node.handle = func(to common.Address, data []byte) ([]byte, error) {
+		if to == arbitrumNPM && methodOf(t, npmABI, data).Name == tokenOfOwnerByIndexMethod && lines < 0 {
+			in, err := npmABI.Methods[tokenOfOwnerByIndexMethod].Inputs.Unpack(data[4:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if in[1].(*big.Int).Int64() == enumerationChunk {
+				out, err := os.ReadFile(stdout)
+				if err != nil {
+					t.Fatal(err)
+				}
+				lines = bytes.Count(out, []byte("\n"))
+			}
+		}
+		return list(to, data)
+	}
+	client := newFakeClient(t, node)
+
+	f, err := os.Create(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	saved := os.Stdout
+	os.Stdout = f
+	err = streamList(context.Background(), client, []common.Address{testOwner}, nil, Page{}, big.NewInt(1), func(r []PositionResult) ([]PositionResult, error) { return r, nil })
+	os.Stdout = saved
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines != enumerationChunk {
+		t.Errorf("%d lines written before the second batch was read, want %d", lines, enumerationChunk)
+	}
+	out, err := os.ReadFile(stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := tokenIDs(t, string(out)); !slices.Equal(ids, tokenRange(1, enumerationChunk+100)) {
+		t.Errorf("streamed %d token ids, want 1..%d", len(ids), enumerationChunk+100)
+	}
+}
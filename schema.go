@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	addressType = reflect.TypeOf(common.Address{})
+	bigIntType  = reflect.TypeOf((*big.Int)(nil))
+)
+
+// outputSchema is the JSON Schema of -format json output. It is derived from
+// PositionResult by reflection so it can't drift from the struct.
+func outputSchema() (map[string]interface{}, error) {
+	item, err := typeSchema(reflect.TypeOf(PositionResult{}))
+	if err != nil {
+		return nil, err
+	}
+
+	// -scaled adds the owed amounts in whole tokens to the position,
+	// scaledPositionJSON in json.go
+	position := item["properties"].(map[string]interface{})["position"].(map[string]interface{})
+	for _, name := range []string{"tokensOwed0Scaled", "tokensOwed1Scaled"} {
+		position["properties"].(map[string]interface{})[name] = map[string]interface{}{"type": "string", "pattern": `^-?[0-9]+(\.[0-9]+)?$`}
+	}
+
+	return map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "UniswapGetPosition results",
+		"type":    "array",
+		"items":   item,
+	}, nil
+}
+
+// typeSchema follows the encoding rules of json.go: big integers and
+// addresses are strings.
+func typeSchema(t reflect.Type) (map[string]interface{}, error) {
+	switch t {
+	case addressType:
+		return map[string]interface{}{"type": "string", "pattern": "^0x[0-9a-fA-F]{40}$"}, nil
+	case bigIntType:
+		return map[string]interface{}{"type": []string{"string", "null"}, "pattern": "^-?[0-9]+$"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema["type"] = []interface{}{schema["type"], "null"}
+		return schema, nil
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Slice:
+		items, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": items}, nil
+	case reflect.Map:
+		values, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		return nil, fmt.Errorf("no schema for %s", t)
+	}
+}
+
+func structSchema(t reflect.Type) (map[string]interface{}, error) {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		schema, err := typeSchema(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+		properties[name] = schema
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}, nil
+}
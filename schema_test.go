@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// validate checks value, decoded with UseNumber, against the subset of JSON
+// Schema that outputSchema emits.
+func validate(schema map[string]interface{}, value interface{}, path string) error {
+	if types, ok := schema["type"]; ok {
+		var allowed []string
+		switch types := types.(type) {
+		case string:
+			allowed = []string{types}
+		case []interface{}:
+			for _, typ := range types {
+				s, ok := typ.(string)
+				if !ok {
+					return fmt.Errorf("%s: invalid type %v in the schema", path, types)
+				}
+				allowed = append(allowed, s)
+			}
+		default:
+			return fmt.Errorf("%s: invalid type %v in the schema", path, types)
+		}
+		if typ := jsonType(value); !slices.Contains(allowed, typ) && !(typ == "integer" && slices.Contains(allowed, "number")) {
+			return fmt.Errorf("%s: %s, want %v", path, typ, allowed)
+		}
+	}
+
+	switch value := value.(type) {
+	case string:
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(value) {
+			return fmt.Errorf("%s: %q doesn't match %s", path, value, pattern)
+		}
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range value {
+			if err := validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := value[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing %s", path, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, field := range value {
+			fieldSchema, ok := properties[name].(map[string]interface{})
+			if !ok {
+				switch additional := schema["additionalProperties"].(type) {
+				case bool:
+					if !additional {
+						return fmt.Errorf("%s: unexpected property %s", path, name)
+					}
+					continue
+				case map[string]interface{}:
+					fieldSchema = additional
+				default:
+					continue
+				}
+			}
+			if err := validate(fieldSchema, field, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if strings.ContainsAny(value.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func decodeJSON(t *testing.T, data []byte) interface{} {
+	t.Helper()
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		t.Fatalf("parse %s: %v", data, err)
+	}
+
+	return value
+}
+
+func TestSchemaValidatesOutput(t *testing.T) {
+	code, stdout, stderr := runOutput(t, "-schema")
+	if code != exitOK {
+		t.Fatalf("-schema: exit code %d; stderr:\n%s", code, stderr)
+	}
+	schema := decodeJSON(t, []byte(stdout)).(map[string]interface{})
+
+	decimals0, decimals1 := uint8(18), uint8(6)
+	full := PositionResult{
+		Pool:        testPool,
+		Owner:       testOwner,
+		TokenID:     big.NewInt(42),
+		TickLower:   -887220,
+		TickUpper:   887220,
+		Position:    Position{Liquidity: maxUint128, FeeGrowthInside0LastX128: maxUint, FeeGrowthInside1LastX128: big.NewInt(0), TokensOwed0: big.NewInt(15e17), TokensOwed1: big.NewInt(-1)},
+		Token0:      arbUSDC,
+		Token1:      arbWETH,
+		Fee:         3000,
+		FeePercent:  "0.3%",
+		TickSpacing: 60,
+		Decimals0:   &decimals0,
+		Decimals1:   &decimals1,
+		FullRange:   true,
+		BaseQuote:   &BaseQuote{Base: arbWETH, Quote: arbUSDC, OwedBase: big.NewInt(1), OwedQuote: big.NewInt(15e17)},
+		Warnings:    []string{"token flagged"},
+		Error:       "execution reverted",
+		Labels:      map[string]string{"strategy": "hedged"},
+	}
+	// an unread position, as a partial batch leaves it
+	empty := PositionResult{Pool: badPool, Owner: testOwner}
+
+	var out bytes.Buffer
+	if err := writeResults(&out, formatJSON, []PositionResult{full, empty}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := validate(schema, decodeJSON(t, out.Bytes()), "$"); err != nil {
+		t.Errorf("output doesn't match -schema: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "tokensOwed0Scaled") {
+		t.Errorf("sample output lacks scaled amounts:\n%s", out.String())
+	}
+
+	// and rejects what the schema doesn't describe
+	bad := decodeJSON(t, []byte(`[{"pool": "0x1234"}]`))
+	if err := validate(schema, bad, "$"); err == nil {
+		t.Error("validate accepted a truncated pool address")
+	}
+}
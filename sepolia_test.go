@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TestSepolia reads from a Sepolia node, to check the registry beyond
+// Arbitrum. It runs only with SEPOLIA_RPC_URL set:
+//
+//	SEPOLIA_RPC_URL=https://ethereum-sepolia-rpc.publicnode.com \
+//	SEPOLIA_TOKEN_ID=12345 go test -run TestSepolia
+//
+// SEPOLIA_TOKEN_ID, an NFT position on the Sepolia deployment, adds a read of
+// that position through the NPM and its pool.
+func TestSepolia(t *testing.T) {
+	rawURL := os.Getenv("SEPOLIA_RPC_URL")
+	if rawURL == "" {
+		t.Skip("SEPOLIA_RPC_URL not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	client, err := NewClient(ctx, rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := client.chain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Name != "sepolia" {
+		t.Fatalf("SEPOLIA_RPC_URL serves chain %d", ch.ID)
+	}
+	for name, address := range map[string]common.Address{"factory": ch.Factory, "NPM": ch.NPM, "Multicall3": ch.Multicall} {
+		code, err := client.eth.CodeAt(ctx, address, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) == 0 {
+			t.Errorf("no %s code at %s", name, address)
+		}
+	}
+
+	rawID := os.Getenv("SEPOLIA_TOKEN_ID")
+	if rawID == "" {
+		return
+	}
+	tokenID, ok := new(big.Int).SetString(rawID, 10)
+	if !ok {
+		t.Fatalf("invalid SEPOLIA_TOKEN_ID: %s", rawID)
+	}
+
+	positions, err := client.TokenPositions(ctx, []*big.Int{tokenID}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := positions[0]
+	if p.Pool == (common.Address{}) {
+		t.Fatalf("position %s has pool %s", tokenID, p.Pool)
+	}
+
+	// the pool holds the liquidity of every NPM position with these ticks
+	position, err := client.Position(ctx, PositionQuery{Pool: p.Pool, Owner: ch.NPM, TickLower: p.TickLower, TickUpper: p.TickUpper}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position.Liquidity.Cmp(p.Liquidity) < 0 {
+		t.Errorf("pool liquidity %s is less than the NFT's %s", position.Liquidity, p.Liquidity)
+	}
+}
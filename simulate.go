@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Simulated reads
+//
+// eth_simulateV1 executes a sequence of calls on top of a block, each seeing
+// the state the earlier ones left, without signing or sending anything. Reading
+// the position as the last call of the sequence returns its state after a
+// hypothetical bundle, e.g. a searcher's swaps ahead of a burn.
+// https://github.com/ethereum/execution-apis/pull/484
+
+type simulateCall struct {
+	From  *common.Address `json:"from,omitempty"`
+	To    *common.Address `json:"to,omitempty"`
+	Gas   *hexutil.Uint64 `json:"gas,omitempty"`
+	Value *hexutil.Big    `json:"value,omitempty"`
+	Data  hexutil.Bytes   `json:"data,omitempty"`
+}
+
+type simulateBlock struct {
+	StateOverrides map[common.Address]OverrideAccount `json:"stateOverrides,omitempty"`
+	Calls          []simulateCall                     `json:"calls"`
+}
+
+type simulateOptions struct {
+	BlockStateCalls []simulateBlock `json:"blockStateCalls"`
+}
+
+type simulateResult struct {
+	Calls []struct {
+		ReturnData hexutil.Bytes  `json:"returnData"`
+		Status     hexutil.Uint64 `json:"status"`
+		Error      *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"calls"`
+}
+
+// SimulatePosition reads q after executing txs in order on top of block, nil
+// meaning latest, with overrides applied first. Transactions aren't
+// validated: nonces, balances and gas prices don't have to work out. A
+// reverting transaction fails the read, since the position would be read from
+// a state the bundle never reaches. The node must support eth_simulateV1, as
+// geth does since 1.14.
+func (c *Client) SimulatePosition(ctx context.Context, q PositionQuery, txs []ethereum.CallMsg, block *big.Int, overrides map[common.Address]OverrideAccount) (Position, error) {
+	calldata, err := c.positionCalldata(q)
+	if err != nil {
+		return Position{}, err
+	}
+
+	calls := make([]simulateCall, 0, len(txs)+1)
+	for _, tx := range txs {
+		sc := simulateCall{To: tx.To, Data: tx.Data}
+		if tx.From != (common.Address{}) {
+			sc.From = &tx.From
+		}
+		if tx.Gas != 0 {
+			sc.Gas = (*hexutil.Uint64)(&tx.Gas)
+		}
+		if tx.Value != nil {
+			sc.Value = (*hexutil.Big)(tx.Value)
+		}
+		calls = append(calls, sc)
+	}
+	calls = append(calls, simulateCall{To: &q.Pool, Data: calldata})
+
+	blockArg := "latest"
+	if block = c.at(block); block != nil {
+		blockArg = hexutil.EncodeBig(block)
+	}
+
+	var results []simulateResult
+	options := simulateOptions{BlockStateCalls: []simulateBlock{{StateOverrides: overrides, Calls: calls}}}
+	if err := c.eth.Client().CallContext(ctx, &results, "eth_simulateV1", options, blockArg); err != nil {
+		return Position{}, fmt.Errorf("simulate: %w", &CallError{Kind: classifyError(err), Err: err})
+	}
+	if len(results) != 1 || len(results[0].Calls) != len(calls) {
+		return Position{}, fmt.Errorf("simulate: node returned results for %d blocks, want 1 block of %d calls", len(results), len(calls))
+	}
+
+	for i, result := range results[0].Calls {
+		if result.Status == 1 {
+			continue
+		}
+		reason := "reverted"
+		if result.Error != nil {
+			reason = result.Error.Message
+		}
+		if i == len(txs) {
+			return Position{}, fmt.Errorf("simulate: read position: %s", reason)
+		}
+		return Position{}, fmt.Errorf("simulate: transaction %d: %s", i, reason)
+	}
+
+	return c.unpackPosition(results[0].Calls[len(txs)].ReturnData)
+}
+
+// readTransactions reads a -simulate file: a JSON array of the calls to
+// execute before reading the position, in eth_call's from, to, gas, value
+// and data fields.
+func readTransactions(path string) ([]ethereum.CallMsg, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var calls []simulateCall
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&calls); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("%s has no transaction", path)
+	}
+
+	txs := make([]ethereum.CallMsg, len(calls))
+	for i, sc := range calls {
+		if sc.To == nil {
+			return nil, fmt.Errorf("transaction %d has no to address; contract creations aren't supported", i)
+		}
+		txs[i] = ethereum.CallMsg{To: sc.To, Data: sc.Data}
+		if sc.From != nil {
+			txs[i].From = *sc.From
+		}
+		if sc.Gas != nil {
+			txs[i].Gas = uint64(*sc.Gas)
+		}
+		if sc.Value != nil {
+			txs[i].Value = sc.Value.ToInt()
+		}
+	}
+
+	return txs, nil
+}
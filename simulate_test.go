@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// mintCall adds 500 liquidity to the position of bundleNode
+	mintCall = []byte{0x01, 0x02, 0x03, 0x04}
+	// revertCall reverts on bundleNode
+	revertCall = []byte{0xde, 0xad, 0xbe, 0xef}
+)
+
+// bundleNode answers positions with 500 liquidity for each mintCall of the
+// simulation so far.
+func bundleNode(t *testing.T) *fakeNode {
+	var mints int64
+	return &fakeNode{head: 100, handle: func(to common.Address, data []byte) ([]byte, error) {
+		switch string(data) {
+		case string(mintCall):
+			mints++
+			return nil, nil
+		case string(revertCall):
+			return nil, errRevert{}
+		}
+		if method := methodOf(t, poolABI, data); method.Name != positionsMethod {
+			return make([]byte, 32), nil
+		}
+		return packOutputs(t, poolABI, positionsMethod, big.NewInt(500*mints), new(big.Int), new(big.Int), new(big.Int), new(big.Int)), nil
+	}}
+}
+
+func TestSimulatePosition(t *testing.T) {
+	node := bundleNode(t)
+	client := newFakeClient(t, node)
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	mint := ethereum.CallMsg{From: testOwner, To: &testPool, Data: mintCall, Gas: 100000}
+
+	position, err := client.SimulatePosition(context.Background(), q, []ethereum.CallMsg{mint, mint}, big.NewInt(80), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position.Liquidity.Int64() != 1000 {
+		t.Errorf("liquidity = %s, want 1000 after two mints", position.Liquidity)
+	}
+	if len(node.simulations) != 1 || len(node.simulations[0]) != 3 || node.blocks[0] != "0x50" {
+		t.Fatalf("simulations = %+v at %v, want the two mints and the read at 0x50", node.simulations, node.blocks)
+	}
+	if sent := node.simulations[0][0]; sent.From == nil || *sent.From != testOwner || sent.Gas == nil || *sent.Gas != 100000 {
+		t.Errorf("first call = %+v, want from testOwner with 100000 gas", sent)
+	}
+
+	failing := ethereum.CallMsg{To: &testPool, Data: revertCall}
+	if _, err := client.SimulatePosition(context.Background(), q, []ethereum.CallMsg{mint, failing}, nil, nil); err == nil || !strings.Contains(err.Error(), "transaction 1") {
+		t.Errorf("err = %v, want transaction 1 to fail", err)
+	}
+}
+
+func TestReadTransactions(t *testing.T) {
+	txs, err := readTransactions(writeInput(t, `[
+		{"to": "0x1111111111111111111111111111111111111111", "data": "0x01020304"},
+		{"from": "0x3333333333333333333333333333333333333333", "to": "0x1111111111111111111111111111111111111111", "gas": "0x10", "value": "0x1"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 2 || *txs[0].To != testPool || string(txs[0].Data) != string(mintCall) || txs[0].Value != nil {
+		t.Fatalf("transactions = %+v", txs)
+	}
+	if txs[1].From != testOwner || txs[1].Gas != 16 || txs[1].Value.Int64() != 1 {
+		t.Errorf("second transaction = %+v", txs[1])
+	}
+
+	for _, content := range []string{`[]`, `[{"data": "0x01"}]`, `[{"to": "0x1111111111111111111111111111111111111111", "input": "0x01"}]`} {
+		if _, err := readTransactions(writeInput(t, content)); err == nil {
+			t.Errorf("readTransactions(%s) succeeded", content)
+		}
+	}
+}
+
+func TestRunSimulate(t *testing.T) {
+	node := bundleNode(t)
+	bundle := writeInput(t, `[{"to": "0x1111111111111111111111111111111111111111", "data": "0x01020304"}]`)
+
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-format", "json", "-simulate", bundle, "-override", writeInput(t, testOverrides))
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	var results []PositionResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, stdout)
+	}
+	if len(results) != 1 || results[0].Position.Liquidity.Int64() != 500 {
+		t.Errorf("results = %+v, want 500 liquidity after the mint", results)
+	}
+	if node.count("eth_simulateV1") != 1 || len(node.overrides) != 1 {
+		t.Errorf("sent %d simulations and %d override sets, want one simulation with -override", node.count("eth_simulateV1"), len(node.overrides))
+	}
+
+	reverting := writeInput(t, `[{"to": "0x1111111111111111111111111111111111111111", "data": "0xdeadbeef"}]`)
+	if code, _, _ := runOutput(t, "-node", bundleNode(t).serve(t), "-max-lag", "0", "-simulate", reverting); code != exitNode {
+		t.Errorf("reverting bundle: exit code = %d, want %d", code, exitNode)
+	}
+	if code, _, _ := runOutput(t, "-simulate", bundle, "-dry-run"); code != exitBadInput {
+		t.Errorf("-simulate -dry-run: exit code = %d, want %d", code, exitBadInput)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrPositionChanged means the position was collected from or had its
+// liquidity changed inside the window, so its fees can't be compared across
+// it.
+var ErrPositionChanged = errors.New("position changed during the window")
+
+// parseSince parses a -since window: a Go duration such as 24h, or a whole
+// number of days (7d) or weeks (2w).
+func parseSince(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.ParseUint(n, 10, 32)
+			if err != nil || count == 0 {
+				return 0, fmt.Errorf("invalid window %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	window, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("window %q must be positive", s)
+	}
+
+	return window, nil
+}
+
+// BlockSince returns the header of block, nil meaning latest, and of the
+// first block at most since older than it. The first block is found by
+// bisecting block timestamps, so it is exact however block times vary, at
+// the cost of about log2(block) header reads.
+func (c *Client) BlockSince(ctx context.Context, since time.Duration, block *big.Int) (from, to *types.Header, err error) {
+	to, err = c.eth.HeaderByNumber(ctx, c.at(block))
+	if err != nil {
+		return nil, nil, fmt.Errorf("get block %s: %w", blockString(c.at(block)), err)
+	}
+
+	start := uint64(0)
+	if window := uint64(since / time.Second); window < to.Time {
+		start = to.Time - window
+	}
+
+	// from is always the header of hi, the lowest block known to be in the
+	// window
+	from = to
+	lo, hi := uint64(0), to.Number.Uint64()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		header, err := c.eth.HeaderByNumber(ctx, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return nil, nil, fmt.Errorf("get block %d: %w", mid, err)
+		}
+		if header.Time >= start {
+			hi, from = mid, header
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	return from, to, nil
+}
+
+// FeeAccrual is what a position earned in fees between two blocks.
+type FeeAccrual struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Elapsed   time.Duration
+	Fees0     *big.Int
+	Fees1     *big.Int
+	// APR is the fee APR over the window, valuing fees and the position in
+	// token1 at the price of ToBlock. It is nil when the position held
+	// nothing.
+	APR *big.Float
+}
+
+// FeesSince returns the fees q earned in the window of length since that
+// ends at block, nil meaning latest. Both ends need the pool's state, so a
+// window reaching past a full node's history needs an archive node. It fails
+// with ErrPositionChanged when the position was collected from or modified
+// inside the window.
+func (c *Client) FeesSince(ctx context.Context, q PositionQuery, since time.Duration, block *big.Int) (FeeAccrual, error) {
+	from, to, err := c.BlockSince(ctx, since, block)
+	if err != nil {
+		return FeeAccrual{}, err
+	}
+
+	accrual, _, err := c.feesBetween(ctx, q, from, to)
+
+	return accrual, err
+}
+
+// feesBetween is FeesSince between two given blocks. It also returns the
+// position's fee state at to.
+func (c *Client) feesBetween(ctx context.Context, q PositionQuery, from, to *types.Header) (FeeAccrual, PositionFees, error) {
+	before, err := c.ComputeUncollectedFees(ctx, q, from.Number)
+	if err != nil {
+		return FeeAccrual{}, PositionFees{}, fmt.Errorf("read fees at block %s: %w", from.Number, err)
+	}
+	after, err := c.ComputeUncollectedFees(ctx, q, to.Number)
+	if err != nil {
+		return FeeAccrual{}, PositionFees{}, fmt.Errorf("read fees at block %s: %w", to.Number, err)
+	}
+
+	if before.Liquidity.Cmp(after.Liquidity) != 0 || after.Fees0.Cmp(before.Fees0) < 0 || after.Fees1.Cmp(before.Fees1) < 0 {
+		return FeeAccrual{}, PositionFees{}, fmt.Errorf("blocks %s-%s: %w", from.Number, to.Number, ErrPositionChanged)
+	}
+
+	accrual := FeeAccrual{
+		FromBlock: from.Number.Uint64(),
+		ToBlock:   to.Number.Uint64(),
+		Elapsed:   time.Duration(to.Time-from.Time) * time.Second,
+		Fees0:     new(big.Int).Sub(after.Fees0, before.Fees0),
+		Fees1:     new(big.Int).Sub(after.Fees1, before.Fees1),
+	}
+
+	earned := valueInToken1(accrual.Fees0, accrual.Fees1, after.SqrtPriceX96)
+	amount0, amount1 := AmountsForLiquidity(after.SqrtPriceX96, q.TickLower, q.TickUpper, after.Liquidity)
+	if apr, err := EstimateAPR(earned, valueInToken1(amount0, amount1, after.SqrtPriceX96), accrual.Elapsed); err == nil {
+		accrual.APR = apr
+	}
+
+	return accrual, after, nil
+}
+
+// ReinvestedAccrual is a FeeAccrual with its APR compounded by
+// ReinvestedAPR for fees reinvested every Period.
+type ReinvestedAccrual struct {
+	FeeAccrual
+	Period time.Duration
+	// ReinvestedAPR is nil when APR is.
+	ReinvestedAPR *big.Float
+}
+
+// writeReinvested prints a as writeAccrual does, with the reinvested APR on
+// a second line.
+func writeReinvested(w io.Writer, format string, a ReinvestedAccrual) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(a)
+	}
+
+	if err := writeAccrual(w, format, a.FeeAccrual); err != nil {
+		return err
+	}
+	line := fmt.Sprintf("reinvested every %s: no apr, the position held nothing", a.Period)
+	if a.ReinvestedAPR != nil {
+		line = fmt.Sprintf("reinvested every %s: apr=%s", a.Period, formatPercent(a.ReinvestedAPR))
+	}
+	_, err := fmt.Fprintln(w, line)
+
+	return err
+}
+
+// writeFeeRate prints r as writeAccrual does, with the time in range and the
+// rates on a second line.
+func writeFeeRate(w io.Writer, format string, r FeeRate) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(r)
+	}
+
+	if err := writeAccrual(w, format, r.FeeAccrual); err != nil {
+		return err
+	}
+	line := fmt.Sprintf("in range %s of %s, rate=%s", r.InRange, r.Elapsed, formatPercent(r.Rate))
+	if r.InRangeAPR != nil {
+		line += " in-range apr=" + formatPercent(r.InRangeAPR)
+	}
+	_, err := fmt.Fprintln(w, line)
+
+	return err
+}
+
+func writeAccrual(w io.Writer, format string, a FeeAccrual) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(a)
+	}
+
+	line := fmt.Sprintf("fees in blocks %d-%d (%s): %s token0 %s token1", a.FromBlock, a.ToBlock, a.Elapsed, a.Fees0, a.Fees1)
+	if a.APR != nil {
+		line += " apr=" + formatPercent(a.APR)
+	}
+	_, err := fmt.Fprintln(w, line)
+
+	return err
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParseSince(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want time.Duration
+	}{
+		{"24h", 24 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"7d", 7 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+	} {
+		if got, err := parseSince(tc.in); err != nil || got != tc.want {
+			t.Errorf("parseSince(%q) = %s, %v, want %s", tc.in, got, err, tc.want)
+		}
+	}
+
+	for _, in := range []string{"", "0d", "-1h", "0s", "1.5d", "d", "7days"} {
+		if got, err := parseSince(in); err == nil {
+			t.Errorf("parseSince(%q) = %s, want an error", in, got)
+		}
+	}
+}
+
+func TestBlockSince(t *testing.T) {
+	// 10 second blocks up to block 50, 2 second blocks after it
+	node := &fakeNode{head: 100, blockTime: func(n uint64) uint64 {
+		if n < 50 {
+			return 1000 + n*10
+		}
+		return 1500 + (n-50)*2
+	}}
+	client := newFakeClient(t, node)
+
+	for _, tc := range []struct {
+		since time.Duration
+		// to is the end of the window, 0 for latest
+		to, wantFrom uint64
+	}{
+		{100 * time.Second, 0, 50},
+		{99 * time.Second, 0, 51},
+		{120 * time.Second, 0, 48},
+		{125 * time.Second, 0, 48},
+		// before the first block
+		{time.Hour, 0, 0},
+		{time.Second, 0, 100},
+		{100 * time.Second, 40, 30},
+	} {
+		var block *big.Int
+		if tc.to != 0 {
+			block = new(big.Int).SetUint64(tc.to)
+		}
+		from, to, err := client.BlockSince(context.Background(), tc.since, block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantTo := tc.to
+		if wantTo == 0 {
+			wantTo = 100
+		}
+		if from.Number.Uint64() != tc.wantFrom || to.Number.Uint64() != wantTo {
+			t.Errorf("BlockSince(%s, %d) = %s, %s, want %d, %d", tc.since, tc.to, from.Number, to.Number, tc.wantFrom, wantTo)
+		}
+	}
+}
+
+func TestRunSince(t *testing.T) {
+	pool := newFakePool(0, 1e18)
+	q := PositionQuery{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}
+	pool.setPosition(t, q, Position{Liquidity: big.NewInt(1e18)})
+	node := &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)}
+
+	// 12 second blocks: 7 minutes are 35 blocks
+	code, stdout, stderr := runOutput(t, "-since", "7m", "-node", node.serve(t), "-max-lag", "0",
+		"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	if want := "fees in blocks 65-100 (7m0s): 0 token0 0 token1"; !strings.HasPrefix(stdout, want) {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	for _, block := range []string{"0x41", "0x64"} {
+		if !slices.Contains(node.blocks, block) {
+			t.Errorf("no read at block %s: %q", block, node.blocks)
+		}
+	}
+}
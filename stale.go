@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultMaxLag is how old the head block may be before the node counts as
+// out of sync. The supported chains make a block every 12 seconds or faster,
+// so minutes without one mean the node stopped following the chain, not a
+// slow block.
+const defaultMaxLag = 3 * time.Minute
+
+// HeadLag returns the node's head number and how long ago, by the local
+// clock, the head block was made.
+func (c *Client) HeadLag(ctx context.Context) (uint64, time.Duration, error) {
+	header, err := c.eth.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("get head: %w", err)
+	}
+
+	return header.Number.Uint64(), time.Since(time.Unix(int64(header.Time), 0)), nil
+}
+
+// staleWarning is the warning for a node whose head is lag old, or "" when
+// lag is within maxLag.
+func staleWarning(head uint64, lag, maxLag time.Duration) string {
+	if lag <= maxLag {
+		return ""
+	}
+
+	return fmt.Sprintf("node head %d is %s old, more than -max-lag %s: the node may be out of sync and results stale", head, lag.Round(time.Second), maxLag)
+}
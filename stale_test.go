@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStaleWarning(t *testing.T) {
+	if w := staleWarning(100, 2*time.Minute, defaultMaxLag); w != "" {
+		t.Errorf("fresh head warns %q", w)
+	}
+	if w, want := staleWarning(100, 10*time.Minute+400*time.Millisecond, defaultMaxLag), "node head 100 is 10m0s old, more than -max-lag 3m0s"; !strings.HasPrefix(w, want) {
+		t.Errorf("staleWarning = %q, want %q...", w, want)
+	}
+}
+
+func TestHeadLag(t *testing.T) {
+	made := time.Now().Add(-time.Hour)
+	client := newFakeClient(t, &fakeNode{head: 100, blockTime: func(uint64) uint64 { return uint64(made.Unix()) }})
+
+	head, lag, err := client.HeadLag(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != 100 || lag < time.Hour || lag > time.Hour+time.Minute {
+		t.Errorf("HeadLag = %d, %s, want 100, about 1h", head, lag)
+	}
+}
+
+func TestRunStaleNode(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		age  time.Duration
+		args []string
+		warn bool
+	}{
+		{"stale", time.Hour, nil, true},
+		{"fresh", 10 * time.Second, nil, false},
+		{"check disabled", time.Hour, []string{"-max-lag", "0"}, false},
+		// the lag of the head says nothing about an older block's state
+		{"historical read", time.Hour, []string{"-block", "90"}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			node := positionNode(t, 1000)
+			node.blockTime = func(uint64) uint64 { return uint64(time.Now().Add(-tc.age).Unix()) }
+
+			code, stdout, stderr := runOutput(t, append([]string{"-node", node.serve(t), "-format", "json"}, tc.args...)...)
+			if code != exitOK {
+				t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+			}
+			var results []PositionResult
+			if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+				t.Fatalf("parse output: %v\n%s", err, stdout)
+			}
+
+			warned := len(results) == 1 && len(results[0].Warnings) == 1 && strings.Contains(results[0].Warnings[0], "out of sync")
+			if warned != tc.warn {
+				t.Errorf("warnings %q, want a stale node warning: %t", results[0].Warnings, tc.warn)
+			}
+			if logged := strings.Contains(stderr, "warning: node head"); logged != tc.warn {
+				t.Errorf("logged a warning: %t, want %t; stderr:\n%s", logged, tc.warn, stderr)
+			}
+		})
+	}
+}
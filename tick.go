@@ -0,0 +1,47 @@
+package main
+
+import "fmt"
+
+// Tick is a pool tick, an int24 on chain. Ticks are int32 in Go so that
+// differences and spacing arithmetic can't overflow; the int24 bound is
+// checked where a tick is encoded.
+type Tick int32
+
+// AlignedTo reports whether t is a multiple of spacing, as the pool requires
+// of a position's bounds. Go's % keeps the sign of t, which doesn't matter
+// for a zero remainder, so negative ticks need no special case.
+func (t Tick) AlignedTo(spacing int32) bool {
+	return spacing > 0 && int32(t)%spacing == 0
+}
+
+// InRange reports whether t is in [lower, upper), where a position's
+// liquidity is active: at the upper tick itself the position is all token1.
+func (t Tick) InRange(lower, upper Tick) bool {
+	return lower <= t && t < upper
+}
+
+// fitsInt24 reports whether t can be encoded as an int24.
+func (t Tick) fitsInt24() bool {
+	return minInt24 <= t && t <= maxInt24
+}
+
+// putInt24 writes the low 3 bytes of t in two's complement, the packed
+// encoding of an int24.
+func (t Tick) putInt24(b []byte) {
+	u := uint32(t)
+	b[0] = byte(u >> 16)
+	b[1] = byte(u >> 8)
+	b[2] = byte(u)
+}
+
+// int24Bytes is t packed as an int24, or an error when t doesn't fit.
+func (t Tick) int24Bytes() ([]byte, error) {
+	if !t.fitsInt24() {
+		return nil, fmt.Errorf("value %d overflows int24", t)
+	}
+
+	b := make([]byte, 3)
+	t.putInt24(b)
+
+	return b, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+var int24Args = abi.Arguments{{Type: mustNewType("int24")}}
+
+func mustNewType(t string) abi.Type {
+	typ, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return typ
+}
+
+// abiInt24 is the reference packed int24: the low 3 bytes of its padded
+// abi.encode word.
+func abiInt24(t *testing.T, tick Tick) []byte {
+	t.Helper()
+
+	word, err := int24Args.Pack(big.NewInt(int64(tick)))
+	if err != nil {
+		t.Fatalf("abi encode %d: %v", tick, err)
+	}
+
+	return word[32-3:]
+}
+
+func TestInt24Bytes(t *testing.T) {
+	for _, tc := range []struct {
+		tick Tick
+		want []byte
+	}{
+		{0, []byte{0x00, 0x00, 0x00}},
+		{1, []byte{0x00, 0x00, 0x01}},
+		{127, []byte{0x00, 0x00, 0x7f}},
+		{128, []byte{0x00, 0x00, 0x80}},
+		{8388607, []byte{0x7f, 0xff, 0xff}},
+		{-1, []byte{0xff, 0xff, 0xff}},
+		{-8388608, []byte{0x80, 0x00, 0x00}},
+	} {
+		got, err := tc.tick.int24Bytes()
+		if err != nil {
+			t.Errorf("int24Bytes(%d): %v", tc.tick, err)
+			continue
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("int24Bytes(%d) = %x, want %x", tc.tick, got, tc.want)
+		}
+		if ref := abiInt24(t, tc.tick); !bytes.Equal(got, ref) {
+			t.Errorf("int24Bytes(%d) = %x, abi encodes %x", tc.tick, got, ref)
+		}
+	}
+}
+
+func TestInt24BytesOverflow(t *testing.T) {
+	for _, tick := range []Tick{maxInt24 + 1, minInt24 - 1, 1 << 30, -1 << 31} {
+		if b, err := tick.int24Bytes(); err == nil {
+			t.Errorf("int24Bytes(%d) = %x, want an overflow error", tick, b)
+		}
+	}
+}
+
+// int24Seeds are the edges of the int24 encoding: sign and byte boundaries,
+// the valid tick range and just outside int24.
+var int24Seeds = []int32{
+	0, 1, -1, 127, -127, 128, -128, 255, -256, 1<<16 - 1, -1 << 16,
+	int32(MinTick), int32(MaxTick), int32(MinTick) - 1, int32(MaxTick) + 1,
+	minInt24, maxInt24, minInt24 - 1, maxInt24 + 1, -1 << 31, 1<<31 - 1,
+}
+
+// decodeInt24 sign-extends a packed int24.
+func decodeInt24(b []byte) Tick {
+	u := uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+	return Tick(int32(u<<8) >> 8)
+}
+
+func TestInt24BytesRoundTrip(t *testing.T) {
+	for tick := MinTick; tick <= MaxTick; tick++ {
+		b, err := tick.int24Bytes()
+		if err != nil {
+			t.Fatalf("int24Bytes(%d): %v", tick, err)
+		}
+		if got := decodeInt24(b); got != tick {
+			t.Fatalf("int24Bytes(%d) = %x decodes to %d", tick, b, got)
+		}
+	}
+}
+
+func FuzzInt24Bytes(f *testing.F) {
+	for _, seed := range int24Seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, v int32) {
+		tick := Tick(v)
+		b, err := tick.int24Bytes()
+		if !tick.fitsInt24() {
+			if err == nil {
+				t.Fatalf("int24Bytes(%d) = %x, want an overflow error", tick, b)
+			}
+			return
+		}
+
+		if err != nil {
+			t.Fatalf("int24Bytes(%d): %v", tick, err)
+		}
+		if len(b) != 3 {
+			t.Fatalf("int24Bytes(%d) has %d bytes", tick, len(b))
+		}
+		if got := decodeInt24(b); got != tick {
+			t.Fatalf("int24Bytes(%d) = %x decodes to %d", tick, b, got)
+		}
+		if ref := abiInt24(t, tick); !bytes.Equal(b, ref) {
+			t.Fatalf("int24Bytes(%d) = %x, abi encodes %x", tick, b, ref)
+		}
+	})
+}
+
+func TestTickAlignedTo(t *testing.T) {
+	for _, tc := range []struct {
+		tick    Tick
+		spacing int32
+		want    bool
+	}{
+		{0, 60, true},
+		{-60, 60, true},
+		{-197760, 10, true},
+		{-30, 60, false},
+		{-887272, 60, false},
+		{-887220, 60, true},
+		{887272, 1, true},
+		// a pool never has a zero or negative spacing
+		{0, 0, false},
+		{60, -60, false},
+	} {
+		if got := tc.tick.AlignedTo(tc.spacing); got != tc.want {
+			t.Errorf("Tick(%d).AlignedTo(%d) = %t, want %t", tc.tick, tc.spacing, got, tc.want)
+		}
+	}
+}
+
+func TestTickInRange(t *testing.T) {
+	for _, tc := range []struct {
+		tick Tick
+		want bool
+	}{
+		{-121, false},
+		{-120, true},
+		{0, true},
+		{119, true},
+		// all token1 at the upper tick
+		{120, false},
+	} {
+		if got := tc.tick.InRange(-120, 120); got != tc.want {
+			t.Errorf("Tick(%d).InRange(-120, 120) = %t, want %t", tc.tick, got, tc.want)
+		}
+	}
+	if Tick(0).InRange(0, 0) {
+		t.Error("an empty range contains its bound")
+	}
+}
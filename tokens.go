@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	abiERC20       = `[{"inputs":[],"name":"decimals","outputs":[{"internalType":"uint8","name":"","type":"uint8"}],"stateMutability":"view","type":"function"}]`
+	decimalsMethod = "decimals"
+)
+
+var erc20ABI = mustParseABI(abiERC20)
+
+const flaggedTokenReason = "flagged as fee-on-transfer or rebasing"
+
+// defaultFlaggedTokens are tokens whose balances don't follow plain ERC20
+// transfers, so the amounts shown for a position may differ from what a
+// withdrawal actually delivers.
+var defaultFlaggedTokens = map[common.Address]string{
+	common.HexToAddress("0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84"): "stETH rebases daily",
+	common.HexToAddress("0xD46bA6D942050d489DBd938a2C909A5d5039A161"): "AMPL rebases supply",
+	common.HexToAddress("0x45804880De22913dAFE09f4980848ECE6EcbAf78"): "PAXG charges a fee on transfer",
+}
+
+// flaggedTokens merges the defaults with a comma separated list of extra
+// token addresses.
+func flaggedTokens(extra string) (map[common.Address]string, error) {
+	flagged := make(map[common.Address]string, len(defaultFlaggedTokens))
+	for token, reason := range defaultFlaggedTokens {
+		flagged[token] = reason
+	}
+
+	for _, s := range strings.Split(extra, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		token, err := parseAddress(s)
+		if err != nil {
+			return nil, err
+		}
+		flagged[token] = flaggedTokenReason
+	}
+
+	return flagged, nil
+}
+
+func tokenWarnings(flagged map[common.Address]string, tokens ...common.Address) []string {
+	var warnings []string
+
+	for _, token := range tokens {
+		if reason, ok := flagged[token]; ok {
+			warnings = append(warnings, fmt.Sprintf("token %s: %s; displayed amounts may not match what a withdrawal receives", token, reason))
+		}
+	}
+
+	return warnings
+}
+
+// defaultDecimals is what a token without decimals() is assumed to have, the
+// most common value.
+const defaultDecimals = 18
+
+// TokenDecimals reads the ERC20 decimals of token.
+func (c *Client) TokenDecimals(ctx context.Context, token common.Address, block *big.Int) (uint8, error) {
+	calldata, err := c.abis.ERC20ABI().Pack(decimalsMethod)
+	if err != nil {
+		return 0, fmt.Errorf("pack decimals: %w", err)
+	}
+
+	response, err := c.callContract(ctx, ethereum.CallMsg{To: &token, Data: calldata}, block)
+	if err != nil {
+		return 0, fmt.Errorf("call decimals of %s: %w", token, err)
+	}
+
+	out, err := c.abis.ERC20ABI().Unpack(decimalsMethod, response)
+	if err != nil {
+		return 0, fmt.Errorf("parse decimals of %s: %w", token, err)
+	}
+
+	return out[0].(uint8), nil
+}
+
+// annotateDecimals sets Decimals0 and Decimals1 of results, whose tokens
+// must already be annotated, reading every token once. A token whose
+// decimals() reverts is taken to have defaultDecimals, with a warning on
+// its results.
+func annotateDecimals(ctx context.Context, client *Client, results []PositionResult, block *big.Int) error {
+	var tokens []*TokenInfo
+	infos := make(map[common.Address]*TokenInfo)
+	for _, r := range results {
+		for _, token := range []common.Address{r.Token0, r.Token1} {
+			if infos[token] == nil {
+				infos[token] = &TokenInfo{Address: token}
+				tokens = append(tokens, infos[token])
+			}
+		}
+	}
+
+	if _, err := client.readDecimals(ctx, block, tokens...); err != nil {
+		return err
+	}
+
+	for i := range results {
+		r := &results[i]
+		token0, token1 := infos[r.Token0], infos[r.Token1]
+		r.Decimals0, r.Decimals1 = &token0.Decimals, &token1.Decimals
+		for _, token := range []*TokenInfo{token0, token1} {
+			if token.Assumed {
+				r.Warnings = append(r.Warnings, token.warning())
+			}
+		}
+	}
+
+	return nil
+}
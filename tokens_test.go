@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestFlaggedTokens(t *testing.T) {
+	extra := common.HexToAddress("0x000000000000000000000000000000000000000b")
+	flagged, err := flaggedTokens(" " + extra.Hex() + ", ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flagged[extra] != flaggedTokenReason || len(flagged) != len(defaultFlaggedTokens)+1 {
+		t.Errorf("flagged = %v", flagged)
+	}
+
+	for _, extra := range []string{"0x1234", "0x000000000000000000000000000000000000000g"} {
+		if _, err := flaggedTokens(extra); err == nil {
+			t.Errorf("flaggedTokens(%q) accepted an invalid address", extra)
+		}
+	}
+}
+
+func TestAnnotatePoolsFlaggedToken(t *testing.T) {
+	pool := newFakePool(0, 1000)
+	node := &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, nil)}
+	client := newFakeClient(t, node)
+
+	flagged, err := flaggedTokens(pool.token1.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []PositionResult{{Pool: testPool, Owner: testOwner, TickLower: -60, TickUpper: 60}}
+	if err := annotatePools(context.Background(), client, results, flagged, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	warnings := results[0].Warnings
+	if len(warnings) != 1 || !strings.Contains(warnings[0], pool.token1.Hex()) || !strings.Contains(warnings[0], flaggedTokenReason) {
+		t.Errorf("warnings = %q, want one for token1", warnings)
+	}
+	if strings.Contains(strings.Join(warnings, ""), pool.token0.Hex()) {
+		t.Errorf("warnings = %q name token0, which isn't flagged", warnings)
+	}
+}
+
+func TestReadDecimalsReverted(t *testing.T) {
+	token0 := common.HexToAddress("0x000000000000000000000000000000000000000a")
+	token1 := common.HexToAddress("0x000000000000000000000000000000000000000b")
+
+	for _, tc := range []struct {
+		name          string
+		multicallFrom int64
+		batch         string
+	}{
+		{"multicall", 0, BatchMulticall},
+		{"sequential", -1, BatchMulticall},
+		{"native", 0, BatchNative},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// token1 has no decimals() and reverts
+			node := &fakeNode{head: 100, multicallFrom: tc.multicallFrom, handle: poolsHandler(t, nil, map[common.Address]uint8{token0: 6})}
+			client := newFakeClient(t, node, WithBatch(tc.batch))
+
+			tokens := []*TokenInfo{{Address: token0}, {Address: token1}}
+			warnings, err := client.readDecimals(context.Background(), big.NewInt(90), tokens...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tokens[0].Decimals != 6 || tokens[0].Assumed {
+				t.Errorf("token0 = %+v, want its 6 decimals", tokens[0])
+			}
+			if tokens[1].Decimals != defaultDecimals || !tokens[1].Assumed {
+				t.Errorf("token1 = %+v, want %d assumed", tokens[1], defaultDecimals)
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0], token1.Hex()) || !strings.Contains(warnings[0], "assuming 18") {
+				t.Errorf("warnings = %q, want one for token1", warnings)
+			}
+		})
+	}
+}
+
+func TestReadDecimalsNodeError(t *testing.T) {
+	node := &fakeNode{head: 100, handle: func(common.Address, []byte) ([]byte, error) {
+		return nil, errRateLimited{}
+	}}
+	client := newFakeClient(t, node)
+
+	tokens := []*TokenInfo{{Address: testPool}}
+	if _, err := client.readDecimals(context.Background(), nil, tokens...); err == nil || tokens[0].Assumed {
+		t.Errorf("err = %v, assumed %t, want the node error and no default", err, tokens[0].Assumed)
+	}
+}
+
+func TestAnnotateDecimalsReverted(t *testing.T) {
+	pool := newFakePool(0, 1000)
+	node := &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, map[common.Address]uint8{pool.token1: 6})}
+	client := newFakeClient(t, node)
+
+	results := []PositionResult{
+		{Pool: testPool, Token0: pool.token0, Token1: pool.token1},
+		{Pool: testPool, Token0: pool.token1, Token1: pool.token1},
+	}
+	if err := annotateDecimals(context.Background(), client, results, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := results[0]; *r.Decimals0 != defaultDecimals || *r.Decimals1 != 6 || len(r.Warnings) != 1 || !strings.Contains(r.Warnings[0], pool.token0.Hex()) {
+		t.Errorf("result with token0 = decimals %d, %d, warnings %q", *r.Decimals0, *r.Decimals1, r.Warnings)
+	}
+	if r := results[1]; *r.Decimals0 != 6 || len(r.Warnings) != 0 {
+		t.Errorf("result without token0 = decimals %d, warnings %q", *r.Decimals0, r.Warnings)
+	}
+}
+
+func TestRunScaled(t *testing.T) {
+	pool := newFakePool(0, 1000)
+	pool.setPosition(t, testQueries[0], Position{Liquidity: big.NewInt(1000), TokensOwed0: big.NewInt(2_500_000), TokensOwed1: big.NewInt(3e17)})
+	// token1 reverts decimals()
+	node := &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, map[common.Address]uint8{pool.token0: 6})}
+	url := node.serve(t)
+
+	code, stdout, stderr := runOutput(t, "-node", url, "-max-lag", "0", "-format", "json", "-scaled",
+		"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60")
+	if code != exitOK {
+		t.Fatalf("exit code %d; stderr:\n%s", code, stderr)
+	}
+	for _, want := range []string{
+		`"tokensOwed0": "2500000"`,
+		`"tokensOwed0Scaled": "2.5"`,
+		`"tokensOwed1Scaled": "0.3"`,
+		`"decimals0": 6`,
+		`"decimals1": 18`,
+		"decimals() reverted, assuming 18",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output lacks %s:\n%s", want, stdout)
+		}
+	}
+
+	// without -scaled no decimals are read
+	before := node.count("eth_call")
+	if code, stdout, _ := runOutput(t, "-node", url, "-max-lag", "0", "-format", "json",
+		"-pool", testPool.Hex(), "-owner", testOwner.Hex(), "-tick-lower", "-60", "-tick-upper", "60"); code != exitOK || strings.Contains(stdout, "Scaled") || strings.Contains(stdout, "decimals0") {
+		t.Errorf("exit code %d, output without -scaled:\n%s", code, stdout)
+	}
+	if n := node.count("eth_call") - before; n != 2 {
+		t.Errorf("sent %d eth_calls without -scaled, want the position and the pool", n)
+	}
+}
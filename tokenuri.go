@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+// maxTokenURISize caps how much metadata is read from an http tokenURI.
+const maxTokenURISize = 1 << 20
+
+// TokenURIMeta is the ERC-721 metadata of a position NFT. The NPM returns it
+// inline as a base64 data URI with the SVG embedded the same way.
+// https://github.com/Uniswap/v3-periphery/blob/main/contracts/libraries/NFTDescriptor.sol
+type TokenURIMeta struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+
+	// ImageType and ImageData are the decoded image when Image is a data
+	// URI, e.g. "image/svg+xml" and the SVG document.
+	ImageType string `json:"-"`
+	ImageData []byte `json:"-"`
+}
+
+// PositionTokenURI reads tokenURI(tokenID) from the NPM at block and decodes
+// the metadata it points to.
+func (c *Client) PositionTokenURI(ctx context.Context, tokenID *big.Int, block *big.Int) (*TokenURIMeta, error) {
+	ch, err := c.chain(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	calldata, err := c.abis.NPMABI().Pack(tokenURIMethod, tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("pack tokenURI: %w", err)
+	}
+
+	response, err := c.callContract(ctx, ethereum.CallMsg{To: &ch.NPM, Data: calldata}, block)
+	if err != nil {
+		return nil, fmt.Errorf("call tokenURI: %w", err)
+	}
+
+	out, err := c.abis.NPMABI().Unpack(tokenURIMethod, response)
+	if err != nil {
+		return nil, fmt.Errorf("parse tokenURI: %w", err)
+	}
+
+	return fetchTokenURI(ctx, out[0].(string))
+}
+
+// writeTokenURI prints the name, description and image type of meta as
+// lines, or meta as JSON.
+func writeTokenURI(w io.Writer, format string, meta *TokenURIMeta) error {
+	if format == formatJSON || format == formatNDJSON {
+		return json.NewEncoder(w).Encode(meta)
+	}
+
+	fmt.Fprintf(w, "name:        %s\n", meta.Name)
+	fmt.Fprintf(w, "description: %s\n", strings.ReplaceAll(meta.Description, "\n", "\n             "))
+	imageType := meta.ImageType
+	if imageType == "" {
+		imageType = meta.Image
+	}
+	_, err := fmt.Fprintf(w, "image:       %s\n", imageType)
+
+	return err
+}
+
+// fetchTokenURI resolves a data, http or https URI into metadata.
+func fetchTokenURI(ctx context.Context, uri string) (*TokenURIMeta, error) {
+	var body []byte
+
+	switch {
+	case strings.HasPrefix(uri, "data:"):
+		_, data, err := parseDataURI(uri)
+		if err != nil {
+			return nil, fmt.Errorf("decode token uri: %w", err)
+		}
+		body = data
+	case strings.HasPrefix(uri, "http://"), strings.HasPrefix(uri, "https://"):
+		data, err := httpGet(ctx, uri)
+		if err != nil {
+			return nil, fmt.Errorf("fetch token uri: %w", err)
+		}
+		body = data
+	default:
+		return nil, fmt.Errorf("unsupported token uri %q", uri)
+	}
+
+	var meta TokenURIMeta
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return nil, fmt.Errorf("parse token metadata: %w", err)
+	}
+
+	if strings.HasPrefix(meta.Image, "data:") {
+		imageType, data, err := parseDataURI(meta.Image)
+		if err != nil {
+			return nil, fmt.Errorf("decode token image: %w", err)
+		}
+		meta.ImageType, meta.ImageData = imageType, data
+	}
+
+	return &meta, nil
+}
+
+// parseDataURI splits an RFC 2397 data URI into its media type and payload.
+func parseDataURI(uri string) (mediaType string, data []byte, err error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return "", nil, errors.New("data uri has no payload")
+	}
+
+	mediaType, isBase64 := strings.CutSuffix(header, ";base64")
+	if mediaType == "" {
+		mediaType = "text/plain;charset=US-ASCII"
+	}
+
+	if isBase64 {
+		data, err = base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return "", nil, fmt.Errorf("base64 payload: %w", err)
+		}
+		return mediaType, data, nil
+	}
+
+	unescaped, err := url.PathUnescape(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("payload: %w", err)
+	}
+
+	return mediaType, []byte(unescaped), nil
+}
+
+func httpGet(ctx context.Context, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxTokenURISize))
+}
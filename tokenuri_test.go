@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg"></svg>`
+
+// testTokenURI is tokenURI as the NPM's descriptor returns it: JSON
+// metadata in a base64 data URI, with the SVG image inlined the same way.
+var testTokenURI = "data:application/json;base64," + base64.StdEncoding.EncodeToString([]byte(
+	`{"name":"Uniswap - 0.05% - WETH/USDC - 1800<>2200","description":"This NFT represents a liquidity position","image":"data:image/svg+xml;base64,`+
+		base64.StdEncoding.EncodeToString([]byte(testSVG))+`"}`))
+
+// tokenURINode answers tokenURI of token 1 with testTokenURI and reverts
+// for any other token.
+func tokenURINode(t *testing.T) *fakeNode {
+	return &fakeNode{head: 100, handle: func(to common.Address, data []byte) ([]byte, error) {
+		method := methodOf(t, npmABI, data)
+		if to != arbitrumNPM || method.Name != tokenURIMethod {
+			t.Errorf("unexpected call of %s on %s", method.Name, to)
+			return nil, errRevert{}
+		}
+		in, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if in[0].(*big.Int).Int64() != 1 {
+			return nil, errRevert{}
+		}
+		return packOutputs(t, npmABI, tokenURIMethod, testTokenURI), nil
+	}}
+}
+
+func TestParseDataURI(t *testing.T) {
+	for _, tc := range []struct {
+		uri, mediaType, data string
+	}{
+		{"data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(testSVG)), "image/svg+xml", testSVG},
+		{"data:application/json,%7B%7D", "application/json", "{}"},
+		{"data:,hello", "text/plain;charset=US-ASCII", "hello"},
+	} {
+		mediaType, data, err := parseDataURI(tc.uri)
+		if err != nil || mediaType != tc.mediaType || string(data) != tc.data {
+			t.Errorf("parseDataURI(%q) = %q, %q, %v, want %q, %q", tc.uri, mediaType, data, err, tc.mediaType, tc.data)
+		}
+	}
+
+	for _, uri := range []string{"data:image/svg+xml;base64", "data:;base64,!!!"} {
+		if _, _, err := parseDataURI(uri); err == nil {
+			t.Errorf("parseDataURI(%q) succeeded", uri)
+		}
+	}
+}
+
+func TestPositionTokenURI(t *testing.T) {
+	client := newFakeClient(t, tokenURINode(t))
+
+	meta, err := client.PositionTokenURI(context.Background(), big.NewInt(1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(meta.Name, "Uniswap - 0.05% - WETH/USDC") || meta.Description != "This NFT represents a liquidity position" {
+		t.Errorf("metadata = %+v", meta)
+	}
+	if meta.ImageType != "image/svg+xml" || string(meta.ImageData) != testSVG {
+		t.Errorf("image = %q, %q, want the SVG", meta.ImageType, meta.ImageData)
+	}
+
+	if _, err := client.PositionTokenURI(context.Background(), big.NewInt(2), nil); !isRevert(err) {
+		t.Errorf("err = %v, want a revert", err)
+	}
+}
+
+func TestFetchTokenURIUnsupported(t *testing.T) {
+	if _, err := fetchTokenURI(context.Background(), "ipfs://Qm"); err == nil {
+		t.Error("want an error for an ipfs uri")
+	}
+}
+
+func TestRunTokenURI(t *testing.T) {
+	node := tokenURINode(t)
+	url := node.serve(t)
+	image := filepath.Join(t.TempDir(), "position.svg")
+
+	code, stdout, stderr := runOutput(t, "-node", url, "-max-lag", "0", "-token-uri", "1", "-token-image", image)
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if !strings.Contains(stdout, "name:        Uniswap - 0.05% - WETH/USDC - 1800<>2200\n") || !strings.Contains(stdout, "image:       image/svg+xml\n") {
+		t.Errorf("output:\n%s", stdout)
+	}
+	if svg, err := os.ReadFile(image); err != nil || string(svg) != testSVG {
+		t.Errorf("image file = %q, %v, want the SVG", svg, err)
+	}
+
+	code, _, stderr = runOutput(t, "-node", url, "-max-lag", "0", "-token-uri", "2")
+	if code != exitNotFound {
+		t.Errorf("burned token: exit code = %d, want %d; stderr:\n%s", code, exitNotFound, stderr)
+	}
+	if code, _, _ := runOutput(t, "-token-image", image); code != exitBadInput {
+		t.Errorf("-token-image alone: exit code = %d, want %d", code, exitBadInput)
+	}
+}
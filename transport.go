@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// proxyFunc selects the proxy for node requests: rawProxy when set, otherwise
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the environment.
+func proxyFunc(rawProxy string) (func(*http.Request) (*url.URL, error), error) {
+	if rawProxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(rawProxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", rawProxy, err)
+	}
+	if proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: expected scheme://host[:port]", rawProxy)
+	}
+
+	return http.ProxyURL(proxyURL), nil
+}
+
+// dialOptions builds the HTTP and websocket transports of the rpc client.
+func dialOptions(options clientOptions) ([]rpc.ClientOption, error) {
+	proxy, err := proxyFunc(options.proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+
+	return []rpc.ClientOption{
+		rpc.WithHTTPClient(&http.Client{Transport: endpointCheck{transport}}),
+		rpc.WithWebsocketDialer(websocket.Dialer{Proxy: proxy}),
+	}, nil
+}
+
+// ErrBadEndpoint matches, with errors.Is, requests answered with something
+// other than JSON-RPC, typically an HTML page from a captive portal, a
+// provider's dashboard URL or a misrouted proxy.
+var ErrBadEndpoint = errors.New("node URL doesn't look like a JSON-RPC endpoint")
+
+// endpointCheck fails successful responses that are HTML, by content type or
+// by a body starting with '<', before go-ethereum reports them as a JSON
+// syntax error. Error statuses are left to go-ethereum, which reports them
+// with their body.
+type endpointCheck struct {
+	next http.RoundTripper
+}
+
+func (t endpointCheck) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode/100 != 2 {
+		return resp, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	body := bufio.NewReader(resp.Body)
+	head, _ := body.Peek(512)
+	if mediaType == "text/html" || bytes.HasPrefix(bytes.TrimSpace(head), []byte("<")) {
+		resp.Body.Close()
+		if mediaType == "" {
+			mediaType = "no content type"
+		}
+		return nil, fmt.Errorf("%w: answered %s with %s", ErrBadEndpoint, resp.Status, mediaType)
+	}
+
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, resp.Body}
+
+	return resp, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// forwardProxy is an HTTP proxy stub that records the hosts requested through
+// it and forwards every request to node.
+func forwardProxy(t *testing.T, node string) (proxyURL string, hosts func() []string) {
+	var mu sync.Mutex
+	var seen []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.URL.Host)
+		mu.Unlock()
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		response, err := http.Post(node, r.Header.Get("Content-Type"), bytes.NewReader(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer response.Body.Close()
+
+		w.Header().Set("Content-Type", response.Header.Get("Content-Type"))
+		w.WriteHeader(response.StatusCode)
+		io.Copy(w, response.Body)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string{}, seen...)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	node := positionNode(t, 1000)
+	proxyURL, hosts := forwardProxy(t, node.serve(t))
+
+	// the node's host only resolves through the proxy
+	code, _, stderr := runOutput(t, "-node", "http://arbitrum.node.invalid:8545", "-proxy", proxyURL, "-max-lag", "0")
+	if code != exitOK {
+		t.Fatalf("exit code %d through the proxy; stderr:\n%s", code, stderr)
+	}
+
+	seen := hosts()
+	if len(seen) == 0 {
+		t.Fatal("no request went through the proxy")
+	}
+	for _, host := range seen {
+		if host != "arbitrum.node.invalid:8545" {
+			t.Errorf("proxied a request to %s", host)
+		}
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	proxy, err := proxyFunc("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(proxy).Pointer() != reflect.ValueOf(http.ProxyFromEnvironment).Pointer() {
+		t.Error("without -proxy, the proxy doesn't come from HTTP_PROXY, HTTPS_PROXY and NO_PROXY")
+	}
+
+	proxy, err = proxyFunc("http://proxy.corp:3128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "https://arb1.arbitrum.io/rpc", nil)
+	if u, err := proxy(request); err != nil || u.String() != "http://proxy.corp:3128" {
+		t.Errorf("proxy of %s = %v, %v", request.URL, u, err)
+	}
+
+	for _, raw := range []string{"proxy.corp:3128", "http://", "://proxy"} {
+		if _, err := proxyFunc(raw); err == nil {
+			t.Errorf("proxyFunc(%q) accepted an invalid URL", raw)
+		}
+	}
+}
+
+func TestEndpointCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		bad         bool
+	}{
+		{"captive portal", http.StatusOK, "text/html; charset=utf-8", "<!DOCTYPE html><html>Sign in to the network</html>", true},
+		{"html without content type", http.StatusOK, "", "\n  <html><body>dashboard</body></html>", true},
+		{"html as json", http.StatusOK, "application/json", "<html></html>", true},
+		{"json-rpc", http.StatusOK, "application/json", `{"jsonrpc":"2.0","id":1,"result":"0xa4b1"}`, false},
+		// go-ethereum reports error statuses with their body
+		{"error page", http.StatusBadGateway, "text/html", "<html>502</html>", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				w.WriteHeader(tc.status)
+				io.WriteString(w, tc.body)
+			}))
+			t.Cleanup(server.Close)
+
+			client := &http.Client{Transport: endpointCheck{http.DefaultTransport}}
+			resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}`))
+			if tc.bad {
+				if !errors.Is(err, ErrBadEndpoint) {
+					t.Errorf("err = %v, want ErrBadEndpoint", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			// the peeked bytes are still there
+			if body, _ := io.ReadAll(resp.Body); string(body) != tc.body {
+				t.Errorf("body = %q, want %q", body, tc.body)
+			}
+		})
+	}
+}
+
+func TestRunHTMLEndpoint(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><body>Welcome to the hotel wifi</body></html>")
+	}))
+	t.Cleanup(portal.Close)
+
+	code, _, stderr := runOutput(t, "-node", portal.URL, "-max-lag", "0", "-retries", "2")
+	if code != exitNode {
+		t.Errorf("exit code %d, want %d", code, exitNode)
+	}
+	if !strings.Contains(stderr, ErrBadEndpoint.Error()) || strings.Contains(stderr, "invalid character") {
+		t.Errorf("stderr doesn't explain the endpoint:\n%s", stderr)
+	}
+	// an HTML page isn't retried
+	if requests != 1 {
+		t.Errorf("sent %d requests to the portal, want 1", requests)
+	}
+}
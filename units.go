@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// parseUnits converts a human amount such as "1.5" into raw token units,
+// e.g. 1500000 with 6 decimals. Digits beyond decimals are an error rather
+// than silently dropped.
+func parseUnits(s string, decimals uint8) (*big.Int, error) {
+	whole, frac, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if whole == "" && frac == "" {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(frac) > int(decimals) {
+		return nil, fmt.Errorf("amount %q has more than %d decimals", s, decimals)
+	}
+
+	digits := whole + frac + strings.Repeat("0", int(decimals)-len(frac))
+	if strings.ContainsAny(digits, "+-") {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+
+	raw, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+
+	return raw, nil
+}
+
+// formatUnits is the inverse of parseUnits: raw token units as an exact
+// human amount, e.g. "1.5" for 1500000 with 6 decimals, without trailing
+// zeros.
+func formatUnits(raw *big.Int, decimals uint8) string {
+	digits := new(big.Int).Abs(raw).String()
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+
+	whole, frac := digits[:len(digits)-int(decimals)], strings.TrimRight(digits[len(digits)-int(decimals):], "0")
+	s := whole
+	if frac != "" {
+		s += "." + frac
+	}
+	if raw.Sign() < 0 {
+		s = "-" + s
+	}
+
+	return s
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParseUnits(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		decimals uint8
+		want     string
+	}{
+		{"1.5", 6, "1500000"},
+		{"1", 18, "1000000000000000000"},
+		{"0.000001", 6, "1"},
+		{".25", 2, "25"},
+		{"7.", 1, "70"},
+		{" 42 ", 0, "42"},
+		{"115792089237316195423570985008687907853269984665640564039457.584007913129639935", 18, maxUint.String()},
+	} {
+		got, err := parseUnits(tc.in, tc.decimals)
+		if err != nil || got.String() != tc.want {
+			t.Errorf("parseUnits(%q, %d) = %v, %v, want %s", tc.in, tc.decimals, got, err, tc.want)
+		}
+	}
+
+	for _, in := range []string{"", ".", "1.2345678", "-1", "+1", "1e6", "1,5", "0x10"} {
+		if got, err := parseUnits(in, 6); err == nil {
+			t.Errorf("parseUnits(%q, 6) = %s, want an error", in, got)
+		}
+	}
+}
+
+func TestFormatUnits(t *testing.T) {
+	for _, tc := range []struct {
+		raw      *big.Int
+		decimals uint8
+		want     string
+	}{
+		{big.NewInt(1_500_000), 6, "1.5"},
+		{big.NewInt(1), 18, "0.000000000000000001"},
+		{big.NewInt(0), 6, "0"},
+		{big.NewInt(-2_000_001), 6, "-2.000001"},
+		{big.NewInt(100), 0, "100"},
+	} {
+		if got := formatUnits(tc.raw, tc.decimals); got != tc.want {
+			t.Errorf("formatUnits(%s, %d) = %s, want %s", tc.raw, tc.decimals, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// flagGroups orders the flags in the usage text. Flags missing here are
+// listed under "other flags", so a new flag is never hidden.
+var flagGroups = []struct {
+	title string
+	names []string
+}{
+	{"connection", []string{"node", "nodes", "lb", "fallback-node", "backend", "archive-dir", "pool-abi", "npm-abi", "position-layout", "batch", "proxy", "timeout", "retries", "retry-budget", "max-lag", "config"}},
+	{"query", []string{"pool", "owner", "url", "tick-lower", "tick-upper", "input", "list", "offset", "limit", "min-liquidity", "summary", "discover", "discover-blocks", "since", "fee-rate", "reinvest", "backfill", "backfill-step", "workers", "reconcile", "share", "amounts", "report", "exit-impact", "collects", "activity", "break-even", "project", "token-uri", "token-image", "override", "simulate", "block", "l1-block", "confirmations", "at", "base", "quote", "price", "flagged-tokens", "sign-key"}},
+	{"output", []string{"format", "scaled", "price-precision", "label", "group", "liquidity-profile", "full-addresses", "schema", "chains", "pushgateway", "quiet", "verbose"}},
+	{"monitoring", []string{"watch", "metrics-addr", "block-time", "follow", "dashboard", "fee-threshold0", "fee-threshold1", "min-change0", "min-change1", "token0-decimals", "token1-decimals", "apr-window"}},
+	{"debugging", []string{"decode-calldata", "price-tick", "tick-spacing", "pool-of", "dex", "keys", "diagnose-key", "key-salt", "audit-log", "dry-run", "hex-intermediates", "cpuprofile", "memprofile"}},
+}
+
+const usageExamples = `examples:
+  read the default position on Arbitrum
+    %[1]s
+  read a position of your own
+    %[1]s -pool 0xC6962004f452bE9203591991D15f6b388e09E8D0 -owner 0xYourAddress -tick-lower -197740 -tick-upper -197640
+  list every NFT position of two wallets as a table
+    %[1]s -list -owner 0xFirst,0xSecond -format table
+  follow fees block by block over a websocket node
+    %[1]s -node wss://arbitrum-one-rpc.publicnode.com -follow -fee-threshold1 25
+  read at one block, reproducibly
+    %[1]s -at 250000000 -format json
+
+environment:
+  CHAIN_ID
+    chain to read, e.g. 1 or 42161: picks its default node unless -node is set, and
+    is checked against the chain of -node otherwise; -chains lists the chains
+  HTTP_PROXY, HTTPS_PROXY, NO_PROXY
+    proxy for node requests unless -proxy is set
+`
+
+// printUsage is the -help text: flags by group, examples and environment.
+func printUsage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "usage: %s [flags]\n\nReads Uniswap V3 positions from a node.\n", fs.Name())
+
+	listed := make(map[string]bool)
+	for _, group := range flagGroups {
+		fmt.Fprintf(w, "\n%s flags:\n", group.title)
+		for _, name := range group.names {
+			if f := fs.Lookup(name); f != nil {
+				printFlag(w, f)
+				listed[name] = true
+			}
+		}
+	}
+
+	var other []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		if !listed[f.Name] {
+			other = append(other, f)
+		}
+	})
+	if len(other) > 0 {
+		fmt.Fprintf(w, "\nother flags:\n")
+		for _, f := range other {
+			printFlag(w, f)
+		}
+	}
+
+	fmt.Fprintf(w, "\n"+usageExamples, fs.Name())
+}
+
+// printFlag writes one flag the way flag.PrintDefaults does.
+func printFlag(w io.Writer, f *flag.Flag) {
+	kind, usage := flag.UnquoteUsage(f)
+
+	line := "  -" + f.Name
+	if kind != "" {
+		line += " " + kind
+	}
+	line += "\n    \t" + strings.ReplaceAll(usage, "\n", "\n    \t")
+
+	switch f.DefValue {
+	case "", "0", "false", "0s":
+	default:
+		if kind == "string" {
+			line += fmt.Sprintf(" (default %q)", f.DefValue)
+		} else {
+			line += fmt.Sprintf(" (default %v)", f.DefValue)
+		}
+	}
+
+	fmt.Fprintln(w, line)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestFlagGroupsListEveryFlag(t *testing.T) {
+	fs, _ := newFlagSet()
+
+	grouped := make(map[string]string)
+	for _, group := range flagGroups {
+		for _, name := range group.names {
+			if fs.Lookup(name) == nil {
+				t.Errorf("group %s lists -%s, which isn't a flag", group.title, name)
+			}
+			if other, ok := grouped[name]; ok {
+				t.Errorf("-%s is in groups %s and %s", name, other, group.title)
+			}
+			grouped[name] = group.title
+		}
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := grouped[f.Name]; !ok {
+			t.Errorf("-%s is in no group", f.Name)
+		}
+	})
+}
+
+func TestPrintUsage(t *testing.T) {
+	fs, _ := newFlagSet()
+	var buf bytes.Buffer
+	printUsage(&buf, fs)
+	usage := buf.String()
+
+	for _, want := range []string{
+		"\nconnection flags:\n  -node string\n",
+		`(default "https://arbitrum.llamarpc.com")`,
+		"\nmonitoring flags:\n  -watch\n",
+		"  -retries int\n    \tretries for calls failing with a transport or rate limit error (default 2)\n",
+		"\nexamples:\n",
+		"  CHAIN_ID\n",
+	} {
+		if !strings.Contains(usage, want) {
+			t.Errorf("usage lacks %q:\n%s", want, usage)
+		}
+	}
+	if strings.Contains(usage, "other flags:") {
+		t.Errorf("usage has ungrouped flags:\n%s", usage)
+	}
+	// groups in order
+	if strings.Index(usage, "connection flags:") > strings.Index(usage, "debugging flags:") {
+		t.Error("connection flags come after debugging flags")
+	}
+}
+
+func TestRunHelp(t *testing.T) {
+	code, stdout, stderr := runOutput(t, "-h")
+	if code != exitOK || !strings.Contains(stdout+stderr, "query flags:") {
+		t.Errorf("exit code %d, want %d and the grouped usage; stdout:\n%s\nstderr:\n%s", code, exitOK, stdout, stderr)
+	}
+}
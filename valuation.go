@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PriceOverrides are manual prices of tokens, per whole token, that
+// valuations use instead of the pool price, e.g. for stablecoins or tokens
+// without a liquid pool. The prices of a pool's two tokens are in one unit,
+// such as USD; when only one of them has a price, it is in whole units of the
+// other.
+type PriceOverrides map[common.Address]*big.Float
+
+// WithPriceOverrides values positions at prices, wherever a token of the
+// pool has one, instead of at the pool price: in reports, groups and
+// portfolio summaries.
+func WithPriceOverrides(prices PriceOverrides) Option {
+	return func(o *clientOptions) {
+		o.prices = prices
+	}
+}
+
+// rate is the whole token1 per whole token0 price p gives a pool of token0
+// and token1, or false when neither token has a price.
+func (p PriceOverrides) rate(token0, token1 common.Address) (*big.Float, bool) {
+	price0, ok0 := p[token0]
+	price1, ok1 := p[token1]
+
+	switch {
+	case ok0 && ok1:
+		return new(big.Float).SetPrec(pricePrec).Quo(price0, price1), true
+	case ok0:
+		return new(big.Float).SetPrec(pricePrec).Set(price0), true
+	case ok1:
+		return new(big.Float).SetPrec(pricePrec).Quo(big.NewFloat(1), price1), true
+	}
+
+	return nil, false
+}
+
+// valuationPrice is the sqrtPriceX96 a position of a pool of token0 and
+// token1, of dec0 and dec1 decimals, is valued at: the rate of the price
+// overrides of its tokens, or else the pool's sqrtPriceX96 spot.
+func (c *Client) valuationPrice(token0, token1 common.Address, dec0, dec1 uint8, spot *big.Int) *big.Int {
+	rate, ok := c.prices.rate(token0, token1)
+	if !ok {
+		return spot
+	}
+
+	sqrtPriceX96, _ := SqrtPriceX96FromPrice(rate, dec0, dec1)
+	return sqrtPriceX96
+}
+
+// overrideDecimals reads at block, in one batch, the decimals of the tokens
+// of the pools in pairs, token0 and token1, that have a price override, which
+// valuationPrice needs to scale it. Tokens whose decimals() reverts are
+// assumed to have defaultDecimals, with a warning.
+func (c *Client) overrideDecimals(ctx context.Context, block *big.Int, pairs [][2]common.Address) (map[common.Address]uint8, error) {
+	decimals := make(map[common.Address]uint8)
+
+	var tokens []*TokenInfo
+	seen := make(map[common.Address]bool)
+	for _, pair := range pairs {
+		if _, ok := c.prices.rate(pair[0], pair[1]); !ok {
+			continue
+		}
+		for _, token := range pair {
+			if !seen[token] {
+				seen[token] = true
+				tokens = append(tokens, &TokenInfo{Address: token})
+			}
+		}
+	}
+	if len(tokens) == 0 {
+		return decimals, nil
+	}
+
+	warnings, err := c.readDecimals(ctx, block, tokens...)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		log.Print("warning: ", warning)
+	}
+	for _, token := range tokens {
+		decimals[token.Address] = token.Decimals
+	}
+
+	return decimals, nil
+}
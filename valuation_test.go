@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestPriceOverridesRate(t *testing.T) {
+	token0, token1 := common.HexToAddress("0xa"), common.HexToAddress("0xb")
+
+	for _, tc := range []struct {
+		name   string
+		prices PriceOverrides
+		want   float64
+	}{
+		{"both", PriceOverrides{token0: big.NewFloat(3), token1: big.NewFloat(1.5)}, 2},
+		{"token0", PriceOverrides{token0: big.NewFloat(3)}, 3},
+		{"token1", PriceOverrides{token1: big.NewFloat(4)}, 0.25},
+	} {
+		rate, ok := tc.prices.rate(token0, token1)
+		if got, _ := rate.Float64(); !ok || got != tc.want {
+			t.Errorf("%s: rate = %v, %t, want %g", tc.name, rate, ok, tc.want)
+		}
+	}
+
+	if _, ok := (PriceOverrides{testOwner: big.NewFloat(1)}).rate(token0, token1); ok {
+		t.Error("a price of neither token applies")
+	}
+}
+
+func TestReportPriceOverride(t *testing.T) {
+	node, q := reportNode(t)
+	spot, err := newFakeClient(t, node).Report(context.Background(), q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// token0 of the pool at price 1 is worth 1.0003 token1 instead
+	node, _ = reportNode(t)
+	token0 := common.HexToAddress("0x000000000000000000000000000000000000000a")
+	client := newFakeClient(t, node, WithPriceOverrides(PriceOverrides{token0: big.NewFloat(1.0003)}))
+	r, err := client.Report(context.Background(), q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Price.Cmp(spot.Price) != 0 || r.Amount0.Cmp(spot.Amount0) != 0 {
+		t.Errorf("price, amount0 = %s, %s, want the pool's %s, %s", r.Price, r.Amount0, spot.Price, spot.Amount0)
+	}
+	if got, _ := r.ValuePrice.Float64(); got != 1.0003 {
+		t.Errorf("value price = %s, want 1.0003", r.ValuePrice)
+	}
+	held0, _ := new(big.Float).SetInt(new(big.Int).Add(r.Amount0, r.Fees0)).Float64()
+	held1, _ := new(big.Float).SetInt(new(big.Int).Add(r.Amount1, r.Fees1)).Float64()
+	if got, _ := new(big.Float).SetInt(r.Value).Float64(); !closeTo(got, held0*1.0003+held1) {
+		t.Errorf("value = %s, want %g", r.Value, held0*1.0003+held1)
+	}
+}
+
+func TestRunPriceOverride(t *testing.T) {
+	token0 := "0x000000000000000000000000000000000000000a"
+	node, _ := reportNode(t)
+	code, stdout, stderr := runOutput(t, "-node", node.serve(t), "-max-lag", "0", "-pool", testPool.Hex(), "-owner", testOwner.Hex(),
+		"-tick-lower", "-60", "-tick-upper", "60", "-report", "-price", token0+"=1.0003")
+	if code != exitOK {
+		t.Fatalf("exit code = %d; stderr:\n%s", code, stderr)
+	}
+	if !strings.Contains(stdout, " token1 at price 1.0003\n") {
+		t.Errorf("output lacks the value at price 1.0003:\n%s", stdout)
+	}
+
+	for _, price := range []string{token0, token0 + "=0", token0 + "=-1", token0 + "=abc", "0x0a=1"} {
+		if code, _, _ := runOutput(t, "-price", price); code != exitBadInput {
+			t.Errorf("-price %s: exit code = %d, want %d", price, code, exitBadInput)
+		}
+	}
+}
+
+func TestGroupByPoolPriceOverride(t *testing.T) {
+	pool := newFakePool(0, 1e18)
+	decimals := map[common.Address]uint8{pool.token0: 18, pool.token1: 6}
+	// token1 is worth half a token0: 2 whole token1, 2e-12 raw, per token0
+	prices := PriceOverrides{pool.token1: big.NewFloat(0.5)}
+	client := newFakeClient(t, &fakeNode{head: 100, handle: poolsHandler(t, map[common.Address]*fakePool{testPool: pool}, decimals)}, WithPriceOverrides(prices))
+
+	results := []PositionResult{{Pool: testPool, Owner: testOwner, Token0: pool.token0, Token1: pool.token1, TickLower: -60, TickUpper: 60, Position: Position{
+		Liquidity:                big.NewInt(1e18),
+		FeeGrowthInside0LastX128: new(big.Int),
+		FeeGrowthInside1LastX128: new(big.Int),
+		TokensOwed0:              new(big.Int),
+		TokensOwed1:              new(big.Int),
+	}}}
+	groups, _, err := client.GroupByPool(context.Background(), results, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amount0, amount1 := AmountsForLiquidity(Tick(0).SqrtRatio(), -60, 60, big.NewInt(1e18))
+	a0, _ := new(big.Float).SetInt(amount0).Float64()
+	a1, _ := new(big.Float).SetInt(amount1).Float64()
+	if got, _ := new(big.Float).SetInt(groups[0].Value).Float64(); len(groups) != 1 || !closeTo(got, a0*2e-12+a1) {
+		t.Errorf("value = %s, want %g", groups[0].Value, a0*2e-12+a1)
+	}
+}
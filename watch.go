@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// PositionUpdate is a position read at a new head.
+type PositionUpdate struct {
+	Block    uint64   `json:"block"`
+	Position Position `json:"position"`
+}
+
+type headSubscriber interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// subscriptionManager keeps a new-head subscription alive: it resubscribes
+// with backoff when the subscription fails or no head arrives for too long.
+type subscriptionManager struct {
+	sub          headSubscriber
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	stallTimeout time.Duration
+	logger       *log.Logger
+}
+
+// newSubscriptionManager times the subscription by the chain's blockTime: it
+// gives up on a subscription after 10 blocks without a head and backs off up
+// to 5 blocks, from a second or a block, whichever is shorter. On mainnet
+// that is 2m and 1s to 1m.
+func newSubscriptionManager(sub headSubscriber, blockTime time.Duration) *subscriptionManager {
+	return &subscriptionManager{
+		sub:          sub,
+		minBackoff:   min(time.Second, blockTime),
+		maxBackoff:   5 * blockTime,
+		stallTimeout: 10 * blockTime,
+		logger:       log.Default(),
+	}
+}
+
+// WithBlockTime times -watch resubscriptions by blockTime instead of the
+// block time of the node's chain.
+func WithBlockTime(blockTime time.Duration) Option {
+	return func(o *clientOptions) {
+		o.blockTime = blockTime
+	}
+}
+
+// headBlockTime is the block time new-head subscriptions are timed by: the
+// WithBlockTime one, or else that of the node's chain, or defaultBlockTime
+// when the chain isn't in the registry.
+func (c *Client) headBlockTime(ctx context.Context) time.Duration {
+	if c.blockTime > 0 {
+		return c.blockTime
+	}
+
+	ch, err := c.chain(ctx)
+	if err != nil || ch.BlockTime == 0 {
+		return defaultBlockTime
+	}
+
+	return ch.BlockTime
+}
+
+var errHeadStall = errors.New("no new head")
+
+// run sends heads to out until ctx is done. out is never closed by run, so
+// callers keep the same channel across reconnects.
+func (m *subscriptionManager) run(ctx context.Context, out chan<- *types.Header) error {
+	backoff := m.minBackoff
+	var last uint64
+
+	for attempt := 0; ; attempt++ {
+		err := m.subscribeOnce(ctx, out, &last, func() { backoff = m.minBackoff })
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		m.logger.Printf("watch: resubscribing event=reconnect attempt=%d backoff=%s last_block=%d err=%q", attempt+1, backoff, last, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, m.maxBackoff)
+	}
+}
+
+// subscribeOnce forwards heads from a single subscription until it fails.
+// healthy is called on every delivered head.
+func (m *subscriptionManager) subscribeOnce(ctx context.Context, out chan<- *types.Header, last *uint64, healthy func()) error {
+	heads := make(chan *types.Header)
+
+	sub, err := m.sub.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	stall := time.NewTimer(m.stallTimeout)
+	defer stall.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-sub.Err():
+			if !ok || err == nil {
+				return errors.New("subscription closed")
+			}
+			return err
+		case <-stall.C:
+			return errHeadStall
+		case head := <-heads:
+			number := head.Number.Uint64()
+			if *last != 0 && number > *last+1 {
+				m.logger.Printf("watch: head gap event=gap from=%d to=%d", *last, number)
+			}
+			*last = number
+			healthy()
+
+			if !stall.Stop() {
+				<-stall.C
+			}
+			stall.Reset(m.stallTimeout)
+
+			select {
+			case out <- head:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// eachHead calls fn for every new head until ctx is done or fn fails. It
+// needs a websocket or IPC node.
+func (c *Client) eachHead(ctx context.Context, fn func(*types.Header) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	heads := make(chan *types.Header)
+	errc := make(chan error, 1)
+
+	go func() {
+		errc <- newSubscriptionManager(c.eth, c.headBlockTime(ctx)).run(ctx, heads)
+	}()
+
+	for {
+		select {
+		case err := <-errc:
+			return err
+		case head := <-heads:
+			if err := fn(head); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WatchPosition reads q at every new head and sends it to out until ctx is
+// done.
+func (c *Client) WatchPosition(ctx context.Context, q PositionQuery, out chan<- PositionUpdate) error {
+	return c.eachHead(ctx, func(head *types.Header) error {
+		position, err := c.Position(ctx, q, head.Number)
+		if err != nil {
+			log.Printf("watch: read position block=%s err=%q", head.Number, err)
+			return nil
+		}
+
+		select {
+		case out <- PositionUpdate{Block: head.Number.Uint64(), Position: position}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type fakeSubscription struct {
+	errc chan error
+	once sync.Once
+	quit chan struct{}
+}
+
+func (s *fakeSubscription) Err() <-chan error { return s.errc }
+
+func (s *fakeSubscription) Unsubscribe() {
+	s.once.Do(func() { close(s.quit) })
+}
+
+// fakeHeads serves one subscription per entry of blocks, each sending its
+// heads and then failing with dropped. A nil entry, and every subscription
+// after the last entry, stays open and silent.
+type fakeHeads struct {
+	blocks  [][]uint64
+	dropped error
+
+	mu         sync.Mutex
+	subscribed int
+}
+
+func (f *fakeHeads) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	f.mu.Lock()
+	n := f.subscribed
+	f.subscribed++
+	f.mu.Unlock()
+
+	sub := &fakeSubscription{errc: make(chan error, 1), quit: make(chan struct{})}
+	if n >= len(f.blocks) || f.blocks[n] == nil {
+		return sub, nil
+	}
+
+	go func() {
+		for _, number := range f.blocks[n] {
+			select {
+			case ch <- &types.Header{Number: new(big.Int).SetUint64(number)}:
+			case <-sub.quit:
+				return
+			}
+		}
+		sub.errc <- f.dropped
+	}()
+
+	return sub, nil
+}
+
+func (f *fakeHeads) subscriptions() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.subscribed
+}
+
+func testManager(sub headSubscriber, logs *bytes.Buffer) *subscriptionManager {
+	m := newSubscriptionManager(sub, defaultBlockTime)
+	m.minBackoff, m.maxBackoff, m.stallTimeout = time.Millisecond, 4*time.Millisecond, time.Hour
+	m.logger = log.New(logs, "", 0)
+
+	return m
+}
+
+// receive reads n heads from out, failing the test after a second.
+func receive(t *testing.T, out <-chan *types.Header, n int) []uint64 {
+	t.Helper()
+
+	var numbers []uint64
+	for len(numbers) < n {
+		select {
+		case head := <-out:
+			numbers = append(numbers, head.Number.Uint64())
+		case <-time.After(time.Second):
+			t.Fatalf("got heads %v, want %d", numbers, n)
+		}
+	}
+
+	return numbers
+}
+
+func TestSubscriptionManagerReconnects(t *testing.T) {
+	sub := &fakeHeads{blocks: [][]uint64{{10, 11}, {12, 15}}, dropped: errors.New("websocket: close 1006")}
+	var logs bytes.Buffer
+	m := testManager(sub, &logs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan *types.Header)
+	errc := make(chan error, 1)
+	go func() { errc <- m.run(ctx, out) }()
+
+	got := receive(t, out, 4)
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("run = %v, want context.Canceled", err)
+	}
+
+	// the same output channel across the drop
+	if want := []uint64{10, 11, 12, 15}; !slices.Equal(got, want) {
+		t.Errorf("heads = %v, want %v", got, want)
+	}
+	if n := sub.subscriptions(); n < 2 {
+		t.Errorf("subscribed %d times, want a resubscription", n)
+	}
+	for _, want := range []string{
+		`event=reconnect attempt=1 backoff=1ms last_block=11 err="websocket: close 1006"`,
+		"event=gap from=12 to=15",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log lacks %q:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestSubscriptionManagerStall(t *testing.T) {
+	// the first subscription never sends a head
+	sub := &fakeHeads{blocks: [][]uint64{nil, {7}}}
+	var logs bytes.Buffer
+	m := testManager(sub, &logs)
+	m.stallTimeout = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan *types.Header)
+	errc := make(chan error, 1)
+	go func() { errc <- m.run(ctx, out) }()
+
+	if got := receive(t, out, 1); got[0] != 7 {
+		t.Errorf("head = %d, want 7", got[0])
+	}
+	cancel()
+	<-errc
+	if !strings.Contains(logs.String(), `err="no new head"`) {
+		t.Errorf("log lacks the stall:\n%s", logs.String())
+	}
+}
+
+func TestSubscriptionManagerBlockTime(t *testing.T) {
+	mainnet, _ := chainByID(1)
+	arbitrum, _ := chainByID(42161)
+
+	m := newSubscriptionManager(nil, mainnet.BlockTime)
+	if m.minBackoff != time.Second || m.maxBackoff != time.Minute || m.stallTimeout != 2*time.Minute {
+		t.Errorf("mainnet backoff %s to %s, stall %s; want 1s to 1m, stall 2m", m.minBackoff, m.maxBackoff, m.stallTimeout)
+	}
+	l2 := newSubscriptionManager(nil, arbitrum.BlockTime)
+	if l2.minBackoff >= m.minBackoff || l2.stallTimeout >= m.stallTimeout {
+		t.Errorf("arbitrum backoff %s, stall %s; want shorter than mainnet's %s, %s", l2.minBackoff, l2.stallTimeout, m.minBackoff, m.stallTimeout)
+	}
+
+	// an override wins over the chain's
+	client := newFakeClient(t, &fakeNode{head: 100}, WithBlockTime(time.Second))
+	if got := client.headBlockTime(context.Background()); got != time.Second {
+		t.Errorf("block time = %s, want the 1s override", got)
+	}
+}
+
+func TestRunBlockTimeFlag(t *testing.T) {
+	for _, args := range [][]string{{"-block-time", "1s"}, {"-watch", "-block-time", "-1s"}} {
+		if code, _, _ := runOutput(t, args...); code != exitBadInput {
+			t.Errorf("%q: exit code = %d, want %d", args, code, exitBadInput)
+		}
+	}
+}